@@ -4,8 +4,10 @@ go 1.25.4
 
 require (
 	github.com/AlecAivazis/survey/v2 v2.3.7
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/spf13/cobra v1.10.2
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
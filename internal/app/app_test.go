@@ -1300,6 +1300,97 @@ func TestValidateVariables(t *testing.T) {
 	}
 }
 
+func TestValidateVariables_NumericConstraints(t *testing.T) {
+	minRatio, maxRatio := 0.0, 1.0
+	minPort := 1.0
+	ignJson := &model.IgnJson{
+		Variables: map[string]model.VarDef{
+			"ratio": {Type: model.VarTypeNumber, Min: &minRatio, Max: &maxRatio},
+			"port":  {Type: model.VarTypeInt, Min: &minPort},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		vars    map[string]interface{}
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "within range",
+			vars:    map[string]interface{}{"ratio": 0.5, "port": float64(8080)},
+			wantErr: false,
+		},
+		{
+			name:    "integer value for number",
+			vars:    map[string]interface{}{"ratio": 1},
+			wantErr: false,
+		},
+		{
+			// ign-var.json may store numbers as strings; rendering coerces them.
+			name:    "numeric strings",
+			vars:    map[string]interface{}{"ratio": "0.5", "port": "8080"},
+			wantErr: false,
+		},
+		{
+			// Rendering parses int strings with strconv.Atoi.
+			name:    "float string for int",
+			vars:    map[string]interface{}{"port": "8080.0"},
+			wantErr: true,
+			errMsg:  "port must be an integer",
+		},
+		{
+			name:    "exponent string for int",
+			vars:    map[string]interface{}{"port": "1e3"},
+			wantErr: true,
+			errMsg:  "port must be an integer",
+		},
+		{
+			name:    "numeric string below min",
+			vars:    map[string]interface{}{"port": "0"},
+			wantErr: true,
+			errMsg:  "port must be >= 1",
+		},
+		{
+			name:    "number above max",
+			vars:    map[string]interface{}{"ratio": 1.5},
+			wantErr: true,
+			errMsg:  "ratio must be <= 1",
+		},
+		{
+			name:    "int below min",
+			vars:    map[string]interface{}{"port": 0},
+			wantErr: true,
+			errMsg:  "port must be >= 1",
+		},
+		{
+			name:    "fractional value for int",
+			vars:    map[string]interface{}{"port": 80.5},
+			wantErr: true,
+			errMsg:  "port must be an integer",
+		},
+		{
+			name:    "string value for number",
+			vars:    map[string]interface{}{"ratio": "half"},
+			wantErr: true,
+			errMsg:  "ratio must be a number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVariables(ignJson, parser.NewMapVariables(tt.vars))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateVariables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error message to contain %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
 func TestAppErrors(t *testing.T) {
 	tests := []struct {
 		name      string
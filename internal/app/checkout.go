@@ -68,6 +68,15 @@ type CompleteCheckoutOptions struct {
 	Verbose bool
 	// GitHubToken is the GitHub personal access token (optional).
 	GitHubToken string
+	// Archive, when set, receives the project as an ArchiveFormat archive
+	// instead of writing it under OutputDir. OutputDir becomes the directory
+	// the files are stored under inside the archive.
+	Archive io.Writer
+	// ArchiveFormat is the format written to Archive.
+	ArchiveFormat generator.ArchiveFormat
+	// History, when set, collects the checkout's changes into a snapshot the
+	// caller commits. Otherwise checkout records and commits its own.
+	History *HistoryRecorder
 }
 
 // DryRunFile contains information about a file that would be created in dry-run mode.
@@ -82,6 +91,17 @@ type DryRunFile struct {
 	WouldOverwrite bool
 	// WouldSkip indicates if the file would be skipped.
 	WouldSkip bool
+	// Injected is set when Content is an existing file with template
+	// injections applied.
+	Injected bool
+	// SymlinkTarget is set when the entry is a symlink; Content then holds
+	// the target.
+	SymlinkTarget string
+	// Mode is the permission a written regular file would get.
+	Mode os.FileMode
+	// ProtectedByProject is set when the project's .ign/overwrite-ignore
+	// makes update skip the path.
+	ProtectedByProject bool
 }
 
 // CheckoutResult contains the results of project checkout.
@@ -96,10 +116,19 @@ type CheckoutResult struct {
 	Errors []error
 	// Files contains the paths of all files processed.
 	Files []string
+	// ExcludedFiles contains template paths excluded by file conditions.
+	ExcludedFiles []string
+	// InjectionsApplied is the number of injections applied to existing files.
+	InjectionsApplied int
+	// Hooks lists the post_checkout hooks that apply to this project. They are
+	// not run by CompleteCheckout; see RunHooks.
+	Hooks []generator.ResolvedHook
 	// DryRunFiles contains detailed information for dry-run mode.
 	DryRunFiles []DryRunFile
 	// Directories contains directories that would be created (dry-run only).
 	Directories []string
+	// HistoryID is the undo snapshot recorded for this checkout, if any.
+	HistoryID string
 }
 
 // maxBackups is the maximum number of backup files allowed to prevent infinite loops
@@ -302,6 +331,10 @@ func CompleteCheckout(ctx context.Context, opts CompleteCheckoutOptions) (*Check
 	vars := preparedInputs.RuntimeVariables
 	prep := opts.PrepareResult
 
+	if opts.Archive != nil {
+		return completeCheckoutToArchive(ctx, opts, rawVars, vars)
+	}
+
 	// Create generator
 	gen := generator.NewGenerator()
 
@@ -314,15 +347,39 @@ func CompleteCheckout(ctx context.Context, opts CompleteCheckoutOptions) (*Check
 		Verbose:   opts.Verbose,
 	}
 
+	hooks, err := generator.ResolveHooks(ctx, prep.Template, generator.HookPostCheckout, vars, opts.OutputDir)
+	if err != nil {
+		return nil, NewCheckoutError("invalid template hooks", err)
+	}
+
 	// Generate or dry run
 	var genResult *generator.GenerateResult
 	var rollback *checkoutGenerationRollback
+	history := opts.History
 	if !opts.DryRun {
+		lock, err := LockProject(ctx, configDir, "checkout")
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
 		rollback, err = prepareCheckoutGenerationRollback(ctx, gen, genOpts)
 		if err != nil {
 			return nil, err
 		}
 		defer rollback.cleanup()
+		if history == nil {
+			history, err = BeginHistory(ctx, configDir, "checkout")
+			if err != nil {
+				return nil, NewCheckoutError("failed to start history snapshot", err)
+			}
+			defer history.Discard()
+		}
+		if err := history.captureGeneration(rollback.preview); err != nil {
+			return nil, NewCheckoutError("failed to record history snapshot", err)
+		}
+		if err := history.captureConfig(configDir); err != nil {
+			return nil, NewCheckoutError("failed to record history snapshot", err)
+		}
 	}
 	if opts.DryRun {
 		debug.Debug("[app] Starting dry run generation")
@@ -353,12 +410,18 @@ func CompleteCheckout(ctx context.Context, opts CompleteCheckoutOptions) (*Check
 
 	// Convert generator result to checkout result
 	result := &CheckoutResult{
-		FilesCreated:     genResult.FilesCreated,
-		FilesSkipped:     genResult.FilesSkipped,
-		FilesOverwritten: genResult.FilesOverwritten,
-		Errors:           genResult.Errors,
-		Files:            genResult.Files,
-		Directories:      genResult.Directories,
+		FilesCreated:      genResult.FilesCreated,
+		FilesSkipped:      genResult.FilesSkipped,
+		FilesOverwritten:  genResult.FilesOverwritten,
+		Errors:            genResult.Errors,
+		Files:             genResult.Files,
+		ExcludedFiles:     genResult.ExcludedFiles,
+		InjectionsApplied: len(genResult.Injections),
+		Hooks:             hooks,
+		Directories:       genResult.Directories,
+	}
+	if !opts.DryRun && opts.History == nil {
+		result.HistoryID = commitHistory(history, &result.Errors)
 	}
 
 	// Convert dry-run files
@@ -379,8 +442,26 @@ func CompleteCheckout(ctx context.Context, opts CompleteCheckoutOptions) (*Check
 	return result, nil
 }
 
+// newCheckoutIgnConfig builds the .ign/ign.json written by checkout.
+func newCheckoutIgnConfig(prep *PrepareCheckoutResult) *model.IgnConfig {
+	return &model.IgnConfig{
+		Template: model.TemplateSource{
+			URL:  prep.NormalizedURL,
+			Path: prep.TemplateRef.Path,
+			Ref:  prep.TemplateRef.Ref,
+		},
+		Hash: prep.IgnJson.Hash,
+		Metadata: &model.FileMetadata{
+			GeneratedAt:     time.Now(),
+			GeneratedBy:     "ign checkout",
+			TemplateName:    prep.IgnJson.Name,
+			TemplateVersion: prep.IgnJson.Version,
+			IgnVersion:      build.Version(),
+		},
+	}
+}
+
 func saveCompleteCheckoutArtifacts(configDir string, prep *PrepareCheckoutResult, rawVars map[string]interface{}, genResult *generator.GenerateResult, rollback *checkoutGenerationRollback) error {
-	templateHash := prep.IgnJson.Hash
 	ignConfigPath := filepath.Join(configDir, model.IgnProjectConfigFile)
 	ignVarPath := filepath.Join(configDir, model.IgnVarFile)
 	manifestPath := manifestPathFromConfigPath(ignConfigPath)
@@ -402,21 +483,7 @@ func saveCompleteCheckoutArtifacts(configDir string, prep *PrepareCheckoutResult
 	}
 
 	debug.Debug("[app] Creating ign.json")
-	ignConfig := &model.IgnConfig{
-		Template: model.TemplateSource{
-			URL:  prep.NormalizedURL,
-			Path: prep.TemplateRef.Path,
-			Ref:  prep.TemplateRef.Ref,
-		},
-		Hash: templateHash,
-		Metadata: &model.FileMetadata{
-			GeneratedAt:     time.Now(),
-			GeneratedBy:     "ign checkout",
-			TemplateName:    prep.IgnJson.Name,
-			TemplateVersion: prep.IgnJson.Version,
-			IgnVersion:      build.Version(),
-		},
-	}
+	ignConfig := newCheckoutIgnConfig(prep)
 
 	debug.Debug("[app] Creating ign-var.json")
 	ignVarJson := &model.IgnVarJson{
@@ -476,6 +543,8 @@ type checkoutGenerationRollback struct {
 	createdDirs                    []checkoutRollbackEntry
 	backupDir                      string
 	retainBackup                   bool
+	// preview is the dry run the rollback was prepared from.
+	preview *generator.GenerateResult
 }
 
 type checkoutRollbackEntry struct {
@@ -505,6 +574,7 @@ func prepareCheckoutGenerationRollback(ctx context.Context, gen generator.Genera
 	if err != nil {
 		return nil, NewCheckoutError("generation failed", err)
 	}
+	rollback.preview = dryRunResult
 
 	if err := rollback.captureOverwrittenFiles(dryRunResult, genOpts.SymlinkTransitions); err != nil {
 		rollback.cleanup()
@@ -851,6 +921,11 @@ func Checkout(ctx context.Context, opts CheckoutOptions) (*CheckoutResult, error
 	var genResult *generator.GenerateResult
 	var rollback *checkoutGenerationRollback
 	if !opts.DryRun {
+		lock, err := LockProject(ctx, configDir, "checkout")
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
 		rollback, err = prepareCheckoutGenerationRollback(ctx, gen, genOpts)
 		if err != nil {
 			return nil, err
@@ -924,12 +999,14 @@ func Checkout(ctx context.Context, opts CheckoutOptions) (*CheckoutResult, error
 
 	// Convert generator result to checkout result
 	result := &CheckoutResult{
-		FilesCreated:     genResult.FilesCreated,
-		FilesSkipped:     genResult.FilesSkipped,
-		FilesOverwritten: genResult.FilesOverwritten,
-		Errors:           genResult.Errors,
-		Files:            genResult.Files,
-		Directories:      genResult.Directories,
+		FilesCreated:      genResult.FilesCreated,
+		FilesSkipped:      genResult.FilesSkipped,
+		FilesOverwritten:  genResult.FilesOverwritten,
+		Errors:            genResult.Errors,
+		Files:             genResult.Files,
+		ExcludedFiles:     genResult.ExcludedFiles,
+		InjectionsApplied: len(genResult.Injections),
+		Directories:       genResult.Directories,
 	}
 
 	// Convert dry-run files
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+// completeCheckoutToArchive generates the project into opts.Archive. The
+// archive holds the generated files under OutputDir and the .ign tracking
+// files, so extracting it and running "ign update" there behaves as after a
+// regular checkout. Nothing is written to the filesystem.
+func completeCheckoutToArchive(ctx context.Context, opts CompleteCheckoutOptions, rawVars map[string]interface{}, vars parser.Variables) (*CheckoutResult, error) {
+	prep := opts.PrepareResult
+	debug.DebugValue("[app] ArchiveFormat", opts.ArchiveFormat)
+
+	if opts.DryRun {
+		return nil, NewValidationError("dry run cannot write an archive", nil)
+	}
+	if filepath.IsAbs(opts.OutputDir) {
+		return nil, NewValidationError("output directory must be relative when writing an archive", nil)
+	}
+
+	archive, err := generator.NewArchiveWriter(opts.Archive, opts.ArchiveFormat, prep.Template.Config.Settings.PreserveExecutableEnabled())
+	if err != nil {
+		return nil, NewValidationError("invalid archive format", err)
+	}
+
+	hooks, err := generator.ResolveHooks(ctx, prep.Template, generator.HookPostCheckout, vars, opts.OutputDir)
+	if err != nil {
+		return nil, NewCheckoutError("invalid template hooks", err)
+	}
+
+	gen := generator.NewGeneratorWithWriter(archive)
+	genResult, err := gen.Generate(ctx, generator.GenerateOptions{
+		Template:  prep.Template,
+		Variables: vars,
+		OutputDir: opts.OutputDir,
+		Verbose:   opts.Verbose,
+	})
+	if err != nil {
+		return nil, NewCheckoutError("generation failed", err)
+	}
+
+	artifacts := []struct {
+		name  string
+		value interface{}
+	}{
+		{model.IgnProjectConfigFile, newCheckoutIgnConfig(prep)},
+		{model.IgnVarFile, &model.IgnVarJson{Variables: rawVars}},
+		{model.IgnManifestFile, manifestFromGenerateResult(&model.IgnManifest{Files: []string{}}, genResult, nil)},
+	}
+	for _, artifact := range artifacts {
+		data, err := json.MarshalIndent(artifact.value, "", "  ")
+		if err != nil {
+			return nil, NewCheckoutError("failed to marshal "+artifact.name, err)
+		}
+		if err := archive.WriteFile(filepath.Join(model.IgnConfigDir, artifact.name), data, 0644); err != nil {
+			return nil, NewCheckoutError("failed to archive "+artifact.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, NewCheckoutError("failed to finish archive", err)
+	}
+
+	debug.Debug("[app] CompleteCheckout archive written successfully")
+	return &CheckoutResult{
+		FilesCreated:  genResult.FilesCreated,
+		Errors:        genResult.Errors,
+		Files:         genResult.Files,
+		ExcludedFiles: genResult.ExcludedFiles,
+		Hooks:         hooks,
+	}, nil
+}
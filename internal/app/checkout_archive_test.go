@@ -0,0 +1,117 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func TestCompleteCheckout_WritesArchiveWithoutTouchingFilesystem(t *testing.T) {
+	tempDir := t.TempDir()
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("failed to change to temp directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(origDir) }()
+
+	// An existing file at the output path must neither be skipped nor read.
+	if err := os.MkdirAll("my-app", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("my-app/README.md", []byte("local copy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "archive-test",
+			Version: "1.0.0",
+			Hash:    strings.Repeat("a", 64),
+			Variables: map[string]model.VarDef{
+				"name": {Type: model.VarTypeString, Required: true},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "README.md", Content: []byte("# @ign-var:name@\n"), Mode: 0644},
+			{Path: "cmd/@ign-var:name@/main.go", Content: []byte("package main\n"), Mode: 0644},
+		},
+	}
+	prep := &PrepareCheckoutResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		TemplateRef:   model.TemplateRef{Provider: "local", Repo: "archive-test"},
+		NormalizedURL: "./template",
+	}
+
+	var archive bytes.Buffer
+	result, err := CompleteCheckout(context.Background(), CompleteCheckoutOptions{
+		PrepareResult: prep,
+		Variables:     map[string]interface{}{"name": "demo"},
+		OutputDir:     "my-app",
+		Archive:       &archive,
+		ArchiveFormat: generator.ArchiveTar,
+	})
+	if err != nil {
+		t.Fatalf("CompleteCheckout failed: %v", err)
+	}
+	if result.FilesCreated != 2 {
+		t.Errorf("FilesCreated = %d, want 2", result.FilesCreated)
+	}
+
+	if _, err := os.Stat(model.IgnConfigDir); !os.IsNotExist(err) {
+		t.Errorf(".ign should not be created on disk: %v", err)
+	}
+	if data, _ := os.ReadFile("my-app/README.md"); string(data) != "local copy" {
+		t.Errorf("existing file was modified: %q", data)
+	}
+
+	entries := map[string]string{}
+	tr := tar.NewReader(&archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		entries[header.Name] = string(content)
+	}
+
+	if entries["my-app/README.md"] != "# demo\n" {
+		t.Errorf("README.md entry = %q", entries["my-app/README.md"])
+	}
+	if _, ok := entries["my-app/cmd/demo/main.go"]; !ok {
+		t.Errorf("archive is missing my-app/cmd/demo/main.go: %v", entries)
+	}
+
+	var manifest model.IgnManifest
+	if err := json.Unmarshal([]byte(entries[".ign/ign-files.json"]), &manifest); err != nil {
+		t.Fatalf("archive manifest: %v", err)
+	}
+	if len(manifest.Files) != 2 || manifest.Files[0] != "my-app/README.md" {
+		t.Errorf("manifest files = %v", manifest.Files)
+	}
+	var ignConfig model.IgnConfig
+	if err := json.Unmarshal([]byte(entries[".ign/ign.json"]), &ignConfig); err != nil {
+		t.Fatalf("archive ign.json: %v", err)
+	}
+	if ignConfig.Template.URL != "./template" || ignConfig.Hash != template.Config.Hash {
+		t.Errorf("ign.json = %+v", ignConfig)
+	}
+	if !strings.Contains(entries[".ign/ign-var.json"], `"demo"`) {
+		t.Errorf("ign-var.json = %s", entries[".ign/ign-var.json"])
+	}
+}
@@ -87,13 +87,16 @@ func Init(ctx context.Context, opts InitOptions) error {
 
 // CompleteInit saves initialization configuration after template preparation.
 func CompleteInit(ctx context.Context, opts CompleteInitOptions) error {
-	_ = ctx
-
 	configDir := model.IgnConfigDir
 	prepResult := opts.PrepareResult
 	if prepResult == nil {
 		return NewValidationError("prepare result cannot be nil", nil)
 	}
+	lock, err := LockProject(ctx, configDir, "init")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
 	templateHash := prepResult.IgnJson.Hash
 	debug.DebugValue("[app] Template hash from ign-template.json", templateHash)
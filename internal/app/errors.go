@@ -16,6 +16,13 @@ const (
 	TemplateFetchFailed
 	// ValidationFailed indicates validation failed.
 	ValidationFailed
+	// HookFailed indicates a template hook command failed.
+	HookFailed
+	// GitFailed indicates a git command failed or the repository state
+	// does not allow the requested operation.
+	GitFailed
+	// LockFailed indicates another ign process holds the project lock.
+	LockFailed
 )
 
 // AppError represents an application-layer error.
@@ -74,3 +81,18 @@ func NewTemplateFetchError(message string, cause error) *AppError {
 func NewValidationError(message string, cause error) *AppError {
 	return NewAppError(ValidationFailed, message, cause)
 }
+
+// NewHookError creates a hook error.
+func NewHookError(message string, cause error) *AppError {
+	return NewAppError(HookFailed, message, cause)
+}
+
+// NewGitError creates a git error.
+func NewGitError(message string, cause error) *AppError {
+	return NewAppError(GitFailed, message, cause)
+}
+
+// NewLockError creates a project lock error.
+func NewLockError(message string, cause error) *AppError {
+	return NewAppError(LockFailed, message, cause)
+}
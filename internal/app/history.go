@@ -0,0 +1,449 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// historyLimit is the number of snapshots kept in .ign/history. Recording a
+// new snapshot prunes the oldest ones beyond it.
+const historyLimit = 20
+
+// historyBlobDir holds copied file contents inside a snapshot directory.
+const historyBlobDir = "files"
+
+// HistoryRecorder collects the state of paths a command is about to change so
+// the command can be undone. Paths are captured before they are touched and
+// the snapshot is written by Commit once the command succeeded. Until then
+// each capture is also appended to .ign/journal, which a killed command
+// leaves behind for `ign recover`. A nil recorder records nothing.
+type HistoryRecorder struct {
+	command   string
+	root      string
+	dir       string
+	journal   *os.File
+	entries   []model.HistoryEntry
+	before    []string
+	captured  map[string]struct{}
+	committed bool
+}
+
+// BeginHistory starts a snapshot for command in configDir/history and its
+// journal in configDir. Callers must either Commit or Discard it.
+func BeginHistory(ctx context.Context, configDir, command string) (*HistoryRecorder, error) {
+	absConfigDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", configDir, err)
+	}
+	historyDir := filepath.Join(absConfigDir, model.IgnHistoryDir)
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", historyDir, err)
+	}
+	// A leading dot keeps an unfinished snapshot out of ListHistory.
+	dir, err := os.MkdirTemp(historyDir, ".pending-")
+	if err != nil {
+		return nil, fmt.Errorf("create history snapshot: %w", err)
+	}
+	journal, err := createJournal(ctx, absConfigDir, dir, command)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return &HistoryRecorder{
+		command:  command,
+		root:     filepath.Dir(absConfigDir),
+		dir:      dir,
+		journal:  journal,
+		captured: map[string]struct{}{},
+	}, nil
+}
+
+// capture records the current state of path, and of everything beneath it
+// when it is a directory. A path already captured keeps its first state.
+func (h *HistoryRecorder) capture(path string) error {
+	if h == nil || path == "" {
+		return nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", path, err)
+	}
+	if _, ok := h.captured[absPath]; ok {
+		return nil
+	}
+	h.captured[absPath] = struct{}{}
+	if h.journal != nil && absPath == h.journal.Name() {
+		// The recorder's own journal is not project state.
+		return nil
+	}
+
+	entry := model.HistoryEntry{Path: h.entryPath(absPath), Kind: model.HistoryEntryAbsent}
+	info, err := os.Lstat(absPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("inspect %s: %w", path, err)
+	}
+	switch {
+	case err != nil:
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return fmt.Errorf("read symlink %s: %w", path, err)
+		}
+		entry.Kind = model.HistoryEntrySymlink
+		entry.Target = target
+	case info.IsDir():
+		entry.Kind = model.HistoryEntryDir
+		entry.Mode = info.Mode().Perm()
+	case info.Mode().IsRegular():
+		blob, err := h.copyBlob(absPath)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", path, err)
+		}
+		entry.Kind = model.HistoryEntryFile
+		entry.Mode = info.Mode().Perm()
+		entry.Blob = blob
+	default:
+		return fmt.Errorf("snapshot %s: unsupported file type %s", path, info.Mode().Type())
+	}
+
+	before, err := historyFingerprint(absPath)
+	if err != nil {
+		return err
+	}
+	if err := appendJournalLine(h.journal, journalEntry{HistoryEntry: entry, Before: before}); err != nil {
+		return fmt.Errorf("journal %s: %w", path, err)
+	}
+	h.entries = append(h.entries, entry)
+	h.before = append(h.before, before)
+
+	if entry.Kind == model.HistoryEntryDir {
+		children, err := os.ReadDir(absPath)
+		if err != nil {
+			return fmt.Errorf("read directory %s: %w", path, err)
+		}
+		for _, child := range children {
+			if err := h.capture(filepath.Join(absPath, child.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// captureGeneration records every path a generation previewed by preview
+// writes or creates.
+func (h *HistoryRecorder) captureGeneration(preview *generator.GenerateResult) error {
+	if h == nil || preview == nil {
+		return nil
+	}
+	for _, dir := range preview.Directories {
+		if err := h.capture(dir); err != nil {
+			return err
+		}
+	}
+	for _, file := range preview.DryRunFiles {
+		if file.WouldSkip {
+			continue
+		}
+		if err := h.capture(file.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// captureConfig records the tracking files in configDir.
+func (h *HistoryRecorder) captureConfig(configDir string) error {
+	for _, name := range []string{model.IgnProjectConfigFile, model.IgnVarFile, model.IgnManifestFile, model.IgnProjectOverwriteIgnoreFile} {
+		if err := h.capture(filepath.Join(configDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HistoryRecorder) entryPath(absPath string) string {
+	rel, err := filepath.Rel(h.root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return absPath
+	}
+	return rel
+}
+
+// resolveHistoryPath turns an entry path back into a filesystem path.
+func resolveHistoryPath(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+func (h *HistoryRecorder) copyBlob(path string) (string, error) {
+	blobDir := filepath.Join(h.dir, historyBlobDir)
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		return "", err
+	}
+	name := strconv.Itoa(len(h.entries))
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(filepath.Join(blobDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return "", err
+	}
+	// The journal entry naming the blob is only useful once the blob is on
+	// disk.
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return "", err
+	}
+	return name, dst.Close()
+}
+
+// Commit writes the snapshot and returns its id. Paths the command left
+// unchanged are dropped; when nothing changed no snapshot is kept and the id
+// is empty.
+func (h *HistoryRecorder) Commit() (string, error) {
+	if h == nil || h.committed {
+		return "", nil
+	}
+	snapshot := &model.HistorySnapshot{Command: h.command, CreatedAt: time.Now().UTC()}
+	for i, entry := range h.entries {
+		after, err := historyFingerprint(resolveHistoryPath(h.root, entry.Path))
+		if err != nil {
+			h.Discard()
+			return "", err
+		}
+		if after == h.before[i] {
+			if entry.Blob != "" {
+				_ = os.Remove(filepath.Join(h.dir, historyBlobDir, entry.Blob))
+			}
+			continue
+		}
+		entry.After = after
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	if len(snapshot.Entries) == 0 {
+		h.Discard()
+		return "", nil
+	}
+
+	historyDir := filepath.Dir(h.dir)
+	id := snapshot.CreatedAt.Format("20060102T150405Z")
+	for n := 2; pathExists(filepath.Join(historyDir, id)); n++ {
+		id = snapshot.CreatedAt.Format("20060102T150405Z") + "-" + strconv.Itoa(n)
+	}
+	snapshot.ID = id
+	if err := config.SaveHistorySnapshot(filepath.Join(h.dir, model.IgnHistorySnapshotFile), snapshot); err != nil {
+		h.Discard()
+		return "", err
+	}
+	// The command is done; from here on only the undo record is at stake.
+	h.closeJournal()
+	if err := os.Rename(h.dir, filepath.Join(historyDir, id)); err != nil {
+		h.Discard()
+		return "", fmt.Errorf("record history snapshot %s: %w", id, err)
+	}
+	h.committed = true
+	pruneHistory(historyDir)
+	return id, nil
+}
+
+// Discard removes an uncommitted snapshot. It is a no-op after Commit.
+func (h *HistoryRecorder) Discard() {
+	if h == nil || h.committed {
+		return
+	}
+	h.committed = true
+	h.closeJournal()
+	if err := os.RemoveAll(h.dir); err != nil {
+		debug.Debug("[app] Failed to remove unfinished history snapshot %s: %v", h.dir, err)
+	}
+	// Leave no empty history directory behind a command that changed nothing.
+	_ = os.Remove(filepath.Dir(h.dir))
+}
+
+// closeJournal removes the journal of a command that finished, or failed and
+// rolled its changes back.
+func (h *HistoryRecorder) closeJournal() {
+	if h.journal == nil {
+		return
+	}
+	path := h.journal.Name()
+	_ = h.journal.Close()
+	h.journal = nil
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		debug.Debug("[app] Failed to remove journal %s: %v", path, err)
+	}
+}
+
+// commitHistory commits a recorder owned by the current command. A failure
+// to record history does not fail the command; it is reported through errs.
+func commitHistory(history *HistoryRecorder, errs *[]error) string {
+	id, err := history.Commit()
+	if err != nil {
+		debug.Debug("[app] Failed to record history snapshot: %v", err)
+		*errs = append(*errs, fmt.Errorf("failed to record undo snapshot: %w", err))
+	}
+	return id
+}
+
+func pruneHistory(historyDir string) {
+	ids, err := historySnapshotIDs(historyDir)
+	if err != nil || len(ids) <= historyLimit {
+		return
+	}
+	for _, id := range ids[:len(ids)-historyLimit] {
+		if err := os.RemoveAll(filepath.Join(historyDir, id)); err != nil {
+			debug.Debug("[app] Failed to prune history snapshot %s: %v", id, err)
+		}
+	}
+}
+
+// historySnapshotIDs returns the committed snapshot ids, oldest first.
+func historySnapshotIDs(historyDir string) ([]string, error) {
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return historyIDLess(ids[i], ids[j])
+	})
+	return ids, nil
+}
+
+// historyIDLess orders snapshot ids by time, then by the -N suffix Commit
+// appends to ids recorded within the same second.
+func historyIDLess(a, b string) bool {
+	aBase, aSeq := splitHistoryID(a)
+	bBase, bSeq := splitHistoryID(b)
+	if aBase != bBase {
+		return aBase < bBase
+	}
+	return aSeq < bSeq
+}
+
+func splitHistoryID(id string) (string, int) {
+	base, suffix, ok := strings.Cut(id, "-")
+	if !ok {
+		return id, 1
+	}
+	seq, err := strconv.Atoi(suffix)
+	if err != nil {
+		return id, 0
+	}
+	return base, seq
+}
+
+// historyFingerprint describes the node at path for change detection.
+func historyFingerprint(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return string(model.HistoryEntryAbsent), nil
+		}
+		return "", fmt.Errorf("inspect %s: %w", path, err)
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("read symlink %s: %w", path, err)
+		}
+		return "symlink:" + target, nil
+	case info.IsDir():
+		return string(model.HistoryEntryDir), nil
+	default:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", path, err)
+		}
+		return fmt.Sprintf("file:%o:%s", info.Mode().Perm(), generator.ContentHash(content)), nil
+	}
+}
+
+// ConfigDirInUse reports whether configDir exists as a project
+// configuration directory. A directory that only keeps undo history, as left
+// behind by rewind, or the lock and journal of a command running on it is not
+// in use.
+func ConfigDirInUse(configDir string) bool {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	for _, entry := range entries {
+		switch entry.Name() {
+		case model.IgnHistoryDir, model.IgnLockFile, model.IgnJournalFile:
+		default:
+			return true
+		}
+	}
+	return len(entries) == 0
+}
+
+// removeConfigDir deletes configDir except the running command's lock and
+// journal, and its history when keepHistory is set. configDir itself is
+// removed when none of them is left.
+func removeConfigDir(configDir string, keepHistory bool) error {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		switch entry.Name() {
+		case model.IgnLockFile, model.IgnJournalFile:
+			continue
+		case model.IgnHistoryDir:
+			if keepHistory {
+				continue
+			}
+		}
+		if err := os.RemoveAll(filepath.Join(configDir, entry.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	removeEmptyHistoryDirs(configDir)
+	return nil
+}
+
+// removeEmptyHistoryDirs removes the history directory and configDir when
+// they are empty.
+func removeEmptyHistoryDirs(configDir string) {
+	_ = os.Remove(filepath.Join(configDir, model.IgnHistoryDir))
+	_ = os.Remove(configDir)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+// RunHooksOptions contains options for running template hooks.
+type RunHooksOptions struct {
+	// Hooks are the resolved hooks, run in order.
+	Hooks []generator.ResolvedHook
+	// Stdout receives the commands' standard output.
+	Stdout io.Writer
+	// Stderr receives the commands' standard error.
+	Stderr io.Writer
+}
+
+// RunHooks runs template hooks with "sh -c", stopping at the first failure.
+// Callers must have obtained the user's consent first.
+func RunHooks(ctx context.Context, opts RunHooksOptions) error {
+	for _, hook := range opts.Hooks {
+		debug.Debug("[app] Running hook in %s: %s", hook.WorkDir, hook.Run)
+
+		if info, err := os.Stat(hook.WorkDir); err != nil || !info.IsDir() {
+			return NewHookError(fmt.Sprintf("hook %q: working directory %s does not exist", hook.Run, hook.WorkDir), err)
+		}
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", hook.Run)
+		cmd.Dir = hook.WorkDir
+		cmd.Env = append(os.Environ(), hook.Env...)
+		cmd.Stdout = opts.Stdout
+		cmd.Stderr = opts.Stderr
+		if err := cmd.Run(); err != nil {
+			return NewHookError(fmt.Sprintf("hook %q failed", hook.Run), err)
+		}
+	}
+	return nil
+}
+
+// HooksTrusted reports whether the global configuration trusts the hooks of
+// a template URL, so they may run without asking.
+func HooksTrusted(templateURL string) (bool, error) {
+	path := config.DefaultConfigPath()
+	if path == "" {
+		return false, nil
+	}
+	cfg, err := config.NewLoader().LoadOrDefault(path)
+	if err != nil {
+		return false, NewValidationError("failed to load global config", err)
+	}
+	return hooksTrustedBy(cfg.Hooks.TrustedTemplates, templateURL), nil
+}
+
+// hooksTrustedBy matches a template URL against trust entries. An entry
+// trusts the URL itself and every path below it, so "github.com/owner"
+// trusts all of that owner's templates.
+func hooksTrustedBy(trusted []string, templateURL string) bool {
+	target := trustComparableURL(templateURL)
+	if target == "" {
+		return false
+	}
+	for _, entry := range trusted {
+		prefix := trustComparableURL(entry)
+		if prefix == "" {
+			continue
+		}
+		if target == prefix || strings.HasPrefix(target, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// trustComparableURL reduces the URL forms NormalizeTemplateURL accepts to
+// one spelling.
+func trustComparableURL(url string) string {
+	url = NormalizeTemplateURL(url)
+	if strings.HasPrefix(url, "git@github.com:") {
+		url = "github.com/" + strings.TrimPrefix(url, "git@github.com:")
+	}
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	return url
+}
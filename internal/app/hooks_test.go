@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+func TestRunHooks(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	err := RunHooks(context.Background(), RunHooksOptions{
+		Hooks: []generator.ResolvedHook{
+			{Run: `printf '%s %s\n' "$IGN_VAR_NAME" "$(basename "$PWD")" > marker`, WorkDir: sub, Env: []string{"IGN_VAR_NAME=my-app"}},
+			{Run: "echo done", WorkDir: dir},
+		},
+		Stdout: &stdout,
+		Stderr: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("RunHooks() error = %v", err)
+	}
+	marker, err := os.ReadFile(filepath.Join(sub, "marker"))
+	if err != nil {
+		t.Fatalf("first hook did not run: %v", err)
+	}
+	if got := string(marker); got != "my-app sub\n" {
+		t.Errorf("marker = %q", got)
+	}
+	if got := stdout.String(); got != "done\n" {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestRunHooksStopsAtFirstFailure(t *testing.T) {
+	dir := t.TempDir()
+	err := RunHooks(context.Background(), RunHooksOptions{
+		Hooks: []generator.ResolvedHook{
+			{Run: "exit 3", WorkDir: dir},
+			{Run: "touch never", WorkDir: dir},
+		},
+		Stdout: &bytes.Buffer{},
+		Stderr: &bytes.Buffer{},
+	})
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Type != HookFailed {
+		t.Fatalf("RunHooks() error = %v, want HookFailed", err)
+	}
+	if !strings.Contains(err.Error(), `"exit 3"`) {
+		t.Errorf("error should name the failing command: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "never")); !os.IsNotExist(err) {
+		t.Error("hooks after a failure should not run")
+	}
+}
+
+func TestHooksTrustedBy(t *testing.T) {
+	trusted := []string{"github.com/acme", "https://github.com/other/templates/go/"}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://github.com/acme/templates", want: true},
+		{url: "acme/templates/go-basic", want: true},
+		{url: "git@github.com:acme/templates.git", want: true},
+		{url: "https://github.com/acme-evil/templates", want: false},
+		{url: "github.com/other/templates/go", want: true},
+		{url: "github.com/other/templates/rust", want: false},
+		{url: "./local-template", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := hooksTrustedBy(trusted, tt.url); got != tt.want {
+				t.Errorf("hooksTrustedBy(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// journalHeader is the first line of .ign/journal. Each following line is a
+// journalEntry, appended and synced before the path it describes changes, so
+// a torn last line only describes a change that never started.
+type journalHeader struct {
+	Command string `json:"command"`
+	// Args is the ign invocation, without the program name, that the
+	// command was started with.
+	Args []string `json:"args,omitempty"`
+	// Dir is the working directory of that invocation.
+	Dir       string    `json:"dir"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	// Snapshot names the unfinished history snapshot in .ign/history that
+	// holds the file contents.
+	Snapshot string `json:"snapshot"`
+}
+
+// journalEntry is a captured path and its fingerprint before the command.
+type journalEntry struct {
+	model.HistoryEntry
+	Before string `json:"before"`
+}
+
+// operationJournal is a journal left by a command that did not finish.
+type operationJournal struct {
+	header  journalHeader
+	entries []journalEntry
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a context recording args as the ign invocation, so
+// a command interrupted under it can be run again by `ign recover --complete`.
+func WithInvocation(ctx context.Context, args []string) context.Context {
+	return context.WithValue(ctx, invocationKey{}, append([]string(nil), args...))
+}
+
+func invocation(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	args, _ := ctx.Value(invocationKey{}).([]string)
+	return args
+}
+
+// createJournal starts .ign/journal for command. It fails when a journal
+// already exists, so an unfinished one is never overwritten.
+func createJournal(ctx context.Context, configDir, snapshotDir, command string) (*os.File, error) {
+	path := filepath.Join(configDir, model.IgnJournalFile)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%s exists; run 'ign recover' first", path)
+		}
+		return nil, fmt.Errorf("create %s: %w", path, err)
+	}
+	dir, _ := os.Getwd()
+	header := journalHeader{
+		Command:   command,
+		Args:      invocation(ctx),
+		Dir:       dir,
+		PID:       os.Getpid(),
+		StartedAt: time.Now().UTC(),
+		Snapshot:  filepath.Base(snapshotDir),
+	}
+	if err := appendJournalLine(file, header); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("write %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// appendJournalLine writes value as one line and syncs it to disk.
+func appendJournalLine(file *os.File, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// loadJournal reads the journal in configDir. It returns nil when there is
+// none.
+func loadJournal(configDir string) (*operationJournal, error) {
+	file, err := os.Open(filepath.Join(configDir, model.IgnJournalFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	journal := &operationJournal{}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		// Killed before the header was written: nothing was changed yet.
+		return journal, nil
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &journal.header); err != nil {
+		// A torn header, likewise.
+		return journal, nil
+	}
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break
+		}
+		journal.entries = append(journal.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// interruptedOperationError is returned to a command that finds the journal
+// of one that did not finish.
+func interruptedOperationError(journal *operationJournal) error {
+	what := "an earlier ign command"
+	if journal.header.Command != "" {
+		what = fmt.Sprintf("ign %s (pid %d, started %s)", journal.header.Command, journal.header.PID,
+			journal.header.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	return NewValidationError(fmt.Sprintf("%s was interrupted before it finished and left the project partly changed.\n"+
+		"Run 'ign recover --rollback' to restore the project as it was before, or 'ign recover --complete' to restore it and run the command again.",
+		what), nil)
+}
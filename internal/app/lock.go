@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// lockPollInterval is how often a waiting command retries the project lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockHolder is the content of .ign/lock. It only describes the holder for
+// error messages; the lock itself is an operating system lock on the file.
+type lockHolder struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	Command    string    `json:"command"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+func (h lockHolder) String() string {
+	return fmt.Sprintf("ign %s (pid %d on %s, since %s)", h.Command, h.PID, h.Hostname, h.AcquiredAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// ProjectLock is the advisory lock on a project's .ign directory. Every
+// command that changes the project holds it, so two ign processes never
+// interleave their writes. A nil lock releases nothing.
+type ProjectLock struct {
+	path      string
+	configDir string
+}
+
+// heldLock is a lock this process holds: the locked file and the number of
+// acquisitions not yet released.
+type heldLock struct {
+	file  *os.File
+	count int
+}
+
+// heldLocks holds the locks of this process, keyed by lock file path. A
+// workflow that runs others, like switch, holds the lock once for all of
+// them.
+var (
+	heldLocksMu sync.Mutex
+	heldLocks   = map[string]*heldLock{}
+)
+
+type lockWaitKey struct{}
+
+// WithLockWait returns a context under which LockProject waits up to wait for
+// another process to release the project lock instead of failing at once.
+func WithLockWait(ctx context.Context, wait time.Duration) context.Context {
+	return context.WithValue(ctx, lockWaitKey{}, wait)
+}
+
+func lockWait(ctx context.Context) time.Duration {
+	if ctx == nil {
+		return 0
+	}
+	wait, _ := ctx.Value(lockWaitKey{}).(time.Duration)
+	return wait
+}
+
+// LockProject acquires the lock on configDir for command, creating configDir
+// if needed. The operating system releases the lock when its process exits,
+// so a crashed command never leaves the project locked. It fails while configDir holds the journal of a command that
+// was interrupted, until `ign recover` resolves it. Callers must Release the
+// lock.
+func LockProject(ctx context.Context, configDir, command string) (*ProjectLock, error) {
+	return lockProject(ctx, configDir, command, true)
+}
+
+func lockProject(ctx context.Context, configDir, command string, checkJournal bool) (*ProjectLock, error) {
+	absConfigDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, NewLockError("failed to resolve "+configDir, err)
+	}
+	lock := &ProjectLock{path: filepath.Join(absConfigDir, model.IgnLockFile), configDir: absConfigDir}
+
+	if err := os.MkdirAll(absConfigDir, 0755); err != nil {
+		return nil, NewLockError("failed to create "+configDir, err)
+	}
+	hostname, _ := os.Hostname()
+	holder := lockHolder{PID: os.Getpid(), Hostname: hostname, Command: command, AcquiredAt: time.Now().UTC()}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, NewLockError("failed to encode project lock", err)
+	}
+
+	var deadline time.Time
+	if wait := lockWait(ctx); wait > 0 {
+		deadline = time.Now().Add(wait)
+	}
+	for {
+		acquired, first, err := tryLock(lock.path, data)
+		if err != nil {
+			return nil, NewLockError("failed to acquire "+lock.path, err)
+		}
+		if acquired {
+			debug.Debug("[app] Acquired project lock %s for %s", lock.path, command)
+			if first && checkJournal {
+				// Holding the lock, a journal can only be one no process is
+				// still writing.
+				journal, err := loadJournal(absConfigDir)
+				if err != nil {
+					lock.Release()
+					return nil, NewLockError("failed to read interrupted command journal", err)
+				}
+				if journal != nil {
+					lock.Release()
+					return nil, interruptedOperationError(journal)
+				}
+			}
+			return lock, nil
+		}
+		if deadline.IsZero() || time.Now().After(deadline) {
+			return nil, NewLockError(lockHeldMessage(lock.path, readLockHolder(lock.path), !deadline.IsZero()), nil)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLock makes one attempt at the lock. It reports whether the lock was
+// acquired and, if so, whether this is its first acquisition in this process.
+func tryLock(path string, data []byte) (bool, bool, error) {
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+	if held := heldLocks[path]; held != nil {
+		held.count++
+		return true, false, nil
+	}
+	file, err := acquireLockFile(path)
+	if err != nil || file == nil {
+		return false, false, err
+	}
+	// Replace the description a previous holder, which may have crashed, left.
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt(data, 0)
+		if err != nil {
+			debug.Debug("[app] Failed to describe project lock %s: %v", path, err)
+		}
+	}
+	heldLocks[path] = &heldLock{file: file, count: 1}
+	return true, true, nil
+}
+
+// Release gives up the lock. The last release of a lock this process holds
+// removes the lock file, and configDir with it when nothing else is left.
+func (l *ProjectLock) Release() {
+	if l == nil {
+		return
+	}
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+	held := heldLocks[l.path]
+	if held == nil {
+		return
+	}
+	if held.count > 1 {
+		held.count--
+		return
+	}
+	delete(heldLocks, l.path)
+	releaseLockFile(l.path, held.file)
+	// A rewind, or a command that failed before writing anything, can leave
+	// the directory empty.
+	_ = os.Remove(l.configDir)
+}
+
+// readLockHolder describes the process holding the lock at path, or returns
+// nil when the file does not say.
+func readLockHolder(path string) *lockHolder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var holder lockHolder
+	if err := json.Unmarshal(data, &holder); err != nil || holder.PID <= 0 {
+		return nil
+	}
+	return &holder
+}
+
+func lockHeldMessage(path string, holder *lockHolder, waited bool) string {
+	who := "another ign process"
+	if holder != nil {
+		who = holder.String()
+	}
+	message := fmt.Sprintf("project is locked by %s", who)
+	if waited {
+		return message + fmt.Sprintf("; gave up waiting for %s", path)
+	}
+	message += "; wait for it to finish or rerun with --wait <duration>"
+	if !lockReleasedOnExit {
+		message += fmt.Sprintf(". If that process is no longer running, remove %s", path)
+	}
+	return message
+}
+
+// projectStateFingerprint identifies the content of the tracking files at
+// paths. A workflow that reads them before it takes the project lock compares
+// the fingerprint under the lock, so it never writes back values another
+// process changed in between.
+func projectStateFingerprint(paths ...string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		fingerprint, err := historyFingerprint(path)
+		if err != nil {
+			fingerprint = "error:" + err.Error()
+		}
+		b.WriteString(fingerprint)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// checkProjectStateUnchanged fails when the files at paths no longer match
+// fingerprint, taken when they were read. An empty fingerprint checks nothing.
+func checkProjectStateUnchanged(fingerprint string, paths ...string) error {
+	if fingerprint == "" || projectStateFingerprint(paths...) == fingerprint {
+		return nil
+	}
+	return NewLockError("another ign command changed the project configuration after this one read it; run the command again", nil)
+}
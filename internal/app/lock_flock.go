@@ -0,0 +1,19 @@
+//go:build unix && !solaris
+
+package app
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile takes an exclusive flock on fd without blocking. It reports
+// false when another process holds the lock.
+func tryLockFile(fd int) (bool, error) {
+	err := unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,30 @@
+//go:build !unix && !windows
+
+package app
+
+import (
+	"errors"
+	"os"
+)
+
+// lockReleasedOnExit reports whether the operating system drops the project
+// lock of a process that exits without releasing it. Without file locking,
+// the lock is a file created exclusively, and one left by a crashed process
+// stays until it is removed by hand.
+const lockReleasedOnExit = false
+
+// acquireLockFile makes one attempt to create path exclusively. It returns
+// the created file, or nil when the lock file exists.
+func acquireLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, nil
+	}
+	return file, err
+}
+
+// releaseLockFile closes file and removes path.
+func releaseLockFile(path string, file *os.File) {
+	_ = file.Close()
+	_ = os.Remove(path)
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile takes an exclusive fcntl lock on fd without blocking, as
+// Solaris and illumos have no flock. It reports false when another process
+// holds the lock. The process already serializes its own holders through
+// heldLocks, so the per-process scope of fcntl locks does not matter.
+func tryLockFile(fd int) (bool, error) {
+	lock := unix.Flock_t{Type: unix.F_WRLCK, Whence: 0}
+	err := unix.FcntlFlock(uintptr(fd), unix.F_SETLK, &lock)
+	if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+		return false, nil
+	}
+	return err == nil, err
+}
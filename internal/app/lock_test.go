@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func writeLockHolder(t *testing.T, configDir string, holder lockHolder) string {
+	t.Helper()
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %v", configDir, err)
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatalf("failed to encode lock holder: %v", err)
+	}
+	path := filepath.Join(configDir, model.IgnLockFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+	return path
+}
+
+func TestLockProject_ReportsLiveHolder(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), model.IgnConfigDir)
+	hostname, _ := os.Hostname()
+	path := writeLockHolder(t, configDir, lockHolder{PID: os.Getppid(), Hostname: hostname, Command: "update", AcquiredAt: time.Now()})
+	// A lock taken outside the registry conflicts like another process's.
+	file, err := acquireLockFile(path)
+	if err != nil || file == nil {
+		t.Fatalf("acquireLockFile = %v, %v; want the lock", file, err)
+	}
+	defer releaseLockFile(path, file)
+
+	_, err = LockProject(context.Background(), configDir, "checkout")
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Type != LockFailed {
+		t.Fatalf("LockProject error = %v, want a LockFailed error", err)
+	}
+	if !strings.Contains(err.Error(), "ign update") || !strings.Contains(err.Error(), "--wait") {
+		t.Errorf("error should name the holder and suggest --wait, got %q", err)
+	}
+
+	start := time.Now()
+	_, err = LockProject(WithLockWait(context.Background(), 250*time.Millisecond), configDir, "checkout")
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting") {
+		t.Fatalf("LockProject with --wait error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("LockProject gave up after %v, before the wait elapsed", elapsed)
+	}
+}
+
+func TestLockProject_TakesOverLockOfExitedProcess(t *testing.T) {
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("failed to run helper process: %v", err)
+	}
+	configDir := filepath.Join(t.TempDir(), model.IgnConfigDir)
+	hostname, _ := os.Hostname()
+	path := writeLockHolder(t, configDir, lockHolder{PID: exited.Process.Pid, Hostname: hostname, Command: "update", AcquiredAt: time.Now()})
+
+	lock, err := LockProject(context.Background(), configDir, "checkout")
+	if err != nil {
+		t.Fatalf("LockProject should take over the lock of an exited process: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), `"command":"checkout"`) {
+		t.Errorf("lock file = %q, %v; want this process as holder", data, err)
+	}
+	lock.Release()
+	if _, err := os.Stat(configDir); !os.IsNotExist(err) {
+		t.Errorf("release should remove the emptied config directory, stat error = %v", err)
+	}
+}
+
+func TestLockProject_Reentrant(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), model.IgnConfigDir)
+	outer, err := LockProject(context.Background(), configDir, "switch")
+	if err != nil {
+		t.Fatalf("LockProject returned error: %v", err)
+	}
+	inner, err := LockProject(context.Background(), configDir, "rewind")
+	if err != nil {
+		t.Fatalf("nested LockProject returned error: %v", err)
+	}
+	inner.Release()
+	path := filepath.Join(configDir, model.IgnLockFile)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("inner release removed the outer lock: %v", err)
+	}
+	if ConfigDirInUse(configDir) {
+		t.Error("a directory holding only the lock should not count as in use")
+	}
+	outer.Release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file should be removed after the last release, stat error = %v", err)
+	}
+}
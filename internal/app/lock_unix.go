@@ -0,0 +1,55 @@
+//go:build unix
+
+package app
+
+import (
+	"errors"
+	"os"
+
+	"github.com/tacogips/ign/internal/debug"
+	"golang.org/x/sys/unix"
+)
+
+// lockReleasedOnExit reports whether the operating system drops the project
+// lock of a process that exits without releasing it.
+const lockReleasedOnExit = true
+
+// acquireLockFile makes one attempt to lock path. It returns the locked file,
+// or nil when another process holds the lock.
+func acquireLockFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		locked, err := tryLockFile(int(file.Fd()))
+		if err != nil || !locked {
+			_ = file.Close()
+			return nil, err
+		}
+		// The previous holder removes the file before unlocking it. A lock
+		// taken on the removed file locks nothing, so retry on the new one.
+		var opened, current unix.Stat_t
+		if err := unix.Fstat(int(file.Fd()), &opened); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		err = unix.Stat(path, &current)
+		if err == nil && opened.Dev == current.Dev && opened.Ino == current.Ino {
+			return file, nil
+		}
+		_ = file.Close()
+		if err != nil && !errors.Is(err, unix.ENOENT) {
+			return nil, err
+		}
+	}
+}
+
+// releaseLockFile removes path while still holding its lock, so a process
+// waiting on the file sees it is gone, then unlocks it.
+func releaseLockFile(path string, file *os.File) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		debug.Debug("[app] Failed to remove project lock %s: %v", path, err)
+	}
+	_ = file.Close()
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package app
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockReleasedOnExit reports whether the operating system drops the project
+// lock of a process that exits without releasing it.
+const lockReleasedOnExit = true
+
+// lockRegion is the byte LockFileEx locks. Windows locks are mandatory, so it
+// lies far past the holder description, which must stay readable.
+func lockRegion() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 1}
+}
+
+// acquireLockFile makes one attempt to lock path. It returns the locked file,
+// or nil when another process holds the lock.
+func acquireLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			// The previous holder is removing the file.
+			return nil, nil
+		}
+		return nil, err
+	}
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, lockRegion()); err != nil {
+		_ = file.Close()
+		if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// releaseLockFile unlocks and closes file, then removes path. The removal
+// fails, leaving the file to its new holder, while another process has it
+// open.
+func releaseLockFile(path string, file *os.File) {
+	_ = windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, lockRegion())
+	_ = file.Close()
+	_ = os.Remove(path)
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -61,7 +62,12 @@ func saveManifestFromGenerateResultWithResult(path string, result *generator.Gen
 	if err != nil {
 		return config.AtomicWriteResult{}, err
 	}
+	return saveIgnManifestWithResult(path, manifestFromGenerateResult(manifest, result, excludedCanonicalPaths))
+}
 
+// manifestFromGenerateResult adds the paths written by a generation to
+// manifest, dropping excluded paths, and returns it.
+func manifestFromGenerateResult(manifest *model.IgnManifest, result *generator.GenerateResult, excludedCanonicalPaths map[string]struct{}) *model.IgnManifest {
 	files := make([]string, 0, len(manifest.Files)+len(result.WrittenFiles)+len(result.CreatedFiles))
 	seen := make(map[string]struct{}, len(manifest.Files))
 	for _, manifestFile := range manifest.Files {
@@ -95,7 +101,173 @@ func saveManifestFromGenerateResultWithResult(path string, result *generator.Gen
 
 	manifest.Files = files
 	sort.Strings(manifest.Files)
-	return saveIgnManifestWithResult(path, manifest)
+	manifest.Policies = mergeManifestPolicies(manifest.Policies, result, seen)
+	manifest.Injections = mergeManifestInjections(manifest.Injections, result.Injections)
+	manifest.Declined = mergeManifestDeclined(manifest.Declined, result, writtenPaths)
+	manifest.Hashes = mergeManifestHashes(manifest.Hashes, result, seen)
+	return manifest
+}
+
+// mergeManifestHashes records the content generated in this run. Paths the
+// run left alone keep the hash of what ign last generated for them; entries
+// for paths that left the manifest are pruned.
+func mergeManifestHashes(existing map[string]string, result *generator.GenerateResult, manifestFiles map[string]struct{}) map[string]string {
+	hashes := make(map[string]string, len(existing)+len(result.ContentHashes))
+	for path, hash := range existing {
+		hashes[filepath.Clean(path)] = hash
+	}
+	for path, hash := range result.ContentHashes {
+		hashes[filepath.Clean(path)] = hash
+	}
+	for path := range hashes {
+		if _, ok := manifestFiles[path]; !ok {
+			delete(hashes, path)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	return hashes
+}
+
+// managedContentHash returns the hash in the form of
+// GenerateResult.ContentHashes of what is at path: a regular file's content
+// or a symlink's target. It fails for anything else.
+func managedContentHash(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return generator.ContentHash([]byte(target)), nil
+	case info.Mode().IsRegular():
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return generator.ContentHash(content), nil
+	default:
+		return "", fmt.Errorf("%s is not a file or symlink", path)
+	}
+}
+
+// mergeManifestDeclined records the changes declined in this run. An earlier
+// entry survives only while its path is still generated and was not written.
+func mergeManifestDeclined(existing map[string]string, result *generator.GenerateResult, writtenPaths []string) map[string]string {
+	generated := make(map[string]struct{}, len(result.Files))
+	for _, path := range result.Files {
+		generated[filepath.Clean(path)] = struct{}{}
+	}
+	declined := make(map[string]string, len(existing)+len(result.DeclinedFiles))
+	for path, hash := range existing {
+		clean := filepath.Clean(path)
+		if _, ok := generated[clean]; ok {
+			declined[clean] = hash
+		}
+	}
+	for _, path := range writtenPaths {
+		delete(declined, filepath.Clean(path))
+	}
+	for path, hash := range result.DeclinedFiles {
+		declined[filepath.Clean(path)] = hash
+	}
+	if len(declined) == 0 {
+		return nil
+	}
+	return declined
+}
+
+// mergeManifestInjections appends newly applied injections. An injection that
+// was applied again, because the user removed its content, replaces the
+// earlier record for the same file and id.
+func mergeManifestInjections(existing []model.InjectionRecord, applied []model.InjectionRecord) []model.InjectionRecord {
+	if len(applied) == 0 {
+		return existing
+	}
+	key := func(record model.InjectionRecord) string {
+		return filepath.Clean(record.Path) + "\x00" + record.ID
+	}
+	replaced := make(map[string]struct{}, len(applied))
+	for _, record := range applied {
+		replaced[key(record)] = struct{}{}
+	}
+
+	merged := make([]model.InjectionRecord, 0, len(existing)+len(applied))
+	for _, record := range existing {
+		if _, ok := replaced[key(record)]; ok {
+			continue
+		}
+		merged = append(merged, record)
+	}
+	for _, record := range applied {
+		record.Path = filepath.Clean(record.Path)
+		merged = append(merged, record)
+	}
+	return merged
+}
+
+// mergeManifestPolicies records the policies declared for generated paths.
+// Paths generated in this run take the template's current declaration, so a
+// rule removed from ign-template.json also drops its recorded policy. Entries
+// for paths that left the manifest are pruned.
+func mergeManifestPolicies(existing map[string]model.FilePolicy, result *generator.GenerateResult, manifestFiles map[string]struct{}) map[string]model.FilePolicy {
+	policies := make(map[string]model.FilePolicy, len(existing)+len(result.Policies))
+	for path, policy := range existing {
+		policies[filepath.Clean(path)] = policy
+	}
+	for _, path := range result.Files {
+		clean := filepath.Clean(path)
+		if policy, ok := result.Policies[clean]; ok {
+			policies[clean] = policy
+		} else {
+			delete(policies, clean)
+		}
+	}
+	for path := range policies {
+		if _, ok := manifestFiles[path]; !ok {
+			delete(policies, path)
+		}
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	return policies
+}
+
+// seededPathsFromManifest returns the absolute paths of manifest entries
+// recorded with the seed policy.
+func seededPathsFromManifest(path string) (map[string]struct{}, error) {
+	manifest, err := loadManifestOrEmpty(path)
+	if err != nil {
+		return nil, err
+	}
+	seeded := make(map[string]struct{})
+	for file, policy := range manifest.Policies {
+		if policy != model.FilePolicySeed {
+			continue
+		}
+		canonical, err := canonicalManagedPathForComparison(file)
+		if err != nil {
+			continue
+		}
+		seeded[canonical] = struct{}{}
+	}
+	return seeded, nil
+}
+
+// declinedChangesFromManifest returns the declined changes recorded in the
+// manifest, keyed by the same paths as the generator's output.
+func declinedChangesFromManifest(path string) (map[string]string, error) {
+	manifest, err := loadManifestOrEmpty(path)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Declined, nil
 }
 
 func isExcludedManifestPath(path string, excludedCanonicalPaths map[string]struct{}) bool {
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// RecoverOptions contains options for recovering from an interrupted command.
+type RecoverOptions struct {
+	// OutputDir is the project directory containing .ign.
+	OutputDir string
+	// DryRun reports what the rollback would restore without changing
+	// anything.
+	DryRun bool
+}
+
+// InterruptedCommand describes a command that stopped before it finished.
+type InterruptedCommand struct {
+	// Command is the interrupted command, e.g. "update".
+	Command string
+	// Args is its ign invocation without the program name. It is empty when
+	// the command was not started from the command line.
+	Args []string
+	// Dir is the working directory it was started in.
+	Dir string
+	// PID is the process that ran it.
+	PID int
+	// StartedAt is when it started changing the project.
+	StartedAt time.Time
+}
+
+// RecoverResult contains the result of a recovery.
+type RecoverResult struct {
+	// Interrupted is the command that was interrupted, or nil when there was
+	// nothing to recover.
+	Interrupted *InterruptedCommand
+	// Restored lists paths put back to their content before the command.
+	Restored []string
+	// Removed lists paths the command created and recovery deleted.
+	Removed []string
+	// Errors contains paths that could not be restored.
+	Errors []error
+}
+
+// Recover rolls back a command that was killed while changing the project:
+// every path recorded in .ign/journal is put back as it was before the
+// command started, then the journal is removed. Running the command again
+// afterwards completes it from a clean state.
+func Recover(ctx context.Context, opts RecoverOptions) (*RecoverResult, error) {
+	debug.DebugSection("[app] Recover workflow start")
+	debug.DebugValue("[app] OutputDir", opts.OutputDir)
+	debug.DebugValue("[app] DryRun", opts.DryRun)
+
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+	configDir := filepath.Join(opts.OutputDir, model.IgnConfigDir)
+	absConfigDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to resolve .ign directory", err)
+	}
+	if !opts.DryRun {
+		lock, err := lockProject(ctx, configDir, "recover", false)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
+	}
+
+	journal, err := loadJournal(absConfigDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to read interrupted command journal", err)
+	}
+	result := &RecoverResult{}
+	if journal == nil {
+		return result, nil
+	}
+	result.Interrupted = &InterruptedCommand{
+		Command:   journal.header.Command,
+		Args:      journal.header.Args,
+		Dir:       journal.header.Dir,
+		PID:       journal.header.PID,
+		StartedAt: journal.header.StartedAt,
+	}
+
+	root := filepath.Dir(absConfigDir)
+	historyDir := filepath.Join(absConfigDir, model.IgnHistoryDir)
+	var snapshotDir string
+	if journal.header.Snapshot != "" {
+		snapshotDir = filepath.Join(historyDir, filepath.Base(journal.header.Snapshot))
+	}
+
+	if !opts.DryRun {
+		// A directory-to-symlink transition keeps its own journal; finish
+		// restoring it before the paths it replaced are compared.
+		if err := recoverSymlinkTransitionJournal(root,
+			filepath.Join(absConfigDir, model.IgnManifestFile),
+			filepath.Join(absConfigDir, model.IgnProjectConfigFile),
+			filepath.Join(absConfigDir, model.IgnVarFile)); err != nil {
+			return result, NewCheckoutError("recover interrupted managed directory-to-symlink transition", err)
+		}
+	}
+
+	// Only paths the command got to change need restoring.
+	var changed []model.HistoryEntry
+	for _, entry := range journal.entries {
+		current, err := historyFingerprint(resolveHistoryPath(root, entry.Path))
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+		if current != entry.Before {
+			changed = append(changed, entry.HistoryEntry)
+		}
+	}
+	restored, removed, errs, err := restoreHistoryEntries(ctx, snapshotDir, root, changed, opts.DryRun)
+	result.Restored, result.Removed = restored, removed
+	result.Errors = append(result.Errors, errs...)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if len(result.Errors) > 0 {
+		return result, NewCheckoutError("failed to restore some paths; the journal was kept", errors.Join(result.Errors...))
+	}
+	if snapshotDir != "" {
+		if err := os.RemoveAll(snapshotDir); err != nil {
+			return result, NewCheckoutError(fmt.Sprintf("failed to remove unfinished history snapshot %s", snapshotDir), err)
+		}
+	}
+	if err := os.Remove(filepath.Join(absConfigDir, model.IgnJournalFile)); err != nil && !os.IsNotExist(err) {
+		return result, NewCheckoutError("failed to remove interrupted command journal", err)
+	}
+	removeEmptyHistoryDirs(absConfigDir)
+	debug.Debug("[app] Recover workflow completed successfully")
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// interruptCommand leaves .ign as a command killed after changing kept.txt
+// and creating new.txt would.
+func interruptCommand(t *testing.T, args []string) {
+	t.Helper()
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("failed to create .ign: %v", err)
+	}
+	if err := os.WriteFile("kept.txt", []byte("before\n"), 0644); err != nil {
+		t.Fatalf("failed to write kept.txt: %v", err)
+	}
+	history, err := BeginHistory(WithInvocation(context.Background(), args), model.IgnConfigDir, "update")
+	if err != nil {
+		t.Fatalf("BeginHistory returned error: %v", err)
+	}
+	for _, path := range []string{"kept.txt", "new.txt"} {
+		if err := history.capture(path); err != nil {
+			t.Fatalf("capture %s returned error: %v", path, err)
+		}
+	}
+	if err := os.WriteFile("kept.txt", []byte("half written"), 0644); err != nil {
+		t.Fatalf("failed to change kept.txt: %v", err)
+	}
+	if err := os.WriteFile("new.txt", []byte("new\n"), 0644); err != nil {
+		t.Fatalf("failed to write new.txt: %v", err)
+	}
+	// The process dies here, mid-way through its next journal line.
+	if _, err := history.journal.WriteString(`{"path":"untracked.tx`); err != nil {
+		t.Fatalf("failed to tear the journal: %v", err)
+	}
+	_ = history.journal.Close()
+}
+
+func TestRecover_RollsBackInterruptedCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	args := []string{"update", "--overwrite"}
+	interruptCommand(t, args)
+	ctx := context.Background()
+
+	_, err := LockProject(ctx, model.IgnConfigDir, "checkout")
+	if err == nil || !strings.Contains(err.Error(), "ign update") || !strings.Contains(err.Error(), "ign recover --rollback") {
+		t.Fatalf("LockProject error = %v, want it to point at ign recover", err)
+	}
+
+	preview, err := Recover(ctx, RecoverOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Recover dry run returned error: %v", err)
+	}
+	if preview.Interrupted == nil || preview.Interrupted.Command != "update" || !slices.Equal(preview.Interrupted.Args, args) {
+		t.Fatalf("Interrupted = %+v, want update %v", preview.Interrupted, args)
+	}
+	if !slices.Equal(preview.Restored, []string{"kept.txt"}) || !slices.Equal(preview.Removed, []string{"new.txt"}) {
+		t.Errorf("dry run Restored = %v, Removed = %v; want [kept.txt] and [new.txt]", preview.Restored, preview.Removed)
+	}
+	if got := readTestFile(t, "kept.txt"); got != "half written" {
+		t.Fatalf("dry run changed kept.txt to %q", got)
+	}
+
+	if _, err := Recover(ctx, RecoverOptions{}); err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if got := readTestFile(t, "kept.txt"); got != "before\n" {
+		t.Errorf("kept.txt = %q, want its content before the command", got)
+	}
+	if _, err := os.Lstat("new.txt"); !os.IsNotExist(err) {
+		t.Errorf("new.txt should be removed, stat error = %v", err)
+	}
+	if _, err := os.Lstat(model.IgnConfigDir); !os.IsNotExist(err) {
+		t.Errorf(".ign should be gone with the journal and unfinished snapshot, stat error = %v", err)
+	}
+
+	lock, err := LockProject(ctx, model.IgnConfigDir, "checkout")
+	if err != nil {
+		t.Fatalf("LockProject after recovery returned error: %v", err)
+	}
+	lock.Release()
+}
+
+func TestHistoryRecorder_RemovesJournalWhenFinished(t *testing.T) {
+	t.Chdir(t.TempDir())
+	journalPath := filepath.Join(model.IgnConfigDir, model.IgnJournalFile)
+
+	for _, commit := range []bool{true, false} {
+		history, err := BeginHistory(context.Background(), model.IgnConfigDir, "update")
+		if err != nil {
+			t.Fatalf("BeginHistory returned error: %v", err)
+		}
+		if _, err := os.Stat(journalPath); err != nil {
+			t.Fatalf("BeginHistory should start the journal: %v", err)
+		}
+		if err := history.capture("file.txt"); err != nil {
+			t.Fatalf("capture returned error: %v", err)
+		}
+		if err := os.WriteFile("file.txt", []byte("changed\n"), 0644); err != nil {
+			t.Fatalf("failed to write file.txt: %v", err)
+		}
+		if commit {
+			if _, err := history.Commit(); err != nil {
+				t.Fatalf("Commit returned error: %v", err)
+			}
+		} else {
+			history.Discard()
+		}
+		if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+			t.Errorf("journal should be removed (commit=%v), stat error = %v", commit, err)
+		}
+	}
+
+	result, err := Recover(context.Background(), RecoverOptions{})
+	if err != nil || result.Interrupted != nil {
+		t.Errorf("Recover = %+v, %v; want nothing to recover", result, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -23,6 +24,24 @@ type RewindOptions struct {
 	OutputDir string
 	// GitHubToken is used when manifest fallback needs to fetch the template.
 	GitHubToken string
+	// History, when set, collects the removed files into a snapshot the
+	// caller commits. Otherwise rewind records and commits its own.
+	History *HistoryRecorder
+	// DryRun reports what rewind would remove without changing anything.
+	DryRun bool
+	// KeepConfig leaves .ign in place, dropping only the removed files from
+	// the manifest.
+	KeepConfig bool
+	// KeepModified leaves files whose content differs from what the recorded
+	// template generates.
+	KeepModified bool
+	// KeepHistory leaves .ign/history, with a snapshot of this rewind, when
+	// .ign is removed, so the rewind can be undone. Otherwise .ign is removed
+	// entirely.
+	KeepHistory bool
+	// Paths limits the rewind to these files or directories, relative to
+	// OutputDir. A limited rewind implies KeepConfig.
+	Paths []string
 }
 
 // RewindResult contains the result of removing generated files.
@@ -34,12 +53,30 @@ type RewindResult struct {
 	Errors             []error
 	Files              []string
 	SkippedFiles       []string
+	// RemovedFiles lists the files removed, or that a dry run would remove.
+	RemovedFiles []string
+	// FilesKept counts files left in place because their file policy is seed or user.
+	FilesKept int
+	KeptFiles []string
+	// FilesProtected counts files left in place because the project's
+	// .ign/overwrite-ignore matches them.
+	FilesProtected int
+	ProtectedFiles []string
+	// InjectionsReverted counts injections undone in files ign did not create.
+	// InjectionsSkipped counts injections whose text was no longer present.
+	InjectionsReverted int
+	InjectionsSkipped  int
+	// HistoryID is the undo snapshot recorded for this rewind, if any.
+	HistoryID string
+	// ConfigRemoved reports whether .ign was removed, or would be in a dry run.
+	ConfigRemoved bool
 }
 
 // Rewind removes files previously created by ign and then deletes .ign.
 func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 	debug.DebugSection("[app] Rewind workflow start")
 	debug.DebugValue("[app] OutputDir", opts.OutputDir)
+	debug.DebugValue("[app] DryRun", opts.DryRun)
 
 	if opts.OutputDir == "" {
 		opts.OutputDir = "."
@@ -47,19 +84,40 @@ func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 	if err := ValidateOutputDir(opts.OutputDir); err != nil {
 		return nil, NewValidationError("invalid output directory", err)
 	}
+	filters, err := cleanRewindPaths(opts.Paths)
+	if err != nil {
+		return nil, NewValidationError("invalid rewind path", err)
+	}
+	opts.Paths = filters
+	keepConfig := opts.KeepConfig || len(filters) > 0
 
-	if _, err := os.Stat(model.IgnConfigDir); os.IsNotExist(err) {
+	if !ConfigDirInUse(model.IgnConfigDir) {
 		return nil, NewValidationError(
 			"rewind requires prior checkout: .ign directory not found.\n"+
 				"Run 'ign checkout <template-url>' first.",
 			nil,
 		)
 	}
+	if !opts.DryRun {
+		lock, err := LockProject(ctx, model.IgnConfigDir, "rewind")
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
+	}
 
-	files, skippedFiles, err := loadManagedFilesForRewind(ctx, opts)
+	files, skippedFiles, keptFiles, err := loadManagedFilesForRewind(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	projectIgnore, err := projectOverwriteIgnorePatterns(model.IgnConfigDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to load project overwrite-ignore", err)
+	}
+	files, protectedFiles := partitionProtectedFiles(files, opts.OutputDir, projectIgnore)
+	skippedFiles, protectedSkipped := partitionProtectedFiles(skippedFiles, opts.OutputDir, projectIgnore)
+	protectedFiles = append(protectedFiles, protectedSkipped...)
+	sort.Strings(protectedFiles)
 
 	sort.Slice(files, func(i, j int) bool {
 		iDepth := managedPathDepth(files[i])
@@ -71,13 +129,36 @@ func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 	})
 
 	result := &RewindResult{
-		Errors:       []error{},
-		Files:        append([]string(nil), files...),
-		FilesSkipped: len(skippedFiles),
-		SkippedFiles: append([]string(nil), skippedFiles...),
+		Errors:         []error{},
+		Files:          append([]string(nil), files...),
+		FilesSkipped:   len(skippedFiles),
+		SkippedFiles:   append([]string(nil), skippedFiles...),
+		FilesKept:      len(keptFiles),
+		KeptFiles:      append([]string(nil), keptFiles...),
+		FilesProtected: len(protectedFiles),
+		ProtectedFiles: protectedFiles,
+	}
+
+	history := opts.History
+	if history == nil && !opts.DryRun {
+		history, err = BeginHistory(ctx, model.IgnConfigDir, "rewind")
+		if err != nil {
+			return nil, NewCheckoutError("failed to start history snapshot", err)
+		}
+		defer history.Discard()
+	}
+	if err := history.captureConfig(model.IgnConfigDir); err != nil {
+		return nil, NewCheckoutError("failed to record history snapshot", err)
+	}
+
+	// Undo injections first: their targets may be files rewind removes next.
+	remainingInjections, err := revertInjectionsForRewind(ctx, opts, projectIgnore, history, result)
+	if err != nil {
+		return result, err
 	}
 
 	removedDirs := make(map[string]struct{})
+	rewound := make(map[string]struct{}, len(files))
 	for _, path := range files {
 		if err := ctx.Err(); err != nil {
 			return result, err
@@ -93,6 +174,7 @@ func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 		if statErr != nil {
 			if os.IsNotExist(statErr) {
 				result.FilesMissing++
+				rewound[filepath.Clean(path)] = struct{}{}
 				continue
 			}
 			result.Errors = append(result.Errors, fmt.Errorf("failed to stat %s: %w", cleanPath, statErr))
@@ -104,11 +186,22 @@ func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 			continue
 		}
 
+		if opts.DryRun {
+			result.FilesRemoved++
+			result.RemovedFiles = append(result.RemovedFiles, path)
+			continue
+		}
+		if err := history.capture(cleanPath); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to record %s before removing it: %w", cleanPath, err))
+			continue
+		}
 		if err := os.Remove(cleanPath); err != nil {
 			result.Errors = append(result.Errors, fmt.Errorf("failed to remove %s: %w", cleanPath, err))
 			continue
 		}
 		result.FilesRemoved++
+		result.RemovedFiles = append(result.RemovedFiles, path)
+		rewound[filepath.Clean(path)] = struct{}{}
 
 		for _, dir := range removeEmptyParentDirs(cleanPath, opts.OutputDir) {
 			removedDirs[dir] = struct{}{}
@@ -117,59 +210,368 @@ func Rewind(ctx context.Context, opts RewindOptions) (*RewindResult, error) {
 
 	result.DirectoriesRemoved = len(removedDirs)
 
+	if opts.DryRun {
+		result.ConfigRemoved = len(result.Errors) == 0 && !keepConfig
+		debug.Debug("[app] Rewind dry run completed")
+		return result, nil
+	}
+
 	if len(result.Errors) > 0 {
 		debug.Debug("[app] Rewind completed with %d errors; preserving .ign for retry", len(result.Errors))
-		return result, NewCheckoutError(
+		// The files already removed can still be restored with undo.
+		rewindErr := NewCheckoutError(
 			"failed to remove some ign-managed files; .ign was preserved",
 			errors.Join(result.Errors...),
 		)
+		if opts.History == nil {
+			result.HistoryID = commitHistory(history, &result.Errors)
+		}
+		return result, rewindErr
 	}
 
-	if err := os.RemoveAll(model.IgnConfigDir); err != nil {
-		return result, NewCheckoutError("failed to remove .ign directory", err)
+	if keepConfig {
+		if err := pruneManifestAfterRewind(manifestPath(), rewound, remainingInjections); err != nil {
+			return result, NewCheckoutError("failed to update ign-files.json", err)
+		}
+	} else {
+		// A caller collecting its own snapshot, such as switch, recreates
+		// .ign and keeps the history there.
+		keepHistory := opts.KeepHistory || opts.History != nil
+		if !keepHistory {
+			history.Discard()
+		}
+		if err := removeConfigDir(model.IgnConfigDir, keepHistory); err != nil {
+			return result, NewCheckoutError("failed to remove .ign directory", err)
+		}
+		result.ConfigRemoved = true
+	}
+	if opts.History == nil {
+		result.HistoryID = commitHistory(history, &result.Errors)
 	}
 
 	debug.Debug("[app] Rewind workflow completed successfully")
 	return result, nil
 }
 
-func loadManagedFilesForRewind(ctx context.Context, opts RewindOptions) ([]string, []string, error) {
+// loadManagedFilesForRewind returns the files to remove, the files skipped
+// because they differ from the template, and the files kept by a seed or user
+// file policy, limited to the selected paths.
+func loadManagedFilesForRewind(ctx context.Context, opts RewindOptions) ([]string, []string, []string, error) {
 	manifest, err := config.LoadIgnManifest(manifestPath())
 	if err == nil {
-		return dedupePaths(manifest.Files), nil, nil
+		selected := selectRewindPaths(dedupePaths(manifest.Files), opts.OutputDir, opts.Paths)
+		files, kept := partitionKeptFiles(selected, manifest.Policies)
+		if !opts.KeepModified || len(files) == 0 {
+			return files, nil, kept, nil
+		}
+		files, skipped := partitionModifiedFiles(files, manifest.Hashes, opts.OutputDir)
+		return files, skipped, kept, nil
 	}
 
 	if cfgErr, ok := err.(*config.ConfigError); !ok || cfgErr.Type != config.ConfigNotFound {
-		return nil, nil, NewCheckoutError("failed to load ign-files.json", err)
+		return nil, nil, nil, NewCheckoutError("failed to load ign-files.json", err)
 	}
 
 	debug.Debug("[app] ign-files.json not found; falling back to current template dry-run")
-	return buildManagedFilesFromCurrentTemplate(ctx, opts)
+	files, skipped, kept, err := buildManagedFilesFromCurrentTemplate(ctx, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return selectRewindPaths(files, opts.OutputDir, opts.Paths),
+		selectRewindPaths(skipped, opts.OutputDir, opts.Paths),
+		selectRewindPaths(kept, opts.OutputDir, opts.Paths), nil
+}
+
+// cleanRewindPaths validates rewind path filters, which must stay inside the
+// output directory. A filter naming the output directory itself selects
+// everything, the same as no filter.
+func cleanRewindPaths(paths []string) ([]string, error) {
+	var cleaned []string
+	for _, path := range paths {
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("path filter is empty")
+		}
+		if filepath.IsAbs(path) {
+			return nil, fmt.Errorf("path filter %s must be relative to the output directory", path)
+		}
+		clean := filepath.Clean(path)
+		if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("path filter %s is outside the output directory", path)
+		}
+		if clean == "." {
+			return nil, nil
+		}
+		cleaned = append(cleaned, clean)
+	}
+	return cleaned, nil
+}
+
+// selectRewindPaths returns the paths at or beneath one of filters, which are
+// relative to outputDir. No filters select every path.
+func selectRewindPaths(paths []string, outputDir string, filters []string) []string {
+	if len(filters) == 0 {
+		return paths
+	}
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil
+	}
+	selected := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absOutputDir, absPath)
+		if err != nil {
+			continue
+		}
+		for _, filter := range filters {
+			if rel == filter || strings.HasPrefix(rel, filter+string(filepath.Separator)) {
+				selected = append(selected, path)
+				break
+			}
+		}
+	}
+	return selected
 }
 
-func buildManagedFilesFromCurrentTemplate(ctx context.Context, opts RewindOptions) ([]string, []string, error) {
+// partitionModifiedFiles separates files whose current content differs from
+// the hash recorded when ign last generated them. A file without a recorded
+// hash, from a manifest written before hashes were recorded, counts as
+// modified. Missing files stay in the first list so rewind reports them as
+// already absent.
+func partitionModifiedFiles(files []string, hashes map[string]string, outputDir string) ([]string, []string) {
+	unmodified := make([]string, 0, len(files))
+	var modified []string
+	for _, path := range files {
+		cleanPath, err := validateManagedPath(path, outputDir)
+		if err != nil {
+			// Rewind reports the invalid path when it tries to remove it.
+			unmodified = append(unmodified, path)
+			continue
+		}
+		if _, err := os.Lstat(cleanPath); err != nil {
+			unmodified = append(unmodified, path)
+			continue
+		}
+		recorded, ok := hashes[filepath.Clean(path)]
+		current, err := managedContentHash(cleanPath)
+		if !ok || err != nil || current != recorded {
+			debug.Debug("[app] Keeping %s: content differs from what ign generated", path)
+			modified = append(modified, path)
+			continue
+		}
+		unmodified = append(unmodified, path)
+	}
+	return unmodified, modified
+}
+
+// revertInjectionsForRewind undoes the manifest's injections in the selected
+// paths, newest first. An injection whose text the user changed or removed is
+// skipped rather than failing the rewind, and one in a path the project
+// protects is left alone. It returns the injection records left to undo.
+func revertInjectionsForRewind(ctx context.Context, opts RewindOptions, projectIgnore []string, history *HistoryRecorder, result *RewindResult) ([]model.InjectionRecord, error) {
+	manifest, err := loadManifestOrEmpty(manifestPath())
+	if err != nil {
+		return nil, NewCheckoutError("failed to load ign-files.json", err)
+	}
+
+	remaining := make([]model.InjectionRecord, 0, len(manifest.Injections))
+	for i := len(manifest.Injections) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record := manifest.Injections[i]
+		if _, protected := partitionProtectedFiles([]string{record.Path}, opts.OutputDir, projectIgnore); len(protected) > 0 ||
+			len(selectRewindPaths([]string{record.Path}, opts.OutputDir, opts.Paths)) == 0 {
+			remaining = append(remaining, record)
+			continue
+		}
+
+		cleanPath, err := validateManagedPath(record.Path, opts.OutputDir)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			remaining = append(remaining, record)
+			continue
+		}
+		info, err := os.Stat(cleanPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				result.InjectionsSkipped++
+				continue
+			}
+			result.Errors = append(result.Errors, fmt.Errorf("failed to stat %s: %w", cleanPath, err))
+			remaining = append(remaining, record)
+			continue
+		}
+		content, err := os.ReadFile(cleanPath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to read %s: %w", cleanPath, err))
+			remaining = append(remaining, record)
+			continue
+		}
+
+		reverted, ok := generator.RevertInjection(content, record)
+		if !ok {
+			debug.Debug("[app] Injection %s no longer present in %s; leaving file unchanged", record.ID, cleanPath)
+			result.InjectionsSkipped++
+			continue
+		}
+		if opts.DryRun {
+			result.InjectionsReverted++
+			continue
+		}
+		if err := history.capture(cleanPath); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to record %s before reverting injection %s: %w", cleanPath, record.ID, err))
+			remaining = append(remaining, record)
+			continue
+		}
+		if err := config.WriteFileAtomic(cleanPath, reverted, info.Mode().Perm()); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to revert injection %s in %s: %w", record.ID, cleanPath, err))
+			remaining = append(remaining, record)
+			continue
+		}
+		result.InjectionsReverted++
+	}
+	// Restore the manifest's oldest-first order.
+	slices.Reverse(remaining)
+	return remaining, nil
+}
+
+// pruneManifestAfterRewind drops rewound files and undone injections from a
+// manifest that rewind leaves in place.
+func pruneManifestAfterRewind(path string, rewound map[string]struct{}, injections []model.InjectionRecord) error {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	manifest, err := config.LoadIgnManifest(path)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if _, ok := rewound[filepath.Clean(file)]; ok {
+			continue
+		}
+		files = append(files, file)
+	}
+	for rewoundPath := range rewound {
+		delete(manifest.Policies, rewoundPath)
+		delete(manifest.Declined, rewoundPath)
+		delete(manifest.Hashes, rewoundPath)
+	}
+	manifest.Files = files
+	if len(manifest.Policies) == 0 {
+		manifest.Policies = nil
+	}
+	if len(manifest.Declined) == 0 {
+		manifest.Declined = nil
+	}
+	if len(manifest.Hashes) == 0 {
+		manifest.Hashes = nil
+	}
+	manifest.Injections = injections
+	if len(manifest.Injections) == 0 {
+		manifest.Injections = nil
+	}
+	return config.SaveIgnManifest(path, manifest)
+}
+
+// partitionProtectedFiles separates files the project's overwrite-ignore
+// patterns match, relative to outputDir.
+func partitionProtectedFiles(files []string, outputDir string, patterns []string) ([]string, []string) {
+	if len(patterns) == 0 {
+		return files, nil
+	}
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return files, nil
+	}
+	unprotected := make([]string, 0, len(files))
+	var protected []string
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err == nil {
+			if rel, err := filepath.Rel(absOutputDir, absPath); err == nil && generator.MatchesGitIgnorePattern(rel, patterns) {
+				debug.Debug("[app] Keeping %s: protected by project overwrite-ignore", file)
+				protected = append(protected, file)
+				continue
+			}
+		}
+		unprotected = append(unprotected, file)
+	}
+	return unprotected, protected
+}
+
+// partitionKeptFiles separates files whose policy keeps them from rewind.
+func partitionKeptFiles(files []string, policies map[string]model.FilePolicy) ([]string, []string) {
+	if len(policies) == 0 {
+		return files, nil
+	}
+	removable := make([]string, 0, len(files))
+	var kept []string
+	for _, file := range files {
+		if policies[filepath.Clean(file)].KeepsFile() {
+			debug.Debug("[app] Keeping %s by %s policy", file, policies[filepath.Clean(file)])
+			kept = append(kept, file)
+			continue
+		}
+		removable = append(removable, file)
+	}
+	return removable, kept
+}
+
+func buildManagedFilesFromCurrentTemplate(ctx context.Context, opts RewindOptions) ([]string, []string, []string, error) {
+	genResult, err := renderCurrentTemplateForRewind(ctx, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	candidates := make([]generator.DryRunFile, 0, len(genResult.DryRunFiles))
+	var kept []string
+	for _, file := range genResult.DryRunFiles {
+		if file.Exists && genResult.Policies[filepath.Clean(file.Path)].KeepsFile() {
+			kept = append(kept, file.Path)
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+	files, skipped, err := managedFilesMatchingDryRunContent(candidates, opts.OutputDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return files, skipped, dedupePaths(kept), nil
+}
+
+// renderCurrentTemplateForRewind dry-runs the checked-out template with the
+// project's variables, rendering what ign generated for the project.
+func renderCurrentTemplateForRewind(ctx context.Context, opts RewindOptions) (*generator.GenerateResult, error) {
 	ignConfigPath := filepath.Join(model.IgnConfigDir, model.IgnProjectConfigFile)
 	ignVarPath := filepath.Join(model.IgnConfigDir, model.IgnVarFile)
 
 	ignConfig, err := config.LoadIgnConfig(ignConfigPath)
 	if err != nil {
-		return nil, nil, NewCheckoutError("failed to load .ign/ign.json: run 'ign checkout <template-url>' first", err)
+		return nil, NewCheckoutError("failed to load .ign/ign.json: run 'ign checkout <template-url>' first", err)
 	}
 
 	ignVar, err := config.LoadIgnVarJson(ignVarPath)
 	if err != nil {
-		return nil, nil, NewCheckoutError("failed to load .ign/ign-var.json: run 'ign checkout <template-url>' first", err)
+		return nil, NewCheckoutError("failed to load .ign/ign-var.json: run 'ign checkout <template-url>' first", err)
 	}
 
 	normalizedURL := NormalizeTemplateURL(ignConfig.Template.URL)
 	prov, err := provider.NewProviderWithToken(normalizedURL, opts.GitHubToken)
 	if err != nil {
-		return nil, nil, NewCheckoutError("failed to create provider", err)
+		return nil, NewCheckoutError("failed to create provider", err)
 	}
 
 	templateRef, err := prov.Resolve(normalizedURL)
 	if err != nil {
-		return nil, nil, NewCheckoutError("failed to resolve template URL", err)
+		return nil, NewCheckoutError("failed to resolve template URL", err)
 	}
 	if ignConfig.Template.Ref != "" {
 		templateRef.Ref = ignConfig.Template.Ref
@@ -180,12 +582,12 @@ func buildManagedFilesFromCurrentTemplate(ctx context.Context, opts RewindOption
 
 	template, err := prov.Fetch(ctx, templateRef)
 	if err != nil {
-		return nil, nil, NewTemplateFetchError("failed to fetch template", err)
+		return nil, NewTemplateFetchError("failed to fetch template", err)
 	}
 
 	_, vars, err := prepareVariablesForGeneration(template.Config.Variables, ignVar.Variables, model.IgnConfigDir, opts.OutputDir)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	gen := generator.NewGenerator()
@@ -196,10 +598,9 @@ func buildManagedFilesFromCurrentTemplate(ctx context.Context, opts RewindOption
 		Overwrite: true,
 	})
 	if err != nil {
-		return nil, nil, NewCheckoutError("failed to enumerate generated files", err)
+		return nil, NewCheckoutError("failed to enumerate generated files", err)
 	}
-
-	return managedFilesMatchingDryRunContent(genResult.DryRunFiles, opts.OutputDir)
+	return genResult, nil
 }
 
 func managedFilesMatchingDryRunContent(files []generator.DryRunFile, outputDir string) ([]string, []string, error) {
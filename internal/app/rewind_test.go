@@ -154,3 +154,233 @@ func TestRewind_MissingManifestSkipsDifferentPreexistingFile(t *testing.T) {
 		t.Fatalf("README content = %q, want user content", content)
 	}
 }
+
+func TestRewind_KeepsSeedAndUserPolicyFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("failed to create .ign directory: %v", err)
+	}
+	for _, name := range []string{"Makefile", "config.yml", "LOCAL.md"} {
+		if err := os.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files: []string{"LOCAL.md", "Makefile", "config.yml"},
+		Policies: map[string]model.FilePolicy{
+			"Makefile":   model.FilePolicyManaged,
+			"config.yml": model.FilePolicySeed,
+			"LOCAL.md":   model.FilePolicyUser,
+		},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.FilesRemoved != 1 || result.FilesKept != 2 {
+		t.Fatalf("removed/kept = %d/%d, want 1/2", result.FilesRemoved, result.FilesKept)
+	}
+	if _, err := os.Stat("Makefile"); !os.IsNotExist(err) {
+		t.Errorf("managed Makefile should be removed, stat error = %v", err)
+	}
+	for _, name := range []string{"config.yml", "LOCAL.md"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("%s should be kept: %v", name, err)
+		}
+	}
+}
+
+func TestRewind_RevertsInjections(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("failed to create .ign directory: %v", err)
+	}
+	if err := os.WriteFile("routes.go", []byte("// routes\nregister(\"users\")\n"), 0644); err != nil {
+		t.Fatalf("failed to write routes.go: %v", err)
+	}
+	if err := os.WriteFile("main.go", []byte("package main // edited\n"), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files: []string{},
+		Injections: []model.InjectionRecord{
+			{Path: "routes.go", ID: "route", Content: "register(\"users\")\n"},
+			{Path: "main.go", ID: "pkg", Content: "package main\n", Original: "package app\n"},
+		},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.InjectionsReverted != 1 || result.InjectionsSkipped != 1 {
+		t.Fatalf("reverted/skipped = %d/%d, want 1/1", result.InjectionsReverted, result.InjectionsSkipped)
+	}
+
+	routes, err := os.ReadFile("routes.go")
+	if err != nil {
+		t.Fatalf("failed to read routes.go: %v", err)
+	}
+	if string(routes) != "// routes\n" {
+		t.Errorf("routes.go = %q, want injection removed", routes)
+	}
+	mainGo, err := os.ReadFile("main.go")
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+	if string(mainGo) != "package main // edited\n" {
+		t.Errorf("main.go = %q, edited injection should be left alone", mainGo)
+	}
+}
+
+func writeRewindProject(t *testing.T, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("failed to create .ign directory: %v", err)
+	}
+	var manifestFiles []string
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		manifestFiles = append(manifestFiles, path)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files:    manifestFiles,
+		Policies: map[string]model.FilePolicy{"api/handler.go": model.FilePolicyManaged},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+}
+
+func TestRewind_DryRunChangesNothing(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	writeRewindProject(t, map[string]string{"README.md": "readme\n", "api/handler.go": "package api\n"})
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, DryRun: true})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.FilesRemoved != 2 || len(result.RemovedFiles) != 2 || !result.ConfigRemoved {
+		t.Fatalf("dry run result = %+v, want 2 files and .ign to be removed", result)
+	}
+	for _, path := range []string{"README.md", "api/handler.go", model.IgnConfigDir} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(model.IgnConfigDir, "history")); !os.IsNotExist(err) {
+		t.Errorf("dry run should not record history, stat error = %v", err)
+	}
+}
+
+func TestRewind_PathsLimitRewindAndKeepConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	writeRewindProject(t, map[string]string{"README.md": "readme\n", "api/handler.go": "package api\n"})
+
+	if _, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, Paths: []string{"../elsewhere"}}); err == nil {
+		t.Fatal("Rewind should reject a path outside the output directory")
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, Paths: []string{"api"}})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.FilesRemoved != 1 || result.ConfigRemoved {
+		t.Fatalf("removed = %d, config removed = %v; want 1 file and .ign kept", result.FilesRemoved, result.ConfigRemoved)
+	}
+	if _, err := os.Lstat("api"); !os.IsNotExist(err) {
+		t.Errorf("api should be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat("README.md"); err != nil {
+		t.Errorf("README.md is outside the rewound path: %v", err)
+	}
+
+	manifest, err := config.LoadIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile))
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0] != "README.md" || len(manifest.Policies) != 0 {
+		t.Errorf("manifest = %+v, want only README.md", manifest)
+	}
+}
+
+func TestRewind_KeepModifiedSkipsChangedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	writeLocalTemplate(t, filepath.Join(tempDir, "template"), &model.IgnJson{
+		Name:    "rewind-template",
+		Version: "1.0.0",
+		Hash:    testHash1,
+	}, map[string]string{
+		"README.md":      "readme\n",
+		"api/handler.go": "package api\n",
+	})
+	writeProjectConfig(t, "./template", "", map[string]interface{}{})
+	if _, err := Checkout(context.Background(), CheckoutOptions{OutputDir: "."}); err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if err := os.WriteFile("api/handler.go", []byte("package api // edited\n"), 0644); err != nil {
+		t.Fatalf("failed to edit api/handler.go: %v", err)
+	}
+	// The recorded hashes are enough; the template is not rendered again.
+	if err := os.RemoveAll("template"); err != nil {
+		t.Fatalf("failed to remove template: %v", err)
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: ".", KeepModified: true, KeepConfig: true})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.FilesRemoved != 1 || result.FilesSkipped != 1 || result.SkippedFiles[0] != "api/handler.go" {
+		t.Fatalf("removed/skipped = %d/%v, want README.md removed and api/handler.go skipped", result.FilesRemoved, result.SkippedFiles)
+	}
+	if _, err := os.Stat("api/handler.go"); err != nil {
+		t.Errorf("modified file should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(model.IgnConfigDir, model.IgnProjectConfigFile)); err != nil {
+		t.Errorf(".ign should be kept: %v", err)
+	}
+}
+
+func TestRewind_KeepsFilesProtectedByProject(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	writeRewindProject(t, map[string]string{"README.md": "readme\n", "api/handler.go": "package api\n"})
+	if err := os.WriteFile(filepath.Join(model.IgnConfigDir, model.IgnProjectOverwriteIgnoreFile), []byte("api/\n"), 0644); err != nil {
+		t.Fatalf("failed to write project overwrite-ignore: %v", err)
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, KeepHistory: true})
+	if err != nil {
+		t.Fatalf("Rewind() error = %v", err)
+	}
+	if result.FilesRemoved != 1 || result.FilesProtected != 1 || result.ProtectedFiles[0] != "api/handler.go" {
+		t.Fatalf("removed/protected = %d/%v, want README.md removed and api/handler.go protected", result.FilesRemoved, result.ProtectedFiles)
+	}
+	if _, err := os.Stat("api/handler.go"); err != nil {
+		t.Errorf("protected file should be kept: %v", err)
+	}
+
+	if _, err := Undo(context.Background(), UndoOptions{OutputDir: tempDir}); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(model.IgnConfigDir, model.IgnProjectOverwriteIgnoreFile)); err != nil {
+		t.Errorf("undo should restore the project overwrite-ignore: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/defaults"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// JSONSchemaDialect is the JSON Schema draft emitted by TemplateSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// TemplateSchemaOptions holds options for generating a template's variable schema.
+type TemplateSchemaOptions struct {
+	// Path is the template directory or the path of its ign-template.json.
+	Path string
+}
+
+// Patterns of the numeric strings NumericVariableValue accepts: int strings
+// go through strconv.Atoi, number strings through strconv.ParseFloat after
+// trimming spaces.
+const (
+	intStringPattern     = `^[+-]?[0-9]+$`
+	numberStringPattern  = `^\s*[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?\s*$`
+	fileReferencePattern = `^@file:`
+)
+
+// JSONSchemaType is the "type" keyword: a single type name, or a list of
+// allowed types.
+type JSONSchemaType []string
+
+// MarshalJSON writes a single type as a string and several as an array.
+func (t JSONSchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// JSONSchema is the subset of JSON Schema (draft 2020-12) used to describe
+// template variables. Field order matches the emitted document.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 JSONSchemaType         `json:"type,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Examples             []interface{}          `json:"examples,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// TemplateSchema loads a template's ign-template.json and builds a JSON Schema
+// for the .ign/ign-var.json file of projects generated from it.
+func TemplateSchema(ctx context.Context, opts TemplateSchemaOptions) (*JSONSchema, error) {
+	debug.DebugSection("[app] TemplateSchema workflow start")
+	debug.DebugValue("[app] Template path", opts.Path)
+
+	absPath, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return nil, NewValidationError("failed to get absolute path", err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, NewValidationError(fmt.Sprintf("path not found: %s", absPath), err)
+	}
+	ignJsonPath := absPath
+	if info.IsDir() {
+		ignJsonPath = filepath.Join(absPath, model.IgnTemplateConfigFile)
+	}
+	debug.DebugValue("[app] Template config", ignJsonPath)
+
+	ignJson, err := config.LoadIgnJson(ignJsonPath)
+	if err != nil {
+		return nil, NewValidationError("failed to load "+model.IgnTemplateConfigFile, err)
+	}
+
+	schema := BuildVarsSchema(ignJson)
+	debug.DebugValue("[app] Schema variables", len(ignJson.Variables))
+	return schema, nil
+}
+
+// BuildVarsSchema builds the JSON Schema of ign-var.json for a template.
+// A variable is listed as required only when it is required and has no
+// default, matching ValidateVarValues. Defaults containing runtime
+// placeholders such as {current_dir} are omitted because their value depends
+// on the project directory. Undeclared variables are allowed, since
+// ign-var.json keeps values of variables a template update removed.
+func BuildVarsSchema(ignJson *model.IgnJson) *JSONSchema {
+	variables := &JSONSchema{
+		Type:       JSONSchemaType{"object"},
+		Properties: make(map[string]*JSONSchema, len(ignJson.Variables)),
+	}
+	for name, varDef := range ignJson.Variables {
+		variables.Properties[name] = varDefSchema(varDef)
+		if varDef.Required && varDef.Default == nil {
+			variables.Required = append(variables.Required, name)
+		}
+	}
+	sort.Strings(variables.Required)
+
+	return &JSONSchema{
+		Schema:      JSONSchemaDialect,
+		Title:       ignJson.Name,
+		Description: ignJson.Description,
+		Type:        JSONSchemaType{"object"},
+		Properties: map[string]*JSONSchema{
+			"variables": variables,
+		},
+		Required: []string{"variables"},
+	}
+}
+
+// varDefSchema describes one variable the way ValidateVarValues checks it.
+// Int and number variables also accept numeric strings, which minimum and
+// maximum do not constrain. A string pattern does not apply to @file:
+// references, whose file content is checked at generation time instead.
+func varDefSchema(varDef model.VarDef) *JSONSchema {
+	prop := &JSONSchema{
+		Type:        jsonSchemaType(varDef.Type),
+		Description: varDef.Description,
+	}
+	if varDef.Default != nil && !defaults.ContainsPlaceholder(varDef.Default) {
+		prop.Default = varDef.Default
+	}
+	if varDef.Example != nil {
+		prop.Examples = []interface{}{varDef.Example}
+	}
+	switch varDef.Type {
+	case model.VarTypeInt:
+		prop.Pattern = intStringPattern
+		prop.Minimum = varDef.Min
+		prop.Maximum = varDef.Max
+	case model.VarTypeNumber:
+		prop.Pattern = numberStringPattern
+		prop.Minimum = varDef.Min
+		prop.Maximum = varDef.Max
+	case model.VarTypeString, "":
+		if varDef.Pattern != "" {
+			prop.AnyOf = []*JSONSchema{
+				{Pattern: varDef.Pattern},
+				{Pattern: fileReferencePattern},
+			}
+		}
+		// Required string variables must not be empty.
+		if varDef.Required {
+			minLength := 1
+			prop.MinLength = &minLength
+		}
+	}
+	return prop
+}
+
+// jsonSchemaType maps a variable type to its JSON Schema types. Unknown types
+// map to no type constraint.
+func jsonSchemaType(typ model.VarType) JSONSchemaType {
+	switch typ {
+	case model.VarTypeString, "":
+		return JSONSchemaType{"string"}
+	case model.VarTypeInt:
+		return JSONSchemaType{"integer", "string"}
+	case model.VarTypeNumber:
+		return JSONSchemaType{"number", "string"}
+	case model.VarTypeBool:
+		return JSONSchemaType{"boolean"}
+	default:
+		return nil
+	}
+}
+
+// MarshalJSONSchema encodes a schema as indented JSON with a trailing newline.
+// HTML escaping is disabled so patterns such as "a&b" stay readable.
+func MarshalJSONSchema(schema *JSONSchema) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
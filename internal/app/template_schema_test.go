@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTemplateSchema(t *testing.T) {
+	dir := t.TempDir()
+	ignJson := `{
+  "name": "svc",
+  "version": "1.0.0",
+  "description": "Service template",
+  "variables": {
+    "app_name": {"type": "string", "description": "Application name", "required": true, "pattern": "^[a-z<>&-]+$", "example": "my-app"},
+    "module": {"type": "string", "description": "Go module", "required": true, "default": "github.com/acme/{current_dir}"},
+    "port": {"type": "int", "description": "HTTP port", "default": 8080, "min": 1, "max": 65535},
+    "ratio": {"type": "number", "description": "Sample ratio", "min": 0, "max": 1},
+    "debug": {"type": "bool", "description": "Enable debug", "default": false}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "ign-template.json"), []byte(ignJson), 0644); err != nil {
+		t.Fatalf("failed to write ign-template.json: %v", err)
+	}
+
+	schema, err := TemplateSchema(context.Background(), TemplateSchemaOptions{Path: dir})
+	if err != nil {
+		t.Fatalf("TemplateSchema() error = %v", err)
+	}
+
+	if schema.Schema != JSONSchemaDialect {
+		t.Errorf("$schema = %q, want %q", schema.Schema, JSONSchemaDialect)
+	}
+	if schema.Title != "svc" || schema.Description != "Service template" {
+		t.Errorf("title/description = %q/%q", schema.Title, schema.Description)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"variables"}) {
+		t.Errorf("root required = %v, want [variables]", schema.Required)
+	}
+
+	variables := schema.Properties["variables"]
+	if variables == nil {
+		t.Fatal("variables property missing")
+	}
+	// ign-var.json keeps values of variables removed by template updates.
+	if variables.AdditionalProperties != nil {
+		t.Error("variables should allow additional properties")
+	}
+	// module is required but has a default, so ign-var.json may omit it.
+	if !reflect.DeepEqual(variables.Required, []string{"app_name"}) {
+		t.Errorf("variables required = %v, want [app_name]", variables.Required)
+	}
+
+	wantTypes := map[string]JSONSchemaType{
+		"app_name": {"string"},
+		"module":   {"string"},
+		"port":     {"integer", "string"},
+		"ratio":    {"number", "string"},
+		"debug":    {"boolean"},
+	}
+	for name, want := range wantTypes {
+		prop := variables.Properties[name]
+		if prop == nil {
+			t.Fatalf("property %s missing", name)
+		}
+		if !reflect.DeepEqual(prop.Type, want) {
+			t.Errorf("%s type = %v, want %v", name, prop.Type, want)
+		}
+	}
+
+	appName := variables.Properties["app_name"]
+	// The pattern is checked unless the value is an @file: reference.
+	wantAnyOf := []*JSONSchema{{Pattern: "^[a-z<>&-]+$"}, {Pattern: "^@file:"}}
+	if appName.Pattern != "" || !reflect.DeepEqual(appName.AnyOf, wantAnyOf) {
+		t.Errorf("app_name pattern/anyOf = %q/%v", appName.Pattern, appName.AnyOf)
+	}
+	if appName.MinLength == nil || *appName.MinLength != 1 {
+		t.Errorf("app_name minLength = %v", appName.MinLength)
+	}
+	if !reflect.DeepEqual(appName.Examples, []interface{}{"my-app"}) {
+		t.Errorf("app_name examples = %v", appName.Examples)
+	}
+	if variables.Properties["module"].Default != nil {
+		t.Errorf("module default with placeholder should be omitted, got %v", variables.Properties["module"].Default)
+	}
+
+	port := variables.Properties["port"]
+	if port.Default != float64(8080) || port.Minimum == nil || *port.Minimum != 1 || port.Maximum == nil || *port.Maximum != 65535 {
+		t.Errorf("port default/min/max = %v/%v/%v", port.Default, port.Minimum, port.Maximum)
+	}
+	// Numeric strings accepted by NumericVariableValue match the string pattern.
+	for prop, values := range map[string]map[string]bool{
+		"port":  {"8080": true, "-1": true, "8080.0": false, "1e3": false, "eighty": false},
+		"ratio": {"0.5": true, " .5 ": true, "1e-3": true, "half": false},
+	} {
+		pattern := regexp.MustCompile(variables.Properties[prop].Pattern)
+		for value, want := range values {
+			if got := pattern.MatchString(value); got != want {
+				t.Errorf("%s pattern matches %q = %v, want %v", prop, value, got, want)
+			}
+		}
+	}
+
+	data, err := MarshalJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("MarshalJSONSchema() error = %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`"$schema": "https://json-schema.org/draft/2020-12/schema"`,
+		`"pattern": "^[a-z<>&-]+$"`,
+		`"default": false`,
+		`"type": "boolean"`,
+		"\"type\": [\n            \"integer\",\n            \"string\"\n          ]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("schema output missing %s:\n%s", want, out)
+		}
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("schema output is not valid JSON: %v", err)
+	}
+}
+
+func TestTemplateSchema_ConfigFilePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ign-template.json")
+	if err := os.WriteFile(path, []byte(`{"name":"t","version":"1.0.0","variables":{"name":{"type":"string","required":true}}}`), 0644); err != nil {
+		t.Fatalf("failed to write ign-template.json: %v", err)
+	}
+
+	schema, err := TemplateSchema(context.Background(), TemplateSchemaOptions{Path: path})
+	if err != nil {
+		t.Fatalf("TemplateSchema() error = %v", err)
+	}
+	if schema.Properties["variables"].Properties["name"] == nil {
+		t.Error("expected name property")
+	}
+
+	if _, err := TemplateSchema(context.Background(), TemplateSchemaOptions{Path: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
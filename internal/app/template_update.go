@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -65,8 +66,8 @@ type UpdateTemplateResult struct {
 var (
 	// Pattern for @ign-var:ARGS@
 	varDirectivePattern = regexp.MustCompile(`@ign-var:([^@]+)@`)
-	// Pattern for @ign-if:VAR@
-	ifDirectivePattern = regexp.MustCompile(`@ign-if:([^@]+)@`)
+	// Pattern for @ign-if:VAR@, with or without a leading trim marker
+	ifDirectivePattern = regexp.MustCompile(`@-?ign-if:([^@]+)@`)
 )
 
 // UpdateTemplate scans template files and updates ign-template.json with variable definitions and hash.
@@ -122,6 +123,21 @@ func UpdateTemplate(ctx context.Context, opts UpdateTemplateOptions) (*UpdateTem
 		return nil, err
 	}
 
+	// Declare bool variables that are referenced only by file conditions.
+	// Existing declarations are left as the author wrote them.
+	if existingIgnJson != nil {
+		for _, rule := range existingIgnJson.Files {
+			name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rule.When), "!"))
+			if _, declared := existingIgnJson.Variables[name]; declared {
+				continue
+			}
+			if _, found := result.Variables[name]; found {
+				continue
+			}
+			addConditionalVar(name, model.IgnTemplateConfigFile, result)
+		}
+	}
+
 	debug.DebugValue("[app] Files scanned", result.FilesScanned)
 	debug.DebugValue("[app] Variables found", len(result.Variables))
 
@@ -356,6 +372,13 @@ func parseVarArgs(args string) (string, model.VarType, interface{}, bool) {
 			varType = model.VarTypeString
 		case "int":
 			varType = model.VarTypeInt
+		case "number":
+			varType = model.VarTypeNumber
+			// Decimal defaults are kept as strings by parseDefaultValueStr so
+			// untyped versions stay verbatim; widen them once the type is known.
+			if hasDefault {
+				defaultValue = numberDefaultValue(defaultValue)
+			}
 		case "bool":
 			varType = model.VarTypeBool
 		}
@@ -391,12 +414,31 @@ func parseDefaultValueStr(value string) interface{} {
 	return value
 }
 
+// numberDefaultValue converts a parsed default to float64 for number variables.
+// Values that are not numeric are returned unchanged so validation reports them.
+func numberDefaultValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case int:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	return val
+}
+
 // inferVarType infers VarType from a value.
 func inferVarType(val interface{}) model.VarType {
-	switch val.(type) {
+	switch v := val.(type) {
 	case bool:
 		return model.VarTypeBool
-	case int, int64, float64:
+	case int, int64:
+		return model.VarTypeInt
+	case float64:
+		if v != math.Trunc(v) {
+			return model.VarTypeNumber
+		}
 		return model.VarTypeInt
 	default:
 		return model.VarTypeString
@@ -251,6 +251,12 @@ func TestParseVarArgs(t *testing.T) {
 		// Plain integers should still work
 		{"count=42", "count", model.VarTypeInt, 42, true},
 		{"level=-5", "level", model.VarTypeInt, -5, true},
+		// Explicit number type widens numeric defaults to float64
+		{"ratio:number", "ratio", model.VarTypeNumber, nil, false},
+		{"ratio:number=0.5", "ratio", model.VarTypeNumber, 0.5, true},
+		{"timeout:number=30", "timeout", model.VarTypeNumber, float64(30), true},
+		// Untyped decimals stay strings so versions render verbatim
+		{"version=1.0", "version", model.VarTypeString, "1.0", true},
 	}
 
 	for _, tt := range tests {
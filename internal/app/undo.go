@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// ListHistory returns the snapshots recorded for the project in outputDir,
+// newest first.
+func ListHistory(outputDir string) ([]*model.HistorySnapshot, error) {
+	historyDir := filepath.Join(outputDir, model.IgnConfigDir, model.IgnHistoryDir)
+	ids, err := historySnapshotIDs(historyDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to read history", err)
+	}
+	snapshots := make([]*model.HistorySnapshot, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		snapshot, err := config.LoadHistorySnapshot(filepath.Join(historyDir, ids[i], model.IgnHistorySnapshotFile))
+		if err != nil {
+			return nil, NewCheckoutError(fmt.Sprintf("failed to load history snapshot %s", ids[i]), err)
+		}
+		snapshot.ID = ids[i]
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// UndoOptions contains options for restoring a history snapshot.
+type UndoOptions struct {
+	// OutputDir is the project directory containing .ign.
+	OutputDir string
+	// ID selects the snapshot to restore. The newest snapshot is used when empty.
+	ID string
+	// Force restores paths that changed after the snapshot was recorded.
+	Force bool
+	// DryRun reports what would be restored without changing anything.
+	DryRun bool
+}
+
+// UndoResult contains the result of restoring a snapshot.
+type UndoResult struct {
+	// Snapshot is the restored snapshot.
+	Snapshot *model.HistorySnapshot
+	// Restored lists paths put back to their recorded content.
+	Restored []string
+	// Removed lists paths the undone command created and undo deleted.
+	Removed []string
+	// Conflicts lists paths changed since the snapshot was recorded.
+	Conflicts []string
+	// Errors contains paths that could not be restored.
+	Errors []error
+}
+
+// Undo restores the files a recorded command replaced, removed or created,
+// then deletes the snapshot. Paths changed since the command ran are only
+// overwritten with Force.
+func Undo(ctx context.Context, opts UndoOptions) (*UndoResult, error) {
+	debug.DebugSection("[app] Undo workflow start")
+	debug.DebugValue("[app] OutputDir", opts.OutputDir)
+	debug.DebugValue("[app] ID", opts.ID)
+
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+	configDir := filepath.Join(opts.OutputDir, model.IgnConfigDir)
+	if !opts.DryRun {
+		lock, err := LockProject(ctx, configDir, "undo")
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
+	}
+	historyDir := filepath.Join(configDir, model.IgnHistoryDir)
+	ids, err := historySnapshotIDs(historyDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to read history", err)
+	}
+	if len(ids) == 0 {
+		return nil, NewValidationError("no history to undo in "+historyDir, nil)
+	}
+	id := opts.ID
+	if id == "" {
+		id = ids[len(ids)-1]
+	} else if !slices.Contains(ids, id) {
+		return nil, NewValidationError(fmt.Sprintf("history snapshot %q not found; run 'ign history' to list snapshots", id), nil)
+	}
+
+	snapshotDir := filepath.Join(historyDir, id)
+	snapshot, err := config.LoadHistorySnapshot(filepath.Join(snapshotDir, model.IgnHistorySnapshotFile))
+	if err != nil {
+		return nil, NewCheckoutError(fmt.Sprintf("failed to load history snapshot %s", id), err)
+	}
+	snapshot.ID = id
+
+	absConfigDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to resolve .ign directory", err)
+	}
+	root := filepath.Dir(absConfigDir)
+	result := &UndoResult{Snapshot: snapshot}
+	for _, entry := range snapshot.Entries {
+		current, err := historyFingerprint(resolveHistoryPath(root, entry.Path))
+		if err != nil {
+			return nil, NewCheckoutError("failed to inspect project state", err)
+		}
+		if current != entry.After {
+			result.Conflicts = append(result.Conflicts, entry.Path)
+		}
+	}
+	if len(result.Conflicts) > 0 && !opts.Force {
+		return result, NewValidationError(fmt.Sprintf("%d paths changed after %s ran; use --force to restore them anyway: %s",
+			len(result.Conflicts), snapshot.Command, strings.Join(result.Conflicts, ", ")), nil)
+	}
+
+	result.Restored, result.Removed, result.Errors, err = restoreHistoryEntries(ctx, snapshotDir, root, snapshot.Entries, opts.DryRun)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if len(result.Errors) > 0 {
+		return result, NewCheckoutError("failed to restore some paths; the snapshot was kept", errors.Join(result.Errors...))
+	}
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return result, NewCheckoutError(fmt.Sprintf("failed to remove history snapshot %s", id), err)
+	}
+	removeEmptyHistoryDirs(configDir)
+	debug.Debug("[app] Undo workflow completed successfully")
+	return result, nil
+}
+
+// restoreHistoryEntries puts the paths of entries, recorded in snapshotDir,
+// back as they were. It recreates directories parent-first, restores files,
+// then deletes created paths child-first so emptied directories can go too.
+// Failures for single paths are returned in errs.
+func restoreHistoryEntries(ctx context.Context, snapshotDir, root string, entries []model.HistoryEntry, dryRun bool) (restored, removed []string, errs []error, err error) {
+	entries = append([]model.HistoryEntry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return managedPathDepth(entries[i].Path) < managedPathDepth(entries[j].Path)
+	})
+	var absent []model.HistoryEntry
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return restored, removed, errs, err
+		}
+		if entry.Kind == model.HistoryEntryAbsent {
+			absent = append(absent, entry)
+			continue
+		}
+		if !dryRun {
+			if err := restoreHistoryEntry(snapshotDir, resolveHistoryPath(root, entry.Path), entry); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", entry.Path, err))
+				continue
+			}
+		}
+		restored = append(restored, entry.Path)
+	}
+	for i := len(absent) - 1; i >= 0; i-- {
+		entry := absent[i]
+		path := resolveHistoryPath(root, entry.Path)
+		if info, err := os.Lstat(path); err == nil && info.IsDir() {
+			if children, err := os.ReadDir(path); err == nil && len(children) > 0 {
+				// The directory now holds files the command did not create.
+				continue
+			}
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", entry.Path, err))
+				continue
+			}
+		}
+		removed = append(removed, entry.Path)
+	}
+	return restored, removed, errs, nil
+}
+
+// restoreHistoryEntry puts path back to the node recorded in entry.
+func restoreHistoryEntry(snapshotDir, path string, entry model.HistoryEntry) error {
+	info, err := os.Lstat(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if entry.Kind == model.HistoryEntryDir {
+		if exists && !info.IsDir() {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+		return os.Chmod(path, entry.Mode)
+	}
+
+	if exists && info.IsDir() {
+		// Only an empty directory created by the undone command is replaced.
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		exists = false
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	switch entry.Kind {
+	case model.HistoryEntrySymlink:
+		if exists {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		return os.Symlink(entry.Target, path)
+	case model.HistoryEntryFile:
+		content, err := os.ReadFile(filepath.Join(snapshotDir, historyBlobDir, entry.Blob))
+		if err != nil {
+			return err
+		}
+		if exists && info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		return config.WriteFileAtomic(path, content, entry.Mode)
+	default:
+		return fmt.Errorf("unknown history entry kind %q", entry.Kind)
+	}
+}
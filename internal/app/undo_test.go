@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestUndo_RestoresOverwriteAllUpdate(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	localPath := filepath.Join(tempDir, "local.txt")
+	if err := os.WriteFile(localPath, []byte("untracked local edits\n"), 0600); err != nil {
+		t.Fatalf("Failed to write local.txt: %v", err)
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{Files: []string{localPath}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+	originalManifest := readTestFile(t, manifestPath)
+
+	prep := newReviewUpdatePrep(t, tempDir, []model.TemplateFile{
+		{Path: "local.txt", Content: []byte("template\n"), Mode: 0644},
+		{Path: "sub/new.txt", Content: []byte("new\n"), Mode: 0644},
+	})
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		NewVariables:  map[string]interface{}{},
+		OutputDir:     tempDir,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteAll,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.HistoryID == "" {
+		t.Fatal("CompleteUpdate should record a history snapshot")
+	}
+	if got := readTestFile(t, localPath); got != "template\n" {
+		t.Fatalf("local.txt = %q, want the template content", got)
+	}
+
+	snapshots, err := ListHistory(tempDir)
+	if err != nil {
+		t.Fatalf("ListHistory failed: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].ID != result.HistoryID || snapshots[0].Command != "update" {
+		t.Fatalf("ListHistory = %+v, want the update snapshot %s", snapshots, result.HistoryID)
+	}
+
+	undo, err := Undo(context.Background(), UndoOptions{OutputDir: tempDir})
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if !slices.Contains(undo.Removed, filepath.Join("sub", "new.txt")) {
+		t.Errorf("Undo Removed = %v, want sub/new.txt", undo.Removed)
+	}
+
+	if got := readTestFile(t, localPath); got != "untracked local edits\n" {
+		t.Errorf("local.txt = %q, want the original content", got)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		t.Fatalf("Failed to stat local.txt: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("local.txt mode = %o, want 600", info.Mode().Perm())
+	}
+	if _, err := os.Lstat(filepath.Join(tempDir, "sub")); !os.IsNotExist(err) {
+		t.Errorf("directory created by the update should be removed, stat error = %v", err)
+	}
+	if got := readTestFile(t, manifestPath); got != originalManifest {
+		t.Errorf("manifest = %q, want the original %q", got, originalManifest)
+	}
+	if _, err := os.Stat(filepath.Join(ignDir, model.IgnHistoryDir)); !os.IsNotExist(err) {
+		t.Errorf("undone snapshot should be removed with its empty history directory, stat error = %v", err)
+	}
+}
+
+func TestUndo_RestoresRewoundProject(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	if err := os.WriteFile("Makefile", []byte("all:\n"), 0644); err != nil {
+		t.Fatalf("Failed to write Makefile: %v", err)
+	}
+	manifestPath := filepath.Join(model.IgnConfigDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{Files: []string{"Makefile"}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	result, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, KeepHistory: true})
+	if err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if result.HistoryID == "" {
+		t.Fatal("Rewind should record a history snapshot")
+	}
+	if ConfigDirInUse(model.IgnConfigDir) {
+		t.Fatal(".ign should only hold history after rewind")
+	}
+
+	if _, err := Undo(context.Background(), UndoOptions{OutputDir: tempDir}); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readTestFile(t, "Makefile"); got != "all:\n" {
+		t.Errorf("Makefile = %q, want the removed content", got)
+	}
+	if _, err := config.LoadIgnManifest(manifestPath); err != nil {
+		t.Errorf("manifest should be restored: %v", err)
+	}
+}
+
+func TestUndo_RefusesChangedPathsWithoutForce(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	if err := os.MkdirAll(model.IgnConfigDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	if err := os.WriteFile("Makefile", []byte("all:\n"), 0644); err != nil {
+		t.Fatalf("Failed to write Makefile: %v", err)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{Files: []string{"Makefile"}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+	if _, err := Rewind(context.Background(), RewindOptions{OutputDir: tempDir, KeepHistory: true}); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if err := os.WriteFile("Makefile", []byte("rewritten by hand\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite Makefile: %v", err)
+	}
+
+	result, err := Undo(context.Background(), UndoOptions{OutputDir: tempDir})
+	if err == nil {
+		t.Fatal("Undo should refuse to overwrite a path changed after the snapshot")
+	}
+	if result == nil || !slices.Contains(result.Conflicts, "Makefile") {
+		t.Fatalf("Undo conflicts = %+v, want Makefile", result)
+	}
+	if got := readTestFile(t, "Makefile"); got != "rewritten by hand\n" {
+		t.Fatalf("Makefile = %q, want it left alone", got)
+	}
+
+	if _, err := Undo(context.Background(), UndoOptions{OutputDir: tempDir, Force: true}); err != nil {
+		t.Fatalf("Undo with Force failed: %v", err)
+	}
+	if got := readTestFile(t, "Makefile"); got != "all:\n" {
+		t.Errorf("Makefile = %q, want the recorded content", got)
+	}
+}
@@ -2,10 +2,13 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/tacogips/ign/internal/build"
@@ -45,6 +48,16 @@ type PrepareUpdateResult struct {
 	NewVars []string
 	// RemovedVars contains names of variables that no longer exist in template.
 	RemovedVars []string
+	// VariableMigrations lists the template-declared migrations applied to
+	// ExistingVars. They are persisted to ign-var.json when the update completes.
+	VariableMigrations []VarMigrationChange
+	// PreviousVersion is the template version recorded when the project was
+	// last generated, if known.
+	PreviousVersion string
+	// TemplateMigrations lists the versioned template migrations this update
+	// crosses, oldest first. Their variable steps are already applied to
+	// ExistingVars; CompleteUpdate applies their file steps.
+	TemplateMigrations []model.TemplateMigration
 	// CurrentHash is the current hash stored in .ign/ign.json.
 	CurrentHash string
 	// NewHash is the new hash of the fetched template.
@@ -67,6 +80,9 @@ type PrepareUpdateResult struct {
 	RefOverrideRequested bool
 	// RefChanged indicates whether the requested ref differs from the stored ref.
 	RefChanged bool
+
+	// readState fingerprints the tracking files before they were read.
+	readState string
 }
 
 // UpdateResult contains the results of the update operation.
@@ -77,12 +93,24 @@ type UpdateResult struct {
 	NewVariables []string
 	// RemovedVariables lists variables that were removed.
 	RemovedVariables []string
+	// VariableMigrations lists the migrations applied to stored variable values.
+	VariableMigrations []VarMigrationChange
+	// MovedFiles lists the generated files moved to new template paths,
+	// carrying the project's copy along.
+	MovedFiles []MovedFile
 	// FilesCreated is the number of new files created.
 	FilesCreated int
 	// FilesSkipped is the number of files skipped (already exist).
 	FilesSkipped int
 	// FilesOverwritten is the number of existing files overwritten.
 	FilesOverwritten int
+	// FilesMerged is the number of existing files updated in place by a region or structured merge.
+	FilesMerged int
+	// InjectionsApplied is the number of injections applied to existing files.
+	InjectionsApplied int
+	// Hooks lists the post_update hooks that apply to this project. They are
+	// not run by CompleteUpdate; see RunHooks.
+	Hooks []generator.ResolvedHook
 	// FilesDeleted is the number of previously managed paths removed from disk
 	// or pruned from tracking because they no longer exist in the template
 	// during an overwrite update.
@@ -95,6 +123,9 @@ type UpdateResult struct {
 	// pruned from tracking because they no longer exist in the template during
 	// an overwrite update.
 	DeletedFiles []string
+	// WrittenFiles lists the paths the update wrote, including files it
+	// injected into.
+	WrittenFiles []string
 	// DryRunFiles contains detailed information for dry-run mode.
 	DryRunFiles []DryRunFile
 	// UnresolvedTransitionPaths lists managed directories preserved because ign
@@ -111,6 +142,8 @@ type UpdateResult struct {
 	// ExecutionPlan is returned by a preview and must be reused for its
 	// confirmed mutation to prevent a changed source tree from being reclassified.
 	ExecutionPlan *UpdateExecutionPlan
+	// HistoryID is the undo snapshot recorded for this update, if any.
+	HistoryID string
 }
 
 // PrepareUpdate prepares for update by checking if .ign exists and fetching template.
@@ -138,6 +171,10 @@ func PrepareUpdate(ctx context.Context, opts UpdateOptions) (*PrepareUpdateResul
 		)
 	}
 
+	// Taken before reading, so CompleteUpdate can tell under the project lock
+	// whether what was read is still current.
+	readState := projectStateFingerprint(ignConfigPath, ignVarPath, manifestPathFromConfigPath(ignConfigPath))
+
 	// Step 2: Load existing configuration
 	debug.Debug("[app] Loading existing ign.json")
 	ignConfig, err := config.LoadIgnConfig(ignConfigPath)
@@ -210,7 +247,28 @@ func PrepareUpdate(ctx context.Context, opts UpdateOptions) (*PrepareUpdateResul
 	debug.DebugValue("[app] Hash changed", hashChanged)
 	debug.DebugValue("[app] Ref changed", refChanged)
 
-	// Step 6: Find new and removed variables
+	// Step 6: Migrate stored values for renamed, retyped, or removed variables
+	var previousVersion string
+	if ignConfig.Metadata != nil {
+		previousVersion = ignConfig.Metadata.TemplateVersion
+	}
+	pendingMigrations := pendingTemplateMigrations(template.Config.Migrations, previousVersion, template.Config.Version)
+	debug.DebugValue("[app] Previous template version", previousVersion)
+	debug.DebugValue("[app] Pending template migrations", len(pendingMigrations))
+	if err := checkRepeatableVariableMigrations(template.Config.VariableMigrations); err != nil {
+		return nil, err
+	}
+	varMigrations := templateMigrationVariables(pendingMigrations, template.Config.VariableMigrations)
+	existingVars, migrationChanges, err := applyVariableMigrations(existingVars, template.Config.Variables, varMigrations)
+	if err != nil {
+		debug.Debug("[app] Variable migration failed: %v", err)
+		return nil, err
+	}
+	for _, change := range migrationChanges {
+		debug.Debug("[app] Variable migration: %s", change)
+	}
+
+	// Step 7: Find new and removed variables
 	newVars, removedVars := findVariableChanges(existingVars, template.Config.Variables)
 	debug.DebugValue("[app] New variables", newVars)
 	debug.DebugValue("[app] Removed variables", removedVars)
@@ -221,6 +279,9 @@ func PrepareUpdate(ctx context.Context, opts UpdateOptions) (*PrepareUpdateResul
 		ExistingVars:         existingVars,
 		NewVars:              newVars,
 		RemovedVars:          removedVars,
+		VariableMigrations:   migrationChanges,
+		PreviousVersion:      previousVersion,
+		TemplateMigrations:   pendingMigrations,
 		CurrentHash:          ignConfig.Hash,
 		NewHash:              newHash,
 		HashChanged:          hashChanged,
@@ -232,6 +293,7 @@ func PrepareUpdate(ctx context.Context, opts UpdateOptions) (*PrepareUpdateResul
 		EffectiveRef:         effectiveRef,
 		RefOverrideRequested: refOverrideRequested,
 		RefChanged:           refChanged,
+		readState:            readState,
 	}
 
 	debug.Debug("[app] PrepareUpdate completed successfully")
@@ -346,23 +408,61 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 		if pending {
 			return nil, NewValidationError("interrupted managed directory-to-symlink transition requires recovery before dry run", nil)
 		}
-	} else if err := recoverSymlinkTransitionJournal(opts.OutputDir, manifestPath, prep.IgnConfigPath, prep.IgnVarPath); err != nil {
-		return nil, NewCheckoutError("recover interrupted managed directory-to-symlink transition", err)
+	} else {
+		lock, err := LockProject(ctx, filepath.Dir(prep.IgnConfigPath), "update")
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Release()
+		if err := recoverSymlinkTransitionJournal(opts.OutputDir, manifestPath, prep.IgnConfigPath, prep.IgnVarPath); err != nil {
+			return nil, NewCheckoutError("recover interrupted managed directory-to-symlink transition", err)
+		}
+		if err := checkProjectStateUnchanged(prep.readState, prep.IgnConfigPath, prep.IgnVarPath, manifestPath); err != nil {
+			return nil, err
+		}
 	}
 
 	if shouldCompleteUpdateConfigOnly(prep, opts) {
-		if !opts.DryRun {
-			if err := saveCompleteUpdateConfigOnlyArtifacts(prep, rawVars); err != nil {
-				return nil, err
-			}
-		}
-		return &UpdateResult{
+		result := &UpdateResult{
 			HashChanged:          prep.HashChanged,
 			NewVariables:         prep.NewVars,
 			RemovedVariables:     prep.RemovedVars,
+			VariableMigrations:   prep.VariableMigrations,
 			RefChanged:           prep.RefChanged,
 			RefOverrideRequested: prep.RefOverrideRequested,
-		}, nil
+		}
+		if !opts.DryRun {
+			history, err := BeginHistory(ctx, configDir, "update")
+			if err != nil {
+				return nil, NewCheckoutError("failed to start history snapshot", err)
+			}
+			defer history.Discard()
+			if err := history.captureConfig(configDir); err != nil {
+				return nil, NewCheckoutError("failed to record history snapshot", err)
+			}
+			if err := saveCompleteUpdateConfigOnlyArtifacts(prep, rawVars); err != nil {
+				return nil, err
+			}
+			result.HistoryID = commitHistory(history, &result.Errors)
+		}
+		return result, nil
+	}
+
+	seededPaths, err := seededPathsFromManifest(manifestPath)
+	if err != nil {
+		return nil, NewCheckoutError("failed to load ign-files.json", err)
+	}
+	declined, err := declinedChangesFromManifest(manifestPath)
+	if err != nil {
+		return nil, NewCheckoutError("failed to load ign-files.json", err)
+	}
+	projectIgnore, err := projectOverwriteIgnorePatterns(configDir)
+	if err != nil {
+		return nil, NewCheckoutError("failed to load project overwrite-ignore", err)
+	}
+	manifest, err := loadManifestOrEmpty(manifestPath)
+	if err != nil {
+		return nil, NewCheckoutError("failed to load ign-files.json", err)
 	}
 
 	// Create generator
@@ -370,13 +470,26 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 
 	// Prepare generate options
 	genOpts := generator.GenerateOptions{
-		Template:      prep.Template,
-		Variables:     vars,
-		OutputDir:     opts.OutputDir,
-		Overwrite:     opts.Overwrite,
-		OverwriteMode: opts.OverwriteMode,
-		Verbose:       opts.Verbose,
-		SkipUnchanged: true,
+		Template:               prep.Template,
+		Variables:              vars,
+		OutputDir:              opts.OutputDir,
+		Overwrite:              opts.Overwrite,
+		OverwriteMode:          opts.OverwriteMode,
+		Verbose:                opts.Verbose,
+		SkipUnchanged:          true,
+		SyncManaged:            effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite) != generator.OverwriteNone,
+		MergeExisting:          true,
+		SeededPaths:            seededPaths,
+		ProjectOverwriteIgnore: projectIgnore,
+		AppliedInjections:      manifest.Injections,
+	}
+	if effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite) != generator.OverwriteAll {
+		genOpts.DeclinedChanges = declined
+	}
+
+	hooks, err := generator.ResolveHooks(ctx, prep.Template, generator.HookPostUpdate, vars, opts.OutputDir)
+	if err != nil {
+		return nil, NewCheckoutError("invalid template hooks", err)
 	}
 
 	plan := opts.ExecutionPlan
@@ -384,7 +497,41 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 		if err := plan.validate(opts.OutputDir, manifestPath, prep.NewHash, effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite)); err != nil {
 			return nil, NewValidationError("invalid update execution plan", err)
 		}
-	} else if effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite) != generator.OverwriteNone {
+	}
+
+	// Template migrations and detected renames move files before generation,
+	// so the generator finds the project's copies at their new paths.
+	overwriteMode := effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite)
+	fileMigrations, err := planFileMigrations(opts.OutputDir, manifestPath, prep.TemplateMigrations)
+	if err != nil {
+		return nil, NewCheckoutError("failed to plan template migrations", err)
+	}
+	if overwriteMode != generator.OverwriteNone {
+		renamePreview, err := gen.DryRun(ctx, genOpts)
+		if err != nil {
+			return nil, NewCheckoutError("failed to detect renamed files", err)
+		}
+		if err := fileMigrations.detectRenames(prep.Template, overwriteMode, renamePreview); err != nil {
+			return nil, NewCheckoutError("failed to detect renamed files", err)
+		}
+	}
+	var history *HistoryRecorder
+	if !opts.DryRun {
+		history, err = BeginHistory(ctx, configDir, "update")
+		if err != nil {
+			return nil, NewCheckoutError("failed to start history snapshot", err)
+		}
+		defer history.Discard()
+		if err := history.captureConfig(configDir); err != nil {
+			return nil, NewCheckoutError("failed to record history snapshot", err)
+		}
+		defer fileMigrations.rollback()
+		if err := fileMigrations.applyMoves(history); err != nil {
+			return nil, NewCheckoutError("failed to apply template migrations", err)
+		}
+	}
+
+	if plan == nil && effectiveUpdateOverwriteMode(opts.OverwriteMode, opts.Overwrite) != generator.OverwriteNone {
 		preview, err := gen.DryRun(ctx, genOpts)
 		if err != nil {
 			return nil, NewCheckoutError("failed to classify symlink transitions", err)
@@ -398,12 +545,18 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 	if plan != nil {
 		genOpts.SymlinkTransitions = plan.transitions
 	}
+	if !opts.DryRun {
+		plan.applyDecisions(&genOpts)
+	}
 
 	// Generate or dry run
 	var genResult *generator.GenerateResult
 	var rollback *checkoutGenerationRollback
 	var transitionTransactions *symlinkTransitionTransactions
 	if !opts.DryRun {
+		if err := captureTransitionHistory(history, genOpts.SymlinkTransitions); err != nil {
+			return nil, NewCheckoutError("failed to record history snapshot", err)
+		}
 		transitionTransactions, err = prepareSymlinkTransitionTransactions(opts.OutputDir, genOpts.SymlinkTransitions, manifestPath, prep.IgnConfigPath, prep.IgnVarPath)
 		if err != nil {
 			return nil, NewCheckoutError("prepare managed directory-to-symlink transition", err)
@@ -418,10 +571,16 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 			return nil, err
 		}
 		defer rollback.cleanup()
+		if err := history.captureGeneration(rollback.preview); err != nil {
+			return nil, NewCheckoutError("failed to record history snapshot", err)
+		}
 	}
 	if opts.DryRun {
 		debug.Debug("[app] Starting dry run generation")
 		genResult, err = gen.DryRun(ctx, genOpts)
+		if err == nil {
+			fileMigrations.adjustPreview(genResult, overwriteMode)
+		}
 	} else {
 		debug.Debug("[app] Starting project generation")
 		genResult, err = gen.Generate(ctx, genOpts)
@@ -452,11 +611,25 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 		Overwrite:          opts.Overwrite,
 		DryRun:             opts.DryRun,
 		SymlinkTransitions: genOpts.SymlinkTransitions,
+		KeepPaths:          keptUpdateDeletions(plan, fileMigrations, opts.DryRun),
+		DeferredPaths:      fileMigrations.deletePaths(),
+		History:            history,
 	})
+	if overwriteIgnorePatterns, err := updateOverwriteIgnorePatterns(prep.Template, manifestPath); err != nil {
+		cleanupErr = errors.Join(cleanupErr, err)
+	} else if err := fileMigrations.applyDeletes(removedManagedFiles, genResult.Files, overwriteMode, overwriteIgnorePatterns, opts.DryRun, history); err != nil {
+		cleanupErr = errors.Join(cleanupErr, err)
+	}
 	if cleanupErr != nil {
 		debug.Debug("[app] Failed to remove stale managed files: %v", cleanupErr)
 	}
 
+	if opts.DryRun {
+		plan.recordPreview(genResult, removedManagedFiles)
+	} else {
+		plan.recordEditedDeclines(genResult)
+	}
+
 	if !opts.DryRun {
 		if err := saveCompleteUpdateArtifacts(prep, rawVars, genResult, removedManagedFiles, rollback, transitionTransactions); err != nil {
 			return nil, err
@@ -465,6 +638,7 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 			rollbackUpdateGeneration(rollback, genResult, transitionTransactions)
 			return nil, NewCheckoutError("record committed managed directory-to-symlink transition", err)
 		}
+		fileMigrations.commit()
 		if err := transitionTransactions.commit(); err != nil {
 			return nil, NewCheckoutError("remove managed directory-to-symlink transaction backup", err)
 		}
@@ -475,11 +649,16 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 		HashChanged:          prep.HashChanged,
 		NewVariables:         prep.NewVars,
 		RemovedVariables:     prep.RemovedVars,
+		VariableMigrations:   prep.VariableMigrations,
+		MovedFiles:           fileMigrations.movedFiles(),
 		FilesCreated:         genResult.FilesCreated,
 		FilesSkipped:         genResult.FilesSkipped,
 		FilesOverwritten:     genResult.FilesOverwritten,
+		FilesMerged:          genResult.FilesMerged,
+		InjectionsApplied:    len(genResult.Injections),
+		Hooks:                hooks,
 		FilesDeleted:         removedManagedFiles.FilesDeleted,
-		Errors:               append(append([]error(nil), genResult.Errors...), transitionDiagnostics...),
+		Errors:               append(append(append([]error(nil), fileMigrations.errors...), genResult.Errors...), transitionDiagnostics...),
 		Files:                genResult.Files,
 		DeletedFiles:         removedManagedFiles.DeletedFiles,
 		Directories:          genResult.Directories,
@@ -489,17 +668,30 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 
 		UnresolvedTransitionPaths: unresolvedTransitionPaths,
 	}
+	if !opts.DryRun {
+		result.WrittenFiles = append([]string(nil), genResult.WrittenFiles...)
+		for _, record := range genResult.Injections {
+			if !slices.Contains(result.WrittenFiles, record.Path) {
+				result.WrittenFiles = append(result.WrittenFiles, record.Path)
+			}
+		}
+		result.HistoryID = commitHistory(history, &result.Errors)
+	}
 
 	// Convert dry-run files
 	if opts.DryRun && len(genResult.DryRunFiles) > 0 {
 		result.DryRunFiles = make([]DryRunFile, len(genResult.DryRunFiles))
 		for i, f := range genResult.DryRunFiles {
 			result.DryRunFiles[i] = DryRunFile{
-				Path:           f.Path,
-				Content:        f.Content,
-				Exists:         f.Exists,
-				WouldOverwrite: f.WouldOverwrite,
-				WouldSkip:      f.WouldSkip,
+				Path:               f.Path,
+				Content:            f.Content,
+				Exists:             f.Exists,
+				WouldOverwrite:     f.WouldOverwrite,
+				WouldSkip:          f.WouldSkip,
+				Injected:           f.Injected,
+				SymlinkTarget:      f.SymlinkTarget,
+				Mode:               f.Mode,
+				ProtectedByProject: f.ProtectedByProject,
 			}
 		}
 	}
@@ -511,6 +703,37 @@ func CompleteUpdate(ctx context.Context, opts CompleteUpdateOptions) (*UpdateRes
 	return result, nil
 }
 
+// captureTransitionHistory records the managed directories an update is
+// about to replace with symlinks, before the transition moves them aside.
+func captureTransitionHistory(history *HistoryRecorder, transitions map[string]generator.SymlinkTransition) error {
+	for path, transition := range transitions {
+		if transition.Disposition != generator.SymlinkTransitionEligible {
+			continue
+		}
+		if err := history.capture(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keptUpdateDeletions returns the removed managed paths cleanup must leave
+// on disk: deletions skipped during review and, in a preview, the sources of
+// migration moves, which the real update relocates instead.
+func keptUpdateDeletions(plan *UpdateExecutionPlan, migrations *fileMigrationPlan, dryRun bool) map[string]struct{} {
+	kept := plan.keptDeletions()
+	if !dryRun || len(migrations.moves) == 0 {
+		return kept
+	}
+	if kept == nil {
+		kept = make(map[string]struct{})
+	}
+	for path := range migrations.movedSources() {
+		kept[path] = struct{}{}
+	}
+	return kept
+}
+
 func shouldCompleteUpdateConfigOnly(prep *PrepareUpdateResult, opts CompleteUpdateOptions) bool {
 	return prep.RefChanged && !prep.HashChanged && !opts.Overwrite
 }
@@ -667,7 +890,7 @@ func ApplyDefaults(newVarDefs map[string]model.VarDef, providedVars map[string]i
 
 // FormatVariableChanges returns a formatted string describing variable changes.
 func FormatVariableChanges(prep *PrepareUpdateResult) string {
-	if len(prep.NewVars) == 0 && len(prep.RemovedVars) == 0 {
+	if len(prep.NewVars) == 0 && len(prep.RemovedVars) == 0 && len(prep.VariableMigrations) == 0 {
 		return ""
 	}
 
@@ -678,5 +901,8 @@ func FormatVariableChanges(prep *PrepareUpdateResult) string {
 	if len(prep.RemovedVars) > 0 {
 		msg += fmt.Sprintf("Removed variables: %v\n", prep.RemovedVars)
 	}
+	if len(prep.VariableMigrations) > 0 {
+		msg += fmt.Sprintf("Migrated variables: %s\n", strings.Join(formatVarMigrationChanges(prep.VariableMigrations), ", "))
+	}
 	return msg
 }
@@ -22,6 +22,14 @@ type cleanupRemovedManagedFilesOptions struct {
 	Overwrite          bool
 	DryRun             bool
 	SymlinkTransitions map[string]generator.SymlinkTransition
+	// KeepPaths lists removed managed paths, in DeletedFiles form, that stay
+	// on disk. They are untracked instead of deleted.
+	KeepPaths map[string]struct{}
+	// DeferredPaths lists removed managed paths, in DeletedFiles form, that
+	// template migration deletes handle instead.
+	DeferredPaths map[string]struct{}
+	// History records removed files before they are deleted.
+	History *HistoryRecorder
 }
 
 type cleanupRemovedManagedFilesResult struct {
@@ -51,7 +59,10 @@ func cleanupRemovedManagedFilesForUpdate(ctx context.Context, opts cleanupRemove
 	if err != nil {
 		return result, err
 	}
-	overwriteIgnorePatterns := overwriteIgnorePatternsFromTemplate(opts.Template)
+	overwriteIgnorePatterns, err := updateOverwriteIgnorePatterns(opts.Template, opts.ManifestPath)
+	if err != nil {
+		return result, err
+	}
 	absOutputDir, err := filepath.Abs(opts.OutputDir)
 	if err != nil {
 		return result, fmt.Errorf("failed to resolve output directory %s: %w", opts.OutputDir, err)
@@ -74,6 +85,10 @@ func cleanupRemovedManagedFilesForUpdate(ctx context.Context, opts cleanupRemove
 			cleanupErrors = append(cleanupErrors, err)
 			continue
 		}
+		if manifest.PolicyFor(manifestFile).KeepsFile() {
+			// seed and user files belong to the project once generated.
+			continue
+		}
 		canonicalPath := filepath.Clean(cleanPath)
 		if isPreservedTransitionPath(canonicalPath, opts.SymlinkTransitions) {
 			// A preserved transition deliberately leaves the former managed tree in
@@ -128,12 +143,25 @@ func cleanupRemovedManagedFilesForUpdate(ctx context.Context, opts cleanupRemove
 			continue
 		}
 
+		if _, deferred := opts.DeferredPaths[outputPathForManagedRelativePath(opts.OutputDir, relPath)]; deferred {
+			continue
+		}
+		if _, keep := opts.KeepPaths[outputPathForManagedRelativePath(opts.OutputDir, relPath)]; keep {
+			debug.Debug("[app] Keeping removed managed file on request: %s", canonicalPath)
+			result.RemovedCanonicalPaths[canonicalPath] = struct{}{}
+			continue
+		}
+
 		if opts.DryRun {
 			recordRemovedManagedPath(result, opts.OutputDir, relPath, canonicalPath)
 			continue
 		}
 
 		debug.Debug("[app] Removing managed file no longer present in template: %s", canonicalPath)
+		if err := opts.History.capture(canonicalPath); err != nil {
+			cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to record removed managed file %s: %w", canonicalPath, err))
+			continue
+		}
 		if err := os.Remove(canonicalPath); err != nil {
 			cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to remove managed file no longer present in template %s: %w", canonicalPath, err))
 			continue
@@ -234,6 +262,31 @@ func canonicalManagedPathForComparison(path string) (string, error) {
 	return filepath.Clean(absPath), nil
 }
 
+// updateOverwriteIgnorePatterns returns the template's overwrite-ignore
+// patterns followed by the project's, read from the .ign directory holding
+// manifestPath.
+func updateOverwriteIgnorePatterns(template *model.Template, manifestPath string) ([]string, error) {
+	project, err := projectOverwriteIgnorePatterns(filepath.Dir(manifestPath))
+	if err != nil {
+		return nil, err
+	}
+	return append(overwriteIgnorePatternsFromTemplate(template), project...), nil
+}
+
+// projectOverwriteIgnorePatterns reads the project's .ign/overwrite-ignore.
+// A missing file has no patterns.
+func projectOverwriteIgnorePatterns(configDir string) ([]string, error) {
+	path := filepath.Join(configDir, model.IgnProjectOverwriteIgnoreFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return generator.ParseIgnoreFilePatterns(content), nil
+}
+
 func overwriteIgnorePatternsFromTemplate(template *model.Template) []string {
 	if template == nil {
 		return nil
@@ -0,0 +1,351 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// GitUpdate runs an update on a new branch of the git repository holding the
+// project and stages or commits what the update changed. It uses the git
+// binary found in PATH.
+type GitUpdate struct {
+	repoRoot  string
+	outputDir string
+	branch    string
+	// original is the branch, or the commit of a detached HEAD, that
+	// CreateBranch switched away from.
+	original string
+}
+
+// PrepareGitUpdate checks that outputDir is inside a git worktree with no
+// uncommitted or untracked changes and that branch can be created. It does
+// not change the repository; call CreateBranch once the update is confirmed.
+func PrepareGitUpdate(ctx context.Context, outputDir, branch string) (*GitUpdate, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, NewGitError("git is not installed or not in PATH", err)
+	}
+	root, err := runGit(ctx, outputDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, NewGitError(fmt.Sprintf("%s is not inside a git repository", outputDir), err)
+	}
+	g := &GitUpdate{repoRoot: strings.TrimSpace(root), outputDir: outputDir, branch: branch}
+
+	if _, err := runGit(ctx, g.repoRoot, "check-ref-format", "--branch", branch); err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid branch name %q", branch), err)
+	}
+	if _, err := runGit(ctx, g.repoRoot, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		return nil, NewGitError(fmt.Sprintf("branch %s already exists", branch), nil)
+	}
+	if err := g.checkClean(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// CreateBranch creates the branch from HEAD and switches to it.
+func (g *GitUpdate) CreateBranch(ctx context.Context) error {
+	// The worktree may have changed while the user reviewed the update.
+	if err := g.checkClean(ctx); err != nil {
+		return err
+	}
+	head, err := runGit(ctx, g.repoRoot, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if head, err = runGit(ctx, g.repoRoot, "rev-parse", "HEAD"); err != nil {
+			return NewGitError("failed to read the current branch", err)
+		}
+	}
+	debug.Debug("[app] Creating git branch %s in %s", g.branch, g.repoRoot)
+	if _, err := runGit(ctx, g.repoRoot, "checkout", "-b", g.branch); err != nil {
+		return NewGitError(fmt.Sprintf("failed to create branch %s", g.branch), err)
+	}
+	g.original = strings.TrimSpace(head)
+	return nil
+}
+
+// Abandon undoes CreateBranch after an update failed: it switches back to
+// the original branch and deletes the new one. Nothing has been committed on
+// the new branch yet, so the switch always succeeds and keeps whatever the
+// update left in the worktree. On failure the error says how to finish by
+// hand.
+func (g *GitUpdate) Abandon(ctx context.Context) error {
+	if g.original == "" {
+		return nil
+	}
+	manual := fmt.Sprintf("run 'git checkout %s && git branch -D %s'", g.original, g.branch)
+	debug.Debug("[app] Abandoning git branch %s, back to %s", g.branch, g.original)
+	if _, err := runGit(ctx, g.repoRoot, "checkout", "--quiet", g.original); err != nil {
+		return NewGitError(fmt.Sprintf("failed to switch back to %s; %s", g.original, manual), err)
+	}
+	if _, err := runGit(ctx, g.repoRoot, "branch", "-D", g.branch); err != nil {
+		return NewGitError(fmt.Sprintf("failed to delete branch %s; run 'git branch -D %s'", g.branch, g.branch), err)
+	}
+	g.original = ""
+	return nil
+}
+
+// Branch returns the branch name.
+func (g *GitUpdate) Branch() string {
+	return g.branch
+}
+
+// OriginalBranch returns the branch, or commit, CreateBranch switched away
+// from. It is empty before CreateBranch and after Abandon.
+func (g *GitUpdate) OriginalBranch() string {
+	return g.original
+}
+
+// Stage stages the files result wrote or deleted together with the .ign
+// tracking files, and returns the staged paths relative to the repository
+// root. Undo history under .ign and paths ignored by git are left out.
+func (g *GitUpdate) Stage(ctx context.Context, result *UpdateResult) ([]string, error) {
+	paths := make(map[string]struct{})
+	var candidates []string
+	candidates = append(candidates, result.WrittenFiles...)
+	candidates = append(candidates, result.DeletedFiles...)
+	for _, moved := range result.MovedFiles {
+		candidates = append(candidates, moved.From, moved.To)
+	}
+	for _, name := range []string{model.IgnProjectConfigFile, model.IgnVarFile, model.IgnManifestFile} {
+		candidates = append(candidates, filepath.Join(g.outputDir, model.IgnConfigDir, name))
+	}
+	for _, path := range candidates {
+		rel, err := g.relPath(path)
+		if err != nil {
+			return nil, err
+		}
+		paths[rel] = struct{}{}
+	}
+
+	ignored, err := g.ignoredPaths(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+	staged := make([]string, 0, len(paths))
+	for path := range paths {
+		if _, skip := ignored[path]; !skip {
+			staged = append(staged, path)
+		}
+	}
+	sort.Strings(staged)
+	if len(staged) == 0 {
+		return nil, nil
+	}
+
+	// "add -A" records deletions and picks up unchanged paths as no-ops.
+	args := append([]string{"add", "-A", "--"}, staged...)
+	if _, err := runGit(ctx, g.repoRoot, args...); err != nil {
+		return nil, NewGitError("failed to stage update changes", err)
+	}
+	out, err := runGit(ctx, g.repoRoot, append([]string{"diff", "--cached", "--name-only", "-z", "--"}, staged...)...)
+	if err != nil {
+		return nil, NewGitError("failed to list staged changes", err)
+	}
+	return splitNUL(out), nil
+}
+
+// Commit commits the staged changes with message and returns the commit id.
+func (g *GitUpdate) Commit(ctx context.Context, message string) (string, error) {
+	if _, err := runGitInput(ctx, g.repoRoot, message, "commit", "--quiet", "--file", "-"); err != nil {
+		return "", NewGitError("failed to commit update", err)
+	}
+	commit, err := runGit(ctx, g.repoRoot, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", NewGitError("failed to read the new commit", err)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// checkClean fails when the worktree has changes. The project's undo history
+// is local state and does not count.
+func (g *GitUpdate) checkClean(ctx context.Context) error {
+	history, err := g.relPath(filepath.Join(g.outputDir, model.IgnConfigDir, model.IgnHistoryDir))
+	if err != nil {
+		return err
+	}
+	out, err := runGit(ctx, g.repoRoot, "status", "--porcelain", "--untracked-files=normal", "--", ".", ":(exclude)"+history)
+	if err != nil {
+		return NewGitError("failed to read worktree status", err)
+	}
+	if status := strings.TrimRight(out, "\n"); status != "" {
+		return NewGitError("worktree has uncommitted changes; commit or stash them first:\n"+status, nil)
+	}
+	return nil
+}
+
+// ignoredPaths returns the paths git ignores, which cannot be staged
+// without force.
+func (g *GitUpdate) ignoredPaths(ctx context.Context, paths map[string]struct{}) (map[string]struct{}, error) {
+	var input strings.Builder
+	for path := range paths {
+		input.WriteString(path)
+		input.WriteByte(0)
+	}
+	out, err := runGitInput(ctx, g.repoRoot, input.String(), "check-ignore", "--stdin", "-z")
+	ignored := make(map[string]struct{})
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			// check-ignore exits 1 when nothing is ignored.
+			return ignored, nil
+		}
+		return nil, NewGitError("failed to check ignored paths", err)
+	}
+	for _, path := range splitNUL(out) {
+		ignored[path] = struct{}{}
+	}
+	return ignored, nil
+}
+
+func (g *GitUpdate) relPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", NewGitError(fmt.Sprintf("failed to resolve %s", path), err)
+	}
+	// Resolve the nearest existing ancestor so a symlinked temp or home
+	// directory matches the root git reports; the path itself may be deleted
+	// or be a symlink.
+	for dir, rest := filepath.Dir(abs), filepath.Base(abs); ; dir, rest = filepath.Dir(dir), filepath.Join(filepath.Base(dir), rest) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			abs = filepath.Join(resolved, rest)
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	rel, err := filepath.Rel(g.repoRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", NewGitError(fmt.Sprintf("%s is outside the git repository %s", path, g.repoRoot), err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// UpdateCommitMessage describes an applied update for a commit: the template
+// and ref change, the variables added, removed or migrated, and the file
+// counts. newVars holds the values given for the added variables.
+func UpdateCommitMessage(prep *PrepareUpdateResult, result *UpdateResult, newVars map[string]interface{}) string {
+	name := prep.IgnJson.Name
+	if name == "" {
+		name = prep.IgnConfig.Template.URL
+	}
+	var b strings.Builder
+	if prep.IgnJson.Version != "" {
+		fmt.Fprintf(&b, "Update template %s to %s\n\n", name, prep.IgnJson.Version)
+	} else {
+		fmt.Fprintf(&b, "Update template %s\n\n", name)
+	}
+
+	fmt.Fprintf(&b, "Template: %s\n", prep.IgnConfig.Template.URL)
+	if prep.RefChanged {
+		fmt.Fprintf(&b, "Ref: %s -> %s\n", displayRef(prep.PreviousRef), displayRef(prep.RequestedRef))
+	} else if prep.EffectiveRef != "" {
+		fmt.Fprintf(&b, "Ref: %s\n", prep.EffectiveRef)
+	}
+	if prep.HashChanged {
+		fmt.Fprintf(&b, "Hash: %s -> %s\n", shortHash(prep.CurrentHash), shortHash(prep.NewHash))
+	}
+	if len(prep.TemplateMigrations) > 0 {
+		fmt.Fprintf(&b, "Migrations: %s -> %s\n", prep.PreviousVersion, prep.IgnJson.Version)
+	}
+
+	if len(prep.NewVars) > 0 || len(prep.RemovedVars) > 0 || len(prep.VariableMigrations) > 0 {
+		b.WriteString("\nVariables:\n")
+		added := append([]string(nil), prep.NewVars...)
+		sort.Strings(added)
+		for _, name := range added {
+			if value, ok := newVars[name]; ok {
+				fmt.Fprintf(&b, "  + %s = %v\n", name, value)
+			} else {
+				fmt.Fprintf(&b, "  + %s\n", name)
+			}
+		}
+		for _, name := range prep.RemovedVars {
+			fmt.Fprintf(&b, "  - %s\n", name)
+		}
+		for _, change := range prep.VariableMigrations {
+			fmt.Fprintf(&b, "  ~ %s\n", change)
+		}
+	}
+
+	if len(result.MovedFiles) > 0 {
+		b.WriteString("\nMoved:\n")
+		for _, moved := range result.MovedFiles {
+			fmt.Fprintf(&b, "  %s\n", moved)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nFiles: %d created, %d overwritten, %d merged, %d deleted\n",
+		result.FilesCreated, result.FilesOverwritten, result.FilesMerged, result.FilesDeleted)
+	return b.String()
+}
+
+func displayRef(ref string) string {
+	if ref == "" {
+		return "(default)"
+	}
+	return ref
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitInput(ctx, dir, "", args...)
+}
+
+// runGitInput runs git in dir with input on stdin. A failure carries git's
+// standard error.
+func runGitInput(ctx context.Context, dir, input string, args ...string) (string, error) {
+	debug.Debug("[app] git %s", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), &gitCommandError{args: args, stderr: msg, err: err}
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// gitCommandError is a failed git command with its standard error.
+type gitCommandError struct {
+	args   []string
+	stderr string
+	err    error
+}
+
+func (e *gitCommandError) Error() string {
+	return fmt.Sprintf("git %s: %s", strings.Join(e.args, " "), e.stderr)
+}
+
+func (e *gitCommandError) Unwrap() error {
+	return e.err
+}
+
+func splitNUL(out string) []string {
+	var paths []string
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
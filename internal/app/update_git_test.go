@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// initGitRepo creates a repository in dir with one commit holding files.
+func initGitRepo(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"config", "user.name", "ign test"},
+		{"config", "user.email", "ign@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "-A"},
+		{"commit", "--quiet", "-m", "initial"},
+	} {
+		if out, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(context.Background(), dir, args...)
+	if err != nil {
+		t.Fatalf("git %s failed: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(out)
+}
+
+func TestGitUpdate_StagesPlanAndCommits(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	initGitRepo(t, repo, map[string]string{
+		".gitignore":                "*.log\n",
+		"unrelated.txt":             "keep\n",
+		"project/keep.txt":          "keep\n",
+		"project/old.txt":           "old\n",
+		"project/.ign/ign.json":     "{}\n",
+		"project/.ign/ign-var.json": "{}\n",
+	})
+	project := filepath.Join(repo, "project")
+
+	g, err := PrepareGitUpdate(ctx, project, "template-sync")
+	if err != nil {
+		t.Fatalf("PrepareGitUpdate failed: %v", err)
+	}
+	if err := g.CreateBranch(ctx); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if branch := gitOutput(t, repo, "rev-parse", "--abbrev-ref", "HEAD"); branch != "template-sync" {
+		t.Fatalf("HEAD branch = %q, want template-sync", branch)
+	}
+
+	// Simulate what the update wrote, plus an unrelated edit and history that
+	// must not be staged.
+	for path, content := range map[string]string{
+		"project/new.txt":                 "new\n",
+		"project/debug.log":               "ignored\n",
+		"project/.ign/ign.json":           "{\"updated\":true}\n",
+		"project/.ign/ign-files.json":     "{}\n",
+		"project/.ign/history/1/snapshot": "snap\n",
+		"unrelated.txt":                   "edited\n",
+	} {
+		full := filepath.Join(repo, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if err := os.Remove(filepath.Join(project, "old.txt")); err != nil {
+		t.Fatalf("Failed to remove old.txt: %v", err)
+	}
+
+	staged, err := g.Stage(ctx, &UpdateResult{
+		WrittenFiles: []string{
+			filepath.Join(project, "new.txt"),
+			filepath.Join(project, "keep.txt"),
+			filepath.Join(project, "debug.log"),
+		},
+		DeletedFiles: []string{filepath.Join(project, "old.txt")},
+	})
+	if err != nil {
+		t.Fatalf("Stage failed: %v", err)
+	}
+	want := []string{"project/.ign/ign-files.json", "project/.ign/ign.json", "project/new.txt", "project/old.txt"}
+	if strings.Join(staged, " ") != strings.Join(want, " ") {
+		t.Errorf("staged = %v, want %v", staged, want)
+	}
+
+	commit, err := g.Commit(ctx, "Update template\n\nFiles: 1 created\n")
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if commit == "" {
+		t.Error("Commit should return the new commit id")
+	}
+	if subject := gitOutput(t, repo, "log", "-1", "--format=%s"); subject != "Update template" {
+		t.Errorf("commit subject = %q", subject)
+	}
+	if diff := gitOutput(t, repo, "diff", "--name-only"); diff != "unrelated.txt" {
+		t.Errorf("unrelated change should stay unstaged, unstaged paths: %q", diff)
+	}
+}
+
+func TestGitUpdate_AbandonReturnsToOriginalBranch(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	initGitRepo(t, repo, map[string]string{"file.txt": "a\n"})
+
+	g, err := PrepareGitUpdate(ctx, repo, "template-sync")
+	if err != nil {
+		t.Fatalf("PrepareGitUpdate failed: %v", err)
+	}
+	if err := g.CreateBranch(ctx); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if got := g.OriginalBranch(); got != "main" {
+		t.Fatalf("OriginalBranch() = %q, want main", got)
+	}
+	// A failed update may leave files behind; they must survive the switch.
+	if err := os.WriteFile(filepath.Join(repo, "partial.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write partial.txt: %v", err)
+	}
+
+	if err := g.Abandon(ctx); err != nil {
+		t.Fatalf("Abandon failed: %v", err)
+	}
+	if branch := gitOutput(t, repo, "rev-parse", "--abbrev-ref", "HEAD"); branch != "main" {
+		t.Errorf("HEAD branch = %q, want main", branch)
+	}
+	if branches := gitOutput(t, repo, "branch", "--list", "template-sync"); branches != "" {
+		t.Errorf("branch template-sync still exists: %q", branches)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "partial.txt")); err != nil {
+		t.Errorf("partial.txt was lost: %v", err)
+	}
+	if err := g.Abandon(ctx); err != nil {
+		t.Errorf("second Abandon failed: %v", err)
+	}
+}
+
+func TestPrepareGitUpdate_RejectsDirtyWorktree(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	initGitRepo(t, repo, map[string]string{"file.txt": "a\n"})
+	if err := os.WriteFile(filepath.Join(repo, "untracked.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write untracked file: %v", err)
+	}
+
+	_, err := PrepareGitUpdate(ctx, repo, "template-sync")
+	if err == nil {
+		t.Fatal("PrepareGitUpdate should reject a dirty worktree")
+	}
+	if !strings.Contains(err.Error(), "untracked.txt") {
+		t.Errorf("error should list the dirty path, got %v", err)
+	}
+	if branches := gitOutput(t, repo, "branch", "--list", "template-sync"); branches != "" {
+		t.Errorf("branch should not be created, got %q", branches)
+	}
+}
+
+func TestPrepareGitUpdate_RejectsExistingBranch(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	initGitRepo(t, repo, map[string]string{"file.txt": "a\n"})
+	gitOutput(t, repo, "branch", "template-sync")
+
+	if _, err := PrepareGitUpdate(ctx, repo, "template-sync"); err == nil {
+		t.Fatal("PrepareGitUpdate should reject an existing branch")
+	}
+}
+
+func TestPrepareGitUpdate_RequiresRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	_, err := PrepareGitUpdate(context.Background(), dir, "template-sync")
+	if err == nil {
+		t.Fatal("PrepareGitUpdate should fail outside a git repository")
+	}
+	if appErr, ok := err.(*AppError); !ok || appErr.Type != GitFailed {
+		t.Errorf("error = %v, want a GitFailed AppError", err)
+	}
+}
+
+func TestUpdateCommitMessage(t *testing.T) {
+	prep := &PrepareUpdateResult{
+		IgnJson:      &model.IgnJson{Name: "go-service", Version: "1.4.0"},
+		IgnConfig:    &model.IgnConfig{Template: model.TemplateSource{URL: "github.com/owner/go-service"}},
+		RefChanged:   true,
+		PreviousRef:  "v1.3.0",
+		RequestedRef: "v1.4.0",
+		HashChanged:  true,
+		CurrentHash:  "aaaaaaaaaaaaaaaaaaaa",
+		NewHash:      "bbbbbbbbbbbbbbbbbbbb",
+		NewVars:      []string{"region", "port"},
+		RemovedVars:  []string{"legacy"},
+	}
+	result := &UpdateResult{FilesCreated: 2, FilesOverwritten: 1, FilesDeleted: 1}
+
+	msg := UpdateCommitMessage(prep, result, map[string]interface{}{"port": 8080})
+	for _, fragment := range []string{
+		"Update template go-service to 1.4.0\n\n",
+		"Template: github.com/owner/go-service\n",
+		"Ref: v1.3.0 -> v1.4.0\n",
+		"Hash: aaaaaaaaaaaa -> bbbbbbbbbbbb\n",
+		"  + port = 8080\n  + region\n",
+		"  - legacy\n",
+		"Files: 2 created, 1 overwritten, 0 merged, 1 deleted\n",
+	} {
+		if !strings.Contains(msg, fragment) {
+			t.Errorf("message is missing %q:\n%s", fragment, msg)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// VarMigrationKind identifies the kind of change a variable migration made.
+type VarMigrationKind string
+
+const (
+	// VarMigrationRenamed indicates a stored value moved to a new variable name.
+	VarMigrationRenamed VarMigrationKind = "renamed"
+	// VarMigrationMapped indicates a stored value was replaced through a value map.
+	VarMigrationMapped VarMigrationKind = "mapped"
+	// VarMigrationConverted indicates a stored value was converted to a new type.
+	VarMigrationConverted VarMigrationKind = "converted"
+	// VarMigrationRemoved indicates a stored value was dropped.
+	VarMigrationRemoved VarMigrationKind = "removed"
+)
+
+// VarMigrationChange describes one change applied to the stored variable values.
+type VarMigrationChange struct {
+	// Kind is the kind of change.
+	Kind VarMigrationKind `json:"kind"`
+	// Variable is the variable name after the change.
+	Variable string `json:"variable"`
+	// From is the previous variable name (renames only).
+	From string `json:"from,omitempty"`
+	// OldValue is the stored value before the change.
+	OldValue interface{} `json:"old_value,omitempty"`
+	// NewValue is the stored value after the change (nil for removals).
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// String formats the change for update summaries.
+func (c VarMigrationChange) String() string {
+	switch c.Kind {
+	case VarMigrationRenamed:
+		return fmt.Sprintf("%s -> %s (renamed)", c.From, c.Variable)
+	case VarMigrationMapped:
+		return fmt.Sprintf("%s: %s -> %s (mapped)", c.Variable, formatMigrationValue(c.OldValue), formatMigrationValue(c.NewValue))
+	case VarMigrationConverted:
+		return fmt.Sprintf("%s: %s -> %s (converted)", c.Variable, formatMigrationValue(c.OldValue), formatMigrationValue(c.NewValue))
+	case VarMigrationRemoved:
+		return fmt.Sprintf("%s (removed)", c.Variable)
+	default:
+		return c.Variable
+	}
+}
+
+// applyVariableMigrations applies the template's declared variable migrations
+// to a copy of the stored values. Within one migration, renames run first,
+// then value mappings, then type conversion, so a value_map can translate old
+// values that a plain conversion could not.
+func applyVariableMigrations(stored map[string]interface{}, varDefs map[string]model.VarDef, migrations []model.VarMigration) (map[string]interface{}, []VarMigrationChange, error) {
+	values := make(map[string]interface{}, len(stored))
+	for name, value := range stored {
+		values[name] = value
+	}
+	if len(migrations) == 0 {
+		return values, nil, nil
+	}
+
+	debug.DebugValue("[app] Variable migrations declared", len(migrations))
+
+	var changes []VarMigrationChange
+	for _, migration := range migrations {
+		name := migration.Variable
+
+		if migration.Remove {
+			if oldValue, ok := values[name]; ok {
+				delete(values, name)
+				changes = append(changes, VarMigrationChange{Kind: VarMigrationRemoved, Variable: name, OldValue: oldValue})
+			}
+			continue
+		}
+
+		for _, oldName := range migration.RenamedFrom {
+			oldValue, ok := values[oldName]
+			if !ok {
+				continue
+			}
+			delete(values, oldName)
+			// A value already stored under the new name wins over the old one.
+			if _, exists := values[name]; exists {
+				debug.Debug("[app] Variable migration: %s already set, dropping %s", name, oldName)
+				continue
+			}
+			values[name] = oldValue
+			changes = append(changes, VarMigrationChange{Kind: VarMigrationRenamed, Variable: name, From: oldName, OldValue: oldValue, NewValue: oldValue})
+		}
+
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		if mapped, found := migration.ValueMap[model.ValueMapKey(value)]; found && !reflect.DeepEqual(mapped, value) {
+			values[name] = mapped
+			changes = append(changes, VarMigrationChange{Kind: VarMigrationMapped, Variable: name, OldValue: value, NewValue: mapped})
+			value = mapped
+		}
+
+		if migration.ConvertFrom != "" && storedValueHasType(value, migration.ConvertFrom) {
+			varDef := varDefs[name]
+			// A value that already has the declared type was converted by an
+			// earlier update, e.g. a whole number under convert_from "number".
+			if varDef.Type == migration.ConvertFrom || storedValueHasType(value, varDef.Type) {
+				continue
+			}
+			converted, err := convertStoredValue(value, varDef.Type)
+			if err != nil {
+				return nil, nil, NewValidationError(
+					fmt.Sprintf("variable migration for %s failed: cannot convert %s from %s to %s", name, formatMigrationValue(value), migration.ConvertFrom, varDef.Type),
+					err,
+				)
+			}
+			values[name] = converted
+			changes = append(changes, VarMigrationChange{Kind: VarMigrationConverted, Variable: name, OldValue: value, NewValue: converted})
+		}
+	}
+
+	debug.DebugValue("[app] Variable migrations applied", len(changes))
+	return values, changes, nil
+}
+
+// checkRepeatableVariableMigrations rejects top-level variable_migrations
+// that would change stored values again on every update. They have no version
+// to record that they ran, so a value_map whose result is itself mapped, by
+// the same or another entry for the variable, would keep advancing the value.
+func checkRepeatableVariableMigrations(migrations []model.VarMigration) error {
+	valueMaps := make(map[string]map[string]string)
+	for _, migration := range migrations {
+		if len(migration.ValueMap) == 0 {
+			continue
+		}
+		if valueMaps[migration.Variable] == nil {
+			valueMaps[migration.Variable] = make(map[string]string)
+		}
+		for oldValue, newValue := range migration.ValueMap {
+			valueMaps[migration.Variable][oldValue] = model.ValueMapKey(newValue)
+		}
+	}
+	for _, migration := range migrations {
+		oldValues := make([]string, 0, len(migration.ValueMap))
+		for oldValue := range migration.ValueMap {
+			oldValues = append(oldValues, oldValue)
+		}
+		sort.Strings(oldValues)
+		for _, oldValue := range oldValues {
+			mapped := model.ValueMapKey(migration.ValueMap[oldValue])
+			if next, chained := valueMaps[migration.Variable][mapped]; chained && next != mapped {
+				return NewValidationError(fmt.Sprintf(
+					"variable_migrations for %s map %s to %s, which is mapped again on the next update; "+
+						"declare chained value maps in a versioned migration instead",
+					migration.Variable, strconv.Quote(oldValue), strconv.Quote(mapped)), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// storedValueHasType reports whether a stored (JSON-decoded) value is of typ.
+// Whole JSON numbers count as int; any number counts as number.
+func storedValueHasType(value interface{}, typ model.VarType) bool {
+	switch typ {
+	case model.VarTypeString:
+		_, ok := value.(string)
+		return ok
+	case model.VarTypeBool:
+		_, ok := value.(bool)
+		return ok
+	case model.VarTypeInt:
+		num, ok := numericVarValue(value)
+		return ok && num == math.Trunc(num)
+	case model.VarTypeNumber:
+		_, ok := numericVarValue(value)
+		return ok
+	default:
+		return false
+	}
+}
+
+// convertStoredValue converts a stored value to the target variable type.
+func convertStoredValue(value interface{}, target model.VarType) (interface{}, error) {
+	key := model.ValueMapKey(value)
+	switch target {
+	case model.VarTypeString:
+		return key, nil
+	case model.VarTypeInt:
+		if num, ok := numericVarValue(value); ok {
+			if num != math.Trunc(num) {
+				return nil, fmt.Errorf("%v is not a whole number", num)
+			}
+			return int(num), nil
+		}
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("unsupported value type %T", value)
+		}
+		return strconv.Atoi(strings.TrimSpace(key))
+	case model.VarTypeNumber:
+		if num, ok := numericVarValue(value); ok {
+			return num, nil
+		}
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("unsupported value type %T", value)
+		}
+		num, err := strconv.ParseFloat(strings.TrimSpace(key), 64)
+		if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+			return nil, fmt.Errorf("%q is not a number", key)
+		}
+		return num, nil
+	case model.VarTypeBool:
+		return strconv.ParseBool(strings.TrimSpace(key))
+	default:
+		return nil, fmt.Errorf("unsupported target type %q", target)
+	}
+}
+
+func formatMigrationValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+	return model.ValueMapKey(value)
+}
+
+// formatVarMigrationChanges returns the display strings for migration changes,
+// preserving the order in which they were applied.
+func formatVarMigrationChanges(changes []VarMigrationChange) []string {
+	formatted := make([]string, 0, len(changes))
+	for _, change := range changes {
+		formatted = append(formatted, change.String())
+	}
+	return formatted
+}
@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func TestApplyVariableMigrations(t *testing.T) {
+	varDefs := map[string]model.VarDef{
+		"service_name": {Type: model.VarTypeString},
+		"port":         {Type: model.VarTypeInt},
+		"log_level":    {Type: model.VarTypeString},
+		"tracing":      {Type: model.VarTypeBool},
+	}
+	migrations := []model.VarMigration{
+		{Variable: "service_name", RenamedFrom: []string{"app_name"}},
+		{Variable: "port", ConvertFrom: model.VarTypeString},
+		{Variable: "log_level", ValueMap: map[string]interface{}{"warn": "warning"}},
+		{Variable: "tracing", RenamedFrom: []string{"trace"}, ValueMap: map[string]interface{}{"on": true, "off": false}, ConvertFrom: model.VarTypeString},
+		{Variable: "legacy_flag", Remove: true},
+	}
+	stored := map[string]interface{}{
+		"app_name":    "demo",
+		"port":        "8080",
+		"log_level":   "warn",
+		"trace":       "on",
+		"legacy_flag": true,
+	}
+
+	got, changes, err := applyVariableMigrations(stored, varDefs, migrations)
+	if err != nil {
+		t.Fatalf("applyVariableMigrations returned error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"service_name": "demo",
+		"port":         8080,
+		"log_level":    "warning",
+		"tracing":      true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("migrated values = %#v, want %#v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %#v, want %#v", name, got[name], value)
+		}
+	}
+	if _, ok := stored["service_name"]; ok {
+		t.Error("applyVariableMigrations modified the input map")
+	}
+
+	wantChanges := []string{
+		"app_name -> service_name (renamed)",
+		`port: "8080" -> 8080 (converted)`,
+		`log_level: "warn" -> "warning" (mapped)`,
+		"trace -> tracing (renamed)",
+		`tracing: "on" -> true (mapped)`,
+		"legacy_flag (removed)",
+	}
+	gotChanges := formatVarMigrationChanges(changes)
+	if strings.Join(gotChanges, "\n") != strings.Join(wantChanges, "\n") {
+		t.Fatalf("changes =\n%s\nwant\n%s", strings.Join(gotChanges, "\n"), strings.Join(wantChanges, "\n"))
+	}
+
+	// Running the migrations again on already migrated values is a no-op.
+	again, changes, err := applyVariableMigrations(got, varDefs, migrations)
+	if err != nil {
+		t.Fatalf("second applyVariableMigrations returned error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("second run changes = %v, want none", formatVarMigrationChanges(changes))
+	}
+	if again["port"] != 8080 {
+		t.Fatalf("second run port = %#v", again["port"])
+	}
+}
+
+func TestApplyVariableMigrations_NewNameWinsOverRenamedValue(t *testing.T) {
+	got, changes, err := applyVariableMigrations(
+		map[string]interface{}{"app_name": "old", "service_name": "new"},
+		map[string]model.VarDef{"service_name": {Type: model.VarTypeString}},
+		[]model.VarMigration{{Variable: "service_name", RenamedFrom: []string{"app_name"}}},
+	)
+	if err != nil {
+		t.Fatalf("applyVariableMigrations returned error: %v", err)
+	}
+	if got["service_name"] != "new" || len(got) != 1 || len(changes) != 0 {
+		t.Fatalf("values = %#v, changes = %v", got, changes)
+	}
+}
+
+func TestApplyVariableMigrations_ConversionFailure(t *testing.T) {
+	_, _, err := applyVariableMigrations(
+		map[string]interface{}{"port": "eighty"},
+		map[string]model.VarDef{"port": {Type: model.VarTypeInt}},
+		[]model.VarMigration{{Variable: "port", ConvertFrom: model.VarTypeString}},
+	)
+	if err == nil || !strings.Contains(err.Error(), `cannot convert "eighty" from string to int`) {
+		t.Fatalf("error = %v, want conversion failure", err)
+	}
+}
+
+func TestPrepareUpdate_AppliesVariableMigrations(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	templateDir := filepath.Join(tempDir, "template")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("failed to create template dir: %v", err)
+	}
+	ignJSON := &model.IgnJson{
+		Name:    "migrating-template",
+		Version: "2.0.0",
+		Hash:    testHash2,
+		Variables: map[string]model.VarDef{
+			"service_name": {Type: model.VarTypeString, Description: "Service name", Required: true},
+		},
+		VariableMigrations: []model.VarMigration{
+			{Variable: "service_name", RenamedFrom: []string{"app_name"}},
+			{Variable: "legacy", Remove: true},
+		},
+	}
+	data, err := json.MarshalIndent(ignJSON, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal template config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, model.IgnTemplateConfigFile), data, 0644); err != nil {
+		t.Fatalf("failed to write template config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "README.md"), []byte("@ign-var:service_name@\n"), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+	writeProjectConfig(t, "./template", "main", map[string]interface{}{
+		"app_name": "demo",
+		"legacy":   true,
+	})
+
+	prep, err := PrepareUpdate(context.Background(), UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	if len(prep.NewVars) != 0 || len(prep.RemovedVars) != 0 {
+		t.Fatalf("NewVars = %v, RemovedVars = %v; migrated names should not be reported", prep.NewVars, prep.RemovedVars)
+	}
+	if prep.ExistingVars["service_name"] != "demo" {
+		t.Fatalf("ExistingVars = %#v", prep.ExistingVars)
+	}
+	if summary := FormatVariableChanges(prep); !strings.Contains(summary, "app_name -> service_name (renamed)") {
+		t.Fatalf("FormatVariableChanges = %q", summary)
+	}
+
+	dryRun, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", DryRun: true})
+	if err != nil {
+		t.Fatalf("CompleteUpdate dry run returned error: %v", err)
+	}
+	if len(dryRun.VariableMigrations) != 2 {
+		t.Fatalf("dry-run VariableMigrations = %v, want 2 changes", dryRun.VariableMigrations)
+	}
+	stored, err := config.LoadIgnVarJson(filepath.Join(model.IgnConfigDir, model.IgnVarFile))
+	if err != nil {
+		t.Fatalf("failed to load ign-var.json: %v", err)
+	}
+	if _, ok := stored.Variables["app_name"]; !ok {
+		t.Fatal("dry run persisted migrated variables")
+	}
+
+	if _, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{PrepareResult: prep, OutputDir: "."}); err != nil {
+		t.Fatalf("CompleteUpdate returned error: %v", err)
+	}
+	stored, err = config.LoadIgnVarJson(filepath.Join(model.IgnConfigDir, model.IgnVarFile))
+	if err != nil {
+		t.Fatalf("failed to load ign-var.json: %v", err)
+	}
+	if len(stored.Variables) != 1 || stored.Variables["service_name"] != "demo" {
+		t.Fatalf("persisted variables = %#v, want migrated service_name only", stored.Variables)
+	}
+	content, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("failed to read generated file: %v", err)
+	}
+	if string(content) != "demo\n" {
+		t.Fatalf("README.md = %q, want migrated value rendered", content)
+	}
+}
+
+func TestCompleteUpdate_VariableMigrationsApplyOnce(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	templateDir := filepath.Join(tempDir, "template")
+	writeLocalTemplate(t, templateDir, &model.IgnJson{
+		Name:    "migrating-template",
+		Version: "2.0.0",
+		Hash:    testHash2,
+		Variables: map[string]model.VarDef{
+			"size":     {Type: model.VarTypeString, Description: "Size", Required: true},
+			"replicas": {Type: model.VarTypeInt, Description: "Replicas", Required: true},
+		},
+		VariableMigrations: []model.VarMigration{
+			{Variable: "size", ValueMap: map[string]interface{}{"small": "s", "s": "s"}},
+			{Variable: "replicas", ConvertFrom: model.VarTypeNumber},
+		},
+	}, map[string]string{"README.md": "@ign-var:size@ x @ign-var:replicas:int@\n"})
+	writeProjectConfig(t, "./template", "main", map[string]interface{}{"size": "small", "replicas": 3.0})
+
+	for run := 1; run <= 2; run++ {
+		prep, err := PrepareUpdate(context.Background(), UpdateOptions{OutputDir: "."})
+		if err != nil {
+			t.Fatalf("run %d: PrepareUpdate returned error: %v", run, err)
+		}
+		wantChanges := 1
+		if run == 2 {
+			wantChanges = 0
+		}
+		if len(prep.VariableMigrations) != wantChanges {
+			t.Errorf("run %d: VariableMigrations = %v, want %d changes", run, prep.VariableMigrations, wantChanges)
+		}
+		if _, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", Overwrite: true}); err != nil {
+			t.Fatalf("run %d: CompleteUpdate returned error: %v", run, err)
+		}
+		stored, err := config.LoadIgnVarJson(filepath.Join(model.IgnConfigDir, model.IgnVarFile))
+		if err != nil {
+			t.Fatalf("run %d: failed to load ign-var.json: %v", run, err)
+		}
+		if stored.Variables["size"] != "s" || stored.Variables["replicas"] != 3.0 {
+			t.Fatalf("run %d: stored variables = %#v, want size s and replicas 3", run, stored.Variables)
+		}
+	}
+}
+
+func TestPrepareUpdate_RejectsChainedValueMap(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	writeLocalTemplate(t, filepath.Join(tempDir, "template"), &model.IgnJson{
+		Name:    "migrating-template",
+		Version: "2.0.0",
+		Hash:    testHash2,
+		Variables: map[string]model.VarDef{
+			"tier": {Type: model.VarTypeString, Description: "Tier", Required: true},
+		},
+		VariableMigrations: []model.VarMigration{
+			{Variable: "tier", ValueMap: map[string]interface{}{"1": "2"}},
+			{Variable: "tier", ValueMap: map[string]interface{}{"2": "3"}},
+		},
+	}, map[string]string{"README.md": "@ign-var:tier@\n"})
+	writeProjectConfig(t, "./template", "main", map[string]interface{}{"tier": "1"})
+
+	_, err := PrepareUpdate(context.Background(), UpdateOptions{OutputDir: "."})
+	if err == nil || !strings.Contains(err.Error(), `map "1" to "2", which is mapped again`) {
+		t.Fatalf("PrepareUpdate error = %v, want the chained value_map rejected", err)
+	}
+}
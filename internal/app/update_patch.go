@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/diff"
+)
+
+// UpdatePatch renders the changes of a dry-run update as a patch that
+// `git apply` accepts from outputDir. It covers file content, modes, new and
+// deleted files, and symlinks; .ign is left out, so the tracked template hash
+// is only advanced by a later `ign update` once the patch has landed.
+func UpdatePatch(outputDir string, preview *UpdateResult) (string, error) {
+	if preview == nil {
+		return "", NewValidationError("update patch requires a dry-run result", nil)
+	}
+	if len(preview.MovedFiles) > 0 {
+		// A patch cannot carry the project's copy to its new path.
+		return "", NewValidationError(fmt.Sprintf("the update moves %d files; run 'ign update' instead of writing a patch", len(preview.MovedFiles)), nil)
+	}
+
+	type change struct {
+		rel string
+		new *diff.File
+	}
+	var changes []change
+	var deletions []string
+	for _, file := range preview.DryRunFiles {
+		if file.WouldSkip {
+			continue
+		}
+		rel, err := patchRelPath(outputDir, file.Path)
+		if err != nil {
+			return "", err
+		}
+		updated := &diff.File{Content: file.Content, Mode: file.Mode}
+		if file.SymlinkTarget != "" {
+			updated = &diff.File{Content: []byte(file.SymlinkTarget), Mode: os.ModeSymlink}
+		}
+		changes = append(changes, change{rel: rel, new: updated})
+	}
+	for _, path := range preview.DeletedFiles {
+		rel, err := patchRelPath(outputDir, path)
+		if err != nil {
+			return "", err
+		}
+		deletions = append(deletions, rel)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].rel < changes[j].rel })
+	sort.Strings(deletions)
+
+	var out strings.Builder
+	// Deletions come first so a path freed by one can be recreated by a later
+	// entry, such as a directory that becomes a symlink.
+	for _, rel := range deletions {
+		old, err := patchFilesOnDisk(outputDir, rel)
+		if err != nil {
+			return "", err
+		}
+		for _, file := range old {
+			out.WriteString(diff.Git(file.rel, file.file, nil))
+		}
+	}
+	for _, c := range changes {
+		old, err := patchFilesOnDisk(outputDir, c.rel)
+		if err != nil {
+			return "", err
+		}
+		if len(old) == 1 && old[0].rel == c.rel {
+			out.WriteString(diff.Git(c.rel, old[0].file, c.new))
+			continue
+		}
+		// The path holds a directory today: remove what is beneath it.
+		for _, file := range old {
+			out.WriteString(diff.Git(file.rel, file.file, nil))
+		}
+		out.WriteString(diff.Git(c.rel, nil, c.new))
+	}
+	return out.String(), nil
+}
+
+// patchFile is a file or symlink on disk, named by its slash-separated path
+// relative to the output directory.
+type patchFile struct {
+	rel  string
+	file *diff.File
+}
+
+// patchFilesOnDisk returns the node at rel, or every file and symlink beneath
+// it when it is a directory. A missing path yields nothing.
+func patchFilesOnDisk(outputDir, rel string) ([]patchFile, error) {
+	root := filepath.Join(outputDir, filepath.FromSlash(rel))
+	var files []patchFile
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file := &diff.File{Mode: info.Mode().Perm()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			file = &diff.File{Content: []byte(target), Mode: os.ModeSymlink}
+		case info.Mode().IsRegular():
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			file.Content = content
+		default:
+			return fmt.Errorf("%s is not a regular file or symlink", path)
+		}
+		fileRel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, patchFile{rel: filepath.ToSlash(fileRel), file: file})
+		return nil
+	})
+	if err != nil {
+		return nil, NewCheckoutError(fmt.Sprintf("failed to read %s for the update patch", rel), err)
+	}
+	return files, nil
+}
+
+// patchRelPath returns path relative to outputDir in the slash-separated form
+// patches use.
+func patchRelPath(outputDir, path string) (string, error) {
+	rel, err := filepath.Rel(outputDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", NewValidationError(fmt.Sprintf("cannot express %s relative to output directory %s in a patch", path, outputDir), err)
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdatePatch(t *testing.T) {
+	tempDir := t.TempDir()
+	for path, content := range map[string]string{
+		"changed.txt":        "old\n",
+		"removed.txt":        "bye\n",
+		"agents/notes.md":    "notes\n",
+		"agents/sub/deep.md": "deep\n",
+	} {
+		full := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	preview := &UpdateResult{
+		DryRunFiles: []DryRunFile{
+			{Path: filepath.Join(tempDir, "skipped.txt"), WouldSkip: true},
+			{Path: filepath.Join(tempDir, "new.sh"), Content: []byte("echo\n"), Mode: 0755},
+			{Path: filepath.Join(tempDir, "changed.txt"), Content: []byte("new\n"), Mode: 0644, Exists: true, WouldOverwrite: true},
+			{Path: filepath.Join(tempDir, "agents"), Content: []byte(".shared"), SymlinkTarget: ".shared", Exists: true, WouldOverwrite: true},
+		},
+		DeletedFiles: []string{filepath.Join(tempDir, "removed.txt")},
+	}
+
+	patch, err := UpdatePatch(tempDir, preview)
+	if err != nil {
+		t.Fatalf("UpdatePatch failed: %v", err)
+	}
+
+	var order []string
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			order = append(order, strings.Fields(line)[3])
+		}
+	}
+	want := []string{"b/removed.txt", "b/agents/notes.md", "b/agents/sub/deep.md", "b/agents", "b/changed.txt", "b/new.sh"}
+	if strings.Join(order, " ") != strings.Join(want, " ") {
+		t.Errorf("patch entries = %v, want %v", order, want)
+	}
+	for _, fragment := range []string{
+		"diff --git a/new.sh b/new.sh\nnew file mode 100755\n",
+		"diff --git a/agents b/agents\nnew file mode 120000\n",
+		"-old\n+new\n",
+		"diff --git a/removed.txt b/removed.txt\ndeleted file mode 100644\n",
+	} {
+		if !strings.Contains(patch, fragment) {
+			t.Errorf("patch is missing %q:\n%s", fragment, patch)
+		}
+	}
+	if strings.Contains(patch, "skipped.txt") {
+		t.Errorf("patch should not mention skipped paths:\n%s", patch)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "changed.txt"))
+	if err != nil || string(content) != "old\n" {
+		t.Errorf("UpdatePatch must not touch the tree: changed.txt = %q, %v", content, err)
+	}
+}
+
+func TestUpdatePatch_RejectsPathsOutsideOutputDir(t *testing.T) {
+	tempDir := t.TempDir()
+	preview := &UpdateResult{
+		DryRunFiles: []DryRunFile{{Path: filepath.Join(filepath.Dir(tempDir), "outside.txt"), Content: []byte("x\n")}},
+	}
+	if _, err := UpdatePatch(tempDir, preview); err == nil {
+		t.Fatal("UpdatePatch should reject a path outside the output directory")
+	}
+}
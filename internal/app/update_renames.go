@@ -0,0 +1,253 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/diff"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// renameSimilarity is the share of content a dropped managed file and a new
+// template file must have in common to be treated as a rename.
+const renameSimilarity = 0.7
+
+// maxRenamePairs bounds the content comparisons of rename detection. Larger
+// reorganizations should declare migrations instead.
+const maxRenamePairs = 10000
+
+type renameCandidate struct {
+	rel     string
+	path    string
+	content []byte
+	// generated is the manifest hash of the content ign last generated for a
+	// dropped file.
+	generated string
+	// declined is the manifest hash of a template change the project declined
+	// for a dropped file.
+	declined string
+}
+
+// matchesRecordedHash reports whether added has the content ign last
+// generated, or the project declined, for dropped.
+func (dropped *renameCandidate) matchesRecordedHash(added *renameCandidate) bool {
+	if dropped.generated == "" && dropped.declined == "" {
+		return false
+	}
+	hash := generator.ContentHash(added.content)
+	return hash == dropped.generated || hash == dropped.declined
+}
+
+// detectRenames adds a move for each managed file the template no longer
+// generates, and that the update would delete, whose content is similar to
+// a file the template now generates for the first time. The project's copy
+// then moves to the new path instead of being deleted next to a pristine
+// one. A new file whose content hashes to what the manifest records for the
+// dropped one, as last generated or as a declined change, is a rename too,
+// however far the project's copy has drifted. preview is a dry run of the
+// update before any move.
+func (p *fileMigrationPlan) detectRenames(template *model.Template, mode generator.OverwriteMode, preview *generator.GenerateResult) error {
+	if p == nil || preview == nil || mode == generator.OverwriteNone {
+		return nil
+	}
+	manifest, err := loadManifestOrEmpty(p.manifestPath)
+	if err != nil {
+		return err
+	}
+	absOutputDir, err := filepath.Abs(p.outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory %s: %w", p.outputDir, err)
+	}
+	generated, err := canonicalGeneratedFileSet(preview.Files)
+	if err != nil {
+		return err
+	}
+	claimed := make(map[string]struct{})
+	for _, move := range p.moves {
+		claimed[move.fromRel] = struct{}{}
+		claimed[move.toRel] = struct{}{}
+	}
+	for _, planned := range p.deletes {
+		claimed[planned.rel] = struct{}{}
+	}
+	relOf := func(path string) (string, bool) {
+		canonical, err := canonicalManagedPathForComparison(path)
+		if err != nil {
+			return "", false
+		}
+		rel, err := filepath.Rel(absOutputDir, canonical)
+		if err != nil {
+			return "", false
+		}
+		return filepath.ToSlash(rel), true
+	}
+
+	ignorePatterns, err := updateOverwriteIgnorePatterns(template, p.manifestPath)
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]struct{}, len(manifest.Files))
+	var dropped []renameCandidate
+	for _, entry := range manifest.Files {
+		rel, ok := relOf(entry)
+		if !ok {
+			continue
+		}
+		tracked[rel] = struct{}{}
+		abs, err := validateManagedPath(entry, p.outputDir)
+		if err != nil || manifest.PolicyFor(entry).KeepsFile() {
+			continue
+		}
+		if _, ok := generated[filepath.Clean(abs)]; ok {
+			continue
+		}
+		if _, ok := claimed[rel]; ok {
+			continue
+		}
+		if !shouldRemoveManagedPathDuringUpdate(filepath.FromSlash(rel), mode, ignorePatterns) {
+			continue
+		}
+		info, err := os.Lstat(abs)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		dropped = append(dropped, renameCandidate{
+			rel:       rel,
+			path:      outputPathForManagedRelativePath(p.outputDir, filepath.FromSlash(rel)),
+			content:   content,
+			generated: manifest.Hashes[entry],
+			declined:  manifest.Declined[entry],
+		})
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	var added []renameCandidate
+	for _, file := range preview.DryRunFiles {
+		if file.Exists || file.WouldSkip || file.Injected || file.SymlinkTarget != "" {
+			continue
+		}
+		rel, ok := relOf(file.Path)
+		if !ok {
+			continue
+		}
+		if _, ok := tracked[rel]; ok {
+			continue
+		}
+		if _, ok := claimed[rel]; ok {
+			continue
+		}
+		added = append(added, renameCandidate{rel: rel, path: file.Path, content: file.Content})
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if len(dropped)*len(added) > maxRenamePairs {
+		debug.Debug("[app] Skipping rename detection: %d removed and %d added files", len(dropped), len(added))
+		return nil
+	}
+
+	type pair struct {
+		from, to *renameCandidate
+		score    float64
+		sameBase bool
+	}
+	var pairs []pair
+	for i := range dropped {
+		for j := range added {
+			from, to := &dropped[i], &added[j]
+			score := 1.0
+			if !bytes.Equal(from.content, to.content) && !from.matchesRecordedHash(to) {
+				score = diff.Similarity(from.content, to.content)
+			}
+			if score >= renameSimilarity {
+				pairs = append(pairs, pair{from: from, to: to, score: score, sameBase: filepath.Base(from.rel) == filepath.Base(to.rel)})
+			}
+		}
+	}
+	// Best matches first; a shared file name breaks ties, then the paths.
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.sameBase != b.sameBase {
+			return a.sameBase
+		}
+		if a.from.rel != b.from.rel {
+			return a.from.rel < b.from.rel
+		}
+		return a.to.rel < b.to.rel
+	})
+
+	used := make(map[*renameCandidate]struct{})
+	for _, candidate := range pairs {
+		_, fromUsed := used[candidate.from]
+		_, toUsed := used[candidate.to]
+		if fromUsed || toUsed {
+			continue
+		}
+		used[candidate.from] = struct{}{}
+		used[candidate.to] = struct{}{}
+		debug.Debug("[app] Detected rename %s -> %s (similarity %.2f)", candidate.from.rel, candidate.to.rel, candidate.score)
+		p.moves = append(p.moves, plannedFileMove{
+			fromRel: candidate.from.rel,
+			toRel:   candidate.to.rel,
+			from:    candidate.from.path,
+			to:      outputPathForManagedRelativePath(p.outputDir, filepath.FromSlash(candidate.to.rel)),
+		})
+	}
+	return nil
+}
+
+// adjustPreview updates a dry run taken before the moves, which sees each
+// destination as a new file, to what the update will do once the project's
+// copy is in place: nothing when the copy already matches, otherwise skip or
+// overwrite it as the overwrite mode and file policy decide.
+func (p *fileMigrationPlan) adjustPreview(preview *generator.GenerateResult, mode generator.OverwriteMode) {
+	if p == nil || preview == nil || len(p.moves) == 0 {
+		return
+	}
+	sources := make(map[string]string, len(p.moves))
+	for _, move := range p.moves {
+		if canonical, err := canonicalManagedPathForComparison(move.to); err == nil {
+			sources[canonical] = move.from
+		}
+	}
+
+	files := preview.DryRunFiles[:0]
+	for _, file := range preview.DryRunFiles {
+		canonical, err := canonicalManagedPathForComparison(file.Path)
+		source, moved := sources[canonical]
+		if err != nil || !moved || file.Exists {
+			files = append(files, file)
+			continue
+		}
+		preview.FilesCreated--
+		preview.CreatedFiles = slices.DeleteFunc(preview.CreatedFiles, func(path string) bool { return path == file.Path })
+		if content, err := os.ReadFile(source); err == nil && bytes.Equal(content, file.Content) {
+			continue
+		}
+		file.Exists = true
+		if mode == generator.OverwriteNone {
+			file.WouldSkip = true
+			preview.FilesSkipped++
+		} else {
+			file.WouldOverwrite = true
+			preview.FilesOverwritten++
+		}
+		files = append(files, file)
+	}
+	preview.DryRunFiles = files
+}
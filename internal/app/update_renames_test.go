@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+const renamedUtilContent = "package util\n\nfunc One() int { return 1 }\n\nfunc Two() int { return 2 }\n\nfunc Three() int { return 3 }\n"
+
+func setupRenamedFileProject(t *testing.T) {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	writeLocalTemplate(t, filepath.Join(tempDir, "template"), &model.IgnJson{
+		Name:    "renaming-template",
+		Version: "1.1.0",
+		Hash:    testHash2,
+	}, map[string]string{
+		"internal/util/util.go": renamedUtilContent,
+		"docs/guide.md":         "# Guide\n\nNew content only.\n",
+	})
+
+	writeProjectConfig(t, "./template", "", map[string]interface{}{})
+	ignConfig := &model.IgnConfig{
+		Template: model.TemplateSource{URL: "./template"},
+		Hash:     testHash1,
+	}
+	if err := config.SaveIgnConfig(filepath.Join(model.IgnConfigDir, model.IgnProjectConfigFile), ignConfig); err != nil {
+		t.Fatalf("failed to save ign config: %v", err)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files: []string{"pkg/util/util.go", "notes.md"},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	for path, content := range map[string]string{
+		// The project's copy carries a customization on top of the old template.
+		"pkg/util/util.go": renamedUtilContent + "\nfunc Four() int { return 4 }\n",
+		"notes.md":         "# Notes\n\nUnrelated text.\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestCompleteUpdate_DetectsRenamedFiles(t *testing.T) {
+	setupRenamedFileProject(t)
+	ctx := context.Background()
+
+	prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	wantMoved := []MovedFile{{From: "pkg/util/util.go", To: "internal/util/util.go"}}
+
+	preview, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", Overwrite: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("CompleteUpdate dry run returned error: %v", err)
+	}
+	if !slices.Equal(preview.MovedFiles, wantMoved) {
+		t.Fatalf("preview MovedFiles = %v, want %v", preview.MovedFiles, wantMoved)
+	}
+	if !slices.Equal(preview.DeletedFiles, []string{"notes.md"}) {
+		t.Errorf("preview DeletedFiles = %v, want [notes.md]", preview.DeletedFiles)
+	}
+	for _, file := range preview.DryRunFiles {
+		if file.Path == "internal/util/util.go" && (!file.Exists || !file.WouldOverwrite) {
+			t.Errorf("renamed destination should preview as an overwrite, got %#v", file)
+		}
+	}
+	if _, err := os.Stat("pkg/util/util.go"); err != nil {
+		t.Fatalf("dry run moved the file: %v", err)
+	}
+
+	result, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", Overwrite: true})
+	if err != nil {
+		t.Fatalf("CompleteUpdate returned error: %v", err)
+	}
+	if !slices.Equal(result.MovedFiles, wantMoved) {
+		t.Errorf("MovedFiles = %v, want %v", result.MovedFiles, wantMoved)
+	}
+	if slices.Contains(result.DeletedFiles, "pkg/util/util.go") {
+		t.Errorf("renamed file reported as deleted: %v", result.DeletedFiles)
+	}
+	for _, gone := range []string{"pkg", "notes.md"} {
+		if _, err := os.Lstat(gone); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat error = %v", gone, err)
+		}
+	}
+	content, err := os.ReadFile("internal/util/util.go")
+	if err != nil || string(content) != renamedUtilContent {
+		t.Errorf("internal/util/util.go = %q, %v; want the template content", content, err)
+	}
+
+	if _, err := Undo(ctx, UndoOptions{OutputDir: "."}); err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+	content, err = os.ReadFile("pkg/util/util.go")
+	if err != nil || string(content) != renamedUtilContent+"\nfunc Four() int { return 4 }\n" {
+		t.Errorf("undo should restore the customized file, pkg/util/util.go = %q, %v", content, err)
+	}
+}
+
+func TestCompleteUpdate_NoRenameWithoutOverwrite(t *testing.T) {
+	setupRenamedFileProject(t)
+	ctx := context.Background()
+
+	prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	result, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: "."})
+	if err != nil {
+		t.Fatalf("CompleteUpdate returned error: %v", err)
+	}
+	if len(result.MovedFiles) != 0 {
+		t.Errorf("MovedFiles = %v, want none when the update keeps existing files", result.MovedFiles)
+	}
+	if _, err := os.Stat("pkg/util/util.go"); err != nil {
+		t.Errorf("pkg/util/util.go should stay in place: %v", err)
+	}
+}
+
+func TestCompleteUpdate_DetectsRenameOfRewrittenFileByRecordedHash(t *testing.T) {
+	setupRenamedFileProject(t)
+	ctx := context.Background()
+	// The project rewrote the file beyond the similarity threshold, but the
+	// manifest records that ign generated exactly the new template content.
+	if err := os.WriteFile("pkg/util/util.go", []byte("package util\n\n// Rewritten by hand.\n"), 0644); err != nil {
+		t.Fatalf("failed to rewrite pkg/util/util.go: %v", err)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files:  []string{"pkg/util/util.go", "notes.md"},
+		Hashes: map[string]string{"pkg/util/util.go": generator.ContentHash([]byte(renamedUtilContent))},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	preview, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", Overwrite: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("CompleteUpdate dry run returned error: %v", err)
+	}
+	if want := []MovedFile{{From: "pkg/util/util.go", To: "internal/util/util.go"}}; !slices.Equal(preview.MovedFiles, want) {
+		t.Errorf("MovedFiles = %v, want %v", preview.MovedFiles, want)
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+// UpdateFileDecision is a reviewer's choice for one change in an update
+// preview. A path without a decision is applied as previewed.
+type UpdateFileDecision struct {
+	// Skip leaves the path as it is on disk. A skipped file change is
+	// remembered in ign-files.json and not offered again until the template
+	// produces different content for it; a skipped deletion keeps the file
+	// and stops tracking it.
+	Skip bool
+	// Content replaces the previewed content of a file change. It is ignored
+	// when Skip is set and rejected for deletions.
+	Content []byte
+}
+
+// WithDecisions returns a copy of the plan that applies decisions when it is
+// passed to the confirmed CompleteUpdate. Decisions are keyed by the paths
+// reported in the preview's DryRunFiles and DeletedFiles; a path the preview
+// did not change is rejected. Without decisions the plan is returned as is.
+func (p *UpdateExecutionPlan) WithDecisions(decisions map[string]UpdateFileDecision) (*UpdateExecutionPlan, error) {
+	if len(decisions) == 0 {
+		return p, nil
+	}
+	if p == nil {
+		return nil, NewValidationError("update decisions require an execution plan from a preview", nil)
+	}
+	paths := make([]string, 0, len(decisions))
+	for path := range decisions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	checked := make(map[string]UpdateFileDecision, len(decisions))
+	for _, path := range paths {
+		decision := decisions[path]
+		clean := filepath.Clean(path)
+		_, changed := p.changes[clean]
+		_, deleted := p.deletions[clean]
+		switch {
+		case !changed && !deleted:
+			return nil, NewValidationError(fmt.Sprintf("update preview does not change %s", path), nil)
+		case deleted && !decision.Skip && decision.Content != nil:
+			return nil, NewValidationError(fmt.Sprintf("cannot edit %s: the update deletes it", path), nil)
+		}
+		if decision.Skip {
+			decision.Content = nil
+		}
+		checked[clean] = decision
+	}
+
+	plan := *p
+	plan.decisions = checked
+	return &plan, nil
+}
+
+// recordPreview remembers what a dry run would change so later decisions can
+// be checked against it and skips can be pinned to the previewed content.
+func (p *UpdateExecutionPlan) recordPreview(result *generator.GenerateResult, removed *cleanupRemovedManagedFilesResult) {
+	if p == nil || result == nil {
+		return
+	}
+	p.changes = make(map[string]string)
+	p.deletions = make(map[string]struct{})
+	for _, file := range result.DryRunFiles {
+		if file.WouldSkip {
+			continue
+		}
+		p.changes[filepath.Clean(file.Path)] = generator.ContentHash(file.Content)
+	}
+	if removed == nil {
+		return
+	}
+	for _, path := range removed.DeletedFiles {
+		p.deletions[filepath.Clean(path)] = struct{}{}
+	}
+}
+
+// applyDecisions feeds the plan's decisions into a confirmed generation.
+// Skipped changes are declined at their previewed hash, and edited content
+// overrides the generated content.
+func (p *UpdateExecutionPlan) applyDecisions(genOpts *generator.GenerateOptions) {
+	if p == nil || len(p.decisions) == 0 {
+		return
+	}
+	declined := make(map[string]string, len(genOpts.DeclinedChanges)+len(p.decisions))
+	for path, hash := range genOpts.DeclinedChanges {
+		declined[path] = hash
+	}
+	overrides := make(map[string][]byte)
+	for path, decision := range p.decisions {
+		hash, changed := p.changes[path]
+		if !changed {
+			continue
+		}
+		if decision.Skip {
+			declined[path] = hash
+		} else if decision.Content != nil {
+			overrides[path] = decision.Content
+		}
+	}
+	genOpts.DeclinedChanges = declined
+	genOpts.ContentOverrides = overrides
+}
+
+// recordEditedDeclines marks edited paths as declined at their previewed
+// hash, so the next update keeps the edit until the template changes the
+// file again.
+func (p *UpdateExecutionPlan) recordEditedDeclines(result *generator.GenerateResult) {
+	if p == nil || result == nil {
+		return
+	}
+	for path, decision := range p.decisions {
+		if decision.Skip || decision.Content == nil {
+			continue
+		}
+		if result.DeclinedFiles == nil {
+			result.DeclinedFiles = map[string]string{}
+		}
+		result.DeclinedFiles[path] = p.changes[path]
+	}
+}
+
+// keptDeletions returns the deletions the reviewer skipped.
+func (p *UpdateExecutionPlan) keptDeletions() map[string]struct{} {
+	if p == nil {
+		return nil
+	}
+	kept := make(map[string]struct{})
+	for path, decision := range p.decisions {
+		if _, deleted := p.deletions[path]; deleted && decision.Skip {
+			kept[path] = struct{}{}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func newReviewUpdatePrep(t *testing.T, tempDir string, files []model.TemplateFile) *PrepareUpdateResult {
+	t.Helper()
+	ignDir := filepath.Join(tempDir, ".ign")
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:      "test",
+			Version:   "1.0.0",
+			Hash:      testHash2,
+			Variables: map[string]model.VarDef{},
+		},
+		Files:    files,
+		RootPath: tempDir,
+	}
+	return &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+}
+
+func selectiveUpdate(prep *PrepareUpdateResult, outputDir string, dryRun bool, plan *UpdateExecutionPlan) (*UpdateResult, error) {
+	return CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		NewVariables:  map[string]interface{}{},
+		OutputDir:     outputDir,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+		DryRun:        dryRun,
+		ExecutionPlan: plan,
+	})
+}
+
+func TestCompleteUpdate_AppliesReviewDecisions(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+
+	skippedPath := filepath.Join(tempDir, "skipped.txt")
+	editedPath := filepath.Join(tempDir, "edited.txt")
+	acceptedPath := filepath.Join(tempDir, "accepted.txt")
+	keptPath := filepath.Join(tempDir, "kept.txt")
+	for path, content := range map[string]string{
+		skippedPath:  "local skipped\n",
+		editedPath:   "local edited\n",
+		acceptedPath: "local accepted\n",
+		keptPath:     "local kept\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{
+		Files: []string{acceptedPath, editedPath, keptPath, skippedPath},
+	}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	prep := newReviewUpdatePrep(t, tempDir, []model.TemplateFile{
+		{Path: "skipped.txt", Content: []byte("template skipped\n"), Mode: 0644},
+		{Path: "edited.txt", Content: []byte("template edited\n"), Mode: 0644},
+		{Path: "accepted.txt", Content: []byte("template accepted\n"), Mode: 0644},
+	})
+
+	preview, err := selectiveUpdate(prep, tempDir, true, nil)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if !slices.Contains(preview.DeletedFiles, keptPath) {
+		t.Fatalf("preview DeletedFiles = %v, want %s", preview.DeletedFiles, keptPath)
+	}
+
+	if _, err := preview.ExecutionPlan.WithDecisions(map[string]UpdateFileDecision{
+		filepath.Join(tempDir, "unknown.txt"): {Skip: true},
+	}); err == nil {
+		t.Fatal("WithDecisions should reject a path the preview does not change")
+	}
+	if _, err := preview.ExecutionPlan.WithDecisions(map[string]UpdateFileDecision{
+		keptPath: {Content: []byte("edited deletion\n")},
+	}); err == nil {
+		t.Fatal("WithDecisions should reject editing a deletion")
+	}
+
+	plan, err := preview.ExecutionPlan.WithDecisions(map[string]UpdateFileDecision{
+		skippedPath: {Skip: true},
+		editedPath:  {Content: []byte("reviewed edit\n")},
+		keptPath:    {Skip: true},
+	})
+	if err != nil {
+		t.Fatalf("WithDecisions failed: %v", err)
+	}
+
+	result, err := selectiveUpdate(prep, tempDir, false, plan)
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.FilesDeleted != 0 {
+		t.Errorf("FilesDeleted = %d, want 0", result.FilesDeleted)
+	}
+
+	for path, want := range map[string]string{
+		skippedPath:  "local skipped\n",
+		editedPath:   "reviewed edit\n",
+		acceptedPath: "template accepted\n",
+		keptPath:     "local kept\n",
+	} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), content, want)
+		}
+	}
+
+	manifest, err := config.LoadIgnManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if slices.Contains(manifest.Files, keptPath) {
+		t.Errorf("manifest should stop tracking the kept deletion: %v", manifest.Files)
+	}
+	wantDeclined := map[string]string{
+		skippedPath: generator.ContentHash([]byte("template skipped\n")),
+		editedPath:  generator.ContentHash([]byte("template edited\n")),
+	}
+	if len(manifest.Declined) != len(wantDeclined) {
+		t.Fatalf("manifest.Declined = %v, want %v", manifest.Declined, wantDeclined)
+	}
+	for path, hash := range wantDeclined {
+		if manifest.Declined[path] != hash {
+			t.Errorf("manifest.Declined[%s] = %q, want %q", path, manifest.Declined[path], hash)
+		}
+	}
+
+	// The next selective update does not offer the declined changes again.
+	next, err := selectiveUpdate(prep, tempDir, true, nil)
+	if err != nil {
+		t.Fatalf("second preview failed: %v", err)
+	}
+	for _, file := range next.DryRunFiles {
+		if !file.WouldSkip {
+			t.Errorf("second preview should skip %s", file.Path)
+		}
+	}
+}
+
+func TestCompleteUpdate_DeclinedChangeOfferedAgainWhenTemplateChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	path := filepath.Join(tempDir, "config.txt")
+	if err := os.WriteFile(path, []byte("local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config.txt: %v", err)
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{
+		Files:    []string{path},
+		Declined: map[string]string{path: generator.ContentHash([]byte("v1\n"))},
+	}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	prep := newReviewUpdatePrep(t, tempDir, []model.TemplateFile{
+		{Path: "config.txt", Content: []byte("v2\n"), Mode: 0644},
+	})
+	if _, err := selectiveUpdate(prep, tempDir, false, nil); err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config.txt: %v", err)
+	}
+	if string(content) != "v2\n" {
+		t.Errorf("config.txt = %q, want the changed template content", content)
+	}
+
+	manifest, err := config.LoadIgnManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if len(manifest.Declined) != 0 {
+		t.Errorf("manifest.Declined = %v, want the overwritten path dropped", manifest.Declined)
+	}
+}
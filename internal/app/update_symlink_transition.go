@@ -29,6 +29,9 @@ type UpdateExecutionPlan struct {
 	transitions   map[string]generator.SymlinkTransition
 	sourcePaths   []string
 	fingerprint   string
+	changes       map[string]string
+	deletions     map[string]struct{}
+	decisions     map[string]UpdateFileDecision
 }
 
 type symlinkTransitionBlockerKind string
@@ -104,7 +107,10 @@ func classifyUpdateSymlinkTransitionsWithVariables(ctx context.Context, outputDi
 		return nil, fmt.Errorf("load manifest for symlink transitions: %w", err)
 	}
 	managed, managedPathsValid := managedCanonicalSet(manifest.Files, outputDir)
-	patterns := overwriteIgnorePatternsFromTemplate(template)
+	patterns, err := updateOverwriteIgnorePatterns(template, manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("load overwrite-ignore patterns for symlink transitions: %w", err)
+	}
 	for _, file := range preview.DryRunFiles {
 		if file.SymlinkTarget == "" {
 			continue
@@ -396,6 +402,13 @@ func renderedTemplateTreeEntries(ctx context.Context, template *model.Template,
 		return entries, nil
 	}
 	for _, file := range template.Files {
+		included, err := generator.FileConditionsMet(template, file.Path, variables)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate template path %s for symlink recovery: %w", file.Path, err)
+		}
+		if !included {
+			continue
+		}
 		processedPath, err := generator.ProcessFilename(ctx, file.Path, variables, parser.NewParser())
 		if err != nil {
 			return nil, fmt.Errorf("process template path %s for symlink recovery: %w", file.Path, err)
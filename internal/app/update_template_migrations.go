@@ -0,0 +1,477 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// MovedFile is a generated file an update moved to a new template path,
+// keeping the project's copy. Moves come from template migrations and from
+// detected renames.
+type MovedFile struct {
+	// From is the previous path.
+	From string `json:"from"`
+	// To is the new path.
+	To string `json:"to"`
+}
+
+// String formats the move for update summaries.
+func (m MovedFile) String() string {
+	return fmt.Sprintf("%s -> %s", m.From, m.To)
+}
+
+// pendingTemplateMigrations returns the migrations a project at fromVersion
+// crosses on its way to toVersion, oldest first. A project without a recorded
+// version runs none: ign cannot tell which steps it already went through.
+func pendingTemplateMigrations(migrations []model.TemplateMigration, fromVersion, toVersion string) []model.TemplateMigration {
+	if fromVersion == "" || len(migrations) == 0 {
+		return nil
+	}
+	var pending []model.TemplateMigration
+	for _, migration := range migrations {
+		if config.CompareVersions(fromVersion, migration.Version) < 0 && config.CompareVersions(migration.Version, toVersion) <= 0 {
+			pending = append(pending, migration)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return config.CompareVersions(pending[i].Version, pending[j].Version) < 0
+	})
+	return pending
+}
+
+// templateMigrationVariables returns the variable migrations to apply: those
+// of the pending versioned migrations in order, then the template's
+// unversioned variable_migrations.
+func templateMigrationVariables(pending []model.TemplateMigration, always []model.VarMigration) []model.VarMigration {
+	var migrations []model.VarMigration
+	for _, migration := range pending {
+		migrations = append(migrations, migration.Variables...)
+	}
+	return append(migrations, always...)
+}
+
+// fileMigrationPlan is the file work of the pending template migrations,
+// resolved against the manifest and the project tree.
+type fileMigrationPlan struct {
+	outputDir    string
+	manifestPath string
+	// moves holds the tracked files to move, in order.
+	moves []plannedFileMove
+	// deletes holds the tracked files to remove once generation succeeded.
+	deletes []plannedFileDelete
+	// errors explains the steps that could not be applied.
+	errors []error
+
+	applied        []plannedFileMove
+	manifestBefore []byte
+	manifestSaved  bool
+	committed      bool
+}
+
+type plannedFileMove struct {
+	// version is the migration that declared the move; it is empty for a
+	// detected rename.
+	version  string
+	fromRel  string
+	toRel    string
+	from, to string
+}
+
+// origin names what requested the move, for messages.
+func (m plannedFileMove) origin() string {
+	if m.version == "" {
+		return "rename"
+	}
+	return "migration " + m.version
+}
+
+type plannedFileDelete struct {
+	version string
+	rel     string
+	path    string
+	// hash is the content hash the manifest recorded for the file, empty
+	// when it recorded none.
+	hash string
+}
+
+// planFileMigrations resolves the moves and deletes of pending against the
+// manifest. Only tracked files are touched. A move whose destination already
+// exists, or whose source is missing, is left to the regular update.
+func planFileMigrations(outputDir, manifestPath string, pending []model.TemplateMigration) (*fileMigrationPlan, error) {
+	plan := &fileMigrationPlan{outputDir: outputDir, manifestPath: manifestPath}
+	if len(pending) == 0 {
+		return plan, nil
+	}
+	manifest, err := loadManifestOrEmpty(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory %s: %w", outputDir, err)
+	}
+	projectIgnore, err := projectOverwriteIgnorePatterns(filepath.Dir(manifestPath))
+	if err != nil {
+		return nil, err
+	}
+
+	// tracked holds the slash-separated relative paths of the tracked files on
+	// disk, as they stand after the steps planned so far.
+	tracked := make(map[string]struct{})
+	hashes := make(map[string]string)
+	for _, entry := range manifest.Files {
+		if manifest.PolicyFor(entry).KeepsFile() {
+			continue
+		}
+		abs, err := validateManagedPath(entry, outputDir)
+		if err != nil {
+			continue
+		}
+		info, err := os.Lstat(abs)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(absOutputDir, abs)
+		if err != nil {
+			continue
+		}
+		if generator.MatchesGitIgnorePattern(rel, projectIgnore) {
+			// The project owns the path; migrations leave it alone.
+			continue
+		}
+		tracked[filepath.ToSlash(rel)] = struct{}{}
+		hashes[filepath.ToSlash(rel)] = manifest.Hashes[filepath.Clean(entry)]
+	}
+	vacated := make(map[string]struct{})
+	occupied := func(rel string) bool {
+		if _, ok := tracked[rel]; ok {
+			return true
+		}
+		if _, ok := vacated[rel]; ok {
+			return false
+		}
+		_, err := os.Lstat(filepath.Join(absOutputDir, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	for _, migration := range pending {
+		for _, move := range migration.Moves {
+			from, to := cleanMigrationPath(move.From), cleanMigrationPath(move.To)
+			for _, rel := range trackedBeneath(tracked, from) {
+				target := to + strings.TrimPrefix(rel, from)
+				if occupied(target) {
+					plan.errors = append(plan.errors, fmt.Errorf("migration %s: cannot move %s to %s: destination already exists", migration.Version, rel, target))
+					continue
+				}
+				plan.moves = append(plan.moves, plannedFileMove{
+					version: migration.Version,
+					fromRel: rel,
+					toRel:   target,
+					from:    outputPathForManagedRelativePath(outputDir, filepath.FromSlash(rel)),
+					to:      outputPathForManagedRelativePath(outputDir, filepath.FromSlash(target)),
+				})
+				delete(tracked, rel)
+				vacated[rel] = struct{}{}
+				tracked[target] = struct{}{}
+				delete(vacated, target)
+				hashes[target] = hashes[rel]
+			}
+		}
+		for _, deleted := range migration.Deletes {
+			clean := cleanMigrationPath(deleted)
+			for _, rel := range trackedBeneath(tracked, clean) {
+				plan.deletes = append(plan.deletes, plannedFileDelete{
+					version: migration.Version,
+					rel:     rel,
+					path:    outputPathForManagedRelativePath(outputDir, filepath.FromSlash(rel)),
+					hash:    hashes[rel],
+				})
+				delete(tracked, rel)
+				vacated[rel] = struct{}{}
+			}
+		}
+	}
+	return plan, nil
+}
+
+// trackedBeneath returns the tracked paths equal to or beneath prefix, sorted.
+func trackedBeneath(tracked map[string]struct{}, prefix string) []string {
+	var matches []string
+	for rel := range tracked {
+		if rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+			matches = append(matches, rel)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func cleanMigrationPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+}
+
+// movedFiles returns the planned moves in result form.
+func (p *fileMigrationPlan) movedFiles() []MovedFile {
+	if p == nil || len(p.moves) == 0 {
+		return nil
+	}
+	moved := make([]MovedFile, 0, len(p.moves))
+	for _, move := range p.moves {
+		moved = append(moved, MovedFile{From: move.from, To: move.to})
+	}
+	return moved
+}
+
+// movedSources returns the move sources in DeletedFiles form, so a preview
+// does not report them as deletions.
+func (p *fileMigrationPlan) movedSources() map[string]struct{} {
+	sources := make(map[string]struct{})
+	if p == nil {
+		return sources
+	}
+	for _, move := range p.moves {
+		sources[move.from] = struct{}{}
+	}
+	return sources
+}
+
+// applyMoves moves the planned files and records the new paths in the
+// manifest, so the generation that follows treats them as existing project
+// files. Until commit is called, rollback undoes both.
+func (p *fileMigrationPlan) applyMoves(history *HistoryRecorder) error {
+	if p == nil || len(p.moves) == 0 {
+		return nil
+	}
+	for _, move := range p.moves {
+		if err := history.capture(move.from); err != nil {
+			return err
+		}
+		if err := history.capture(move.to); err != nil {
+			return err
+		}
+	}
+
+	before, err := os.ReadFile(p.manifestPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", p.manifestPath, err)
+	}
+	p.manifestBefore = before
+
+	for _, move := range p.moves {
+		debug.Debug("[app] %s: moving %s to %s", move.origin(), move.from, move.to)
+		if err := os.MkdirAll(filepath.Dir(move.to), 0755); err != nil {
+			return fmt.Errorf("%s: create directory for %s: %w", move.origin(), move.to, err)
+		}
+		if _, err := os.Lstat(move.to); err == nil {
+			return fmt.Errorf("%s: cannot move %s to %s: destination already exists", move.origin(), move.from, move.to)
+		}
+		if err := os.Rename(move.from, move.to); err != nil {
+			return fmt.Errorf("%s: move %s to %s: %w", move.origin(), move.from, move.to, err)
+		}
+		p.applied = append(p.applied, move)
+		p.removeEmptyParents(move.from)
+	}
+
+	manifest, err := loadManifestOrEmpty(p.manifestPath)
+	if err != nil {
+		return err
+	}
+	renameManifestPaths(manifest, p.outputDir, p.applied)
+	if err := config.SaveIgnManifest(p.manifestPath, manifest); err != nil {
+		return fmt.Errorf("save %s: %w", p.manifestPath, err)
+	}
+	p.manifestSaved = true
+	return nil
+}
+
+// renameManifestPaths moves the manifest records of each moved file to its
+// new path. A declined change no longer applies to the new path and is dropped.
+func renameManifestPaths(manifest *model.IgnManifest, outputDir string, moves []plannedFileMove) {
+	renamed := make(map[string]string, len(moves))
+	for _, move := range moves {
+		renamed[move.fromRel] = move.toRel
+	}
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return
+	}
+	target := func(entry string) (string, bool) {
+		abs, err := validateManagedPath(entry, outputDir)
+		if err != nil {
+			return "", false
+		}
+		rel, err := filepath.Rel(absOutputDir, abs)
+		if err != nil {
+			return "", false
+		}
+		// A file may move again in a later migration.
+		current, moved := filepath.ToSlash(rel), false
+		for steps := 0; steps < len(moves); steps++ {
+			next, ok := renamed[current]
+			if !ok {
+				break
+			}
+			current, moved = next, true
+		}
+		if !moved {
+			return "", false
+		}
+		return outputPathForManagedRelativePath(outputDir, filepath.FromSlash(current)), true
+	}
+
+	files := make([]string, 0, len(manifest.Files))
+	seen := make(map[string]struct{}, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		if to, ok := target(entry); ok {
+			entry = to
+		}
+		if _, dup := seen[entry]; !dup {
+			seen[entry] = struct{}{}
+			files = append(files, entry)
+		}
+	}
+	sort.Strings(files)
+	manifest.Files = files
+	for entry, policy := range manifest.Policies {
+		if to, ok := target(entry); ok {
+			delete(manifest.Policies, entry)
+			manifest.Policies[to] = policy
+		}
+	}
+	for i := range manifest.Injections {
+		if to, ok := target(manifest.Injections[i].Path); ok {
+			manifest.Injections[i].Path = to
+		}
+	}
+	for entry, hash := range manifest.Hashes {
+		if to, ok := target(entry); ok {
+			delete(manifest.Hashes, entry)
+			manifest.Hashes[to] = hash
+		}
+	}
+	for entry := range manifest.Declined {
+		if _, ok := target(entry); ok {
+			delete(manifest.Declined, entry)
+		}
+	}
+}
+
+// applyDeletes removes the planned files the generation did not produce
+// again and records them in removed. Like update cleanup, deletes need an
+// overwrite and skip paths matched by overwriteIgnorePatterns. A file whose
+// content no longer matches the hash the manifest recorded is kept and
+// reported, since the project changed it. In a dry run the files are only
+// recorded.
+func (p *fileMigrationPlan) applyDeletes(removed *cleanupRemovedManagedFilesResult, generated []string, mode generator.OverwriteMode, overwriteIgnorePatterns []string, dryRun bool, history *HistoryRecorder) error {
+	if p == nil || len(p.deletes) == 0 {
+		return nil
+	}
+	regenerated, err := canonicalGeneratedFileSet(generated)
+	if err != nil {
+		return err
+	}
+	var deleteErrors []error
+	for _, planned := range p.deletes {
+		canonical, err := canonicalManagedPathForComparison(planned.path)
+		if err != nil {
+			deleteErrors = append(deleteErrors, err)
+			continue
+		}
+		if _, done := removed.RemovedCanonicalPaths[canonical]; done {
+			continue
+		}
+		if _, ok := regenerated[canonical]; ok {
+			debug.Debug("[app] Migration %s: keeping %s, the template still generates it", planned.version, planned.path)
+			continue
+		}
+		if !shouldRemoveManagedPathDuringUpdate(filepath.FromSlash(planned.rel), mode, overwriteIgnorePatterns) {
+			debug.Debug("[app] Migration %s: keeping %s, the update does not overwrite it", planned.version, planned.path)
+			continue
+		}
+		if current, err := managedContentHash(canonical); err == nil && current != planned.hash {
+			// The file stays, but is no longer tracked, so a later update does
+			// not delete it either.
+			p.errors = append(p.errors, fmt.Errorf("migration %s: kept %s: it changed since ign generated it", planned.version, planned.rel))
+			removed.RemovedCanonicalPaths[canonical] = struct{}{}
+			continue
+		}
+		if !dryRun {
+			debug.Debug("[app] Migration %s: deleting %s", planned.version, planned.path)
+			if err := history.capture(canonical); err != nil {
+				deleteErrors = append(deleteErrors, fmt.Errorf("failed to record %s: %w", planned.path, err))
+				continue
+			}
+			if err := os.Remove(canonical); err != nil && !os.IsNotExist(err) {
+				deleteErrors = append(deleteErrors, fmt.Errorf("migration %s: delete %s: %w", planned.version, planned.path, err))
+				continue
+			}
+			p.removeEmptyParents(canonical)
+		}
+		recordRemovedManagedPath(removed, p.outputDir, filepath.FromSlash(planned.rel), canonical)
+	}
+	sort.Strings(removed.DeletedFiles)
+	return errors.Join(deleteErrors...)
+}
+
+// deletePaths returns the planned deletes in the form of
+// cleanupRemovedManagedFilesOptions.DeferredPaths.
+func (p *fileMigrationPlan) deletePaths() map[string]struct{} {
+	if p == nil || len(p.deletes) == 0 {
+		return nil
+	}
+	paths := make(map[string]struct{}, len(p.deletes))
+	for _, planned := range p.deletes {
+		paths[planned.path] = struct{}{}
+	}
+	return paths
+}
+
+// removeEmptyParents removes the directories a move or delete left empty.
+func (p *fileMigrationPlan) removeEmptyParents(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		removeEmptyParentDirs(abs, p.outputDir)
+	}
+}
+
+// commit keeps the applied moves.
+func (p *fileMigrationPlan) commit() {
+	if p != nil {
+		p.committed = true
+	}
+}
+
+// rollback moves applied files back and restores the manifest, unless the
+// update committed.
+func (p *fileMigrationPlan) rollback() {
+	if p == nil || p.committed {
+		return
+	}
+	for i := len(p.applied) - 1; i >= 0; i-- {
+		move := p.applied[i]
+		if err := os.MkdirAll(filepath.Dir(move.from), 0755); err != nil {
+			debug.Debug("[app] Failed to restore migrated file %s: %v", move.from, err)
+			continue
+		}
+		if err := os.Rename(move.to, move.from); err != nil {
+			debug.Debug("[app] Failed to restore migrated file %s: %v", move.from, err)
+			continue
+		}
+		p.removeEmptyParents(move.to)
+	}
+	p.applied = nil
+	if p.manifestSaved {
+		if err := os.WriteFile(p.manifestPath, p.manifestBefore, 0644); err != nil {
+			debug.Debug("[app] Failed to restore %s: %v", p.manifestPath, err)
+		}
+		p.manifestSaved = false
+	}
+}
@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func TestPendingTemplateMigrations(t *testing.T) {
+	migrations := []model.TemplateMigration{
+		{Version: "2.0.0"},
+		{Version: "1.1.0"},
+		{Version: "1.0.0"},
+		{Version: "2.1.0-rc.1"},
+	}
+	versions := func(pending []model.TemplateMigration) []string {
+		var out []string
+		for _, migration := range pending {
+			out = append(out, migration.Version)
+		}
+		return out
+	}
+
+	if got := versions(pendingTemplateMigrations(migrations, "1.0.0", "2.1.0")); !slices.Equal(got, []string{"1.1.0", "2.0.0", "2.1.0-rc.1"}) {
+		t.Errorf("1.0.0 -> 2.1.0 = %v", got)
+	}
+	if got := versions(pendingTemplateMigrations(migrations, "1.1.0", "2.0.0")); !slices.Equal(got, []string{"2.0.0"}) {
+		t.Errorf("1.1.0 -> 2.0.0 = %v", got)
+	}
+	if got := pendingTemplateMigrations(migrations, "2.0.0", "1.0.0"); len(got) != 0 {
+		t.Errorf("downgrade should run no migrations, got %v", versions(got))
+	}
+	if got := pendingTemplateMigrations(migrations, "", "2.0.0"); len(got) != 0 {
+		t.Errorf("unknown previous version should run no migrations, got %v", versions(got))
+	}
+}
+
+func setupTemplateMigrationProject(t *testing.T) {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	writeLocalTemplate(t, filepath.Join(tempDir, "template"), &model.IgnJson{
+		Name:    "migrating-template",
+		Version: "2.0.0",
+		Hash:    testHash2,
+		Variables: map[string]model.VarDef{
+			"service_name": {Type: model.VarTypeString, Description: "Service name", Required: true},
+		},
+		Migrations: []model.TemplateMigration{
+			// Already applied to a 1.0.0 project.
+			{Version: "1.0.0", Deletes: []string{"keep.txt"}},
+			{
+				Version:   "2.0.0",
+				Moves:     []model.FileMove{{From: "cmd/server", To: "cmd/api"}},
+				Deletes:   []string{"old.txt"},
+				Variables: []model.VarMigration{{Variable: "service_name", RenamedFrom: []string{"app_name"}}},
+			},
+		},
+	}, map[string]string{
+		"cmd/api/main.go": "package main // @ign-var:service_name@\n",
+		"keep.txt":        "keep\n",
+	})
+
+	writeProjectConfig(t, "./template", "", map[string]interface{}{"app_name": "demo"})
+	ignConfig := &model.IgnConfig{
+		Template: model.TemplateSource{URL: "./template"},
+		Hash:     testHash1,
+		Metadata: &model.FileMetadata{TemplateName: "migrating-template", TemplateVersion: "1.0.0"},
+	}
+	if err := config.SaveIgnConfig(filepath.Join(model.IgnConfigDir, model.IgnProjectConfigFile), ignConfig); err != nil {
+		t.Fatalf("failed to save ign config: %v", err)
+	}
+	if err := config.SaveIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile), &model.IgnManifest{
+		Files:    []string{"cmd/server/main.go", "keep.txt", "old.txt"},
+		Declined: map[string]string{"cmd/server/main.go": "abc"},
+		Hashes:   map[string]string{"old.txt": generator.ContentHash([]byte("old\n"))},
+	}); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+	for path, content := range map[string]string{
+		"cmd/server/main.go": "package main // customized\n",
+		"keep.txt":           "keep\n",
+		"old.txt":            "old\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestCompleteUpdate_AppliesTemplateMigrations(t *testing.T) {
+	setupTemplateMigrationProject(t)
+	ctx := context.Background()
+
+	prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	if prep.PreviousVersion != "1.0.0" || len(prep.TemplateMigrations) != 1 || prep.TemplateMigrations[0].Version != "2.0.0" {
+		t.Fatalf("PreviousVersion = %q, TemplateMigrations = %#v", prep.PreviousVersion, prep.TemplateMigrations)
+	}
+	if prep.ExistingVars["service_name"] != "demo" || len(prep.NewVars) != 0 {
+		t.Fatalf("ExistingVars = %#v, NewVars = %v; versioned variable step not applied", prep.ExistingVars, prep.NewVars)
+	}
+
+	preview, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: ".", DryRun: true})
+	if err != nil {
+		t.Fatalf("CompleteUpdate dry run returned error: %v", err)
+	}
+	wantMoved := []MovedFile{{From: "cmd/server/main.go", To: "cmd/api/main.go"}}
+	if !slices.Equal(preview.MovedFiles, wantMoved) {
+		t.Fatalf("preview MovedFiles = %v, want %v", preview.MovedFiles, wantMoved)
+	}
+	// Deletes need --overwrite, like update cleanup.
+	if len(preview.DeletedFiles) != 0 {
+		t.Fatalf("preview DeletedFiles = %v, want none without an overwrite", preview.DeletedFiles)
+	}
+	if _, err := os.Stat("cmd/server/main.go"); err != nil {
+		t.Fatalf("dry run moved the file: %v", err)
+	}
+
+	result, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: "."})
+	if err != nil {
+		t.Fatalf("CompleteUpdate returned error: %v", err)
+	}
+	if !slices.Equal(result.MovedFiles, wantMoved) {
+		t.Errorf("MovedFiles = %v, want %v", result.MovedFiles, wantMoved)
+	}
+	content, err := os.ReadFile("cmd/api/main.go")
+	if err != nil || string(content) != "package main // customized\n" {
+		t.Errorf("cmd/api/main.go = %q, %v; want the customized file carried over", content, err)
+	}
+	if _, err := os.Lstat("cmd/server"); !os.IsNotExist(err) {
+		t.Errorf("cmd/server should be removed, stat error = %v", err)
+	}
+	for _, kept := range []string{"keep.txt", "old.txt"} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("%s was deleted by an update without --overwrite: %v", kept, err)
+		}
+	}
+
+	manifest, err := config.LoadIgnManifest(filepath.Join(model.IgnConfigDir, model.IgnManifestFile))
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if !slices.Equal(manifest.Files, []string{"cmd/api/main.go", "keep.txt", "old.txt"}) {
+		t.Errorf("manifest files = %v", manifest.Files)
+	}
+	if len(manifest.Declined) != 0 {
+		t.Errorf("declined change of the old path should be dropped, got %v", manifest.Declined)
+	}
+	ignConfig, err := config.LoadIgnConfig(filepath.Join(model.IgnConfigDir, model.IgnProjectConfigFile))
+	if err != nil {
+		t.Fatalf("failed to load ign.json: %v", err)
+	}
+	if ignConfig.Metadata == nil || ignConfig.Metadata.TemplateVersion != "2.0.0" {
+		t.Errorf("recorded template version = %#v, want 2.0.0", ignConfig.Metadata)
+	}
+
+	// The next update starts from 2.0.0 and runs nothing again.
+	again, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("second PrepareUpdate returned error: %v", err)
+	}
+	if len(again.TemplateMigrations) != 0 {
+		t.Errorf("second update TemplateMigrations = %#v, want none", again.TemplateMigrations)
+	}
+
+	if _, err := Undo(ctx, UndoOptions{OutputDir: "."}); err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+	content, err = os.ReadFile("cmd/server/main.go")
+	if err != nil || string(content) != "package main // customized\n" {
+		t.Errorf("undo should move the file back, cmd/server/main.go = %q, %v", content, err)
+	}
+	if _, err := os.Lstat("cmd/api/main.go"); !os.IsNotExist(err) {
+		t.Errorf("undo should remove cmd/api/main.go, stat error = %v", err)
+	}
+}
+
+func TestCompleteUpdate_TemplateMigrationDeletesOnOverwrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T)
+		wantGone  bool
+		wantError string
+	}{
+		{name: "unchanged file", wantGone: true},
+		{
+			name: "changed file",
+			setup: func(t *testing.T) {
+				if err := os.WriteFile("old.txt", []byte("old\nmine\n"), 0644); err != nil {
+					t.Fatalf("failed to edit old.txt: %v", err)
+				}
+			},
+			wantError: "kept old.txt",
+		},
+		{
+			name: "protected by the template",
+			setup: func(t *testing.T) {
+				if err := os.WriteFile(filepath.Join("template", model.IgnOverwriteIgnoreFile), []byte("old.txt\n"), 0644); err != nil {
+					t.Fatalf("failed to write .ign-overwrite-ignore: %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTemplateMigrationProject(t)
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			ctx := context.Background()
+			prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+			if err != nil {
+				t.Fatalf("PrepareUpdate returned error: %v", err)
+			}
+			result, err := CompleteUpdate(ctx, CompleteUpdateOptions{
+				PrepareResult: prep,
+				OutputDir:     ".",
+				OverwriteMode: generator.OverwriteSelective,
+			})
+			if err != nil {
+				t.Fatalf("CompleteUpdate returned error: %v", err)
+			}
+			_, statErr := os.Stat("old.txt")
+			if gone := os.IsNotExist(statErr); gone != tt.wantGone {
+				t.Errorf("old.txt removed = %v, want %v", gone, tt.wantGone)
+			}
+			if gone := slices.Contains(result.DeletedFiles, "old.txt"); gone != tt.wantGone {
+				t.Errorf("DeletedFiles = %v", result.DeletedFiles)
+			}
+			reported := false
+			for _, err := range result.Errors {
+				reported = reported || (tt.wantError != "" && strings.Contains(err.Error(), tt.wantError))
+			}
+			if reported != (tt.wantError != "") {
+				t.Errorf("Errors = %v, want one containing %q", result.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCompleteUpdate_TemplateMigrationMoveKeepsExistingDestination(t *testing.T) {
+	setupTemplateMigrationProject(t)
+	if err := os.MkdirAll("cmd/api", 0755); err != nil {
+		t.Fatalf("failed to create cmd/api: %v", err)
+	}
+	if err := os.WriteFile("cmd/api/main.go", []byte("package main // mine\n"), 0644); err != nil {
+		t.Fatalf("failed to write cmd/api/main.go: %v", err)
+	}
+
+	prep, err := PrepareUpdate(context.Background(), UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{PrepareResult: prep, OutputDir: "."})
+	if err != nil {
+		t.Fatalf("CompleteUpdate returned error: %v", err)
+	}
+	if len(result.MovedFiles) != 0 {
+		t.Errorf("MovedFiles = %v, want none when the destination exists", result.MovedFiles)
+	}
+	if len(result.Errors) == 0 {
+		t.Error("the skipped move should be reported")
+	}
+	for path, want := range map[string]string{
+		"cmd/api/main.go":    "package main // mine\n",
+		"cmd/server/main.go": "package main // customized\n",
+	} {
+		content, err := os.ReadFile(path)
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v; want %q", path, content, err, want)
+		}
+	}
+}
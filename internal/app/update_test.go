@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
@@ -1542,6 +1543,86 @@ func TestCompleteUpdate_OverwriteDeletesRemovedManagedFiles(t *testing.T) {
 	}
 }
 
+func TestCompleteUpdate_OverwriteDeletesFilesExcludedByCondition(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+
+	readmePath := filepath.Join(tempDir, "README.md")
+	dockerfilePath := filepath.Join(tempDir, "Dockerfile")
+	for _, path := range []string{readmePath, dockerfilePath} {
+		if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{
+		Files: []string{readmePath, dockerfilePath},
+	}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Variables: map[string]model.VarDef{
+				"use_docker": {Type: model.VarTypeBool, Default: true},
+			},
+			Files: map[string]model.FileRule{
+				"Dockerfile": {When: "use_docker"},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "README.md", Content: []byte("readme"), Mode: 0644},
+			{Path: "Dockerfile", Content: []byte("FROM scratch"), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{"use_docker": false},
+		CurrentHash:   testHash1,
+		NewHash:       testHash1,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		NewVariables:  map[string]interface{}{"use_docker": false},
+		OutputDir:     tempDir,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.FilesDeleted != 1 {
+		t.Fatalf("FilesDeleted = %d, want 1", result.FilesDeleted)
+	}
+	if _, err := os.Stat(dockerfilePath); !os.IsNotExist(err) {
+		t.Fatalf("Dockerfile should be deleted after use_docker turned false, stat error = %v", err)
+	}
+
+	manifest, err := config.LoadIgnManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if slices.Contains(manifest.Files, dockerfilePath) {
+		t.Fatalf("manifest should not contain excluded file %s: %v", dockerfilePath, manifest.Files)
+	}
+}
+
 func TestCompleteUpdate_OverwriteDeleteReportsOutputRelativePath(t *testing.T) {
 	workspaceDir := t.TempDir()
 	t.Chdir(workspaceDir)
@@ -2175,3 +2256,524 @@ func TestCompleteUpdate_OverwriteSymlinkReplacesRegularFile(t *testing.T) {
 		t.Errorf("CLAUDE.md symlink target = %q, want %q", target, "AGENTS.md")
 	}
 }
+
+func TestCompleteUpdate_AppliesFilePolicies(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+
+	makefilePath := filepath.Join(tempDir, "Makefile")
+	localPath := filepath.Join(tempDir, "LOCAL.md")
+	seedPath := filepath.Join(tempDir, "config.yml")
+	retiredPath := filepath.Join(tempDir, "NOTES.md")
+	for _, path := range []string{makefilePath, localPath, retiredPath} {
+		if err := os.WriteFile(path, []byte("edited"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	// config.yml was seeded at checkout and later deleted by the project;
+	// NOTES.md was a user file that the template has since dropped.
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{
+		Files: []string{localPath, makefilePath, retiredPath, seedPath},
+		Policies: map[string]model.FilePolicy{
+			seedPath:    model.FilePolicySeed,
+			retiredPath: model.FilePolicyUser,
+		},
+	}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Files: map[string]model.FileRule{
+				"Makefile":   {Policy: model.FilePolicyManaged},
+				"LOCAL.md":   {Policy: model.FilePolicyUser},
+				"config.yml": {Policy: model.FilePolicySeed},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "Makefile", Content: []byte("template make"), Mode: 0644},
+			{Path: "LOCAL.md", Content: []byte("template notes"), Mode: 0644},
+			{Path: "config.yml", Content: []byte("seed"), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash1,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		OutputDir:     tempDir,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.FilesDeleted != 0 {
+		t.Fatalf("FilesDeleted = %d, want 0: %v", result.FilesDeleted, result.DeletedFiles)
+	}
+
+	for path, want := range map[string]string{makefilePath: "template make", localPath: "edited", retiredPath: "edited"} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := os.Stat(seedPath); !os.IsNotExist(err) {
+		t.Errorf("deleted seed file should not be recreated, stat error = %v", err)
+	}
+
+	manifest, err := config.LoadIgnManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	wantPolicies := map[string]model.FilePolicy{
+		makefilePath: model.FilePolicyManaged,
+		localPath:    model.FilePolicyUser,
+		seedPath:     model.FilePolicySeed,
+		retiredPath:  model.FilePolicyUser,
+	}
+	if !reflect.DeepEqual(manifest.Policies, wantPolicies) {
+		t.Errorf("manifest policies = %v, want %v", manifest.Policies, wantPolicies)
+	}
+}
+
+func TestCompleteUpdate_SyncsManagedFilesOnlyOnOverwrite(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	makefilePath := filepath.Join(tempDir, "Makefile")
+	if err := os.WriteFile(makefilePath, []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to write Makefile: %v", err)
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{Files: []string{makefilePath}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Files:   map[string]model.FileRule{"Makefile": {Policy: model.FilePolicyManaged}},
+		},
+		Files:    []model.TemplateFile{{Path: "Makefile", Content: []byte("template make"), Mode: 0644}},
+		RootPath: tempDir,
+	}
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	for _, tt := range []struct {
+		mode generator.OverwriteMode
+		want string
+	}{
+		{mode: generator.OverwriteNone, want: "edited"},
+		{mode: generator.OverwriteSelective, want: "template make"},
+	} {
+		if _, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+			PrepareResult: prep,
+			OutputDir:     tempDir,
+			OverwriteMode: tt.mode,
+		}); err != nil {
+			t.Fatalf("CompleteUpdate(%s) failed: %v", tt.mode, err)
+		}
+		if got, _ := os.ReadFile(makefilePath); string(got) != tt.want {
+			t.Errorf("after update with overwrite mode %s, Makefile = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteUpdate_MergesManagedRegionsOnOverwrite(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+
+	makefilePath := filepath.Join(tempDir, "Makefile")
+	existing := "custom:\n\techo mine\n# ign:begin ci\nci:\n\tgo test ./...\n# ign:end ci\n"
+	if err := os.WriteFile(makefilePath, []byte(existing), 0644); err != nil {
+		t.Fatalf("Failed to write Makefile: %v", err)
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{Files: []string{makefilePath}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{Name: "test", Version: "1.0.0"},
+		Files: []model.TemplateFile{
+			{Path: "Makefile", Content: []byte("# ign:begin ci\nci:\n\tgo test -race ./...\n# ign:end ci\n"), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	// A plain update does not edit existing files.
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		OutputDir:     tempDir,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if got, _ := os.ReadFile(makefilePath); result.FilesMerged != 0 || string(got) != existing {
+		t.Fatalf("update without overwrite merged %d files:\n%s", result.FilesMerged, got)
+	}
+
+	// Even a full overwrite keeps the content outside the regions.
+	result, err = CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		OutputDir:     tempDir,
+		OverwriteMode: generator.OverwriteAll,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.FilesMerged != 1 || result.FilesOverwritten != 0 {
+		t.Fatalf("merged/overwritten = %d/%d, want 1/0", result.FilesMerged, result.FilesOverwritten)
+	}
+
+	got, err := os.ReadFile(makefilePath)
+	if err != nil {
+		t.Fatalf("Failed to read Makefile: %v", err)
+	}
+	want := "custom:\n\techo mine\n# ign:begin ci\nci:\n\tgo test -race ./...\n# ign:end ci\n"
+	if string(got) != want {
+		t.Errorf("Makefile =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompleteUpdate_RecordsInjectionsInManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+
+	gitignorePath := filepath.Join(tempDir, ".gitignore")
+	if err := os.WriteFile(gitignorePath, []byte("node_modules/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+	manifestPath := filepath.Join(ignDir, model.IgnManifestFile)
+	if err := config.SaveIgnManifest(manifestPath, &model.IgnManifest{Files: []string{}}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{Name: "test", Version: "1.0.0"},
+		Files: []model.TemplateFile{
+			{Path: ".gitignore.ign-inject", Content: []byte(`{"injections": [{"id": "env", "append": true, "content": ".env\n"}]}`), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	for run := 1; run <= 2; run++ {
+		result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+			PrepareResult: prep,
+			OutputDir:     tempDir,
+		})
+		if err != nil {
+			t.Fatalf("CompleteUpdate run %d failed: %v", run, err)
+		}
+		wantApplied := 0
+		if run == 1 {
+			wantApplied = 1
+		}
+		if result.InjectionsApplied != wantApplied {
+			t.Fatalf("run %d InjectionsApplied = %d, want %d", run, result.InjectionsApplied, wantApplied)
+		}
+	}
+
+	got, err := os.ReadFile(gitignorePath)
+	if err != nil {
+		t.Fatalf("Failed to read .gitignore: %v", err)
+	}
+	if string(got) != "node_modules/\n.env\n" {
+		t.Errorf(".gitignore = %q", got)
+	}
+
+	manifest, err := config.LoadIgnManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	offset := len("node_modules/\n")
+	want := []model.InjectionRecord{{Path: gitignorePath, ID: "env", Content: ".env\n", Offset: &offset}}
+	if !reflect.DeepEqual(manifest.Injections, want) {
+		t.Errorf("manifest injections = %+v, want %+v", manifest.Injections, want)
+	}
+	if slices.Contains(manifest.Files, gitignorePath) {
+		t.Errorf("injection target must not be tracked as a generated file: %v", manifest.Files)
+	}
+}
+
+func TestCompleteUpdate_SelectiveOverwriteRespectsProjectIgnore(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ignDir, model.IgnProjectOverwriteIgnoreFile), []byte("# owned by the payments team\nlocal/\nlegacy.txt\n"), 0644); err != nil {
+		t.Fatalf("Failed to write project overwrite-ignore: %v", err)
+	}
+	files := map[string]string{
+		"README.md":        "old readme",
+		"config/app.yaml":  "old config",
+		"local/team.yaml":  "old team",
+		"legacy.txt":       "old legacy",
+		"obsolete.txt":     "old obsolete",
+		"local/extra.yaml": "untracked",
+	}
+	var manifestFiles []string
+	for path, content := range files {
+		full := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		if path != "local/extra.yaml" {
+			manifestFiles = append(manifestFiles, full)
+		}
+	}
+	if err := config.SaveIgnManifest(filepath.Join(ignDir, model.IgnManifestFile), &model.IgnManifest{Files: manifestFiles}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:      "test",
+			Version:   "1.0.0",
+			Variables: map[string]model.VarDef{},
+		},
+		Files: []model.TemplateFile{
+			{Path: model.IgnOverwriteIgnoreFile, Content: []byte("config/\n"), Mode: 0644},
+			{Path: "README.md", Content: []byte("new readme"), Mode: 0644},
+			{Path: "config/app.yaml", Content: []byte("new config"), Mode: 0644},
+			{Path: "local/team.yaml", Content: []byte("new team"), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+	opts := CompleteUpdateOptions{
+		PrepareResult: prep,
+		NewVariables:  map[string]interface{}{},
+		OutputDir:     tempDir,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+		DryRun:        true,
+	}
+
+	preview, err := CompleteUpdate(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("CompleteUpdate dry run failed: %v", err)
+	}
+	protected := map[string]bool{}
+	for _, file := range preview.DryRunFiles {
+		if file.WouldSkip {
+			protected[filepath.Base(file.Path)] = file.ProtectedByProject
+		}
+	}
+	if want := map[string]bool{"app.yaml": false, "team.yaml": true}; !reflect.DeepEqual(protected, want) {
+		t.Fatalf("skipped files (protected by project) = %v, want %v", protected, want)
+	}
+	if want := []string{filepath.Join(tempDir, "obsolete.txt")}; !slices.Equal(preview.DeletedFiles, want) {
+		t.Fatalf("DeletedFiles = %v, want %v", preview.DeletedFiles, want)
+	}
+
+	opts.DryRun = false
+	if _, err := CompleteUpdate(context.Background(), opts); err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	for path, want := range map[string]string{
+		"README.md":       "new readme",
+		"config/app.yaml": "old config",
+		"local/team.yaml": "old team",
+		"legacy.txt":      "old legacy",
+	} {
+		content, err := os.ReadFile(filepath.Join(tempDir, path))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v; want %q", path, content, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "obsolete.txt")); !os.IsNotExist(err) {
+		t.Errorf("obsolete.txt should be removed, stat error = %v", err)
+	}
+}
+
+func TestCompleteUpdate_ProjectIgnoreProtectsInPlaceEdits(t *testing.T) {
+	tempDir := t.TempDir()
+	ignDir := filepath.Join(tempDir, ".ign")
+	if err := os.MkdirAll(ignDir, 0755); err != nil {
+		t.Fatalf("Failed to create .ign directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ignDir, model.IgnProjectOverwriteIgnoreFile), []byte("local/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write project overwrite-ignore: %v", err)
+	}
+	files := map[string]string{
+		"local/Makefile":    "mine\n# ign:begin ci\nold\n# ign:end ci\n",
+		"local/config.json": "{\"mine\": true}\n",
+		"local/routes.go":   "// routes\n",
+	}
+	var manifestFiles []string
+	for path, content := range files {
+		full := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		if path != "local/routes.go" {
+			manifestFiles = append(manifestFiles, full)
+		}
+	}
+	if err := config.SaveIgnManifest(filepath.Join(ignDir, model.IgnManifestFile), &model.IgnManifest{Files: manifestFiles}); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Files:   map[string]model.FileRule{"local/config.json": {Strategy: model.FileStrategyMerge}},
+		},
+		Files: []model.TemplateFile{
+			{Path: "local/Makefile", Content: []byte("# ign:begin ci\nnew\n# ign:end ci\n"), Mode: 0644},
+			{Path: "local/config.json", Content: []byte("{\"template\": true}\n"), Mode: 0644},
+			{Path: "local/routes.go.ign-inject", Content: []byte(`{"injections": [{"id": "route", "after": "// routes", "content": "register()"}]}`), Mode: 0644},
+		},
+		RootPath: tempDir,
+	}
+	prep := &PrepareUpdateResult{
+		Template:      template,
+		IgnJson:       &template.Config,
+		ExistingVars:  map[string]interface{}{},
+		CurrentHash:   testHash1,
+		NewHash:       testHash2,
+		HashChanged:   true,
+		IgnConfigPath: filepath.Join(ignDir, model.IgnProjectConfigFile),
+		IgnVarPath:    filepath.Join(ignDir, model.IgnVarFile),
+		IgnConfig: &model.IgnConfig{
+			Template: model.TemplateSource{URL: "https://github.com/test/template"},
+			Hash:     testHash1,
+		},
+	}
+
+	result, err := CompleteUpdate(context.Background(), CompleteUpdateOptions{
+		PrepareResult: prep,
+		OutputDir:     tempDir,
+		OverwriteMode: generator.OverwriteSelective,
+	})
+	if err != nil {
+		t.Fatalf("CompleteUpdate failed: %v", err)
+	}
+	if result.FilesMerged != 0 || len(result.Errors) != 0 {
+		t.Errorf("merged = %d, errors = %v; want neither", result.FilesMerged, result.Errors)
+	}
+	for path, want := range files {
+		content, err := os.ReadFile(filepath.Join(tempDir, path))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v; want it untouched", path, content, err)
+		}
+	}
+}
+
+func TestCompleteUpdate_RejectsConfigChangedAfterPrepare(t *testing.T) {
+	setupRenamedFileProject(t)
+	ctx := context.Background()
+
+	prep, err := PrepareUpdate(ctx, UpdateOptions{OutputDir: "."})
+	if err != nil {
+		t.Fatalf("PrepareUpdate returned error: %v", err)
+	}
+	// Another command, e.g. 'ign vars set', saves in between.
+	if err := config.SaveIgnVarJson(prep.IgnVarPath, &model.IgnVarJson{Variables: map[string]interface{}{"changed": true}}); err != nil {
+		t.Fatalf("failed to save ign-var.json: %v", err)
+	}
+
+	if _, err := CompleteUpdate(ctx, CompleteUpdateOptions{PrepareResult: prep, OutputDir: "."}); err == nil || !strings.Contains(err.Error(), "run the command again") {
+		t.Fatalf("CompleteUpdate error = %v, want it to refuse stale configuration", err)
+	}
+	saved, err := config.LoadIgnVarJson(prep.IgnVarPath)
+	if err != nil || saved.Variables["changed"] != true {
+		t.Errorf("ign-var.json = %#v, %v; want the other command's values kept", saved, err)
+	}
+}
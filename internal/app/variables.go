@@ -2,8 +2,11 @@ package app
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/tacogips/ign/internal/debug"
@@ -283,8 +286,18 @@ func ValidateVariables(ignJson *model.IgnJson, vars parser.Variables) error {
 	debug.DebugValue("[app] Number of variable definitions", len(ignJson.Variables))
 
 	var missingVars []string
+	var invalidVars []string
 
 	for name, varDef := range ignJson.Variables {
+		if varDef.Type == model.VarTypeInt || varDef.Type == model.VarTypeNumber {
+			if value, exists := vars.Get(name); exists {
+				if _, err := NumericVariableValue(varDef, value); err != nil {
+					debug.Debug("[app] Variable '%s': %v", name, err)
+					invalidVars = append(invalidVars, fmt.Sprintf("variable %s %v", name, err))
+				}
+			}
+		}
+
 		// Skip if not required
 		if !varDef.Required {
 			debug.Debug("[app] Variable '%s': not required, skipping", name)
@@ -314,6 +327,15 @@ func ValidateVariables(ignJson *model.IgnJson, vars parser.Variables) error {
 		}
 	}
 
+	if len(invalidVars) > 0 {
+		sort.Strings(invalidVars)
+		debug.Debug("[app] ValidateVariables: validation failed, %d invalid variables", len(invalidVars))
+		return NewValidationError(
+			fmt.Sprintf("invalid variable values: %s", strings.Join(invalidVars, "; ")),
+			nil,
+		)
+	}
+
 	if len(missingVars) > 0 {
 		debug.Debug("[app] ValidateVariables: validation failed, missing %d variables", len(missingVars))
 		return NewValidationError(
@@ -325,3 +347,41 @@ func ValidateVariables(ignJson *model.IgnJson, vars parser.Variables) error {
 	debug.Debug("[app] ValidateVariables: all required variables validated successfully")
 	return nil
 }
+
+// NumericVariableValue checks a value of an int or number variable against
+// its declaration and returns it as a float64. Strings are parsed, as --var
+// values and ign-var.json entries are when the template is rendered, so
+// "8080" is a valid int. Int strings go through strconv.Atoi like the
+// renderer's coercion, so "8080.0" and "1e3" are not. Errors describe the
+// value without naming the variable.
+func NumericVariableValue(varDef model.VarDef, value interface{}) (float64, error) {
+	num, ok := numericVarValue(value)
+	if str, isString := value.(string); isString {
+		if varDef.Type == model.VarTypeInt {
+			parsed, err := strconv.Atoi(str)
+			num, ok = float64(parsed), err == nil
+		} else {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+			num, ok = parsed, err == nil && !math.IsNaN(parsed) && !math.IsInf(parsed, 0)
+		}
+	}
+	switch varDef.Type {
+	case model.VarTypeInt:
+		if !ok || num != math.Trunc(num) {
+			return 0, fmt.Errorf("must be an integer (got %s)", describeVarValue(value))
+		}
+	case model.VarTypeNumber:
+		if !ok {
+			return 0, fmt.Errorf("must be a number (got %s)", describeVarValue(value))
+		}
+	default:
+		return 0, fmt.Errorf("is a %s variable, not a number", varDef.Type)
+	}
+	if varDef.Min != nil && num < *varDef.Min {
+		return 0, fmt.Errorf("must be >= %v (got %v)", *varDef.Min, num)
+	}
+	if varDef.Max != nil && num > *varDef.Max {
+		return 0, fmt.Errorf("must be <= %v (got %v)", *varDef.Max, num)
+	}
+	return num, nil
+}
@@ -0,0 +1,313 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// VarsEditOptions contains options for modifying project template variables.
+type VarsEditOptions struct {
+	// GitHubToken is the GitHub personal access token (optional).
+	GitHubToken string
+}
+
+// VarsEditSession holds the template declarations and current values needed to
+// change .ign/ign-var.json. Declarations are mandatory: unlike 'ign vars',
+// modifications are never written without validating them first.
+type VarsEditSession struct {
+	// VarDefs are the variable declarations of the tracked template.
+	VarDefs map[string]model.VarDef
+	// Current contains the values currently stored in ign-var.json.
+	Current map[string]interface{}
+	// IgnVarPath is the path of the ign-var.json file being edited.
+	IgnVarPath string
+
+	ignConfigPath string
+	// readState fingerprints ign.json and ign-var.json before they were read.
+	readState string
+}
+
+// PrepareVarsEdit loads ign-var.json and fetches the tracked template's variable
+// declarations so that modifications can be validated before they are saved.
+func PrepareVarsEdit(ctx context.Context, opts VarsEditOptions) (*VarsEditSession, error) {
+	debug.DebugSection("[app] PrepareVarsEdit workflow start")
+
+	configDir := model.IgnConfigDir
+	ignConfigPath := filepath.Join(configDir, model.IgnProjectConfigFile)
+	ignVarPath := filepath.Join(configDir, model.IgnVarFile)
+
+	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+		return nil, NewValidationError(
+			"vars requires prior checkout: .ign directory not found.\n"+
+				"Run 'ign checkout <template-url>' first.",
+			nil,
+		)
+	}
+
+	// Taken before reading, so Save can tell under the project lock whether
+	// the values being edited are still current.
+	readState := projectStateFingerprint(ignConfigPath, ignVarPath)
+
+	ignConfig, err := config.LoadIgnConfig(ignConfigPath)
+	if err != nil {
+		return nil, NewCheckoutError(
+			"failed to load .ign/ign.json: run 'ign checkout <template-url>' first",
+			err,
+		)
+	}
+
+	ignVar, err := config.LoadIgnVarJson(ignVarPath)
+	if err != nil {
+		return nil, NewCheckoutError(
+			"failed to load .ign/ign-var.json: run 'ign checkout <template-url>' first",
+			err,
+		)
+	}
+	current := ignVar.Variables
+	if current == nil {
+		current = map[string]interface{}{}
+	}
+
+	fetched, err := fetchTrackedTemplate(ctx, trackedTemplateFetchOptions{
+		Source:      ignConfig.Template,
+		GitHubToken: opts.GitHubToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	varDefs := fetched.Template.Config.Variables
+	if varDefs == nil {
+		varDefs = map[string]model.VarDef{}
+	}
+	debug.DebugValue("[app] Declared variables", len(varDefs))
+	debug.DebugValue("[app] Current variables", len(current))
+
+	return &VarsEditSession{
+		VarDefs:       varDefs,
+		Current:       current,
+		IgnVarPath:    ignVarPath,
+		ignConfigPath: ignConfigPath,
+		readState:     readState,
+	}, nil
+}
+
+// Set returns a copy of the current values with the given assignments applied.
+// Assignments must name declared variables.
+func (s *VarsEditSession) Set(assignments map[string]interface{}) (map[string]interface{}, error) {
+	var unknown []string
+	for name := range assignments {
+		if _, ok := s.VarDefs[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, NewValidationError(
+			fmt.Sprintf("unknown template variables: %s", strings.Join(unknown, ", ")),
+			nil,
+		)
+	}
+
+	values := s.copyCurrent()
+	for name, value := range assignments {
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Unset returns a copy of the current values with the given names removed.
+// Required variables cannot be unset.
+func (s *VarsEditSession) Unset(names []string) (map[string]interface{}, error) {
+	var required []string
+	var missing []string
+	for _, name := range names {
+		if varDef, ok := s.VarDefs[name]; ok && varDef.Required {
+			required = append(required, name)
+			continue
+		}
+		if _, ok := s.Current[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		return nil, NewValidationError(
+			fmt.Sprintf("cannot unset required variables: %s", strings.Join(required, ", ")),
+			nil,
+		)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, NewValidationError(
+			fmt.Sprintf("variables are not set: %s", strings.Join(missing, ", ")),
+			nil,
+		)
+	}
+
+	values := s.copyCurrent()
+	for _, name := range names {
+		delete(values, name)
+	}
+	return values, nil
+}
+
+// Save validates values against the template declarations and atomically
+// replaces ign-var.json. Values that were already stored but are no longer
+// declared are kept as-is; newly introduced names must be declared. Save
+// fails when another command changed the files since the session read them.
+func (s *VarsEditSession) Save(ctx context.Context, values map[string]interface{}) error {
+	if err := s.validate(values); err != nil {
+		return err
+	}
+	lock, err := LockProject(ctx, filepath.Dir(s.IgnVarPath), "vars")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if err := checkProjectStateUnchanged(s.readState, s.ignConfigPath, s.IgnVarPath); err != nil {
+		return err
+	}
+
+	if err := config.SaveIgnVarJson(s.IgnVarPath, &model.IgnVarJson{Variables: values}); err != nil {
+		return NewVariableLoadError("failed to save .ign/ign-var.json", err)
+	}
+	debug.Debug("[app] Saved %d variables to %s", len(values), s.IgnVarPath)
+	s.Current = values
+	if s.readState != "" {
+		s.readState = projectStateFingerprint(s.ignConfigPath, s.IgnVarPath)
+	}
+	return nil
+}
+
+func (s *VarsEditSession) validate(values map[string]interface{}) error {
+	problems := varValueProblems(s.VarDefs, values)
+	for name := range values {
+		if _, declared := s.VarDefs[name]; declared {
+			continue
+		}
+		if _, existed := s.Current[name]; !existed {
+			problems = append(problems, fmt.Sprintf("%s: not declared by the template", name))
+		}
+	}
+	return varValueProblemsError(problems)
+}
+
+func (s *VarsEditSession) copyCurrent() map[string]interface{} {
+	values := make(map[string]interface{}, len(s.Current))
+	for name, value := range s.Current {
+		values[name] = value
+	}
+	return values
+}
+
+// ValidateVarValues checks stored variable values against their declarations:
+// every required variable must be present (and non-empty for strings), and each
+// present value must match the declared type, pattern, and min/max range.
+// All problems are reported together, one per line.
+func ValidateVarValues(varDefs map[string]model.VarDef, values map[string]interface{}) error {
+	return varValueProblemsError(varValueProblems(varDefs, values))
+}
+
+func varValueProblems(varDefs map[string]model.VarDef, values map[string]interface{}) []string {
+	var problems []string
+	for name, varDef := range varDefs {
+		value, ok := values[name]
+		if !ok {
+			// Defaults fill required variables at generation time.
+			if varDef.Required && varDef.Default == nil {
+				problems = append(problems, fmt.Sprintf("%s: required variable is not set", name))
+			}
+			continue
+		}
+		if err := validateVarValue(varDef, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+func varValueProblemsError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return NewValidationError(
+		"invalid variable values:\n  "+strings.Join(problems, "\n  "),
+		nil,
+	)
+}
+
+// validateVarValue checks a single stored value against its declaration.
+// Values loaded from JSON carry float64 for every number.
+func validateVarValue(varDef model.VarDef, value interface{}) error {
+	switch varDef.Type {
+	case model.VarTypeString, "":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %s", describeVarValue(value))
+		}
+		if varDef.Required && strings.TrimSpace(str) == "" {
+			return fmt.Errorf("required variable is empty")
+		}
+		// @file: references are resolved at generation time; the pattern
+		// applies to the file content, not to the reference itself.
+		if varDef.Pattern != "" && !strings.HasPrefix(str, "@file:") {
+			matched, err := regexp.MatchString(varDef.Pattern, str)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", varDef.Pattern, err)
+			}
+			if !matched {
+				return fmt.Errorf("value %q does not match pattern %s", str, varDef.Pattern)
+			}
+		}
+	case model.VarTypeInt, model.VarTypeNumber:
+		_, err := NumericVariableValue(varDef, value)
+		return err
+	case model.VarTypeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected bool, got %s", describeVarValue(value))
+		}
+	}
+	return nil
+}
+
+func numericVarValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func describeVarValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case float64:
+		return fmt.Sprintf("number %v", v)
+	default:
+		return fmt.Sprintf("%T %v", value, value)
+	}
+}
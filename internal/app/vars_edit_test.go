@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func TestVarsEditSession_SetValidatesAndSaves(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	maxPort := 65535.0
+	templateDir := writeVarsTemplate(t, tempDir, map[string]model.VarDef{
+		"project_name": {Type: model.VarTypeString, Description: "Project name", Required: true},
+		"port":         {Type: model.VarTypeInt, Description: "Port", Max: &maxPort},
+		"ratio":        {Type: model.VarTypeNumber, Description: "Ratio"},
+	})
+	writeProjectConfig(t, templateDir, "main", map[string]interface{}{
+		"project_name": "demo",
+		"port":         8080,
+	})
+
+	session, err := PrepareVarsEdit(context.Background(), VarsEditOptions{})
+	if err != nil {
+		t.Fatalf("PrepareVarsEdit returned error: %v", err)
+	}
+
+	values, err := session.Set(map[string]interface{}{"port": 9090, "ratio": 0.5})
+	if err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := session.Save(context.Background(), values); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	saved, err := config.LoadIgnVarJson(filepath.Join(model.IgnConfigDir, model.IgnVarFile))
+	if err != nil {
+		t.Fatalf("failed to reload ign-var.json: %v", err)
+	}
+	if saved.Variables["port"] != float64(9090) || saved.Variables["ratio"] != 0.5 || saved.Variables["project_name"] != "demo" {
+		t.Fatalf("saved variables = %#v", saved.Variables)
+	}
+
+	if _, err := session.Set(map[string]interface{}{"missing": "x"}); err == nil {
+		t.Fatal("Set accepted an undeclared variable")
+	}
+
+	values, err = session.Set(map[string]interface{}{"port": 70000})
+	if err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := session.Save(context.Background(), values); err == nil || !strings.Contains(err.Error(), "must be <= 65535") {
+		t.Fatalf("Save error = %v, want max violation", err)
+	}
+}
+
+func TestVarsEditSession_UnsetRejectsRequired(t *testing.T) {
+	session := &VarsEditSession{
+		VarDefs: map[string]model.VarDef{
+			"project_name": {Type: model.VarTypeString, Required: true},
+			"port":         {Type: model.VarTypeInt},
+		},
+		Current: map[string]interface{}{"project_name": "demo", "port": float64(8080)},
+	}
+
+	if _, err := session.Unset([]string{"project_name"}); err == nil || !strings.Contains(err.Error(), "cannot unset required") {
+		t.Fatalf("Unset error = %v, want required variable error", err)
+	}
+	if _, err := session.Unset([]string{"ratio"}); err == nil {
+		t.Fatal("Unset accepted a variable that is not set")
+	}
+
+	values, err := session.Unset([]string{"port"})
+	if err != nil {
+		t.Fatalf("Unset returned error: %v", err)
+	}
+	if _, ok := values["port"]; ok {
+		t.Fatalf("port still present after unset: %#v", values)
+	}
+	if _, ok := session.Current["port"]; !ok {
+		t.Fatal("Unset modified the session's current values")
+	}
+}
+
+func TestValidateVarValues(t *testing.T) {
+	minRatio := 0.0
+	varDefs := map[string]model.VarDef{
+		"name":    {Type: model.VarTypeString, Required: true, Pattern: `^[a-z]+$`},
+		"port":    {Type: model.VarTypeInt},
+		"ratio":   {Type: model.VarTypeNumber, Min: &minRatio},
+		"enabled": {Type: model.VarTypeBool},
+		"region":  {Type: model.VarTypeString, Required: true, Default: "us"},
+	}
+
+	if err := ValidateVarValues(varDefs, map[string]interface{}{
+		"name":    "demo",
+		"port":    float64(8080),
+		"ratio":   0.25,
+		"enabled": true,
+	}); err != nil {
+		t.Fatalf("ValidateVarValues returned error for valid values: %v", err)
+	}
+
+	err := ValidateVarValues(varDefs, map[string]interface{}{
+		"port":    80.5,
+		"ratio":   -1.0,
+		"enabled": "yes",
+	})
+	if err == nil {
+		t.Fatal("ValidateVarValues expected error")
+	}
+	// Every problem is reported at once.
+	for _, want := range []string{
+		"name: required variable is not set",
+		"port: must be an integer",
+		"ratio: must be >= 0",
+		"enabled: expected bool",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err.Error(), want)
+		}
+	}
+	if strings.Contains(err.Error(), "region") {
+		t.Errorf("required variable with default reported as missing: %v", err)
+	}
+
+	if err := ValidateVarValues(varDefs, map[string]interface{}{"name": "@file:name.txt"}); err != nil {
+		t.Fatalf("@file: reference should not be matched against pattern: %v", err)
+	}
+}
+
+func TestVarsEditSession_SaveRejectsConcurrentChange(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	templateDir := writeVarsTemplate(t, tempDir, map[string]model.VarDef{
+		"project_name": {Type: model.VarTypeString, Description: "Project name"},
+		"port":         {Type: model.VarTypeInt, Description: "Port"},
+	})
+	writeProjectConfig(t, templateDir, "main", map[string]interface{}{"project_name": "demo", "port": 8080})
+
+	first, err := PrepareVarsEdit(context.Background(), VarsEditOptions{})
+	if err != nil {
+		t.Fatalf("PrepareVarsEdit returned error: %v", err)
+	}
+	second, err := PrepareVarsEdit(context.Background(), VarsEditOptions{})
+	if err != nil {
+		t.Fatalf("PrepareVarsEdit returned error: %v", err)
+	}
+
+	values, _ := first.Set(map[string]interface{}{"port": 9090})
+	if err := first.Save(context.Background(), values); err != nil {
+		t.Fatalf("first Save returned error: %v", err)
+	}
+	values, _ = second.Set(map[string]interface{}{"project_name": "other"})
+	if err := second.Save(context.Background(), values); err == nil || !strings.Contains(err.Error(), "run the command again") {
+		t.Fatalf("second Save error = %v, want it to refuse overwriting the first", err)
+	}
+
+	saved, err := config.LoadIgnVarJson(filepath.Join(model.IgnConfigDir, model.IgnVarFile))
+	if err != nil {
+		t.Fatalf("failed to reload ign-var.json: %v", err)
+	}
+	if saved.Variables["port"] != float64(9090) || saved.Variables["project_name"] != "demo" {
+		t.Fatalf("saved variables = %#v, want the first save kept", saved.Variables)
+	}
+
+	// A session keeps working after its own save.
+	values, _ = first.Set(map[string]interface{}{"port": 7070})
+	if err := first.Save(context.Background(), values); err != nil {
+		t.Fatalf("second Save of the same session returned error: %v", err)
+	}
+}
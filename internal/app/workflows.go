@@ -89,6 +89,8 @@ func CreateVariablesMap(ignJson *model.IgnJson, providedVars map[string]interfac
 			vars[name] = ""
 		case model.VarTypeInt:
 			vars[name] = 0
+		case model.VarTypeNumber:
+			vars[name] = float64(0)
 		case model.VarTypeBool:
 			vars[name] = false
 		default:
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// variableInputs holds the non-interactive variable sources of checkout, init, and switch.
+type variableInputs struct {
+	// Assignments are the raw --var key=value flags. They override answers.
+	Assignments []string
+	// AnswersFile is the --answers file. When set, prompting is disabled.
+	AnswersFile string
+	// RecordAnswersFile is the --record-answers file written after collection.
+	RecordAnswersFile string
+}
+
+// collectTemplateVariables gathers variable values from --answers, --var, and
+// interactive prompts. Every invalid or missing value is reported in a single
+// error before anything is prompted or written.
+//
+// With an answers file the run is fully non-interactive: unanswered variables
+// take their (already resolved) default, and variables without a default are
+// reported as missing. The returned values then include those defaults so that
+// a recorded answer set reproduces the run exactly.
+func collectTemplateVariables(ignJson *model.IgnJson, inputs variableInputs) (map[string]interface{}, error) {
+	var varDefs map[string]model.VarDef
+	if ignJson != nil {
+		varDefs = ignJson.Variables
+	}
+
+	var problems []string
+	vars := map[string]interface{}{}
+
+	if inputs.AnswersFile != "" {
+		answers, answerProblems, err := loadAnswers(inputs.AnswersFile, varDefs)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, answerProblems...)
+		for name, value := range answers {
+			vars[name] = value
+		}
+	}
+
+	for _, assignment := range inputs.Assignments {
+		parsed, err := ParseVariableAssignments([]string{assignment}, varDefs)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		for name, value := range parsed {
+			vars[name] = value
+		}
+	}
+
+	var missing []string
+	for _, name := range sortedVarDefNames(varDefs) {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		if inputs.AnswersFile != "" {
+			if def := varDefs[name].Default; def != nil {
+				vars[name] = def
+				continue
+			}
+		}
+		missing = append(missing, name)
+	}
+
+	interactive := inputs.AnswersFile == "" && promptInputIsTerminal()
+	switch {
+	case len(problems) > 0 || (len(missing) > 0 && inputs.AnswersFile != ""):
+		return nil, newVariableInputError(problems, missing, interactive)
+	case len(missing) > 0 && !interactive:
+		return nil, newNonInteractivePromptError(missing)
+	}
+
+	vars, err := PromptForVariablesWithProvided(ignJson, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if inputs.RecordAnswersFile != "" {
+		if err := recordAnswers(inputs.RecordAnswersFile, vars); err != nil {
+			return nil, err
+		}
+		printInfo(fmt.Sprintf("Answers recorded to: %s", inputs.RecordAnswersFile))
+	}
+
+	return vars, nil
+}
+
+// newVariableInputError lists every invalid and missing variable value.
+func newVariableInputError(problems []string, missing []string, interactive bool) error {
+	lines := append([]string(nil), problems...)
+	sort.Strings(lines)
+	for _, name := range missing {
+		lines = append(lines, fmt.Sprintf("variable %q has no answer and no default", name))
+	}
+
+	msg := "template variables are missing or invalid:\n  - " + strings.Join(lines, "\n  - ")
+	if !interactive && len(missing) > 0 {
+		msg += "\nsupply the missing values with --answers FILE or repeatable --var key=value"
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// loadAnswers reads an answers file, which uses the ign-var.json layout
+// ({"variables": {...}}). Values are checked against the variable definitions;
+// problems are returned rather than failing on the first one.
+func loadAnswers(path string, varDefs map[string]model.VarDef) (map[string]interface{}, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read answers file %s: %w", path, err)
+	}
+
+	var answers model.IgnVarJson
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON in answers file %s: %w", path, err)
+	}
+
+	values := make(map[string]interface{}, len(answers.Variables))
+	var problems []string
+	for name, raw := range answers.Variables {
+		varDef, ok := varDefs[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown template variable %q in %s", name, path))
+			continue
+		}
+		value, err := answerValue(name, raw, varDef)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		values[name] = value
+	}
+	return values, problems, nil
+}
+
+// answerValue converts a JSON-decoded answer to the declared variable type.
+// String answers are parsed like --var values, so "8080" is accepted for an int.
+func answerValue(name string, raw interface{}, varDef model.VarDef) (interface{}, error) {
+	if str, ok := raw.(string); ok {
+		return parseVariableValue(name, str, varDef)
+	}
+
+	switch varDef.Type {
+	case model.VarTypeInt:
+		num, err := app.NumericVariableValue(varDef, raw)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q %w", name, err)
+		}
+		return int(num), nil
+	case model.VarTypeNumber:
+		num, err := app.NumericVariableValue(varDef, raw)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q %w", name, err)
+		}
+		return num, nil
+	case model.VarTypeBool:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("variable %q must be a boolean, got %v", name, raw)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("variable %q must be a string, got %v", name, raw)
+	}
+}
+
+// recordAnswers writes the collected values in the answers file layout.
+func recordAnswers(path string, vars map[string]interface{}) error {
+	data, err := json.MarshalIndent(&model.IgnVarJson{Variables: vars}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode answers: %w", err)
+	}
+	if err := config.WriteFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to record answers to %s: %w", path, err)
+	}
+	return nil
+}
+
+func sortedVarDefNames(varDefs map[string]model.VarDef) []string {
+	names := make([]string, 0, len(varDefs))
+	for name := range varDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func answersTestIgnJson() *model.IgnJson {
+	minPort := 1.0
+	return &model.IgnJson{
+		Name:    "answers",
+		Version: "1.0.0",
+		Variables: map[string]model.VarDef{
+			"name":  {Type: model.VarTypeString, Required: true, Pattern: "^[a-z-]+$"},
+			"port":  {Type: model.VarTypeInt, Default: 8080, Min: &minPort},
+			"ratio": {Type: model.VarTypeNumber},
+			"debug": {Type: model.VarTypeBool, Default: false},
+		},
+	}
+}
+
+func writeAnswersFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "answers.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write answers file: %v", err)
+	}
+	return path
+}
+
+func TestCollectTemplateVariables_AnswersFile(t *testing.T) {
+	origPromptInputIsTerminal := promptInputIsTerminal
+	defer func() { promptInputIsTerminal = origPromptInputIsTerminal }()
+	// Answers never prompt, even on a terminal.
+	promptInputIsTerminal = func() bool { return true }
+
+	answersPath := writeAnswersFile(t, `{"variables": {"name": "my-app", "port": 9090, "ratio": "0.5"}}`)
+	recordPath := filepath.Join(t.TempDir(), "recorded.json")
+
+	vars, err := collectTemplateVariables(answersTestIgnJson(), variableInputs{
+		Assignments:       []string{"debug=true"},
+		AnswersFile:       answersPath,
+		RecordAnswersFile: recordPath,
+	})
+	if err != nil {
+		t.Fatalf("collectTemplateVariables() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"name":  "my-app",
+		"port":  9090,
+		"ratio": 0.5,
+		"debug": true,
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Fatalf("vars = %#v, want %#v", vars, want)
+	}
+
+	data, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatalf("failed to read recorded answers: %v", err)
+	}
+	var recorded model.IgnVarJson
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatalf("recorded answers are not valid JSON: %v", err)
+	}
+	if len(recorded.Variables) != 4 || recorded.Variables["port"] != float64(9090) {
+		t.Fatalf("recorded answers = %v", recorded.Variables)
+	}
+}
+
+func TestCollectTemplateVariables_UsesDefaultsForUnanswered(t *testing.T) {
+	answersPath := writeAnswersFile(t, `{"variables": {"name": "my-app", "ratio": 1.5}}`)
+
+	vars, err := collectTemplateVariables(answersTestIgnJson(), variableInputs{AnswersFile: answersPath})
+	if err != nil {
+		t.Fatalf("collectTemplateVariables() error = %v", err)
+	}
+	if vars["port"] != 8080 || vars["debug"] != false {
+		t.Fatalf("defaults not applied: %v", vars)
+	}
+}
+
+func TestCollectTemplateVariables_ReportsAllProblems(t *testing.T) {
+	origPromptInputIsTerminal := promptInputIsTerminal
+	defer func() { promptInputIsTerminal = origPromptInputIsTerminal }()
+	promptInputIsTerminal = func() bool { return false }
+
+	answersPath := writeAnswersFile(t, `{"variables": {"name": "Bad Name", "port": 1.5, "extra": 1}}`)
+
+	_, err := collectTemplateVariables(answersTestIgnJson(), variableInputs{
+		Assignments: []string{"debug=maybe", "unknown=1"},
+		AnswersFile: answersPath,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`variable "name" must match pattern`,
+		`variable "port" must be an integer`,
+		`unknown template variable "extra"`,
+		`variable "debug" must be a boolean`,
+		`unknown template variable "unknown"`,
+		`variable "ratio" has no answer and no default`,
+		"--answers FILE",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestCollectTemplateVariables_InvalidVarFlagsReportedTogether(t *testing.T) {
+	origPromptInputIsTerminal := promptInputIsTerminal
+	defer func() { promptInputIsTerminal = origPromptInputIsTerminal }()
+	promptInputIsTerminal = func() bool { return true }
+
+	_, err := collectTemplateVariables(answersTestIgnJson(), variableInputs{
+		Assignments: []string{"port=abc", "ratio=x"},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{`variable "port" must be an integer`, `variable "ratio" must be a number`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRunCheckoutReplaysRecordedAnswers(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	templateDir := writeTemplateWithRequiredVariable(t, tempDir, "template")
+	answersPath := filepath.Join(tempDir, "answers.json")
+
+	origRef := checkoutRef
+	origVars := checkoutVars
+	origAnswers := checkoutAnswers
+	origRecordAnswers := checkoutRecordAnswers
+	origDryRun := checkoutDryRun
+	origPromptInputIsTerminal := promptInputIsTerminal
+	defer func() {
+		checkoutRef = origRef
+		checkoutVars = origVars
+		checkoutAnswers = origAnswers
+		checkoutRecordAnswers = origRecordAnswers
+		checkoutDryRun = origDryRun
+		promptInputIsTerminal = origPromptInputIsTerminal
+	}()
+
+	checkoutRef = "main"
+	checkoutDryRun = true
+	promptInputIsTerminal = func() bool { return false }
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+
+	// Record answers from a first run.
+	checkoutVars = []string{"project_name=my-app"}
+	checkoutRecordAnswers = answersPath
+	if err := runCheckout(cmd, []string{templateDir, "first"}); err != nil {
+		t.Fatalf("recording runCheckout returned error: %v", err)
+	}
+
+	// Replay them in a second, real run.
+	checkoutVars = nil
+	checkoutRecordAnswers = ""
+	checkoutAnswers = answersPath
+	checkoutDryRun = false
+	if err := runCheckout(cmd, []string{templateDir, "."}); err != nil {
+		t.Fatalf("replaying runCheckout returned error: %v", err)
+	}
+
+	generated, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("failed to read generated output: %v", err)
+	}
+	if string(generated) != "my-app" {
+		t.Fatalf("README.md = %q, want replayed answer", generated)
+	}
+}
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tacogips/ign/internal/app"
@@ -36,20 +35,34 @@ Examples:
   ign checkout github.com/owner/repo ./my-project
   ign checkout github.com/owner/repo --ref v1.2.0
   ign checkout github.com/owner/repo --var project_name=my-app --var port=8080
+  ign checkout github.com/owner/repo --answers answers.json
+  ign checkout github.com/owner/repo --record-answers answers.json
   ign checkout ./my-local-template ./output
   ign checkout github.com/owner/repo --force
-  ign checkout github.com/owner/repo --dry-run`,
+  ign checkout github.com/owner/repo --dry-run
+  ign checkout github.com/owner/repo --allow-hooks
+  ign checkout github.com/owner/repo my-app -o my-app.zip
+  ign checkout github.com/owner/repo my-app --output-format tar -o - > my-app.tar
+
+With --output-format tar or zip, nothing is written to disk: the project is
+stored under [output-path] inside the archive, together with its .ign
+configuration, and written to the -o file or stdout. Hooks are not run.`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: runCheckout,
 }
 
 // Checkout command flags
 var (
-	checkoutRef     string
-	checkoutForce   bool
-	checkoutDryRun  bool
-	checkoutVerbose bool
-	checkoutVars    []string
+	checkoutRef           string
+	checkoutForce         bool
+	checkoutDryRun        bool
+	checkoutVerbose       bool
+	checkoutVars          []string
+	checkoutAnswers       string
+	checkoutRecordAnswers string
+	checkoutAllowHooks    bool
+	checkoutOutputFormat  string
+	checkoutOutput        string
 )
 
 func init() {
@@ -59,6 +72,11 @@ func init() {
 	checkoutCmd.Flags().BoolVarP(&checkoutDryRun, "dry-run", "d", false, "Show what would be generated without writing files")
 	checkoutCmd.Flags().BoolVarP(&checkoutVerbose, "verbose", "v", false, "Show detailed processing information")
 	checkoutCmd.Flags().StringArrayVarP(&checkoutVars, FlagVar, "V", nil, DescVar)
+	checkoutCmd.Flags().StringVar(&checkoutAnswers, FlagAnswers, "", DescAnswers)
+	checkoutCmd.Flags().StringVar(&checkoutRecordAnswers, FlagRecordAnswers, "", DescRecordAnswers)
+	checkoutCmd.Flags().BoolVar(&checkoutAllowHooks, FlagAllowHooks, false, DescAllowHooks)
+	checkoutCmd.Flags().StringVar(&checkoutOutputFormat, FlagOutputFormat, outputFormatDir, DescOutputFormat)
+	checkoutCmd.Flags().StringVarP(&checkoutOutput, FlagOutput, "o", "", "Write a tar or zip archive to this file (\"-\" for stdout) instead of the output directory")
 }
 
 func runCheckout(cmd *cobra.Command, args []string) error {
@@ -73,11 +91,19 @@ func runCheckout(cmd *cobra.Command, args []string) error {
 		outputPath = args[1]
 	}
 
+	archiveFormat, archivePath, err := resolveCheckoutOutput(checkoutOutputFormat, cmd.Flags().Changed(FlagOutputFormat), checkoutOutput)
+	if err != nil {
+		return err
+	}
+	if archiveFormat != "" {
+		return runCheckoutToArchive(cmd, url, outputPath, archiveFormat, archivePath)
+	}
+
 	configDir := model.IgnConfigDir
 	configExists := false
 
-	// Check if .ign already exists
-	if _, err := os.Stat(configDir); err == nil {
+	// Check if .ign already holds a configuration; undo history alone does not count
+	if app.ConfigDirInUse(configDir) {
 		configExists = true
 		if !checkoutForce {
 			return fmt.Errorf("configuration already exists at %s (use --force to backup and reinitialize)", configDir)
@@ -109,13 +135,12 @@ func runCheckout(cmd *cobra.Command, args []string) error {
 	}
 
 	resolvedIgnJSON := templatedefaults.ResolveIgnJSON(prepResult.IgnJson, outputPath)
-	providedVars, err := ParseVariableAssignments(checkoutVars, resolvedIgnJSON.Variables)
-	if err != nil {
-		return err
-	}
-
-	// Prompt only for variables that were not supplied by flag.
-	vars, err := PromptForVariablesWithProvided(resolvedIgnJSON, providedVars)
+	// Prompt only for variables that were not supplied by --answers or --var.
+	vars, err := collectTemplateVariables(resolvedIgnJSON, variableInputs{
+		Assignments:       checkoutVars,
+		AnswersFile:       checkoutAnswers,
+		RecordAnswersFile: checkoutRecordAnswers,
+	})
 	if err != nil {
 		return err
 	}
@@ -154,6 +179,7 @@ func runCheckout(cmd *cobra.Command, args []string) error {
 	if checkoutDryRun {
 		// Output patch format to stdout
 		printDryRunPatch(result)
+		printDryRunHooks(result.Hooks)
 	} else {
 		printSuccess("Project generated successfully")
 		printInfo("")
@@ -165,6 +191,12 @@ func runCheckout(cmd *cobra.Command, args []string) error {
 		if result.FilesOverwritten > 0 {
 			printInfo(fmt.Sprintf("  Overwritten: %d files", result.FilesOverwritten))
 		}
+		if len(result.ExcludedFiles) > 0 {
+			printInfo(fmt.Sprintf("  Excluded: %d files (file conditions not met)", len(result.ExcludedFiles)))
+		}
+		if result.InjectionsApplied > 0 {
+			printInfo(fmt.Sprintf("  Injected: %d edits into existing files", result.InjectionsApplied))
+		}
 
 		// Print any non-fatal errors
 		if len(result.Errors) > 0 {
@@ -177,6 +209,11 @@ func runCheckout(cmd *cobra.Command, args []string) error {
 		printInfo("")
 		printInfo("Configuration saved to: .ign/ign.json, .ign/ign-var.json, .ign/ign-files.json")
 		printInfo(fmt.Sprintf("Project ready at: %s", outputPath))
+		printUndoHint(result.HistoryID)
+
+		if err := runTemplateHooks(cmd.Context(), result.Hooks, prepResult.NormalizedURL, checkoutAllowHooks); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
+	templatedefaults "github.com/tacogips/ign/internal/template/defaults"
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+// outputFormatDir is the --output-format that writes files to the output directory.
+const outputFormatDir = "dir"
+
+// resolveCheckoutOutput validates --output-format and --output. It returns an
+// empty format for directory output. Without an explicit format, --output
+// picks zip for a ".zip" file and tar otherwise; an explicit archive format
+// without --output writes to stdout.
+func resolveCheckoutOutput(format string, formatSet bool, output string) (generator.ArchiveFormat, string, error) {
+	if !formatSet && output != "" {
+		if strings.HasSuffix(strings.ToLower(output), ".zip") {
+			return generator.ArchiveZip, output, nil
+		}
+		return generator.ArchiveTar, output, nil
+	}
+
+	switch format {
+	case outputFormatDir:
+		if output != "" {
+			return "", "", fmt.Errorf("--%s requires --%s tar or zip", FlagOutput, FlagOutputFormat)
+		}
+		return "", "", nil
+	case string(generator.ArchiveTar), string(generator.ArchiveZip):
+		if output == "" {
+			output = "-"
+		}
+		return generator.ArchiveFormat(format), output, nil
+	default:
+		return "", "", fmt.Errorf("invalid --%s %q (must be dir, tar, or zip)", FlagOutputFormat, format)
+	}
+}
+
+// runCheckoutToArchive generates the project into a tar or zip archive
+// written to archivePath, or to stdout when archivePath is "-".
+func runCheckoutToArchive(cmd *cobra.Command, url string, outputPath string, format generator.ArchiveFormat, archivePath string) (err error) {
+	if checkoutDryRun {
+		return fmt.Errorf("--%s cannot be combined with --%s %s", FlagDryRun, FlagOutputFormat, format)
+	}
+
+	var out io.Writer
+	if archivePath == "-" {
+		// Keep stdout for the archive alone; prompts are drawn on stderr.
+		out = cmd.OutOrStdout()
+		origQuiet, origPromptOutput := globalQuiet, promptOutput
+		globalQuiet, promptOutput = true, os.Stderr
+		defer func() { globalQuiet, promptOutput = origQuiet, origPromptOutput }()
+	}
+
+	githubToken := getGitHubToken("")
+
+	printInfo(fmt.Sprintf("Template: %s", url))
+	if checkoutRef != "main" {
+		printInfo(fmt.Sprintf("Reference: %s", checkoutRef))
+	}
+
+	prepResult, err := app.PrepareCheckout(cmd.Context(), app.PrepareCheckoutOptions{
+		URL:             url,
+		Ref:             checkoutRef,
+		GitHubToken:     githubToken,
+		SkipConfigSetup: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	resolvedIgnJSON := templatedefaults.ResolveIgnJSON(prepResult.IgnJson, outputPath)
+	vars, err := collectTemplateVariables(resolvedIgnJSON, variableInputs{
+		Assignments:       checkoutVars,
+		AnswersFile:       checkoutAnswers,
+		RecordAnswersFile: checkoutRecordAnswers,
+	})
+	if err != nil {
+		return err
+	}
+
+	completeOpts := app.CompleteCheckoutOptions{
+		PrepareResult: prepResult,
+		Variables:     vars,
+		OutputDir:     outputPath,
+		Verbose:       checkoutVerbose,
+		GitHubToken:   githubToken,
+		ArchiveFormat: format,
+	}
+	preparedInputs, err := app.PrepareCompleteCheckoutInputs(completeOpts)
+	if err != nil {
+		return err
+	}
+	completeOpts.PreparedInputs = preparedInputs
+
+	if out == nil {
+		file, err := os.Create(archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write archive: %w", closeErr)
+			}
+			if err != nil {
+				_ = os.Remove(archivePath)
+			}
+		}()
+		out = file
+	}
+	completeOpts.Archive = out
+
+	printInfo(fmt.Sprintf("Generating %s archive from template...", format))
+	result, err := app.CompleteCheckout(cmd.Context(), completeOpts)
+	if err != nil {
+		return err
+	}
+
+	if archivePath == "-" {
+		printSuccess(fmt.Sprintf("Wrote %d files to stdout", result.FilesCreated))
+	} else {
+		printSuccess(fmt.Sprintf("Wrote %d files to %s", result.FilesCreated, archivePath))
+	}
+	if len(result.ExcludedFiles) > 0 {
+		printInfo(fmt.Sprintf("  Excluded: %d files (file conditions not met)", len(result.ExcludedFiles)))
+	}
+	if len(result.Errors) > 0 {
+		// Reported on stderr, so they are seen even when the archive is
+		// written to stdout.
+		printErrorMsg(fmt.Sprintf("%d errors occurred during generation:", len(result.Errors)))
+		for _, e := range result.Errors {
+			printErrorMsg(fmt.Sprintf("  - %v", e))
+		}
+	}
+	if len(result.Hooks) > 0 {
+		printWarning(fmt.Sprintf("Skipped %d hooks (run them after extracting the archive):", len(result.Hooks)))
+		printHookCommands(result.Hooks)
+	}
+	return nil
+}
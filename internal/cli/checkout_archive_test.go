@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+func TestResolveCheckoutOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		formatSet  bool
+		output     string
+		wantFormat generator.ArchiveFormat
+		wantPath   string
+		wantErr    bool
+	}{
+		{name: "directory default", format: "dir"},
+		{name: "zip inferred from output", format: "dir", output: "app.ZIP", wantFormat: generator.ArchiveZip, wantPath: "app.ZIP"},
+		{name: "tar inferred from output", format: "dir", output: "app.tar", wantFormat: generator.ArchiveTar, wantPath: "app.tar"},
+		{name: "stdout defaults to tar", format: "dir", output: "-", wantFormat: generator.ArchiveTar, wantPath: "-"},
+		{name: "explicit format writes stdout", format: "zip", formatSet: true, wantFormat: generator.ArchiveZip, wantPath: "-"},
+		{name: "explicit format with output", format: "tar", formatSet: true, output: "out.bin", wantFormat: generator.ArchiveTar, wantPath: "out.bin"},
+		{name: "dir with output", format: "dir", formatSet: true, output: "app.tar", wantErr: true},
+		{name: "unknown format", format: "rar", formatSet: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, path, err := resolveCheckoutOutput(tt.format, tt.formatSet, tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveCheckoutOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if format != tt.wantFormat || path != tt.wantPath {
+				t.Errorf("resolveCheckoutOutput() = (%q, %q), want (%q, %q)", format, path, tt.wantFormat, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestRunCheckoutToStdoutWritesOnlyArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	templateDir := writeTemplateWithRequiredVariable(t, tempDir, "template")
+
+	origRef, origDryRun, origVars := checkoutRef, checkoutDryRun, checkoutVars
+	origFormat, origOutput, origQuiet := checkoutOutputFormat, checkoutOutput, globalQuiet
+	defer func() {
+		checkoutRef, checkoutDryRun, checkoutVars = origRef, origDryRun, origVars
+		checkoutOutputFormat, checkoutOutput, globalQuiet = origFormat, origOutput, origQuiet
+	}()
+	checkoutRef = "main"
+	checkoutDryRun = false
+	checkoutVars = []string{"project_name=my-app"}
+	checkoutOutputFormat = outputFormatDir
+	checkoutOutput = "-"
+	globalQuiet = false
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&out)
+	if err := runCheckout(cmd, []string{templateDir, "proj"}); err != nil {
+		t.Fatalf("runCheckout returned error: %v", err)
+	}
+	if globalQuiet || promptOutput != os.Stdout {
+		t.Error("runCheckout should restore quiet mode and the prompt output")
+	}
+
+	tr := tar.NewReader(&out)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("stdout is not a tar archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	found := false
+	for _, name := range names {
+		found = found || name == "proj/README.md"
+	}
+	if !found {
+		t.Errorf("archive entries = %v, want proj/README.md", names)
+	}
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -286,3 +287,27 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+// TestTemplateSchemaCommand tests writing the variable schema to a file
+func TestTemplateSchemaCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ign-template.json"), []byte(`{"name":"t","version":"1.0.0","variables":{"ratio":{"type":"number","min":0}}}`), 0644); err != nil {
+		t.Fatalf("failed to write ign-template.json: %v", err)
+	}
+	outPath := filepath.Join(dir, "schema.json")
+
+	templateSchemaOutput = outPath
+	t.Cleanup(func() { templateSchemaOutput = "" })
+
+	if err := runTemplateSchema(templateSchemaCmd, []string{dir}); err != nil {
+		t.Fatalf("runTemplateSchema() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+	if !strings.Contains(string(data), `"number",`) || !strings.Contains(string(data), `"minimum": 0`) {
+		t.Errorf("schema missing number type:\n%s", data)
+	}
+}
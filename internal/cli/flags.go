@@ -13,30 +13,40 @@ import (
 // Common flag names and descriptions
 const (
 	// Flag names
-	FlagOutput    = "output"
-	FlagOverwrite = "overwrite"
-	FlagConfig    = "config"
-	FlagRef       = "ref"
-	FlagForce     = "force"
-	FlagDryRun    = "dry-run"
-	FlagVerbose   = "verbose"
-	FlagNoColor   = "no-color"
-	FlagQuiet     = "quiet"
-	FlagDebug     = "debug"
-	FlagVar       = "var"
+	FlagOutput        = "output"
+	FlagOverwrite     = "overwrite"
+	FlagConfig        = "config"
+	FlagRef           = "ref"
+	FlagForce         = "force"
+	FlagDryRun        = "dry-run"
+	FlagVerbose       = "verbose"
+	FlagNoColor       = "no-color"
+	FlagQuiet         = "quiet"
+	FlagDebug         = "debug"
+	FlagVar           = "var"
+	FlagAnswers       = "answers"
+	FlagRecordAnswers = "record-answers"
+	FlagAllowHooks    = "allow-hooks"
+	FlagOutputFormat  = "output-format"
+	FlagWait          = "wait"
 
 	// Flag descriptions
-	DescOutput    = "Output directory"
-	DescOverwrite = "Overwrite existing files"
-	DescConfig    = "Path to config file"
-	DescRef       = "Git branch, tag, or commit SHA"
-	DescForce     = "Force overwrite"
-	DescDryRun    = "Show actions without execution"
-	DescVerbose   = "Verbose output"
-	DescNoColor   = "Disable colored output"
-	DescQuiet     = "Suppress output"
-	DescDebug     = "Enable debug logging"
-	DescVar       = "Set a template variable as key=value (repeatable)"
+	DescOutput        = "Output directory"
+	DescOverwrite     = "Overwrite existing files"
+	DescConfig        = "Path to config file"
+	DescRef           = "Git branch, tag, or commit SHA"
+	DescForce         = "Force overwrite"
+	DescDryRun        = "Show actions without execution"
+	DescVerbose       = "Verbose output"
+	DescNoColor       = "Disable colored output"
+	DescQuiet         = "Suppress output"
+	DescDebug         = "Enable debug logging"
+	DescVar           = "Set a template variable as key=value (repeatable)"
+	DescAnswers       = "Read all variable values from a JSON answers file (no prompts)"
+	DescRecordAnswers = "Write the collected variable values to a JSON answers file"
+	DescAllowHooks    = "Run the template's hook commands without asking"
+	DescOutputFormat  = "Output format: dir, tar, or zip"
+	DescWait          = "Wait up to this long for another ign process to release the project lock (e.g. 30s)"
 )
 
 // URL validation patterns
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
+)
+
+var (
+	undoID     string
+	undoForce  bool
+	undoDryRun bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [output-path]",
+	Short: "List snapshots that ign undo can restore",
+	Long: `List the snapshots recorded in .ign/history, newest first.
+
+Every checkout, update, switch, and rewind that changes files records the
+previous state of the paths it touched. The newest 20 snapshots are kept.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistory,
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [output-path]",
+	Short: "Restore the files changed by a previous ign command",
+	Long: `Restore the files a previous checkout, update, switch, or rewind replaced,
+removed, or created, including its .ign configuration.
+
+The newest snapshot is restored unless --id selects one from 'ign history'.
+Paths changed after the command ran are reported and left alone unless
+--force is given. A restored snapshot is removed from the history.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUndo,
+}
+
+func init() {
+	undoCmd.Flags().StringVar(&undoID, "id", "", "Snapshot to restore (default: newest)")
+	undoCmd.Flags().BoolVarP(&undoForce, FlagForce, "f", false, "Restore paths changed after the snapshot was recorded")
+	undoCmd.Flags().BoolVarP(&undoDryRun, FlagDryRun, "d", false, DescDryRun)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	outputPath := "."
+	if len(args) > 0 {
+		outputPath = args[0]
+	}
+
+	snapshots, err := app.ListHistory(outputPath)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		printInfo("No history recorded")
+		return nil
+	}
+	if globalQuiet {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCOMMAND\tCREATED\tPATHS")
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", snapshot.ID, snapshot.Command,
+			snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"), len(snapshot.Entries))
+	}
+	return w.Flush()
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	outputPath := "."
+	if len(args) > 0 {
+		outputPath = args[0]
+	}
+
+	result, err := app.Undo(cmd.Context(), app.UndoOptions{
+		OutputDir: outputPath,
+		ID:        undoID,
+		Force:     undoForce,
+		DryRun:    undoDryRun,
+	})
+	if result != nil {
+		if len(result.Conflicts) > 0 {
+			printWarning(fmt.Sprintf("%d paths changed after %s ran:", len(result.Conflicts), result.Snapshot.Command))
+			for _, path := range result.Conflicts {
+				printInfo(fmt.Sprintf("  ! %s", path))
+			}
+		}
+		if undoDryRun {
+			for _, path := range result.Restored {
+				printInfo(fmt.Sprintf("  restore %s", path))
+			}
+			for _, path := range result.Removed {
+				printInfo(fmt.Sprintf("  remove  %s", path))
+			}
+		}
+		for _, e := range result.Errors {
+			printWarning(fmt.Sprintf("  - %v", e))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if undoDryRun {
+		printInfo(fmt.Sprintf("Dry run: would undo %s %s", result.Snapshot.Command, result.Snapshot.ID))
+		return nil
+	}
+	printSuccess(fmt.Sprintf("Undid %s %s", result.Snapshot.Command, result.Snapshot.ID))
+	printInfo(fmt.Sprintf("  Restored: %d paths", len(result.Restored)))
+	if len(result.Removed) > 0 {
+		printInfo(fmt.Sprintf("  Removed: %d paths", len(result.Removed)))
+	}
+	return nil
+}
+
+// printUndoHint tells the user how to revert the command that recorded id.
+func printUndoHint(id string) {
+	if id == "" {
+		return
+	}
+	printInfo(fmt.Sprintf("Undo snapshot: %s (run 'ign undo' to revert)", id))
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+// Swap points for tests.
+var (
+	hooksTrusted = app.HooksTrusted
+	runHooks     = app.RunHooks
+	confirmHooks = confirmHooksPrompt
+)
+
+// runTemplateHooks runs the hooks a template declared for the finished
+// workflow, once the user consented through allowFlag, the global trust list,
+// or an interactive prompt. Without consent the hooks are listed and skipped.
+func runTemplateHooks(ctx context.Context, hooks []generator.ResolvedHook, templateURL string, allowFlag bool) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	printSeparator()
+	printInfo("The template declares commands to run in the project:")
+	printHookCommands(hooks)
+
+	allowed := allowFlag
+	if !allowed {
+		trusted, err := hooksTrusted(templateURL)
+		if err != nil {
+			return err
+		}
+		allowed = trusted
+	}
+	if !allowed {
+		if !promptInputIsTerminal() {
+			printWarning(fmt.Sprintf("Skipped %d hooks (use --%s or trust the template in the global config to run them)", len(hooks), FlagAllowHooks))
+			return nil
+		}
+		confirmed, err := confirmHooks()
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			printInfo("Skipped hooks")
+			return nil
+		}
+	}
+
+	var stdout io.Writer = os.Stdout
+	if globalQuiet {
+		stdout = io.Discard
+	}
+	if err := runHooks(ctx, app.RunHooksOptions{Hooks: hooks, Stdout: stdout, Stderr: os.Stderr}); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Ran %d hooks", len(hooks)))
+	return nil
+}
+
+// printDryRunHooks lists the hooks a real run would offer to execute.
+func printDryRunHooks(hooks []generator.ResolvedHook) {
+	if len(hooks) == 0 {
+		return
+	}
+	printInfo("[DRY RUN] Would run hooks (after consent):")
+	printHookCommands(hooks)
+}
+
+func printHookCommands(hooks []generator.ResolvedHook) {
+	for _, hook := range hooks {
+		if hook.Dir != "" {
+			printInfo(fmt.Sprintf("  $ %s  (in %s)", hook.Run, hook.Dir))
+		} else {
+			printInfo(fmt.Sprintf("  $ %s", hook.Run))
+		}
+	}
+}
+
+func confirmHooksPrompt() (bool, error) {
+	var confirmed bool
+	prompt := &survey.Confirm{
+		Message: "Run these commands?",
+		Default: false,
+	}
+	if err := survey.AskOne(prompt, &confirmed); err != nil {
+		return false, err
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/template/generator"
+)
+
+func TestRunTemplateHooksRequiresConsent(t *testing.T) {
+	origTerminal, origTrusted, origRun, origConfirm := promptInputIsTerminal, hooksTrusted, runHooks, confirmHooks
+	origQuiet := globalQuiet
+	defer func() {
+		promptInputIsTerminal, hooksTrusted, runHooks, confirmHooks = origTerminal, origTrusted, origRun, origConfirm
+		globalQuiet = origQuiet
+	}()
+	globalQuiet = true
+
+	hooks := []generator.ResolvedHook{{Run: "go mod tidy"}}
+
+	tests := []struct {
+		name      string
+		allowFlag bool
+		trusted   bool
+		terminal  bool
+		confirm   bool
+		wantRun   bool
+		wantAsked bool
+	}{
+		{name: "allow flag", allowFlag: true, wantRun: true},
+		{name: "trusted template", trusted: true, wantRun: true},
+		{name: "non-interactive without consent", wantRun: false},
+		{name: "prompt accepted", terminal: true, confirm: true, wantRun: true, wantAsked: true},
+		{name: "prompt declined", terminal: true, confirm: false, wantRun: false, wantAsked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran, asked := false, false
+			promptInputIsTerminal = func() bool { return tt.terminal }
+			hooksTrusted = func(string) (bool, error) { return tt.trusted, nil }
+			confirmHooks = func() (bool, error) {
+				asked = true
+				return tt.confirm, nil
+			}
+			runHooks = func(_ context.Context, opts app.RunHooksOptions) error {
+				ran = len(opts.Hooks) == len(hooks)
+				return nil
+			}
+
+			if err := runTemplateHooks(context.Background(), hooks, "github.com/acme/templates", tt.allowFlag); err != nil {
+				t.Fatalf("runTemplateHooks() error = %v", err)
+			}
+			if ran != tt.wantRun {
+				t.Errorf("hooks ran = %v, want %v", ran, tt.wantRun)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("prompted = %v, want %v", asked, tt.wantAsked)
+			}
+		})
+	}
+}
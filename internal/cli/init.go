@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tacogips/ign/internal/app"
@@ -11,9 +10,11 @@ import (
 )
 
 var (
-	initRef   string
-	initForce bool
-	initVars  []string
+	initRef           string
+	initForce         bool
+	initVars          []string
+	initAnswers       string
+	initRecordAnswers string
 )
 
 var initCmd = &cobra.Command{
@@ -22,8 +23,9 @@ var initCmd = &cobra.Command{
 	Long: `Initialize ign configuration from a template source.
 
 The init command creates .ign/ign.json and .ign/ign-var.json. Template variables
-can be supplied non-interactively with --var key=value. Missing variables are
-prompted interactively.`,
+can be supplied non-interactively with --var key=value or replayed from an
+--answers file recorded with --record-answers. Missing variables are prompted
+interactively unless --answers is given.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInit,
 }
@@ -32,6 +34,8 @@ func init() {
 	initCmd.Flags().StringVarP(&initRef, FlagRef, "r", "main", DescRef)
 	initCmd.Flags().BoolVarP(&initForce, FlagForce, "f", false, "Backup existing config and reinitialize")
 	initCmd.Flags().StringArrayVarP(&initVars, FlagVar, "V", nil, DescVar)
+	initCmd.Flags().StringVar(&initAnswers, FlagAnswers, "", DescAnswers)
+	initCmd.Flags().StringVar(&initRecordAnswers, FlagRecordAnswers, "", DescRecordAnswers)
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -43,7 +47,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 	configDir := model.IgnConfigDir
 	configExists := false
 
-	if _, err := os.Stat(configDir); err == nil {
+	if app.ConfigDirInUse(configDir) {
 		configExists = true
 		if !initForce {
 			err := fmt.Errorf("configuration already exists at %s (use --force to reinitialize)", configDir)
@@ -72,12 +76,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	resolvedIgnJSON := templatedefaults.ResolveIgnJSON(prepResult.IgnJson, ".")
-	providedVars, err := ParseVariableAssignments(initVars, resolvedIgnJSON.Variables)
-	if err != nil {
-		return err
-	}
-
-	vars, err := PromptForVariablesWithProvided(resolvedIgnJSON, providedVars)
+	vars, err := collectTemplateVariables(resolvedIgnJSON, variableInputs{
+		Assignments:       initVars,
+		AnswersFile:       initAnswers,
+		RecordAnswersFile: initRecordAnswers,
+	})
 	if err != nil {
 		return err
 	}
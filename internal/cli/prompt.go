@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/tacogips/ign/internal/app"
 	"github.com/tacogips/ign/internal/template/model"
 	"golang.org/x/term"
 )
@@ -17,6 +19,15 @@ var promptInputIsTerminal = func() bool {
 	return term.IsTerminal(int(os.Stdin.Fd()))
 }
 
+// promptOutput is where variable prompts are drawn. It is stderr while an
+// archive is being written to stdout.
+var promptOutput terminal.FileWriter = os.Stdout
+
+// promptStdio draws a survey prompt on promptOutput.
+func promptStdio() survey.AskOpt {
+	return survey.WithStdio(os.Stdin, promptOutput, os.Stderr)
+}
+
 // PromptForVariables interactively prompts the user for variable values.
 // Returns a map of variable names to their values.
 func PromptForVariables(ignJson *model.IgnJson) (map[string]interface{}, error) {
@@ -61,9 +72,9 @@ func PromptForVariablesWithProvided(ignJson *model.IgnJson, providedVars map[str
 		return nil, newNonInteractivePromptError(missingVarNames)
 	}
 
-	fmt.Println()
-	fmt.Println("Please provide values for template variables:")
-	fmt.Println()
+	_, _ = fmt.Fprintln(promptOutput)
+	_, _ = fmt.Fprintln(promptOutput, "Please provide values for template variables:")
+	_, _ = fmt.Fprintln(promptOutput)
 
 	for _, name := range missingVarNames {
 		varDef := ignJson.Variables[name]
@@ -152,7 +163,7 @@ func promptString(name string, varDef model.VarDef, help string) (string, error)
 		validators = append(validators, matchPattern(varDef.Pattern, "value must match pattern: "+varDef.Pattern))
 	}
 
-	opts := []survey.AskOpt{}
+	opts := []survey.AskOpt{promptStdio()}
 	if len(validators) > 0 {
 		opts = append(opts, survey.WithValidator(survey.ComposeValidators(validators...)))
 	}
@@ -217,22 +228,11 @@ func promptInt(name string, varDef model.VarDef, help string) (int, error) {
 			return nil
 		}
 
-		num, err := strconv.Atoi(str)
-		if err != nil {
-			return fmt.Errorf("must be an integer")
-		}
-
-		if varDef.Min != nil && float64(num) < *varDef.Min {
-			return fmt.Errorf("must be >= %d", int(*varDef.Min))
-		}
-		if varDef.Max != nil && float64(num) > *varDef.Max {
-			return fmt.Errorf("must be <= %d", int(*varDef.Max))
-		}
-
-		return nil
+		_, err := app.NumericVariableValue(varDef, str)
+		return err
 	}
 
-	if err := survey.AskOne(prompt, &result, survey.WithValidator(intValidator)); err != nil {
+	if err := survey.AskOne(prompt, &result, promptStdio(), survey.WithValidator(intValidator)); err != nil {
 		return 0, err
 	}
 
@@ -240,7 +240,8 @@ func promptInt(name string, varDef model.VarDef, help string) (int, error) {
 		return 0, nil
 	}
 
-	return strconv.Atoi(result)
+	num, err := app.NumericVariableValue(varDef, result)
+	return int(num), err
 }
 
 // promptNumber prompts for a floating-point number variable.
@@ -298,22 +299,11 @@ func promptNumber(name string, varDef model.VarDef, help string) (float64, error
 			return nil
 		}
 
-		num, err := strconv.ParseFloat(str, 64)
-		if err != nil {
-			return fmt.Errorf("must be a number")
-		}
-
-		if varDef.Min != nil && num < *varDef.Min {
-			return fmt.Errorf("must be >= %v", *varDef.Min)
-		}
-		if varDef.Max != nil && num > *varDef.Max {
-			return fmt.Errorf("must be <= %v", *varDef.Max)
-		}
-
-		return nil
+		_, err := app.NumericVariableValue(varDef, str)
+		return err
 	}
 
-	if err := survey.AskOne(prompt, &result, survey.WithValidator(numberValidator)); err != nil {
+	if err := survey.AskOne(prompt, &result, promptStdio(), survey.WithValidator(numberValidator)); err != nil {
 		return 0, err
 	}
 
@@ -324,7 +314,7 @@ func promptNumber(name string, varDef model.VarDef, help string) (float64, error
 		return 0, nil
 	}
 
-	return strconv.ParseFloat(result, 64)
+	return app.NumericVariableValue(varDef, result)
 }
 
 // promptBool prompts for a boolean variable.
@@ -354,7 +344,7 @@ func promptBool(name string, varDef model.VarDef, help string) (bool, error) {
 		Help:    help,
 	}
 
-	if err := survey.AskOne(prompt, &result); err != nil {
+	if err := survey.AskOne(prompt, &result, promptStdio()); err != nil {
 		return false, err
 	}
 
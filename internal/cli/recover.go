@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
+)
+
+var (
+	recoverRollback bool
+	recoverComplete bool
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover [output-path]",
+	Short: "Recover a project left partly changed by an interrupted ign command",
+	Long: `Recover a project after a checkout, update, switch, or rewind was killed
+before it finished.
+
+Those commands record in .ign/journal the state of every path before they
+change it. While an unfinished journal is present, commands that change the
+project refuse to run. Without a flag, recover shows the interrupted command
+and the paths it changed.
+
+  --rollback   Restore every changed path as it was before the command
+  --complete   Restore the same way, then run the interrupted command again
+               with its original arguments so it finishes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRecover,
+}
+
+func init() {
+	recoverCmd.Flags().BoolVar(&recoverRollback, "rollback", false, "Restore the project as it was before the interrupted command")
+	recoverCmd.Flags().BoolVar(&recoverComplete, "complete", false, "Roll back, then run the interrupted command again")
+}
+
+func runRecover(cmd *cobra.Command, args []string) error {
+	if recoverRollback && recoverComplete {
+		return fmt.Errorf("--rollback cannot be combined with --complete")
+	}
+	outputPath := "."
+	if len(args) > 0 {
+		outputPath = args[0]
+	}
+	dryRun := !recoverRollback && !recoverComplete
+
+	result, err := app.Recover(cmd.Context(), app.RecoverOptions{
+		OutputDir: outputPath,
+		DryRun:    dryRun,
+	})
+	if result != nil {
+		if dryRun {
+			for _, path := range result.Restored {
+				printInfo(fmt.Sprintf("  restore %s", path))
+			}
+			for _, path := range result.Removed {
+				printInfo(fmt.Sprintf("  remove  %s", path))
+			}
+		}
+		for _, e := range result.Errors {
+			printWarning(fmt.Sprintf("  - %v", e))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	interrupted := result.Interrupted
+	if interrupted == nil {
+		printInfo("No interrupted command to recover")
+		return nil
+	}
+	what := fmt.Sprintf("ign %s (pid %d, started %s)", interrupted.Command, interrupted.PID,
+		interrupted.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	if dryRun {
+		printInfo(fmt.Sprintf("%s was interrupted after changing %d paths", what, len(result.Restored)+len(result.Removed)))
+		printInfo("Run 'ign recover --rollback' to restore them, or 'ign recover --complete' to restore them and run the command again")
+		return nil
+	}
+
+	printSuccess(fmt.Sprintf("Rolled back interrupted %s", what))
+	printInfo(fmt.Sprintf("  Restored: %d paths", len(result.Restored)))
+	if len(result.Removed) > 0 {
+		printInfo(fmt.Sprintf("  Removed: %d paths", len(result.Removed)))
+	}
+	if !recoverComplete {
+		return nil
+	}
+
+	if len(interrupted.Args) == 0 {
+		return fmt.Errorf("the interrupted %s did not record its arguments; run it again yourself", interrupted.Command)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate ign to rerun %s: %w", interrupted.Command, err)
+	}
+	printInfo(fmt.Sprintf("Running: ign %s", strings.Join(interrupted.Args, " ")))
+	rerun := exec.CommandContext(cmd.Context(), executable, interrupted.Args...)
+	rerun.Dir = interrupted.Dir
+	rerun.Stdin = os.Stdin
+	rerun.Stdout = cmd.OutOrStdout()
+	rerun.Stderr = cmd.ErrOrStderr()
+	if err := rerun.Run(); err != nil {
+		return fmt.Errorf("rerunning ign %s failed: %w", interrupted.Command, err)
+	}
+	return nil
+}
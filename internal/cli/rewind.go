@@ -7,6 +7,14 @@ import (
 	"github.com/tacogips/ign/internal/app"
 )
 
+var (
+	rewindDryRun       bool
+	rewindKeepConfig   bool
+	rewindKeepModified bool
+	rewindKeepHistory  bool
+	rewindPaths        []string
+)
+
 var rewindCmd = &cobra.Command{
 	Use:   "rewind [output-path]",
 	Short: "Remove files previously created by ign",
@@ -16,26 +24,54 @@ This command is the reverse of project generation:
 1. Reads .ign/ign-files.json when available
 2. Removes files that ign created
 3. Cleans up empty directories
-4. Deletes the .ign directory
+4. Deletes the .ign directory; --keep-history keeps .ign/history, with a
+   snapshot of this rewind, so 'ign undo' can restore what was removed
 
 If the manifest does not exist yet, ign falls back to the currently checked-out
-template and variables to infer which files belong to ign.`,
+template and variables to infer which files belong to ign.
+
+--path limits the rewind to files at or beneath a path relative to the output
+directory and keeps .ign, dropping only the rewound files from the manifest.
+--keep-modified leaves files whose content differs from what ign last
+generated for them, as recorded in the manifest. Use --dry-run to see what would be removed first.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runRewind,
 }
 
+func init() {
+	rewindCmd.Flags().BoolVarP(&rewindDryRun, FlagDryRun, "d", false, DescDryRun)
+	rewindCmd.Flags().BoolVar(&rewindKeepConfig, "keep-config", false, "Keep .ign after removing the files")
+	rewindCmd.Flags().BoolVar(&rewindKeepHistory, "keep-history", false, "Keep .ign/history with a snapshot of this rewind so it can be undone")
+	rewindCmd.Flags().BoolVar(&rewindKeepModified, "keep-modified", false, "Keep files whose content differs from what ign last generated for them")
+	rewindCmd.Flags().StringArrayVar(&rewindPaths, "path", nil, "Only rewind files at or beneath this path, relative to the output directory (repeatable)")
+}
+
 func runRewind(cmd *cobra.Command, args []string) error {
 	outputPath := "."
 	if len(args) > 0 {
 		outputPath = args[0]
 	}
 
-	printInfo("Removing files generated by ign...")
+	if rewindDryRun {
+		printInfo("Dry run: checking files generated by ign...")
+	} else {
+		printInfo("Removing files generated by ign...")
+	}
 	result, err := app.Rewind(cmd.Context(), app.RewindOptions{
-		OutputDir:   outputPath,
-		GitHubToken: getGitHubToken(""),
+		OutputDir:    outputPath,
+		GitHubToken:  getGitHubToken(""),
+		DryRun:       rewindDryRun,
+		KeepConfig:   rewindKeepConfig,
+		KeepModified: rewindKeepModified,
+		KeepHistory:  rewindKeepHistory,
+		Paths:        rewindPaths,
 	})
 
+	if result != nil && rewindDryRun && err == nil {
+		printRewindDryRun(result)
+		return nil
+	}
+
 	if result != nil {
 		printInfo("")
 		printInfo("Summary:")
@@ -46,6 +82,18 @@ func runRewind(cmd *cobra.Command, args []string) error {
 		if result.FilesSkipped > 0 {
 			printInfo(fmt.Sprintf("  Skipped: %d files (content differs)", result.FilesSkipped))
 		}
+		if result.FilesKept > 0 {
+			printInfo(fmt.Sprintf("  Kept: %d files (seed or user file policy)", result.FilesKept))
+		}
+		if result.FilesProtected > 0 {
+			printInfo(fmt.Sprintf("  Kept: %d files (protected by project)", result.FilesProtected))
+		}
+		if result.InjectionsReverted > 0 {
+			printInfo(fmt.Sprintf("  Reverted: %d injections", result.InjectionsReverted))
+		}
+		if result.InjectionsSkipped > 0 {
+			printInfo(fmt.Sprintf("  Skipped: %d injections (content changed)", result.InjectionsSkipped))
+		}
 		if result.DirectoriesRemoved > 0 {
 			printInfo(fmt.Sprintf("  Cleaned: %d empty directories", result.DirectoriesRemoved))
 		}
@@ -61,6 +109,60 @@ func runRewind(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if !result.ConfigRemoved {
+		printInfo("Kept .ign; the removed files were dropped from ign-files.json")
+	}
 	printSuccess("ign-managed files removed")
+	printUndoHint(result.HistoryID)
 	return nil
 }
+
+// printRewindDryRun lists what a rewind would remove and leave in place.
+func printRewindDryRun(result *app.RewindResult) {
+	printInfo("")
+	for _, path := range result.RemovedFiles {
+		printInfo(fmt.Sprintf("  remove %s", path))
+	}
+	for _, path := range result.SkippedFiles {
+		printInfo(fmt.Sprintf("  skip   %s (content differs)", path))
+	}
+	for _, path := range result.KeptFiles {
+		printInfo(fmt.Sprintf("  keep   %s (seed or user file policy)", path))
+	}
+	for _, path := range result.ProtectedFiles {
+		printInfo(fmt.Sprintf("  keep   %s (protected by project)", path))
+	}
+	printInfo("")
+	printInfo("Summary:")
+	printInfo(fmt.Sprintf("  Would remove: %d files", result.FilesRemoved))
+	if result.FilesMissing > 0 {
+		printInfo(fmt.Sprintf("  Missing: %d files (already absent)", result.FilesMissing))
+	}
+	if result.FilesSkipped > 0 {
+		printInfo(fmt.Sprintf("  Would skip: %d files (content differs)", result.FilesSkipped))
+	}
+	if result.FilesKept > 0 {
+		printInfo(fmt.Sprintf("  Would keep: %d files (seed or user file policy)", result.FilesKept))
+	}
+	if result.FilesProtected > 0 {
+		printInfo(fmt.Sprintf("  Would keep: %d files (protected by project)", result.FilesProtected))
+	}
+	if result.InjectionsReverted > 0 {
+		printInfo(fmt.Sprintf("  Would revert: %d injections", result.InjectionsReverted))
+	}
+	if result.InjectionsSkipped > 0 {
+		printInfo(fmt.Sprintf("  Would skip: %d injections (content changed)", result.InjectionsSkipped))
+	}
+	if len(result.Errors) > 0 {
+		printWarning(fmt.Sprintf("%d errors would stop the rewind:", len(result.Errors)))
+		for _, e := range result.Errors {
+			printWarning(fmt.Sprintf("  - %v", e))
+		}
+	}
+	if result.ConfigRemoved {
+		printInfo("  Would remove .ign (keeping .ign/history)")
+	} else {
+		printInfo("  Would keep .ign")
+	}
+	printInfo("Dry run: no files were changed")
+}
@@ -1,10 +1,13 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
 	"github.com/tacogips/ign/internal/debug"
 )
 
@@ -13,6 +16,7 @@ var (
 	globalNoColor bool
 	globalQuiet   bool
 	globalDebug   bool
+	globalWait    time.Duration
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -34,6 +38,13 @@ conditionals, and file inclusions for flexible project generation.`,
 		// Set debug mode
 		debug.SetDebug(globalDebug)
 		debug.SetNoColor(globalNoColor)
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = app.WithLockWait(ctx, globalWait)
+		cmd.SetContext(app.WithInvocation(ctx, os.Args[1:]))
 	},
 }
 
@@ -51,6 +62,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&globalNoColor, "no-color", false, "Disable colored output")
 	rootCmd.PersistentFlags().BoolVarP(&globalQuiet, "quiet", "q", false, "Suppress non-error output")
 	rootCmd.PersistentFlags().BoolVar(&globalDebug, FlagDebug, false, DescDebug)
+	rootCmd.PersistentFlags().DurationVar(&globalWait, FlagWait, 0, DescWait)
 
 	// Add subcommands
 	rootCmd.AddCommand(initCmd)
@@ -61,6 +73,9 @@ func init() {
 	rootCmd.AddCommand(templateCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(varsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(recoverCmd)
 }
 
 // printError prints an error message to stderr
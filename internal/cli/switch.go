@@ -6,13 +6,17 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tacogips/ign/internal/app"
 	templatedefaults "github.com/tacogips/ign/internal/template/defaults"
+	"github.com/tacogips/ign/internal/template/model"
 )
 
 var (
-	switchRef     string
-	switchForce   bool
-	switchVerbose bool
-	switchVars    []string
+	switchRef           string
+	switchForce         bool
+	switchVerbose       bool
+	switchVars          []string
+	switchAnswers       string
+	switchRecordAnswers string
+	switchAllowHooks    bool
 )
 
 var switchCmd = &cobra.Command{
@@ -35,6 +39,9 @@ func init() {
 	switchCmd.Flags().BoolVarP(&switchForce, "force", "f", false, "Overwrite existing files when applying the new template")
 	switchCmd.Flags().BoolVarP(&switchVerbose, "verbose", "v", false, "Show detailed processing information")
 	switchCmd.Flags().StringArrayVarP(&switchVars, FlagVar, "V", nil, DescVar)
+	switchCmd.Flags().StringVar(&switchAnswers, FlagAnswers, "", DescAnswers)
+	switchCmd.Flags().StringVar(&switchRecordAnswers, FlagRecordAnswers, "", DescRecordAnswers)
+	switchCmd.Flags().BoolVar(&switchAllowHooks, FlagAllowHooks, false, DescAllowHooks)
 }
 
 func runSwitch(cmd *cobra.Command, args []string) error {
@@ -69,12 +76,11 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 	}
 
 	resolvedIgnJSON := templatedefaults.ResolveIgnJSON(prepResult.IgnJson, outputPath)
-	providedVars, err := ParseVariableAssignments(switchVars, resolvedIgnJSON.Variables)
-	if err != nil {
-		return err
-	}
-
-	vars, err := PromptForVariablesWithProvided(resolvedIgnJSON, providedVars)
+	vars, err := collectTemplateVariables(resolvedIgnJSON, variableInputs{
+		Assignments:       switchVars,
+		AnswersFile:       switchAnswers,
+		RecordAnswersFile: switchRecordAnswers,
+	})
 	if err != nil {
 		return err
 	}
@@ -92,12 +98,27 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Hold the project lock across both halves; rewind and checkout reenter it.
+	lock, err := app.LockProject(cmd.Context(), model.IgnConfigDir, "switch")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// One snapshot covers both halves so a single undo restores the old template.
+	history, err := app.BeginHistory(cmd.Context(), model.IgnConfigDir, "switch")
+	if err != nil {
+		return err
+	}
+	defer history.Discard()
+
 	printInfo("Removing current template output...")
 	if _, err := app.Rewind(cmd.Context(), app.RewindOptions{
 		OutputDir:   outputPath,
 		GitHubToken: githubToken,
+		History:     history,
 	}); err != nil {
-		return err
+		return commitPartialSwitch(history, err)
 	}
 
 	if err := app.PrepareCheckoutConfigDir(false); err != nil {
@@ -111,9 +132,14 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 		Overwrite:     switchForce,
 		Verbose:       switchVerbose,
 		GitHubToken:   githubToken,
+		History:       history,
 	})
 	if err != nil {
-		return err
+		return commitPartialSwitch(history, err)
+	}
+	historyID, err := history.Commit()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to record undo snapshot: %w", err))
 	}
 
 	printSuccess("Template switched successfully")
@@ -132,6 +158,18 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 			printWarning(fmt.Sprintf("  - %v", e))
 		}
 	}
+	printUndoHint(historyID)
+
+	return runTemplateHooks(cmd.Context(), result.Hooks, prepResult.NormalizedURL, switchAllowHooks)
+}
 
-	return nil
+// commitPartialSwitch keeps the snapshot of a switch that failed after it
+// started removing the old template, so 'ign undo' can put it back.
+func commitPartialSwitch(history *app.HistoryRecorder, switchErr error) error {
+	id, err := history.Commit()
+	if err != nil {
+		printWarning(fmt.Sprintf("failed to record undo snapshot: %v", err))
+	}
+	printUndoHint(id)
+	return switchErr
 }
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tacogips/ign/internal/app"
@@ -93,6 +94,32 @@ Examples:
 	RunE: runTemplateUpdate,
 }
 
+// templateSchemaCmd represents the template schema command
+var templateSchemaCmd = &cobra.Command{
+	Use:   "schema [PATH]",
+	Short: "Print a JSON Schema for the template's variables",
+	Long: `Generate a JSON Schema (draft 2020-12) from the variable definitions in
+ign-template.json.
+
+The schema describes the .ign/ign-var.json file of projects generated from the
+template, so editors can validate it, and it can be used to build input forms.
+Each variable's type, description, default, example, pattern, and min/max are
+included. Int and number variables also accept numeric strings, string
+patterns do not apply to @file: references, and undeclared variables are
+allowed, matching what ign accepts. Required variables without a default are
+listed as required.
+
+If PATH is not specified, the current directory is used. PATH may also point
+directly at an ign-template.json file.
+
+Examples:
+  ign template schema
+  ign template schema ./my-template > ign-var.schema.json
+  ign template schema ./my-template -o ign-var.schema.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTemplateSchema,
+}
+
 // Template new command flags
 var (
 	templateNewType  string
@@ -110,11 +137,17 @@ var (
 	templateUpdateMerge  bool
 )
 
+// Template schema command flags
+var (
+	templateSchemaOutput string
+)
+
 func init() {
 	// Add subcommands to template
 	templateCmd.AddCommand(templateNewCmd)
 	templateCmd.AddCommand(templateCheckCmd)
 	templateCmd.AddCommand(templateUpdateCmd)
+	templateCmd.AddCommand(templateSchemaCmd)
 
 	// Flags for template new
 	templateNewCmd.Flags().StringVarP(&templateNewType, "type", "t", "default", "Scaffold type to use (e.g., default, go, web)")
@@ -128,6 +161,9 @@ func init() {
 	// 'ign update' flags that control project file generation
 	templateUpdateCmd.Flags().BoolVar(&templateUpdateDryRun, "dry-run", false, "Preview ign-template.json changes without writing the file")
 	templateUpdateCmd.Flags().BoolVar(&templateUpdateMerge, "merge", false, "Only add new variables to ign-template.json, preserve existing ones")
+
+	// Flags for template schema
+	templateSchemaCmd.Flags().StringVarP(&templateSchemaOutput, "output", "o", "", "Write the schema to a file instead of stdout")
 }
 
 func runTemplateCheck(cmd *cobra.Command, args []string) error {
@@ -325,3 +361,35 @@ func runTemplateUpdate(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runTemplateSchema(cmd *cobra.Command, args []string) error {
+	// Default to current directory if no path specified
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	schema, err := app.TemplateSchema(cmd.Context(), app.TemplateSchemaOptions{
+		Path: path,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := app.MarshalJSONSchema(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	// The schema goes to stdout unadorned so it can be redirected.
+	if templateSchemaOutput == "" {
+		_, err := cmd.OutOrStdout().Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(templateSchemaOutput, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	printSuccess(fmt.Sprintf("Wrote schema: %s", templateSchemaOutput))
+	return nil
+}
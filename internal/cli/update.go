@@ -2,7 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
@@ -42,8 +45,12 @@ Examples:
   ign update                     # Update if template changed, skip existing files
   ign update ./my-project        # Update to specific directory
   ign update --dry-run           # Preview changes without writing
+  ign update --patch out.diff    # Write the changes as a git-apply patch instead
+  ign update -o --yes --git-branch template-sync --commit
+                                 # Apply on a new git branch and commit the result
   ign update --overwrite         # Selectively overwrite existing files, respecting .ign-overwrite-ignore
   ign update --overwrite --yes   # Selectively overwrite without confirmation
+  ign update -i                  # Review each changed file before applying it
   ign update --overwrite-all     # Overwrite all existing files
   ign update --ref v2.0.0        # Retarget the tracked template ref non-destructively
   ign update --force             # Regenerate even if unchanged and overwrite all existing files`,
@@ -57,12 +64,18 @@ var (
 	updateOverwrite    bool
 	updateOverwriteAll bool
 	updateDryRun       bool
+	updateAllowHooks   bool
 	updateVerbose      bool
 	updateYes          bool
+	updateInteractive  bool
 	updateRef          string
+	updatePatch        string
+	updateGitBranch    string
+	updateCommit       bool
 	prepareUpdate      = app.PrepareUpdate
 	completeUpdate     = app.CompleteUpdate
 	confirmUpdate      = confirmUpdateOverwrite
+	reviewUpdate       = reviewUpdateChanges
 )
 
 func init() {
@@ -70,12 +83,17 @@ func init() {
 	// Note: These flags control project file generation behavior, which differs from
 	// 'ign template update' flags that control template metadata updates
 	updateCmd.Flags().BoolVarP(&updateForce, "force", "f", false, "Regenerate even if template unchanged (implies --overwrite-all)")
-	updateCmd.Flags().BoolVarP(&updateOverwrite, "overwrite", "o", false, "Overwrite existing files except paths matched by the template's .ign-overwrite-ignore")
-	updateCmd.Flags().BoolVar(&updateOverwriteAll, "overwrite-all", false, "Overwrite all existing files, ignoring .ign-overwrite-ignore")
+	updateCmd.Flags().BoolVarP(&updateOverwrite, "overwrite", "o", false, "Overwrite existing files except paths matched by .ign-overwrite-ignore or .ign/overwrite-ignore")
+	updateCmd.Flags().BoolVar(&updateOverwriteAll, "overwrite-all", false, "Overwrite all existing files, ignoring .ign-overwrite-ignore and .ign/overwrite-ignore")
 	updateCmd.Flags().BoolVarP(&updateDryRun, "dry-run", "d", false, "Preview what files would be generated without writing them")
 	updateCmd.Flags().BoolVarP(&updateVerbose, "verbose", "v", false, "Show detailed processing information during project generation")
 	updateCmd.Flags().BoolVarP(&updateYes, "yes", "y", false, "Skip overwrite confirmation prompt")
+	updateCmd.Flags().BoolVarP(&updateInteractive, "interactive", "i", false, "Review each changed file: accept, skip, show diff, or edit (implies --overwrite)")
 	updateCmd.Flags().StringVarP(&updateRef, "ref", "r", "", "Retarget the tracked template branch, tag, or commit SHA")
+	updateCmd.Flags().StringVar(&updateGitBranch, "git-branch", "", "Apply the update on a new git branch and stage its changes (requires a clean worktree)")
+	updateCmd.Flags().BoolVar(&updateCommit, "commit", false, "With --git-branch, commit the staged update with a generated message")
+	updateCmd.Flags().StringVar(&updatePatch, "patch", "", "Write the update as a git-apply patch to FILE ('-' for stdout) without changing files or .ign")
+	updateCmd.Flags().BoolVar(&updateAllowHooks, FlagAllowHooks, false, DescAllowHooks)
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
@@ -94,9 +112,46 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if updatePatch != "" {
+		if updateInteractive || updateDryRun {
+			return fmt.Errorf("--patch cannot be combined with --interactive or --dry-run")
+		}
+		if updatePatch == "-" {
+			// Keep stdout for the patch alone.
+			origQuiet := globalQuiet
+			globalQuiet = true
+			defer func() { globalQuiet = origQuiet }()
+		}
+	}
+	dryRun := updateDryRun || updatePatch != ""
+	if updateCommit && updateGitBranch == "" {
+		return fmt.Errorf("--commit requires --git-branch")
+	}
+	var gitUpdate *app.GitUpdate
+	if updateGitBranch != "" {
+		if dryRun {
+			return fmt.Errorf("--git-branch cannot be combined with --dry-run or --patch")
+		}
+		// Check the repository before fetching so a dirty worktree fails fast.
+		prepared, err := app.PrepareGitUpdate(cmd.Context(), outputPath, updateGitBranch)
+		if err != nil {
+			return err
+		}
+		gitUpdate = prepared
+	}
+
+	if updateInteractive {
+		if updateYes || updateDryRun {
+			return fmt.Errorf("--interactive cannot be combined with --yes or --dry-run")
+		}
+		if !promptInputIsTerminal() {
+			return fmt.Errorf("--interactive requires a terminal")
+		}
+	}
+
 	printInfo("Checking for template updates...")
 
-	overwriteMode := updateOverwriteMode(updateOverwrite, updateOverwriteAll, updateForce)
+	overwriteMode := updateOverwriteMode(updateOverwrite || updateInteractive, updateOverwriteAll, updateForce)
 	shouldOverwrite := overwriteMode != generator.OverwriteNone
 
 	// Prepare update - fetch template and check for changes
@@ -104,7 +159,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		OutputDir:     outputPath,
 		Overwrite:     shouldOverwrite,
 		OverwriteMode: overwriteMode,
-		DryRun:        updateDryRun,
+		DryRun:        dryRun,
 		Verbose:       updateVerbose,
 		GitHubToken:   githubToken,
 		TargetRef:     updateRef,
@@ -126,6 +181,9 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	// By default, unchanged template exits early. --overwrite or --force bypasses this.
 	if !shouldCompleteUpdate(prepResult, updateForce, shouldOverwrite) {
 		printSuccess("Template is up to date (no changes detected)")
+		if updatePatch != "" {
+			return writeUpdatePatch(cmd, updatePatch, "")
+		}
 		return nil
 	}
 
@@ -153,42 +211,30 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Prompt for new variables if any
-	var newVarValues map[string]interface{}
-	if len(prepResult.NewVars) > 0 {
+	if len(prepResult.VariableMigrations) > 0 {
 		printSeparator()
-		printInfo("New variables have been added to the template:")
-
-		// Get variable definitions for new variables
-		newVarDefs := templatedefaults.ResolveVarDefs(app.GetNewVariableDefinitions(prepResult), outputPath)
-
-		// Separate variables into those needing prompt and those with defaults
-		varsNeedingPrompt := app.FilterVariablesForPrompt(newVarDefs)
-
-		// Show variables with defaults
-		for name, varDef := range newVarDefs {
-			if _, needsPrompt := varsNeedingPrompt[name]; !needsPrompt {
-				printInfo(fmt.Sprintf("  + %s (default: %v)", name, varDef.Default))
-			}
+		printInfo("Variable migrations declared by the template:")
+		for _, change := range prepResult.VariableMigrations {
+			printInfo(fmt.Sprintf("  ~ %s", change))
 		}
+	}
 
-		// Prompt for variables that need input
-		if len(varsNeedingPrompt) > 0 {
-			printInfo("")
-			printInfo("Please provide values for the following new variables:")
-			promptedVars, err := PromptForNewVariables(varsNeedingPrompt)
-			if err != nil {
-				return err
-			}
-			newVarValues = app.ApplyDefaults(newVarDefs, promptedVars)
-		} else {
-			// All new variables have defaults
-			newVarValues = app.ApplyDefaults(newVarDefs, nil)
+	if len(prepResult.TemplateMigrations) > 0 {
+		versions := make([]string, 0, len(prepResult.TemplateMigrations))
+		for _, migration := range prepResult.TemplateMigrations {
+			versions = append(versions, migration.Version)
 		}
+		printSeparator()
+		printInfo(fmt.Sprintf("Template migrations from version %s: %s", prepResult.PreviousVersion, strings.Join(versions, ", ")))
+	}
+
+	newVarValues, err := resolveNewUpdateVariables(prepResult, outputPath)
+	if err != nil {
+		return err
 	}
 
 	var executionPlan *app.UpdateExecutionPlan
-	if shouldOverwrite && !updateDryRun && !updateYes {
+	if shouldOverwrite && !dryRun && !updateYes {
 		preview, err := completeUpdate(cmd.Context(), app.CompleteUpdateOptions{
 			PrepareResult: prepResult,
 			NewVariables:  newVarValues,
@@ -203,19 +249,39 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		}
 		executionPlan = preview.ExecutionPlan
 		printUpdateWritePreview(preview)
-		confirmed, err := confirmUpdate()
-		if err != nil {
-			return err
+		if updateInteractive {
+			decisions, err := reviewUpdate(cmd.OutOrStdout(), preview)
+			if err != nil {
+				return err
+			}
+			executionPlan, err = executionPlan.WithDecisions(decisions)
+			if err != nil {
+				return err
+			}
+		} else {
+			confirmed, err := confirmUpdate()
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				printInfo("Update cancelled")
+				return nil
+			}
 		}
-		if !confirmed {
-			printInfo("Update cancelled")
-			return nil
+	}
+
+	if gitUpdate != nil {
+		if err := gitUpdate.CreateBranch(cmd.Context()); err != nil {
+			return err
 		}
+		printInfo(fmt.Sprintf("Switched to new branch %s", gitUpdate.Branch()))
 	}
 
 	// Complete update
 	printSeparator()
-	if updateDryRun {
+	if updatePatch != "" {
+		printInfo("Computing update patch...")
+	} else if updateDryRun {
 		printInfo("[DRY RUN] Would regenerate project from template")
 	} else {
 		printInfo("Regenerating project from template...")
@@ -227,47 +293,176 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		OutputDir:     outputPath,
 		Overwrite:     shouldOverwrite,
 		OverwriteMode: overwriteMode,
-		DryRun:        updateDryRun,
+		DryRun:        dryRun,
 		Verbose:       updateVerbose,
 		ExecutionPlan: executionPlan,
 	})
 
 	if err != nil {
+		if gitUpdate != nil {
+			abandonGitUpdate(cmd, gitUpdate)
+		}
 		return err
 	}
 
+	if updatePatch != "" {
+		patch, err := app.UpdatePatch(outputPath, result)
+		if err != nil {
+			return err
+		}
+		if err := writeUpdatePatch(cmd, updatePatch, patch); err != nil {
+			return err
+		}
+		return unresolvedTransitionError(result)
+	}
+
 	// Print results
 	if updateDryRun {
 		printUpdateDryRunPatch(result)
-	} else {
-		printSuccess("Project updated successfully")
-		printInfo("")
-		printInfo("Summary:")
-		printInfo(fmt.Sprintf("  Created: %d files", result.FilesCreated))
-		if result.FilesSkipped > 0 {
-			printInfo(fmt.Sprintf("  Skipped: %d files (already exist)", result.FilesSkipped))
-		}
-		if result.FilesOverwritten > 0 {
-			printInfo(fmt.Sprintf("  Overwritten: %d files", result.FilesOverwritten))
-		}
-		if result.FilesDeleted > 0 {
-			printInfo(fmt.Sprintf("  Deleted: %d files", result.FilesDeleted))
+		printDryRunHooks(result.Hooks)
+		return unresolvedTransitionError(result)
+	}
+	printUpdateSummary(result, outputPath)
+
+	if err := unresolvedTransitionError(result); err != nil {
+		return err
+	}
+	if gitUpdate != nil {
+		if err := recordGitUpdate(cmd, gitUpdate, prepResult, result, newVarValues); err != nil {
+			return err
 		}
+	}
+	return runTemplateHooks(cmd.Context(), result.Hooks, prepResult.IgnConfig.Template.URL, updateAllowHooks)
+}
 
-		// Print any non-fatal errors
-		if len(result.Errors) > 0 {
-			printWarning(fmt.Sprintf("%d errors occurred during generation:", len(result.Errors)))
-			for _, e := range result.Errors {
-				printWarning(fmt.Sprintf("  - %v", e))
-			}
+// abandonGitUpdate returns to the original branch after a failed update and
+// deletes the branch created for it, or explains how to do so.
+func abandonGitUpdate(cmd *cobra.Command, gitUpdate *app.GitUpdate) {
+	original := gitUpdate.OriginalBranch()
+	if err := gitUpdate.Abandon(cmd.Context()); err != nil {
+		printWarning(fmt.Sprintf("The update failed on branch %s: %v", gitUpdate.Branch(), err))
+		return
+	}
+	printInfo(fmt.Sprintf("Switched back to %s and deleted branch %s", original, gitUpdate.Branch()))
+}
+
+// recordGitUpdate stages the update on its branch and, with --commit,
+// commits it. Template hooks run afterwards, so their output stays unstaged.
+func recordGitUpdate(cmd *cobra.Command, gitUpdate *app.GitUpdate, prep *app.PrepareUpdateResult, result *app.UpdateResult, newVars map[string]interface{}) error {
+	staged, err := gitUpdate.Stage(cmd.Context(), result)
+	if err != nil {
+		return err
+	}
+	printSeparator()
+	if len(staged) == 0 {
+		printInfo(fmt.Sprintf("No changes to stage on branch %s", gitUpdate.Branch()))
+		return nil
+	}
+	printInfo(fmt.Sprintf("Staged %d paths on branch %s", len(staged), gitUpdate.Branch()))
+	if !updateCommit {
+		return nil
+	}
+	commit, err := gitUpdate.Commit(cmd.Context(), app.UpdateCommitMessage(prep, result, newVars))
+	if err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Committed %s on branch %s", commit, gitUpdate.Branch()))
+	return nil
+}
+
+// writeUpdatePatch writes patch to dest, or to stdout when dest is "-".
+func writeUpdatePatch(cmd *cobra.Command, dest, patch string) error {
+	if dest == "-" {
+		_, err := io.WriteString(cmd.OutOrStdout(), patch)
+		return err
+	}
+	if err := os.WriteFile(dest, []byte(patch), 0644); err != nil {
+		return fmt.Errorf("failed to write patch: %w", err)
+	}
+	if patch == "" {
+		printInfo(fmt.Sprintf("No changes; wrote an empty patch to %s", dest))
+	} else {
+		printSuccess(fmt.Sprintf("Update patch written to %s", dest))
+		printInfo("Apply it with 'git apply' from the output directory")
+	}
+	return nil
+}
+
+// resolveNewUpdateVariables collects values for variables the template added
+// since the last checkout, using defaults where declared and prompting otherwise.
+func resolveNewUpdateVariables(prepResult *app.PrepareUpdateResult, outputPath string) (map[string]interface{}, error) {
+	if len(prepResult.NewVars) == 0 {
+		return nil, nil
+	}
+
+	printSeparator()
+	printInfo("New variables have been added to the template:")
+
+	// Get variable definitions for new variables
+	newVarDefs := templatedefaults.ResolveVarDefs(app.GetNewVariableDefinitions(prepResult), outputPath)
+
+	// Separate variables into those needing prompt and those with defaults
+	varsNeedingPrompt := app.FilterVariablesForPrompt(newVarDefs)
+
+	// Show variables with defaults
+	for name, varDef := range newVarDefs {
+		if _, needsPrompt := varsNeedingPrompt[name]; !needsPrompt {
+			printInfo(fmt.Sprintf("  + %s (default: %v)", name, varDef.Default))
 		}
+	}
 
-		printInfo("")
-		printInfo("Configuration updated: .ign/ign.json, .ign/ign-var.json, .ign/ign-files.json")
-		printInfo(fmt.Sprintf("Project ready at: %s", outputPath))
+	// All new variables have defaults
+	if len(varsNeedingPrompt) == 0 {
+		return app.ApplyDefaults(newVarDefs, nil), nil
 	}
 
-	return unresolvedTransitionError(result)
+	// Prompt for variables that need input
+	printInfo("")
+	printInfo("Please provide values for the following new variables:")
+	promptedVars, err := PromptForNewVariables(varsNeedingPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return app.ApplyDefaults(newVarDefs, promptedVars), nil
+}
+
+// printUpdateSummary reports the outcome of a non-dry-run update.
+func printUpdateSummary(result *app.UpdateResult, outputPath string) {
+	printSuccess("Project updated successfully")
+	printInfo("")
+	printInfo("Summary:")
+	printInfo(fmt.Sprintf("  Created: %d files", result.FilesCreated))
+	if result.FilesSkipped > 0 {
+		printInfo(fmt.Sprintf("  Skipped: %d files (already exist)", result.FilesSkipped))
+	}
+	if result.FilesOverwritten > 0 {
+		printInfo(fmt.Sprintf("  Overwritten: %d files", result.FilesOverwritten))
+	}
+	if result.FilesMerged > 0 {
+		printInfo(fmt.Sprintf("  Merged: %d files (updated in place)", result.FilesMerged))
+	}
+	if result.InjectionsApplied > 0 {
+		printInfo(fmt.Sprintf("  Injected: %d edits into existing files", result.InjectionsApplied))
+	}
+	if len(result.MovedFiles) > 0 {
+		printInfo(fmt.Sprintf("  Moved: %d files (renames and template migrations)", len(result.MovedFiles)))
+	}
+	if result.FilesDeleted > 0 {
+		printInfo(fmt.Sprintf("  Deleted: %d files", result.FilesDeleted))
+	}
+
+	// Print any non-fatal errors
+	if len(result.Errors) > 0 {
+		printWarning(fmt.Sprintf("%d errors occurred during generation:", len(result.Errors)))
+		for _, e := range result.Errors {
+			printWarning(fmt.Sprintf("  - %v", e))
+		}
+	}
+
+	printInfo("")
+	printInfo("Configuration updated: .ign/ign.json, .ign/ign-var.json, .ign/ign-files.json")
+	printInfo(fmt.Sprintf("Project ready at: %s", outputPath))
+	printUndoHint(result.HistoryID)
 }
 
 // unresolvedTransitionError fails the command when a managed directory was
@@ -321,12 +516,16 @@ func updateOverwriteMode(overwrite, overwriteAll, force bool) generator.Overwrit
 func printUpdateWritePreview(result *app.UpdateResult) {
 	printSeparator()
 	printInfo("Files to change:")
-	if result == nil || (len(result.DryRunFiles) == 0 && len(result.DeletedFiles) == 0) {
+	if result == nil || (len(result.DryRunFiles) == 0 && len(result.DeletedFiles) == 0 && len(result.MovedFiles) == 0) {
 		printInfo("  (none)")
 		return
 	}
 
 	changed := 0
+	for _, moved := range result.MovedFiles {
+		printInfo(fmt.Sprintf("  R %s", moved))
+		changed++
+	}
 	for _, file := range result.DryRunFiles {
 		if file.WouldSkip {
 			continue
@@ -417,8 +616,11 @@ func printUpdateDryRunPatch(result *app.UpdateResult) {
 	fmt.Println("#")
 
 	// Print variable changes
-	if len(result.NewVariables) > 0 || len(result.RemovedVariables) > 0 {
+	if len(result.NewVariables) > 0 || len(result.RemovedVariables) > 0 || len(result.VariableMigrations) > 0 {
 		fmt.Println("# Variable changes:")
+		for _, change := range result.VariableMigrations {
+			fmt.Printf("#   ~ %s\n", change)
+		}
 		for _, name := range result.NewVariables {
 			fmt.Printf("#   + %s (new)\n", name)
 		}
@@ -428,6 +630,15 @@ func printUpdateDryRunPatch(result *app.UpdateResult) {
 		fmt.Println("#")
 	}
 
+	// Print files renames and template migrations would move
+	if len(result.MovedFiles) > 0 {
+		fmt.Println("# Files to move (renames and template migrations):")
+		for _, moved := range result.MovedFiles {
+			fmt.Printf("#   R %s\n", moved)
+		}
+		fmt.Println("#")
+	}
+
 	// Print directories that would be created
 	if len(result.Directories) > 0 {
 		fmt.Println("# Directories to create:")
@@ -442,6 +653,12 @@ func printUpdateDryRunPatch(result *app.UpdateResult) {
 	if result.FilesOverwritten > 0 {
 		fmt.Printf(", %d to overwrite", result.FilesOverwritten)
 	}
+	if result.FilesMerged > 0 {
+		fmt.Printf(", %d to merge in place", result.FilesMerged)
+	}
+	if result.InjectionsApplied > 0 {
+		fmt.Printf(", %d injections", result.InjectionsApplied)
+	}
 	if result.FilesDeleted > 0 {
 		fmt.Printf(", %d to delete", result.FilesDeleted)
 	}
@@ -463,6 +680,10 @@ func printUpdateDryRunPatch(result *app.UpdateResult) {
 				fmt.Printf("# BLOCKED: %s\n#   %s\n\n", file.Path, diagnostic)
 				continue
 			}
+			if file.ProtectedByProject {
+				fmt.Printf("# SKIP: %s (protected by project)\n\n", file.Path)
+				continue
+			}
 			fmt.Printf("# SKIP: %s (file exists, use --overwrite or --force to overwrite)\n\n", file.Path)
 			continue
 		}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/diff"
+)
+
+// Review actions offered for each change during `ign update -i`.
+const (
+	reviewAccept    = "accept"
+	reviewSkip      = "skip"
+	reviewShowDiff  = "show diff"
+	reviewEdit      = "edit new version"
+	reviewAcceptAll = "accept all remaining"
+)
+
+var (
+	promptReviewAction = promptReviewActionSelect
+	editReviewContent  = editReviewContentInEditor
+)
+
+// reviewedChange is one entry of the update preview offered for review.
+type reviewedChange struct {
+	status string
+	path   string
+	file   *app.DryRunFile
+}
+
+// reviewUpdateChanges walks every change in preview and returns the
+// reviewer's decisions, keyed by path. Accepted changes have no decision.
+// Requested diffs are written to out; with --quiet none are offered.
+func reviewUpdateChanges(out io.Writer, preview *app.UpdateResult) (map[string]app.UpdateFileDecision, error) {
+	changes := reviewedChanges(preview)
+	decisions := make(map[string]app.UpdateFileDecision)
+	printSeparator()
+	if len(changes) == 0 {
+		printInfo("No files to review")
+		return decisions, nil
+	}
+	printInfo(fmt.Sprintf("Reviewing %d changes:", len(changes)))
+
+	skipped, edited := 0, 0
+review:
+	for i, change := range changes {
+		options := []string{reviewAccept, reviewSkip}
+		if !globalQuiet {
+			options = append(options, reviewShowDiff)
+		}
+		if change.editable() {
+			options = append(options, reviewEdit)
+		}
+		options = append(options, reviewAcceptAll)
+
+		message := fmt.Sprintf("[%d/%d] %s %s", i+1, len(changes), change.status, change.path)
+		for {
+			action, err := promptReviewAction(message, options)
+			if err != nil {
+				return nil, err
+			}
+			switch action {
+			case reviewAccept:
+				continue review
+			case reviewSkip:
+				decisions[change.path] = app.UpdateFileDecision{Skip: true}
+				skipped++
+				continue review
+			case reviewShowDiff:
+				if _, err := io.WriteString(out, reviewDiff(change)); err != nil {
+					return nil, err
+				}
+			case reviewEdit:
+				content, err := editReviewContent(change.path, change.file.Content)
+				if err != nil {
+					return nil, err
+				}
+				if bytes.Equal(content, change.file.Content) {
+					continue review
+				}
+				decisions[change.path] = app.UpdateFileDecision{Content: content}
+				edited++
+				continue review
+			case reviewAcceptAll:
+				break review
+			default:
+				return nil, fmt.Errorf("unknown review action %q", action)
+			}
+		}
+	}
+
+	printInfo(fmt.Sprintf("Review complete: %d skipped, %d edited, %d accepted", skipped, edited, len(changes)-skipped-edited))
+	return decisions, nil
+}
+
+// reviewedChanges lists the preview's changes in the order they are shown
+// by printUpdateWritePreview.
+func reviewedChanges(preview *app.UpdateResult) []reviewedChange {
+	if preview == nil {
+		return nil
+	}
+	var changes []reviewedChange
+	for i := range preview.DryRunFiles {
+		file := &preview.DryRunFiles[i]
+		if file.WouldSkip {
+			continue
+		}
+		status := "A"
+		if file.WouldOverwrite {
+			status = "M"
+		}
+		changes = append(changes, reviewedChange{status: status, path: file.Path, file: file})
+	}
+	for _, path := range preview.DeletedFiles {
+		changes = append(changes, reviewedChange{status: "D", path: path})
+	}
+	return changes
+}
+
+// editable reports whether the change has content a reviewer can edit.
+// Deletions, symlinks, and injections into existing files are accept or skip
+// only.
+func (c reviewedChange) editable() bool {
+	return c.file != nil && !c.file.Injected && c.file.SymlinkTarget == ""
+}
+
+// reviewDiff renders the change as a unified diff against the file on disk.
+func reviewDiff(change reviewedChange) string {
+	oldLabel, newLabel := "a/"+change.path, "b/"+change.path
+	var existing []byte
+	if change.file == nil || change.file.Exists {
+		content, err := os.ReadFile(change.path)
+		if err != nil {
+			return fmt.Sprintf("(cannot read %s: %v)\n", change.path, err)
+		}
+		existing = content
+	} else {
+		oldLabel = "/dev/null"
+	}
+
+	var updated []byte
+	switch {
+	case change.file == nil:
+		newLabel = "/dev/null"
+	case change.file.SymlinkTarget != "":
+		return fmt.Sprintf("symlink %s -> %s\n", change.path, change.file.SymlinkTarget)
+	default:
+		updated = change.file.Content
+	}
+
+	out := diff.Unified(oldLabel, newLabel, existing, updated)
+	if out == "" {
+		return "(no content changes)\n"
+	}
+	return out
+}
+
+func promptReviewActionSelect(message string, options []string) (string, error) {
+	var action string
+	prompt := &survey.Select{
+		Message: message,
+		Options: options,
+		Default: reviewAccept,
+	}
+	if err := survey.AskOne(prompt, &action); err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+// editReviewContentInEditor opens content in the user's editor and returns
+// the saved result.
+func editReviewContentInEditor(path string, content []byte) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "ign-review-*"+filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runEditor(tmpPath); err != nil {
+		return nil, fmt.Errorf("editor failed for %s: %w", path, err)
+	}
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited %s: %w", path, err)
+	}
+	return edited, nil
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+func TestRunUpdate_InteractiveReviewsPreviewWithoutConfirmation(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	updateInteractive = true
+	promptInputIsTerminal = func() bool { return true }
+
+	prepareUpdate = func(context.Context, app.UpdateOptions) (*app.PrepareUpdateResult, error) {
+		return &app.PrepareUpdateResult{
+			HashChanged: true,
+			IgnConfig:   &model.IgnConfig{Template: model.TemplateSource{URL: "https://github.com/test/template"}},
+		}, nil
+	}
+	previewPlan := &app.UpdateExecutionPlan{}
+	var calls []app.CompleteUpdateOptions
+	completeUpdate = func(_ context.Context, opts app.CompleteUpdateOptions) (*app.UpdateResult, error) {
+		calls = append(calls, opts)
+		if opts.DryRun {
+			return &app.UpdateResult{ExecutionPlan: previewPlan}, nil
+		}
+		return &app.UpdateResult{}, nil
+	}
+	confirmUpdate = func() (bool, error) {
+		t.Fatal("interactive update should not ask for a blanket confirmation")
+		return false, nil
+	}
+	reviewed := false
+	reviewUpdate = func(io.Writer, *app.UpdateResult) (map[string]app.UpdateFileDecision, error) {
+		reviewed = true
+		return nil, nil
+	}
+
+	if err := runUpdate(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("run update: %v", err)
+	}
+	if !reviewed {
+		t.Fatal("interactive update did not review the preview")
+	}
+	if len(calls) != 2 {
+		t.Fatalf("CompleteUpdate calls = %d, want preview and mutation", len(calls))
+	}
+	if calls[0].OverwriteMode != generator.OverwriteSelective {
+		t.Errorf("OverwriteMode = %q, want selective overwrite implied by --interactive", calls[0].OverwriteMode)
+	}
+	if calls[1].ExecutionPlan != previewPlan {
+		t.Error("confirmed update did not receive the preview execution plan")
+	}
+}
+
+func TestRunUpdate_InteractiveRejectsIncompatibleFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func()
+		terminal bool
+		want     string
+	}{
+		{name: "yes", setup: func() { updateYes = true }, terminal: true, want: "--yes"},
+		{name: "dry run", setup: func() { updateDryRun = true }, terminal: true, want: "--dry-run"},
+		{name: "no terminal", setup: func() {}, terminal: false, want: "terminal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetUpdateCommandDependencies(t)
+			updateInteractive = true
+			tt.setup()
+			terminal := tt.terminal
+			promptInputIsTerminal = func() bool { return terminal }
+			prepareUpdate = func(context.Context, app.UpdateOptions) (*app.PrepareUpdateResult, error) {
+				t.Fatal("update should be rejected before preparing")
+				return nil, nil
+			}
+
+			err := runUpdate(&cobra.Command{}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("runUpdate error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewUpdateChanges(t *testing.T) {
+	originalPrompt := promptReviewAction
+	originalEdit := editReviewContent
+	t.Cleanup(func() {
+		promptReviewAction = originalPrompt
+		editReviewContent = originalEdit
+	})
+
+	dir := t.TempDir()
+	existingPath := filepath.Join(dir, "existing.txt")
+	if err := os.WriteFile(existingPath, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("failed to write existing.txt: %v", err)
+	}
+	preview := &app.UpdateResult{
+		DryRunFiles: []app.DryRunFile{
+			{Path: existingPath, Content: []byte("new\n"), Exists: true, WouldOverwrite: true},
+			{Path: filepath.Join(dir, "skipped.txt"), Content: []byte("skip me\n")},
+			{Path: filepath.Join(dir, "unchanged.txt"), WouldSkip: true},
+			{Path: filepath.Join(dir, "injected.txt"), Content: []byte("injected\n"), Exists: true, WouldOverwrite: true, Injected: true},
+			{Path: filepath.Join(dir, "later.txt"), Content: []byte("later\n")},
+		},
+		DeletedFiles: []string{filepath.Join(dir, "removed.txt")},
+	}
+
+	answers := []string{reviewShowDiff, reviewEdit, reviewSkip, reviewAccept, reviewAcceptAll}
+	var offered [][]string
+	promptReviewAction = func(message string, options []string) (string, error) {
+		offered = append(offered, options)
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+	editReviewContent = func(path string, content []byte) ([]byte, error) {
+		if path != existingPath || string(content) != "new\n" {
+			t.Errorf("edit got %s %q, want existing.txt with the previewed content", path, content)
+		}
+		return []byte("merged by hand\n"), nil
+	}
+
+	var out bytes.Buffer
+	decisions, err := reviewUpdateChanges(&out, preview)
+	if err != nil {
+		t.Fatalf("reviewUpdateChanges() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "-old\n+new\n") {
+		t.Errorf("shown diff = %q, want it written to the command output", out.String())
+	}
+	if len(answers) != 0 {
+		t.Fatalf("%d review answers left unused", len(answers))
+	}
+	if len(decisions) != 2 {
+		t.Fatalf("decisions = %v, want existing.txt and skipped.txt", decisions)
+	}
+	if got := string(decisions[existingPath].Content); got != "merged by hand\n" {
+		t.Errorf("existing.txt decision content = %q, want the edited result", got)
+	}
+	if !decisions[filepath.Join(dir, "skipped.txt")].Skip {
+		t.Error("skipped.txt should be skipped")
+	}
+	// The injection is accept-or-skip only.
+	for _, option := range offered[3] {
+		if option == reviewEdit {
+			t.Errorf("injected change offered %q", reviewEdit)
+		}
+	}
+}
+
+func TestReviewUpdateChanges_QuietOffersNoDiff(t *testing.T) {
+	originalPrompt, originalQuiet := promptReviewAction, globalQuiet
+	t.Cleanup(func() {
+		promptReviewAction, globalQuiet = originalPrompt, originalQuiet
+	})
+	globalQuiet = true
+
+	preview := &app.UpdateResult{
+		DryRunFiles: []app.DryRunFile{{Path: filepath.Join(t.TempDir(), "new.txt"), Content: []byte("new\n")}},
+	}
+	promptReviewAction = func(message string, options []string) (string, error) {
+		for _, option := range options {
+			if option == reviewShowDiff {
+				t.Errorf("quiet review offered %q", reviewShowDiff)
+			}
+		}
+		return reviewAccept, nil
+	}
+	var out bytes.Buffer
+	if _, err := reviewUpdateChanges(&out, preview); err != nil {
+		t.Fatalf("reviewUpdateChanges() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("quiet review wrote %q", out.String())
+	}
+}
+
+func TestReviewDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("a\nb\n"), 0644); err != nil {
+		t.Fatalf("failed to write file.txt: %v", err)
+	}
+
+	got := reviewDiff(reviewedChange{status: "M", path: path, file: &app.DryRunFile{Path: path, Content: []byte("a\nc\n"), Exists: true}})
+	for _, want := range []string{"-b\n", "+c\n", " a\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("modified diff = %q, missing %q", got, want)
+		}
+	}
+
+	got = reviewDiff(reviewedChange{status: "D", path: path})
+	if !strings.Contains(got, "+++ /dev/null") || !strings.Contains(got, "-a\n") {
+		t.Errorf("deletion diff = %q, want removal of every line", got)
+	}
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -50,6 +52,71 @@ func TestRunUpdate_ConfirmationPreviewReusesSymlinkTransitionPlan(t *testing.T)
 	}
 }
 
+func TestRunUpdate_PatchWritesDryRunChangesWithoutConfirmation(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	outputDir := t.TempDir()
+	updateOverwrite = true
+	updatePatch = filepath.Join(t.TempDir(), "update.diff")
+
+	prepareUpdate = func(context.Context, app.UpdateOptions) (*app.PrepareUpdateResult, error) {
+		return &app.PrepareUpdateResult{
+			HashChanged: true,
+			IgnConfig:   &model.IgnConfig{Template: model.TemplateSource{URL: "https://github.com/test/template"}},
+		}, nil
+	}
+	var calls []app.CompleteUpdateOptions
+	completeUpdate = func(_ context.Context, opts app.CompleteUpdateOptions) (*app.UpdateResult, error) {
+		calls = append(calls, opts)
+		return &app.UpdateResult{DryRunFiles: []app.DryRunFile{
+			{Path: filepath.Join(outputDir, "new.txt"), Content: []byte("new\n"), Mode: 0644},
+		}}, nil
+	}
+	confirmUpdate = func() (bool, error) {
+		t.Fatal("--patch should not ask for confirmation")
+		return false, nil
+	}
+
+	if err := runUpdate(&cobra.Command{}, []string{outputDir}); err != nil {
+		t.Fatalf("run update: %v", err)
+	}
+	if len(calls) != 1 || !calls[0].DryRun {
+		t.Fatalf("CompleteUpdate calls = %#v, want a single dry run", calls)
+	}
+	patch, err := os.ReadFile(updatePatch)
+	if err != nil {
+		t.Fatalf("read patch: %v", err)
+	}
+	if !strings.Contains(string(patch), "diff --git a/new.txt b/new.txt\nnew file mode 100644\n") {
+		t.Errorf("patch = %q, want the new file", patch)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("--patch must not write files, stat error = %v", err)
+	}
+}
+
+func TestRunUpdate_PatchRejectsDryRunAndInteractive(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	updatePatch = "-"
+	updateDryRun = true
+	if err := runUpdate(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "--patch") {
+		t.Fatalf("runUpdate error = %v, want --patch conflict", err)
+	}
+}
+
+func TestRunUpdate_GitBranchFlagConflicts(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	updateCommit = true
+	if err := runUpdate(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "--git-branch") {
+		t.Fatalf("runUpdate error = %v, want --commit to require --git-branch", err)
+	}
+
+	updateGitBranch = "template-sync"
+	updateDryRun = true
+	if err := runUpdate(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "--dry-run") {
+		t.Fatalf("runUpdate error = %v, want --git-branch to reject --dry-run", err)
+	}
+}
+
 func TestRunUpdatePassesOutputPathToPrepareAndComplete(t *testing.T) {
 	resetUpdateCommandDependencies(t)
 	updateDryRun = true
@@ -85,24 +152,36 @@ func resetUpdateCommandDependencies(t *testing.T) {
 	originalPrepare := prepareUpdate
 	originalComplete := completeUpdate
 	originalConfirm := confirmUpdate
+	originalReview := reviewUpdate
+	originalTerminal := promptInputIsTerminal
 	originalForce := updateForce
 	originalOverwrite := updateOverwrite
 	originalOverwriteAll := updateOverwriteAll
 	originalDryRun := updateDryRun
 	originalVerbose := updateVerbose
 	originalYes := updateYes
+	originalInteractive := updateInteractive
 	originalRef := updateRef
+	originalPatch := updatePatch
+	originalGitBranch := updateGitBranch
+	originalCommit := updateCommit
 	t.Cleanup(func() {
 		prepareUpdate = originalPrepare
 		completeUpdate = originalComplete
 		confirmUpdate = originalConfirm
+		reviewUpdate = originalReview
+		promptInputIsTerminal = originalTerminal
 		updateForce = originalForce
 		updateOverwrite = originalOverwrite
 		updateOverwriteAll = originalOverwriteAll
 		updateDryRun = originalDryRun
 		updateVerbose = originalVerbose
 		updateYes = originalYes
+		updateInteractive = originalInteractive
 		updateRef = originalRef
+		updatePatch = originalPatch
+		updateGitBranch = originalGitBranch
+		updateCommit = originalCommit
 	})
 }
 
@@ -149,7 +228,9 @@ func TestUpdateCmd_FlagRegistration(t *testing.T) {
 		{"dry-run", "d"},
 		{"verbose", "v"},
 		{"yes", "y"},
+		{"interactive", "i"},
 		{"ref", "r"},
+		{"patch", ""},
 	}
 
 	for _, tt := range tests {
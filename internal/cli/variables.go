@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/tacogips/ign/internal/app"
 	"github.com/tacogips/ign/internal/template/model"
 )
 
@@ -51,21 +52,15 @@ func ValidateVariableAssignmentSyntax(assignments []string) error {
 func parseVariableValue(name string, rawValue string, varDef model.VarDef) (interface{}, error) {
 	switch varDef.Type {
 	case model.VarTypeInt:
-		value, err := strconv.Atoi(rawValue)
+		value, err := app.NumericVariableValue(varDef, rawValue)
 		if err != nil {
-			return nil, fmt.Errorf("variable %q must be an integer: %w", name, err)
+			return nil, fmt.Errorf("variable %q %w", name, err)
 		}
-		if err := validateNumericRange(name, float64(value), varDef); err != nil {
-			return nil, err
-		}
-		return value, nil
+		return int(value), nil
 	case model.VarTypeNumber:
-		value, err := strconv.ParseFloat(rawValue, 64)
+		value, err := app.NumericVariableValue(varDef, rawValue)
 		if err != nil {
-			return nil, fmt.Errorf("variable %q must be a number: %w", name, err)
-		}
-		if err := validateNumericRange(name, value, varDef); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("variable %q %w", name, err)
 		}
 		return value, nil
 	case model.VarTypeBool:
@@ -92,13 +87,3 @@ func parseVariableValue(name string, rawValue string, varDef model.VarDef) (inte
 		return rawValue, nil
 	}
 }
-
-func validateNumericRange(name string, value float64, varDef model.VarDef) error {
-	if varDef.Min != nil && value < *varDef.Min {
-		return fmt.Errorf("variable %q must be >= %v", name, *varDef.Min)
-	}
-	if varDef.Max != nil && value > *varDef.Max {
-		return fmt.Errorf("variable %q must be <= %v", name, *varDef.Max)
-	}
-	return nil
-}
@@ -57,6 +57,7 @@ func TestParseVariableAssignments_Invalid(t *testing.T) {
 	varDefs := map[string]model.VarDef{
 		"name":    {Type: model.VarTypeString, Required: true},
 		"port":    {Type: model.VarTypeInt},
+		"ratio":   {Type: model.VarTypeNumber},
 		"enabled": {Type: model.VarTypeBool},
 	}
 
@@ -70,6 +71,9 @@ func TestParseVariableAssignments_Invalid(t *testing.T) {
 		{name: "empty required string", assignments: []string{"name="}},
 		{name: "invalid int", assignments: []string{"port=abc"}},
 		{name: "invalid bool", assignments: []string{"enabled=maybe"}},
+		{name: "invalid number", assignments: []string{"ratio=half"}},
+		{name: "non-finite number", assignments: []string{"ratio=NaN"}},
+		{name: "infinite number", assignments: []string{"ratio=Inf"}},
 	}
 
 	for _, tt := range tests {
@@ -26,7 +26,9 @@ var varsCmd = &cobra.Command{
 
 The default output is a table with NAME, TYPE, REQUIRED, DEFAULT, CURRENT, and
 DESCRIPTION columns. Use --json for scripting and --unset to show only variables
-without a current value.`,
+without a current value.
+
+Use 'ign vars set', 'ign vars unset', or 'ign vars edit' to change values.`,
 	Args: cobra.NoArgs,
 	RunE: runVars,
 }
@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// Vars modification flags, shared by set, unset, and edit
+var (
+	varsApply       bool
+	varsYes         bool
+	prepareVarsEdit = app.PrepareVarsEdit
+	runVarsEditor   = runEditor
+)
+
+var varsSetCmd = &cobra.Command{
+	Use:   "set NAME=VALUE...",
+	Short: "Set template variable values in .ign/ign-var.json",
+	Long: `Set one or more variable values in .ign/ign-var.json.
+
+Values are parsed and validated against the template's variable declarations
+(type, pattern, and min/max) before anything is written. Use --apply to run the
+update flow afterwards so files rendered from the changed variables are regenerated.
+
+Examples:
+  ign vars set port=8080
+  ign vars set ratio=0.75 debug=true
+  ign vars set app_name=my-app --apply`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runVarsSet,
+}
+
+var varsUnsetCmd = &cobra.Command{
+	Use:   "unset NAME...",
+	Short: "Remove template variable values from .ign/ign-var.json",
+	Long: `Remove one or more variable values from .ign/ign-var.json.
+
+Required variables cannot be unset. Optional variables fall back to their
+template default on the next update. Use --apply to run the update flow afterwards.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runVarsUnset,
+}
+
+var varsEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit .ign/ign-var.json in $EDITOR with validation",
+	Long: `Open the current variable values in $VISUAL or $EDITOR (falling back to vi).
+
+The edited values are validated against the template's variable declarations
+when the editor exits; .ign/ign-var.json is left untouched if validation fails.
+Use --apply to run the update flow afterwards.`,
+	Args: cobra.NoArgs,
+	RunE: runVarsEdit,
+}
+
+func init() {
+	varsCmd.AddCommand(varsSetCmd)
+	varsCmd.AddCommand(varsUnsetCmd)
+	varsCmd.AddCommand(varsEditCmd)
+
+	for _, cmd := range []*cobra.Command{varsSetCmd, varsUnsetCmd, varsEditCmd} {
+		cmd.Flags().BoolVar(&varsApply, "apply", false, "Run the update flow with the new values to regenerate affected files")
+		cmd.Flags().BoolVarP(&varsYes, "yes", "y", false, "Skip the overwrite confirmation prompt when applying")
+	}
+}
+
+func runVarsSet(cmd *cobra.Command, args []string) error {
+	session, err := prepareVarsEdit(cmd.Context(), app.VarsEditOptions{
+		GitHubToken: getGitHubToken(""),
+	})
+	if err != nil {
+		return err
+	}
+
+	assignments, err := ParseVariableAssignments(args, session.VarDefs)
+	if err != nil {
+		return err
+	}
+
+	values, err := session.Set(assignments)
+	if err != nil {
+		return err
+	}
+	if err := session.Save(cmd.Context(), values); err != nil {
+		return err
+	}
+
+	printSuccess(fmt.Sprintf("Set %s in .ign/ign-var.json", strings.Join(sortedVarNames(assignments), ", ")))
+	return applyVarsChanges(cmd)
+}
+
+func runVarsUnset(cmd *cobra.Command, args []string) error {
+	session, err := prepareVarsEdit(cmd.Context(), app.VarsEditOptions{
+		GitHubToken: getGitHubToken(""),
+	})
+	if err != nil {
+		return err
+	}
+
+	values, err := session.Unset(args)
+	if err != nil {
+		return err
+	}
+	if err := session.Save(cmd.Context(), values); err != nil {
+		return err
+	}
+
+	names := append([]string(nil), args...)
+	sort.Strings(names)
+	printSuccess(fmt.Sprintf("Unset %s in .ign/ign-var.json", strings.Join(names, ", ")))
+	return applyVarsChanges(cmd)
+}
+
+func runVarsEdit(cmd *cobra.Command, args []string) error {
+	session, err := prepareVarsEdit(cmd.Context(), app.VarsEditOptions{
+		GitHubToken: getGitHubToken(""),
+	})
+	if err != nil {
+		return err
+	}
+
+	original, err := json.MarshalIndent(&model.IgnVarJson{Variables: session.Current}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode current variables: %w", err)
+	}
+	original = append(original, '\n')
+
+	tmp, err := os.CreateTemp("", "ign-var-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(original); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runVarsEditor(tmpPath); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to read edited variables: %w", err)
+	}
+	if bytes.Equal(edited, original) {
+		printInfo("No changes to .ign/ign-var.json")
+		return nil
+	}
+
+	var ignVar model.IgnVarJson
+	if err := json.Unmarshal(edited, &ignVar); err != nil {
+		return fmt.Errorf("edited variables are not valid JSON; .ign/ign-var.json was not changed: %w", err)
+	}
+	if ignVar.Variables == nil {
+		ignVar.Variables = map[string]interface{}{}
+	}
+
+	if err := session.Save(cmd.Context(), ignVar.Variables); err != nil {
+		return err
+	}
+
+	printSuccess("Updated .ign/ign-var.json")
+	return applyVarsChanges(cmd)
+}
+
+// applyVarsChanges runs the update flow with selective overwrite when --apply is
+// set. The template hash is usually unchanged, so regeneration is driven by the
+// variables themselves; unchanged files are skipped by the generator.
+func applyVarsChanges(cmd *cobra.Command) error {
+	if !varsApply {
+		printInfo("Run 'ign update --overwrite' or pass --apply to regenerate files with the new values")
+		return nil
+	}
+
+	outputPath := "."
+	printSeparator()
+	printInfo("Applying variable changes...")
+
+	prepResult, err := prepareUpdate(cmd.Context(), app.UpdateOptions{
+		OutputDir:     outputPath,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+		GitHubToken:   getGitHubToken(""),
+	})
+	if err != nil {
+		return err
+	}
+
+	newVarValues, err := resolveNewUpdateVariables(prepResult, outputPath)
+	if err != nil {
+		return err
+	}
+
+	var executionPlan *app.UpdateExecutionPlan
+	if !varsYes {
+		preview, err := completeUpdate(cmd.Context(), app.CompleteUpdateOptions{
+			PrepareResult: prepResult,
+			NewVariables:  newVarValues,
+			OutputDir:     outputPath,
+			Overwrite:     true,
+			OverwriteMode: generator.OverwriteSelective,
+			DryRun:        true,
+		})
+		if err != nil {
+			return err
+		}
+		executionPlan = preview.ExecutionPlan
+		printUpdateWritePreview(preview)
+		confirmed, err := confirmUpdate()
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			printInfo("Update cancelled; the new values are saved and will be used by the next 'ign update --overwrite'")
+			return nil
+		}
+	}
+
+	printSeparator()
+	printInfo("Regenerating project from template...")
+	result, err := completeUpdate(cmd.Context(), app.CompleteUpdateOptions{
+		PrepareResult: prepResult,
+		NewVariables:  newVarValues,
+		OutputDir:     outputPath,
+		Overwrite:     true,
+		OverwriteMode: generator.OverwriteSelective,
+		ExecutionPlan: executionPlan,
+	})
+	if err != nil {
+		return err
+	}
+
+	printUpdateSummary(result, outputPath)
+	return unresolvedTransitionError(result)
+}
+
+// runEditor opens path in the user's editor, attached to the terminal.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Editors are commonly configured with arguments, e.g. "code --wait".
+	fields := strings.Fields(editor)
+	editorCmd := exec.Command(fields[0], append(fields[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	return editorCmd.Run()
+}
+
+func sortedVarNames(vars map[string]interface{}) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,11 +2,18 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/tacogips/ign/internal/app"
+	"github.com/tacogips/ign/internal/config"
+	"github.com/tacogips/ign/internal/template/generator"
+	"github.com/tacogips/ign/internal/template/model"
 )
 
 func TestVarsCmd_FlagRegistration(t *testing.T) {
@@ -79,3 +86,141 @@ func TestVarsUnsetRequiredError(t *testing.T) {
 		t.Fatalf("RequiredUnsetCount = %d, want 1", result.RequiredUnsetCount)
 	}
 }
+
+func TestVarsSubcommands_Registered(t *testing.T) {
+	for _, name := range []string{"set", "unset", "edit"} {
+		cmd, _, err := varsCmd.Find([]string{name})
+		if err != nil || cmd.Name() != name {
+			t.Fatalf("vars %s subcommand is not registered", name)
+		}
+		if cmd.Flags().Lookup("apply") == nil {
+			t.Fatalf("vars %s --apply flag is not registered", name)
+		}
+	}
+}
+
+func TestRunVarsSet_ParsesTypesAndApplies(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	ignVarPath := stubVarsEditSession(t, map[string]interface{}{"port": float64(8080)})
+	varsApply = true
+	varsYes = true
+
+	var prepareOpts app.UpdateOptions
+	var completeCalls []app.CompleteUpdateOptions
+	prepareUpdate = func(_ context.Context, opts app.UpdateOptions) (*app.PrepareUpdateResult, error) {
+		prepareOpts = opts
+		return &app.PrepareUpdateResult{
+			IgnConfig: &model.IgnConfig{Template: model.TemplateSource{URL: "https://github.com/test/template"}},
+		}, nil
+	}
+	completeUpdate = func(_ context.Context, opts app.CompleteUpdateOptions) (*app.UpdateResult, error) {
+		completeCalls = append(completeCalls, opts)
+		return &app.UpdateResult{}, nil
+	}
+
+	if err := runVarsSet(&cobra.Command{}, []string{"port=9090", "ratio=0.25"}); err != nil {
+		t.Fatalf("runVarsSet returned error: %v", err)
+	}
+
+	saved, err := config.LoadIgnVarJson(ignVarPath)
+	if err != nil {
+		t.Fatalf("failed to reload ign-var.json: %v", err)
+	}
+	if saved.Variables["port"] != float64(9090) || saved.Variables["ratio"] != 0.25 {
+		t.Fatalf("saved variables = %#v", saved.Variables)
+	}
+	if prepareOpts.OverwriteMode != generator.OverwriteSelective {
+		t.Fatalf("apply OverwriteMode = %v, want selective", prepareOpts.OverwriteMode)
+	}
+	if len(completeCalls) != 1 || completeCalls[0].DryRun {
+		t.Fatalf("CompleteUpdate calls = %#v, want one confirmed mutation", completeCalls)
+	}
+}
+
+func TestRunVarsSet_TypeErrorLeavesFileUntouched(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	ignVarPath := stubVarsEditSession(t, map[string]interface{}{"port": float64(8080)})
+
+	if err := runVarsSet(&cobra.Command{}, []string{"port=eighty"}); err == nil {
+		t.Fatal("runVarsSet accepted a non-integer port")
+	}
+	if _, err := os.Stat(ignVarPath); !os.IsNotExist(err) {
+		t.Fatalf("ign-var.json was written despite the type error: %v", err)
+	}
+}
+
+func TestRunVarsEdit_ValidatesEditedJSON(t *testing.T) {
+	resetUpdateCommandDependencies(t)
+	ignVarPath := stubVarsEditSession(t, map[string]interface{}{"port": float64(8080)})
+
+	runVarsEditor = func(path string) error {
+		return os.WriteFile(path, []byte(`{"variables": {"port": "eighty"}}`), 0644)
+	}
+	err := runVarsEdit(&cobra.Command{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "port: must be an integer") {
+		t.Fatalf("runVarsEdit error = %v, want type error", err)
+	}
+	if _, err := os.Stat(ignVarPath); !os.IsNotExist(err) {
+		t.Fatalf("ign-var.json was written despite the validation error: %v", err)
+	}
+
+	// A quoted numeric string is a valid int, as it is when rendering.
+	runVarsEditor = func(path string) error {
+		return os.WriteFile(path, []byte(`{"variables": {"port": "8080"}}`), 0644)
+	}
+	if err := runVarsEdit(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runVarsEdit rejected a numeric string: %v", err)
+	}
+	saved, err := config.LoadIgnVarJson(ignVarPath)
+	if err != nil {
+		t.Fatalf("failed to reload ign-var.json: %v", err)
+	}
+	if saved.Variables["port"] != "8080" {
+		t.Fatalf("saved port = %#v, want \"8080\"", saved.Variables["port"])
+	}
+
+	runVarsEditor = func(path string) error {
+		return os.WriteFile(path, []byte(`{"variables": {"port": 3000, "ratio": 1.5}}`), 0644)
+	}
+	if err := runVarsEdit(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runVarsEdit returned error: %v", err)
+	}
+	saved, err = config.LoadIgnVarJson(ignVarPath)
+	if err != nil {
+		t.Fatalf("failed to reload ign-var.json: %v", err)
+	}
+	if saved.Variables["port"] != float64(3000) || saved.Variables["ratio"] != 1.5 {
+		t.Fatalf("saved variables = %#v", saved.Variables)
+	}
+}
+
+// stubVarsEditSession replaces template fetching with a fixed session whose
+// ign-var.json lives in a temporary directory that does not exist yet.
+func stubVarsEditSession(t *testing.T, current map[string]interface{}) string {
+	t.Helper()
+	originalPrepare := prepareVarsEdit
+	originalEditor := runVarsEditor
+	originalApply := varsApply
+	originalYes := varsYes
+	t.Cleanup(func() {
+		prepareVarsEdit = originalPrepare
+		runVarsEditor = originalEditor
+		varsApply = originalApply
+		varsYes = originalYes
+	})
+	varsApply = false
+	varsYes = false
+
+	ignVarPath := filepath.Join(t.TempDir(), model.IgnVarFile)
+	prepareVarsEdit = func(context.Context, app.VarsEditOptions) (*app.VarsEditSession, error) {
+		return &app.VarsEditSession{
+			VarDefs: map[string]model.VarDef{
+				"port":  {Type: model.VarTypeInt},
+				"ratio": {Type: model.VarTypeNumber},
+			},
+			Current:    current,
+			IgnVarPath: ignVarPath,
+		}, nil
+	}
+	return ignVarPath
+}
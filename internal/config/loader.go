@@ -236,6 +236,42 @@ func SaveIgnManifestWithResult(path string, manifest *model.IgnManifest) (Atomic
 	return result, nil
 }
 
+// LoadHistorySnapshot loads a snapshot.json from .ign/history/<id>/.
+func LoadHistorySnapshot(path string) (*model.HistorySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NewConfigErrorWithCause(ConfigNotFound, path, "snapshot.json not found", err)
+		}
+		return nil, NewConfigErrorWithCause(ConfigInvalid, path, "failed to read snapshot.json", err)
+	}
+
+	var snapshot model.HistorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, NewConfigErrorWithCause(ConfigInvalid, path, "invalid JSON syntax in snapshot.json", err)
+	}
+	return &snapshot, nil
+}
+
+// SaveHistorySnapshot saves a snapshot.json to the specified path.
+// Security: The path is validated to prevent path traversal attacks.
+func SaveHistorySnapshot(path string, snapshot *model.HistorySnapshot) error {
+	cleanPath := filepath.Clean(path)
+	if strings.Contains(cleanPath, "..") {
+		return NewConfigErrorWithCause(ConfigInvalid, path,
+			"path contains '..' which is not allowed for security reasons", nil)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return NewConfigErrorWithCause(ConfigInvalid, cleanPath, "failed to marshal snapshot.json", err)
+	}
+	if err := writeFileAtomic(cleanPath, data, 0644); err != nil {
+		return NewConfigErrorWithCause(ConfigInvalid, cleanPath, "failed to write snapshot.json", err)
+	}
+	return nil
+}
+
 // LoadIgnConfig loads user project configuration (ign.json) from the specified path.
 // This function reads the user's project configuration file (.ign/ign.json) which contains
 // template source information and template hash. This is DIFFERENT from LoadIgnJson which
@@ -10,6 +10,8 @@ type Config struct {
 	Output OutputConfig `json:"output"`
 	// Defaults configuration for default values.
 	Defaults DefaultsConfig `json:"defaults"`
+	// Hooks configuration for template-declared commands.
+	Hooks HooksConfig `json:"hooks"`
 }
 
 // GitHubConfig represents GitHub-specific settings.
@@ -55,3 +57,10 @@ type DefaultsConfig struct {
 	// OutputDir is the default output directory for ign init.
 	OutputDir string `json:"output_dir"`
 }
+
+// HooksConfig represents settings for template hooks.
+type HooksConfig struct {
+	// TrustedTemplates lists template URLs whose hooks run without a prompt.
+	// An entry also trusts every template below it (e.g. "github.com/owner").
+	TrustedTemplates []string `json:"trusted_templates,omitempty"`
+}
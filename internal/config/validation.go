@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/tacogips/ign/internal/template/model"
@@ -41,7 +44,6 @@ func ValidateIgnJson(ign *model.IgnJson) error {
 	}
 
 	// Validate version format (basic semver check)
-	versionPattern := regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-zA-Z0-9.-]+)?(\+[a-zA-Z0-9.-]+)?$`)
 	if !versionPattern.MatchString(ign.Version) {
 		return NewConfigErrorWithField(
 			ConfigValidationFailed,
@@ -56,6 +58,26 @@ func ValidateIgnJson(ign *model.IgnJson) error {
 		return err
 	}
 
+	// Validate variable migrations against the declared variables
+	if err := validateVariableMigrations(ign.Variables, ign.VariableMigrations); err != nil {
+		return err
+	}
+
+	// Validate versioned migrations against the template version
+	if err := validateTemplateMigrations(ign.Version, ign.Variables, ign.Migrations); err != nil {
+		return err
+	}
+
+	// Validate file rules: conditions against the declared variables, policies, and strategies
+	if err := validateFileRules(ign.Variables, ign.Files); err != nil {
+		return err
+	}
+
+	// Validate hooks: commands, directories, and conditions
+	if err := validateHooks(ign.Variables, ign.Hooks); err != nil {
+		return err
+	}
+
 	// Validate settings if present
 	if ign.Settings != nil {
 		if ign.Settings.MaxIncludeDepth < 0 {
@@ -66,6 +88,12 @@ func ValidateIgnJson(ign *model.IgnJson) error {
 				"max include depth cannot be negative",
 			)
 		}
+		if err := validateTextSettings("settings", ign.Settings.TextSettings); err != nil {
+			return err
+		}
+		if err := validateFormatters(ign.Settings); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -335,6 +363,290 @@ func validateVariables(variables map[string]model.VarDef) error {
 	return nil
 }
 
+// validateVariableMigrations validates the variable_migrations section of template config file.
+func validateVariableMigrations(variables map[string]model.VarDef, migrations []model.VarMigration) error {
+	return validateVarMigrationEntries("variable_migrations", variables, migrations, false)
+}
+
+// validateVarMigrationEntries validates variable migrations under section.
+// Versioned migrations run once, from an older template version, so their
+// targets may be renamed again by a later version and need not be declared.
+func validateVarMigrationEntries(section string, variables map[string]model.VarDef, migrations []model.VarMigration, versioned bool) error {
+	for i, migration := range migrations {
+		field := fmt.Sprintf("%s[%d]", section, i)
+		fail := func(subField, message string) error {
+			fieldPath := field
+			if subField != "" {
+				fieldPath += "." + subField
+			}
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, fieldPath, message)
+		}
+
+		if migration.Variable == "" {
+			return fail("variable", "migration variable is required")
+		}
+		varDef, declared := variables[migration.Variable]
+
+		if migration.Remove {
+			if len(migration.RenamedFrom) > 0 || migration.ConvertFrom != "" || len(migration.ValueMap) > 0 {
+				return fail("remove", "remove cannot be combined with renamed_from, convert_from, or value_map")
+			}
+			if declared {
+				return fail("remove", fmt.Sprintf("cannot remove %s: it is still declared in variables", migration.Variable))
+			}
+			continue
+		}
+
+		if !declared && (!versioned || migration.ConvertFrom != "") {
+			return fail("variable", fmt.Sprintf("migration target %s is not declared in variables", migration.Variable))
+		}
+		if len(migration.RenamedFrom) == 0 && migration.ConvertFrom == "" && len(migration.ValueMap) == 0 {
+			return fail("", "migration must specify renamed_from, convert_from, value_map, or remove")
+		}
+
+		for _, oldName := range migration.RenamedFrom {
+			if oldName == "" || oldName == migration.Variable {
+				return fail("renamed_from", fmt.Sprintf("invalid previous name %q", oldName))
+			}
+			if _, exists := variables[oldName]; exists {
+				return fail("renamed_from", fmt.Sprintf("previous name %s is still declared in variables", oldName))
+			}
+		}
+
+		if migration.ConvertFrom != "" {
+			if err := validateVarType(migration.ConvertFrom); err != nil {
+				return fail("convert_from", err.Error())
+			}
+		}
+
+		for oldValue, newValue := range migration.ValueMap {
+			if declared {
+				if err := validateValueType(newValue, varDef.Type); err != nil {
+					return fail("value_map", fmt.Sprintf("mapped value for %q: %v", oldValue, err))
+				}
+			}
+			if versioned {
+				continue
+			}
+			// Migrations run on every update, so a mapped value must not be
+			// mapped again on the next run.
+			if next, chained := migration.ValueMap[model.ValueMapKey(newValue)]; chained && model.ValueMapKey(next) != model.ValueMapKey(newValue) {
+				return fail("value_map", fmt.Sprintf("mapped value %v for %q is itself a value_map key", newValue, oldValue))
+			}
+		}
+	}
+	return nil
+}
+
+// validateTemplateMigrations validates the migrations section of template
+// config file: versions, file paths, and variable steps.
+func validateTemplateMigrations(templateVersion string, variables map[string]model.VarDef, migrations []model.TemplateMigration) error {
+	seen := make(map[string]struct{}, len(migrations))
+	for i, migration := range migrations {
+		field := fmt.Sprintf("migrations[%d]", i)
+		fail := func(subField, message string) error {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+"."+subField, message)
+		}
+
+		if !versionPattern.MatchString(migration.Version) {
+			return fail("version", fmt.Sprintf("invalid version format: %q (expected semantic version like 1.0.0)", migration.Version))
+		}
+		if CompareVersions(migration.Version, templateVersion) > 0 {
+			return fail("version", fmt.Sprintf("migration version %s is newer than the template version %s", migration.Version, templateVersion))
+		}
+		if _, dup := seen[migration.Version]; dup {
+			return fail("version", fmt.Sprintf("duplicate migration version %s", migration.Version))
+		}
+		seen[migration.Version] = struct{}{}
+
+		if len(migration.Moves) == 0 && len(migration.Deletes) == 0 && len(migration.Variables) == 0 {
+			return fail("", "migration must specify moves, deletes, or variables")
+		}
+		for j, move := range migration.Moves {
+			moveField := fmt.Sprintf("moves[%d]", j)
+			if err := validateMigrationPath(move.From); err != nil {
+				return fail(moveField+".from", err.Error())
+			}
+			if err := validateMigrationPath(move.To); err != nil {
+				return fail(moveField+".to", err.Error())
+			}
+			from, to := path.Clean(filepath.ToSlash(move.From)), path.Clean(filepath.ToSlash(move.To))
+			if from == to || strings.HasPrefix(to, from+"/") || strings.HasPrefix(from, to+"/") {
+				return fail(moveField, fmt.Sprintf("cannot move %s to %s", move.From, move.To))
+			}
+		}
+		for j, deleted := range migration.Deletes {
+			if err := validateMigrationPath(deleted); err != nil {
+				return fail(fmt.Sprintf("deletes[%d]", j), err.Error())
+			}
+		}
+		if err := validateVarMigrationEntries(field+".variables", variables, migration.Variables, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMigrationPath checks that p is a relative template path that stays
+// inside the project and outside .ign.
+func validateMigrationPath(p string) error {
+	if strings.TrimSpace(p) == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+	clean := path.Clean(filepath.ToSlash(p))
+	if filepath.IsAbs(p) || strings.HasPrefix(clean, "/") || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("path %q must be relative to the output directory", p)
+	}
+	if clean == model.IgnConfigDir || strings.HasPrefix(clean, model.IgnConfigDir+"/") {
+		return fmt.Errorf("path %q cannot be inside %s", p, model.IgnConfigDir)
+	}
+	return nil
+}
+
+// validateFileRules validates the files section of template config file.
+func validateFileRules(variables map[string]model.VarDef, files map[string]model.FileRule) error {
+	patterns := make([]string, 0, len(files))
+	for pattern := range files {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		rule := files[pattern]
+		field := fmt.Sprintf("files[%q]", pattern)
+		if strings.TrimSpace(pattern) == "" {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field, "file rule pattern cannot be empty")
+		}
+
+		if rule.Policy != "" && !rule.Policy.IsValid() {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".policy",
+				fmt.Sprintf("invalid policy %q (must be managed, seed, or user)", rule.Policy))
+		}
+		if rule.Strategy != "" && rule.Strategy != model.FileStrategyMerge {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".strategy",
+				fmt.Sprintf("invalid strategy %q (must be merge)", rule.Strategy))
+		}
+		if rule.Arrays != "" {
+			if rule.Strategy != model.FileStrategyMerge {
+				return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".arrays", "arrays requires strategy merge")
+			}
+			if !rule.Arrays.IsValid() {
+				return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".arrays",
+					fmt.Sprintf("invalid array policy %q (must be replace, union, or keep)", rule.Arrays))
+			}
+		}
+		if err := validateTextSettings(field, rule.TextSettings); err != nil {
+			return err
+		}
+		if rule.When == "" && (rule.Policy != "" || rule.Strategy != "" || !rule.TextSettings.IsZero()) {
+			continue
+		}
+
+		if err := validateWhenCondition(variables, field+".when", rule.When); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWhenCondition validates that a "when" condition names a declared
+// bool variable, optionally negated with "!".
+func validateWhenCondition(variables map[string]model.VarDef, field string, when string) error {
+	name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(when), "!"))
+	if name == "" {
+		return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field, "when must name a bool variable")
+	}
+	varDef, declared := variables[name]
+	if !declared {
+		return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field,
+			fmt.Sprintf("condition variable %s is not declared in variables", name))
+	}
+	if varDef.Type != model.VarTypeBool {
+		return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field,
+			fmt.Sprintf("condition variable %s must be bool, got %s", name, varDef.Type))
+	}
+	return nil
+}
+
+// validateTextSettings validates text normalization settings under field.
+func validateTextSettings(field string, text model.TextSettings) error {
+	if text.EOL != "" && !text.EOL.IsValid() {
+		return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".eol",
+			fmt.Sprintf("invalid eol %q (must be lf, crlf, or preserve)", text.EOL))
+	}
+	return nil
+}
+
+// validateFormatters validates settings.formatters and settings.format_errors.
+func validateFormatters(settings *model.TemplateSettings) error {
+	patterns := make([]string, 0, len(settings.Formatters))
+	for pattern := range settings.Formatters {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		field := fmt.Sprintf("settings.formatters[%q]", pattern)
+		if strings.TrimSpace(pattern) == "" {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field,
+				"formatter pattern cannot be empty")
+		}
+		if formatter := settings.Formatters[pattern]; !formatter.IsValid() {
+			return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field,
+				fmt.Sprintf("unknown formatter %q (must be gofmt, sort-imports, or json)", formatter))
+		}
+	}
+	if settings.FormatErrors != "" && !settings.FormatErrors.IsValid() {
+		return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, "settings.format_errors",
+			fmt.Sprintf("invalid format_errors %q (must be warn or fail)", settings.FormatErrors))
+	}
+	return nil
+}
+
+// validateHooks validates the hooks section of template config file.
+func validateHooks(variables map[string]model.VarDef, hooks *model.TemplateHooks) error {
+	if hooks == nil {
+		return nil
+	}
+	for _, stage := range []struct {
+		name  string
+		hooks []model.Hook
+	}{
+		{"post_checkout", hooks.PostCheckout},
+		{"post_update", hooks.PostUpdate},
+	} {
+		for i, hook := range stage.hooks {
+			field := fmt.Sprintf("hooks.%s[%d]", stage.name, i)
+			if strings.TrimSpace(hook.Run) == "" {
+				return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".run", "hook command cannot be empty")
+			}
+			if strings.Contains(hook.Run, "@ign-var:") {
+				// Substituted values would be parsed by the shell.
+				return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".run",
+					"hook command cannot use @ign-var: substitution; read the variable from $IGN_VAR_<NAME> instead")
+			}
+			if hook.Dir != "" {
+				dir := filepath.ToSlash(filepath.Clean(hook.Dir))
+				if filepath.IsAbs(hook.Dir) || strings.HasPrefix(hook.Dir, "/") || dir == ".." || strings.HasPrefix(dir, "../") {
+					return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".dir",
+						fmt.Sprintf("hook directory %q must be relative to the output directory", hook.Dir))
+				}
+			}
+			for name := range hook.Env {
+				if name == "" || strings.ContainsAny(name, "=\x00") {
+					return NewConfigErrorWithField(ConfigValidationFailed, model.IgnTemplateConfigFile, field+".env",
+						fmt.Sprintf("invalid environment variable name %q", name))
+				}
+			}
+			if hook.When != "" {
+				if err := validateWhenCondition(variables, field+".when", hook.When); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // validateVarType validates that a variable type is valid.
 func validateVarType(typ model.VarType) error {
 	switch typ {
@@ -37,6 +37,39 @@ func TestValidateIgnJson(t *testing.T) {
 		}
 	})
 
+	t.Run("unknown settings eol", func(t *testing.T) {
+		ign := &model.IgnJson{
+			Name:     "test",
+			Version:  "1.0.0",
+			Settings: &model.TemplateSettings{TextSettings: model.TextSettings{EOL: "cr"}},
+		}
+		if err := ValidateIgnJson(ign); err == nil {
+			t.Error("Expected error for unknown settings.eol")
+		}
+	})
+
+	t.Run("formatters", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			settings model.TemplateSettings
+			wantErr  bool
+		}{
+			{name: "valid", settings: model.TemplateSettings{Formatters: map[string]model.Formatter{"*.go": model.FormatterGofmt, "*.json": model.FormatterJSON}, FormatErrors: model.FormatErrorsFail}},
+			{name: "unknown formatter", settings: model.TemplateSettings{Formatters: map[string]model.Formatter{"*.py": "black"}}, wantErr: true},
+			{name: "empty pattern", settings: model.TemplateSettings{Formatters: map[string]model.Formatter{" ": model.FormatterGofmt}}, wantErr: true},
+			{name: "unknown format_errors", settings: model.TemplateSettings{FormatErrors: "ignore"}, wantErr: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				settings := tt.settings
+				err := ValidateIgnJson(&model.IgnJson{Name: "test", Version: "1.0.0", Settings: &settings})
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ValidateIgnJson() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+
 	t.Run("missing name", func(t *testing.T) {
 		ign := &model.IgnJson{
 			Version:   "1.0.0",
@@ -116,6 +149,203 @@ func TestValidateIgnJson(t *testing.T) {
 	})
 }
 
+func TestValidateVariableMigrations(t *testing.T) {
+	variables := map[string]model.VarDef{
+		"service_name": {Type: model.VarTypeString, Description: "Service name"},
+		"port":         {Type: model.VarTypeInt, Description: "Port"},
+	}
+
+	tests := []struct {
+		name       string
+		migrations []model.VarMigration
+		wantErr    bool
+	}{
+		{
+			name: "valid migrations",
+			migrations: []model.VarMigration{
+				{Variable: "service_name", RenamedFrom: []string{"app_name"}, ValueMap: map[string]interface{}{"old": "new"}},
+				{Variable: "port", ConvertFrom: model.VarTypeString},
+				{Variable: "legacy", Remove: true},
+			},
+		},
+		{name: "missing variable", migrations: []model.VarMigration{{RenamedFrom: []string{"a"}}}, wantErr: true},
+		{name: "undeclared target", migrations: []model.VarMigration{{Variable: "missing", RenamedFrom: []string{"a"}}}, wantErr: true},
+		{name: "no operation", migrations: []model.VarMigration{{Variable: "port"}}, wantErr: true},
+		{name: "remove declared variable", migrations: []model.VarMigration{{Variable: "port", Remove: true}}, wantErr: true},
+		{name: "remove combined with rename", migrations: []model.VarMigration{{Variable: "legacy", Remove: true, RenamedFrom: []string{"a"}}}, wantErr: true},
+		{name: "renamed from declared variable", migrations: []model.VarMigration{{Variable: "service_name", RenamedFrom: []string{"port"}}}, wantErr: true},
+		{name: "invalid convert_from", migrations: []model.VarMigration{{Variable: "port", ConvertFrom: "float"}}, wantErr: true},
+		{name: "mapped value type mismatch", migrations: []model.VarMigration{{Variable: "port", ValueMap: map[string]interface{}{"http": "80"}}}, wantErr: true},
+		{name: "chained value map", migrations: []model.VarMigration{{Variable: "service_name", ValueMap: map[string]interface{}{"a": "b", "b": "c"}}}, wantErr: true},
+		{name: "chained large number", migrations: []model.VarMigration{{Variable: "port", ValueMap: map[string]interface{}{"0": float64(1000000), "1000000": float64(8080)}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIgnJson(&model.IgnJson{
+				Name:               "test",
+				Version:            "1.0.0",
+				Variables:          variables,
+				VariableMigrations: tt.migrations,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateIgnJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateMigrations(t *testing.T) {
+	variables := map[string]model.VarDef{
+		"service_name": {Type: model.VarTypeString, Description: "Service name"},
+	}
+
+	tests := []struct {
+		name       string
+		migrations []model.TemplateMigration
+		wantErr    bool
+	}{
+		{
+			name: "valid migrations",
+			migrations: []model.TemplateMigration{
+				{Version: "1.5.0", Moves: []model.FileMove{{From: "cmd/server", To: "cmd/api"}}, Deletes: []string{"old.txt"}},
+				// A versioned rename may target a name a later version renames again.
+				{Version: "1.2.0", Variables: []model.VarMigration{{Variable: "app", RenamedFrom: []string{"name"}}}},
+				{Version: "2.0.0", Variables: []model.VarMigration{{Variable: "service_name", RenamedFrom: []string{"app"}}}},
+			},
+		},
+		{name: "invalid version", migrations: []model.TemplateMigration{{Version: "v2", Deletes: []string{"a"}}}, wantErr: true},
+		{name: "newer than template", migrations: []model.TemplateMigration{{Version: "2.0.1", Deletes: []string{"a"}}}, wantErr: true},
+		{name: "duplicate version", migrations: []model.TemplateMigration{{Version: "1.0.0", Deletes: []string{"a"}}, {Version: "1.0.0", Deletes: []string{"b"}}}, wantErr: true},
+		{name: "no steps", migrations: []model.TemplateMigration{{Version: "1.0.0"}}, wantErr: true},
+		{name: "absolute move", migrations: []model.TemplateMigration{{Version: "1.0.0", Moves: []model.FileMove{{From: "/etc/a", To: "b"}}}}, wantErr: true},
+		{name: "move outside project", migrations: []model.TemplateMigration{{Version: "1.0.0", Moves: []model.FileMove{{From: "a", To: "../b"}}}}, wantErr: true},
+		{name: "move into itself", migrations: []model.TemplateMigration{{Version: "1.0.0", Moves: []model.FileMove{{From: "a", To: "a/b"}}}}, wantErr: true},
+		{name: "delete .ign", migrations: []model.TemplateMigration{{Version: "1.0.0", Deletes: []string{".ign/ign.json"}}}, wantErr: true},
+		{name: "convert undeclared", migrations: []model.TemplateMigration{{Version: "1.0.0", Variables: []model.VarMigration{{Variable: "port", ConvertFrom: model.VarTypeString}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIgnJson(&model.IgnJson{
+				Name:       "test",
+				Version:    "2.0.0",
+				Variables:  variables,
+				Migrations: tt.migrations,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateIgnJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"2.0.0-alpha", "2.0.0-alpha.1", -1},
+		{"2.0.0-alpha.2", "2.0.0-alpha.10", -1},
+		{"2.0.0-1", "2.0.0-alpha", -1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"invalid", "0.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := CompareVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestValidateFileRules(t *testing.T) {
+	variables := map[string]model.VarDef{
+		"use_docker": {Type: model.VarTypeBool, Description: "Use Docker"},
+		"port":       {Type: model.VarTypeInt, Description: "Port"},
+	}
+
+	tests := []struct {
+		name    string
+		files   map[string]model.FileRule
+		wantErr bool
+	}{
+		{name: "valid rules", files: map[string]model.FileRule{"docker/**": {When: "use_docker"}, "local.md": {When: "!use_docker"}}},
+		{name: "empty pattern", files: map[string]model.FileRule{" ": {When: "use_docker"}}, wantErr: true},
+		{name: "missing when", files: map[string]model.FileRule{"docker/**": {}}, wantErr: true},
+		{name: "undeclared variable", files: map[string]model.FileRule{"docker/**": {When: "use_k8s"}}, wantErr: true},
+		{name: "non-bool variable", files: map[string]model.FileRule{"docker/**": {When: "port"}}, wantErr: true},
+		{name: "policy only", files: map[string]model.FileRule{"config/**": {Policy: model.FilePolicySeed}, "Makefile": {Policy: model.FilePolicyManaged}}},
+		{name: "policy with condition", files: map[string]model.FileRule{"docker/**": {When: "use_docker", Policy: model.FilePolicyUser}}},
+		{name: "unknown policy", files: map[string]model.FileRule{"config/**": {Policy: "sticky"}}, wantErr: true},
+		{name: "merge strategy", files: map[string]model.FileRule{"package.json": {Strategy: model.FileStrategyMerge, Arrays: model.ArrayMergeUnion}}},
+		{name: "unknown strategy", files: map[string]model.FileRule{"package.json": {Strategy: "patch"}}, wantErr: true},
+		{name: "unknown array policy", files: map[string]model.FileRule{"package.json": {Strategy: model.FileStrategyMerge, Arrays: "zip"}}, wantErr: true},
+		{name: "arrays without merge", files: map[string]model.FileRule{"package.json": {Policy: model.FilePolicyUser, Arrays: model.ArrayMergeKeep}}, wantErr: true},
+		{name: "text settings only", files: map[string]model.FileRule{"*.bat": {TextSettings: model.TextSettings{EOL: model.EOLCRLF}}}},
+		{name: "unknown eol", files: map[string]model.FileRule{"*.bat": {TextSettings: model.TextSettings{EOL: "cr"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIgnJson(&model.IgnJson{
+				Name:      "test",
+				Version:   "1.0.0",
+				Variables: variables,
+				Files:     tt.files,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateIgnJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	variables := map[string]model.VarDef{
+		"use_git": {Type: model.VarTypeBool, Description: "Initialize git"},
+		"port":    {Type: model.VarTypeInt, Description: "Port"},
+	}
+
+	tests := []struct {
+		name    string
+		hooks   *model.TemplateHooks
+		wantErr bool
+	}{
+		{name: "valid hooks", hooks: &model.TemplateHooks{
+			PostCheckout: []model.Hook{{Run: "git init", When: "use_git"}, {Run: "go mod tidy", Dir: "backend", Env: map[string]string{"GOFLAGS": "-mod=mod"}}},
+			PostUpdate:   []model.Hook{{Run: "npm install", When: "!use_git"}},
+		}},
+		{name: "empty command", hooks: &model.TemplateHooks{PostCheckout: []model.Hook{{Run: "  "}}}, wantErr: true},
+		{name: "substitution in command", hooks: &model.TemplateHooks{PostCheckout: []model.Hook{{Run: "go mod init @ign-var:module@"}}}, wantErr: true},
+		{name: "absolute dir", hooks: &model.TemplateHooks{PostUpdate: []model.Hook{{Run: "make", Dir: "/tmp"}}}, wantErr: true},
+		{name: "dir outside output", hooks: &model.TemplateHooks{PostUpdate: []model.Hook{{Run: "make", Dir: "sub/../../x"}}}, wantErr: true},
+		{name: "invalid env name", hooks: &model.TemplateHooks{PostCheckout: []model.Hook{{Run: "make", Env: map[string]string{"A=B": "1"}}}}, wantErr: true},
+		{name: "undeclared condition", hooks: &model.TemplateHooks{PostCheckout: []model.Hook{{Run: "make", When: "use_make"}}}, wantErr: true},
+		{name: "non-bool condition", hooks: &model.TemplateHooks{PostCheckout: []model.Hook{{Run: "make", When: "port"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIgnJson(&model.IgnJson{
+				Name:      "test",
+				Version:   "1.0.0",
+				Variables: variables,
+				Hooks:     tt.hooks,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateIgnJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateVariables(t *testing.T) {
 	t.Run("valid variables", func(t *testing.T) {
 		vars := map[string]model.VarDef{
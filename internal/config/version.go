@@ -0,0 +1,97 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern matches the semantic versions accepted for templates.
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-zA-Z0-9.-]+)?(\+[a-zA-Z0-9.-]+)?$`)
+
+// IsValidVersion reports whether version is a semantic version.
+func IsValidVersion(version string) bool {
+	return versionPattern.MatchString(version)
+}
+
+// CompareVersions compares two semantic versions by precedence and returns
+// -1, 0, or 1. Build metadata is ignored, and a pre-release sorts before its
+// release. Invalid versions sort before valid ones.
+func CompareVersions(a, b string) int {
+	validA, validB := IsValidVersion(a), IsValidVersion(b)
+	switch {
+	case !validA && !validB:
+		return strings.Compare(a, b)
+	case !validA:
+		return -1
+	case !validB:
+		return 1
+	}
+
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for i := range coreA {
+		if c := compareNumeric(coreA[i], coreB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		numA, errA := strconv.ParseUint(idsA[i], 10, 64)
+		numB, errB := strconv.ParseUint(idsB[i], 10, 64)
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareNumeric(numA, numB)
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(idsA[i], idsB[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareNumeric(uint64(len(idsA)), uint64(len(idsB)))
+}
+
+// splitVersion returns the major, minor, and patch numbers and the
+// pre-release of a valid version.
+func splitVersion(version string) ([3]uint64, string) {
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	core, pre, _ := strings.Cut(version, "-")
+	var nums [3]uint64
+	for i, part := range strings.SplitN(core, ".", 3) {
+		// Overflowing numbers saturate, which keeps the ordering sensible.
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			n = ^uint64(0)
+		}
+		nums[i] = n
+	}
+	return nums, pre
+}
+
+func compareNumeric(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,214 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// Unified returns a unified diff turning old into new, or "" when they are
+// equal. oldLabel and newLabel name the two sides in the "---" and "+++"
+// header lines; use "/dev/null" for a side that does not exist. Lines without
+// a final newline are marked with "\ No newline at end of file", so the
+// output can be applied by patch and git apply.
+func Unified(oldLabel, newLabel string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := lineEdits(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for _, h := range hunks(ops) {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldLines), hunkRange(h.newStart, h.newLines))
+		for _, o := range ops[h.first:h.last] {
+			var prefix, line string
+			switch o.kind {
+			case opEqual:
+				prefix, line = " ", a[o.a]
+			case opDelete:
+				prefix, line = "-", a[o.a]
+			case opInsert:
+				prefix, line = "+", b[o.b]
+			}
+			out.WriteString(prefix)
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// splitLines splits content into lines, each keeping its line ending.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is one step of an edit script. a and b index the old and new lines the
+// step consumes; an insert does not consume an old line and vice versa.
+type op struct {
+	kind opKind
+	a, b int
+}
+
+// lineEdits returns the shortest edit script turning a into b, computed with
+// Myers' O(ND) algorithm.
+func lineEdits(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards from the end to recover the path.
+	var reversed []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, op{kind: opEqual, a: x, b: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, op{kind: opInsert, a: x, b: prevY})
+			} else {
+				reversed = append(reversed, op{kind: opDelete, a: prevX, b: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]op, len(reversed))
+	for i, o := range reversed {
+		ops[len(reversed)-1-i] = o
+	}
+	return ops
+}
+
+// hunk is a range of ops printed under one "@@" header.
+type hunk struct {
+	first, last        int // ops[first:last]
+	oldStart, oldLines int
+	newStart, newLines int
+}
+
+// hunks groups changed ops with up to contextLines unchanged lines on each
+// side, merging changes whose context would overlap.
+func hunks(ops []op) []hunk {
+	var result []hunk
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		first := i - contextLines
+		if first < 0 {
+			first = 0
+		}
+		if len(result) > 0 && first <= result[len(result)-1].last {
+			first = result[len(result)-1].first
+			result = result[:len(result)-1]
+		}
+
+		// Extend through changes separated by at most 2*contextLines equal lines.
+		last := i
+		for last < len(ops) {
+			if ops[last].kind != opEqual {
+				last++
+				continue
+			}
+			run := last
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-last > 2*contextLines {
+				break
+			}
+			last = run
+		}
+		i = last
+		last += contextLines
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		h := hunk{first: first, last: last, oldStart: ops[first].a, newStart: ops[first].b}
+		for _, o := range ops[first:last] {
+			if o.kind != opInsert {
+				h.oldLines++
+			}
+			if o.kind != opDelete {
+				h.newLines++
+			}
+		}
+		result = append(result, h)
+	}
+	return result
+}
+
+// hunkRange formats one side of a hunk header. Line numbers are 1-based; an
+// empty range names the line before it, as diff does.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
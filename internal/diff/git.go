@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// File is one side of a Git diff entry.
+type File struct {
+	// Content is the file content, or the link target of a symlink.
+	Content []byte
+	// Mode holds the permission bits of a regular file, or os.ModeSymlink
+	// for a symlink.
+	Mode os.FileMode
+}
+
+// zeroOID is the object id Git writes for a side that does not exist.
+const zeroOID = "0000000000000000000000000000000000000000"
+
+// Git returns the Git extended diff turning old into new at path, or "" when
+// nothing changes. A nil side does not exist. The output carries full object
+// ids and binary literals, so `git apply` accepts it without a repository
+// holding the old blobs. A change between a regular file and a symlink is
+// written as a deletion followed by a creation, as Git does.
+func Git(path string, old, new *File) string {
+	if old != nil && new != nil && old.isSymlink() != new.isSymlink() {
+		return Git(path, old, nil) + Git(path, nil, new)
+	}
+	if old == nil && new == nil {
+		return ""
+	}
+	if old != nil && new != nil && old.gitMode() == new.gitMode() && bytes.Equal(old.Content, new.Content) {
+		return ""
+	}
+
+	oldLabel, newLabel := quotePath("a/"+path), quotePath("b/"+path)
+	var out strings.Builder
+	fmt.Fprintf(&out, "diff --git %s %s\n", oldLabel, newLabel)
+
+	var oldContent, newContent []byte
+	oldOID, newOID := zeroOID, zeroOID
+	switch {
+	case old == nil:
+		fmt.Fprintf(&out, "new file mode %s\n", new.gitMode())
+		oldLabel = "/dev/null"
+	case new == nil:
+		fmt.Fprintf(&out, "deleted file mode %s\n", old.gitMode())
+		newLabel = "/dev/null"
+	case old.gitMode() != new.gitMode():
+		fmt.Fprintf(&out, "old mode %s\nnew mode %s\n", old.gitMode(), new.gitMode())
+	}
+	if old != nil {
+		oldContent, oldOID = old.Content, blobOID(old.Content)
+	}
+	if new != nil {
+		newContent, newOID = new.Content, blobOID(new.Content)
+	}
+	if old != nil && new != nil && bytes.Equal(oldContent, newContent) {
+		// A pure mode change has no content section.
+		return out.String()
+	}
+
+	out.WriteString("index " + oldOID + ".." + newOID)
+	if old != nil && new != nil {
+		out.WriteString(" " + new.gitMode())
+	}
+	out.WriteString("\n")
+
+	if isBinary(oldContent) || isBinary(newContent) {
+		out.WriteString("GIT binary patch\n")
+		out.WriteString(binaryLiteral(newContent))
+		out.WriteString(binaryLiteral(oldContent))
+		return out.String()
+	}
+	out.WriteString(Unified(oldLabel, newLabel, oldContent, newContent))
+	return out.String()
+}
+
+func (f *File) isSymlink() bool {
+	return f.Mode&os.ModeSymlink != 0
+}
+
+// gitMode returns the mode Git records for f. Git tracks only the
+// executable bit of a regular file.
+func (f *File) gitMode() string {
+	switch {
+	case f.isSymlink():
+		return "120000"
+	case f.Mode.Perm()&0111 != 0:
+		return "100755"
+	default:
+		return "100644"
+	}
+}
+
+// blobOID returns the Git blob object id of content.
+func blobOID(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isBinary reports whether content is treated as binary, using Git's rule of
+// a NUL byte within the first 8000 bytes.
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// base85Alphabet is the alphabet of Git's base85 encoding.
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// binaryLiteral encodes content as a "literal" hunk of a Git binary patch:
+// zlib-deflated, split into 52-byte lines, each base85-encoded behind a
+// length character.
+func binaryLiteral(content []byte) string {
+	var deflated bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&deflated, zlib.BestCompression)
+	_, _ = zw.Write(content)
+	_ = zw.Close()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "literal %d\n", len(content))
+	data := deflated.Bytes()
+	for len(data) > 0 {
+		n := min(len(data), 52)
+		if n <= 26 {
+			out.WriteByte(byte('A' + n - 1))
+		} else {
+			out.WriteByte(byte('a' + n - 27))
+		}
+		encodeBase85(&out, data[:n])
+		out.WriteByte('\n')
+		data = data[n:]
+	}
+	out.WriteByte('\n')
+	return out.String()
+}
+
+// encodeBase85 writes data in groups of four bytes, the last one padded with
+// zeros, each as five base85 digits.
+func encodeBase85(out *strings.Builder, data []byte) {
+	for len(data) > 0 {
+		var acc uint32
+		for i := 0; i < 4; i++ {
+			acc <<= 8
+			if i < len(data) {
+				acc |= uint32(data[i])
+			}
+		}
+		var group [5]byte
+		for i := 4; i >= 0; i-- {
+			group[i] = base85Alphabet[acc%85]
+			acc /= 85
+		}
+		out.Write(group[:])
+		data = data[min(len(data), 4):]
+	}
+}
+
+// quotePath quotes path the way Git does when it holds a double quote,
+// backslash, control character or non-ASCII byte.
+func quotePath(path string) string {
+	needsQuote := false
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c == '"' || c == '\\' || c < 0x20 || c >= 0x7f {
+			needsQuote = true
+			break
+		}
+	}
+	if !needsQuote {
+		return path
+	}
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\n':
+			out.WriteString(`\n`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&out, "\\%03o", c)
+			} else {
+				out.WriteByte(c)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
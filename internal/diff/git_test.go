@@ -0,0 +1,148 @@
+package diff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGit(t *testing.T) {
+	const (
+		oidA    = "78981922613b2afb6025042ff6bd878ac1994e85"
+		oidB    = "61780798228d17af2d34fce4cfbdf35556832472"
+		oidLink = "e0e63473c2593040d7d1c67637864821b28cef4b"
+	)
+	tests := []struct {
+		name string
+		old  *File
+		new  *File
+		want string
+	}{
+		{name: "unchanged", old: &File{Content: []byte("a\n"), Mode: 0644}, new: &File{Content: []byte("a\n"), Mode: 0600}, want: ""},
+		{
+			name: "create",
+			new:  &File{Content: []byte("a\n"), Mode: 0755},
+			want: "diff --git a/f b/f\nnew file mode 100755\nindex " + zeroOID + ".." + oidA + "\n--- /dev/null\n+++ b/f\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name: "delete",
+			old:  &File{Content: []byte("a\n"), Mode: 0644},
+			want: "diff --git a/f b/f\ndeleted file mode 100644\nindex " + oidA + ".." + zeroOID + "\n--- a/f\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "modify",
+			old:  &File{Content: []byte("a\n"), Mode: 0644},
+			new:  &File{Content: []byte("b\n"), Mode: 0644},
+			want: "diff --git a/f b/f\nindex " + oidA + ".." + oidB + " 100644\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name: "mode only",
+			old:  &File{Content: []byte("a\n"), Mode: 0755},
+			new:  &File{Content: []byte("a\n"), Mode: 0644},
+			want: "diff --git a/f b/f\nold mode 100755\nnew mode 100644\n",
+		},
+		{
+			name: "file to symlink",
+			old:  &File{Content: []byte("a\n"), Mode: 0644},
+			new:  &File{Content: []byte("run.sh"), Mode: os.ModeSymlink},
+			want: "diff --git a/f b/f\ndeleted file mode 100644\nindex " + oidA + ".." + zeroOID + "\n--- a/f\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n" +
+				"diff --git a/f b/f\nnew file mode 120000\nindex " + zeroOID + ".." + oidLink + "\n--- /dev/null\n+++ b/f\n@@ -0,0 +1 @@\n+run.sh\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Git("f", tt.old, tt.new); got != tt.want {
+				t.Errorf("Git() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGit_BinaryLiteralRoundTrips(t *testing.T) {
+	old := bytes.Repeat([]byte("old\x00"), 40)
+	updated := bytes.Repeat([]byte{0, 1, 2, 250}, 100)
+
+	got := Git("data.bin", &File{Content: old, Mode: 0644}, &File{Content: updated, Mode: 0644})
+	header, body, ok := strings.Cut(got, "GIT binary patch\n")
+	if !ok {
+		t.Fatalf("Git() = %q, want a binary patch", got)
+	}
+	if !strings.Contains(header, "index "+blobOID(old)+".."+blobOID(updated)+" 100644\n") {
+		t.Errorf("header = %q, want full object ids", header)
+	}
+
+	forward, rest := decodeLiteral(t, body)
+	reverse, rest := decodeLiteral(t, rest)
+	if rest != "" {
+		t.Errorf("unexpected trailing patch text %q", rest)
+	}
+	if !bytes.Equal(forward, updated) {
+		t.Errorf("forward literal = %v, want the new content", forward)
+	}
+	if !bytes.Equal(reverse, old) {
+		t.Errorf("reverse literal = %v, want the old content", reverse)
+	}
+}
+
+func TestQuotePath(t *testing.T) {
+	tests := map[string]string{
+		"a/plain name.txt": "a/plain name.txt",
+		`a/"quoted"`:       `"a/\"quoted\""`,
+		"a/tab\there":      `"a/tab\there"`,
+		"a/café":           `"a/caf\303\251"`,
+	}
+	for path, want := range tests {
+		if got := quotePath(path); got != want {
+			t.Errorf("quotePath(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+// decodeLiteral reads one "literal" hunk from a Git binary patch and returns
+// its inflated content and the remaining text.
+func decodeLiteral(t *testing.T, patch string) ([]byte, string) {
+	t.Helper()
+	header, rest, _ := strings.Cut(patch, "\n")
+	size, err := strconv.Atoi(strings.TrimPrefix(header, "literal "))
+	if err != nil {
+		t.Fatalf("bad literal header %q", header)
+	}
+	var deflated []byte
+	for {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if line == "" {
+			break
+		}
+		n := int(line[0]-'A') + 1
+		if line[0] >= 'a' {
+			n = int(line[0]-'a') + 27
+		}
+		var chunk []byte
+		for i := 1; i < len(line); i += 5 {
+			var acc uint32
+			for _, c := range []byte(line[i : i+5]) {
+				acc = acc*85 + uint32(strings.IndexByte(base85Alphabet, c))
+			}
+			chunk = append(chunk, byte(acc>>24), byte(acc>>16), byte(acc>>8), byte(acc))
+		}
+		deflated = append(deflated, chunk[:n]...)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(deflated))
+	if err != nil {
+		t.Fatalf("inflate literal: %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("inflate literal: %v", err)
+	}
+	if len(content) != size {
+		t.Fatalf("literal size = %d, header says %d", len(content), size)
+	}
+	return content, rest
+}
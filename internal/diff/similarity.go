@@ -0,0 +1,23 @@
+package diff
+
+// Similarity returns how much of a and b is shared, from 0 (nothing) to 1
+// (equal). It counts the bytes of the lines both contents contain, in any
+// order, so moving blocks around keeps the score high while edited lines
+// lower it. Two empty contents are equal.
+func Similarity(a, b []byte) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	counts := make(map[string]int)
+	for _, line := range splitLines(a) {
+		counts[line]++
+	}
+	shared := 0
+	for _, line := range splitLines(b) {
+		if counts[line] > 0 {
+			counts[line]--
+			shared += len(line)
+		}
+	}
+	return float64(2*shared) / float64(len(a)+len(b))
+}
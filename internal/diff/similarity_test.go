@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "both empty", a: "", b: "", want: 1},
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: 1},
+		{name: "one empty", a: "a\n", b: "", want: 0},
+		{name: "disjoint", a: "a\n", b: "b\n", want: 0},
+		{name: "reordered", a: "a\nb\n", b: "b\na\n", want: 1},
+		{name: "one of two lines changed", a: "aaaa\nbbbb\n", b: "aaaa\ncccc\n", want: 0.5},
+		{name: "duplicate lines count once each", a: "x\nx\n", b: "x\n", want: 2.0 * 2 / 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Similarity([]byte(tt.a), []byte(tt.b)); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tacogips/ign/internal/debug"
+)
+
+// ArchiveFormat is the container format of an ArchiveWriter.
+type ArchiveFormat string
+
+const (
+	// ArchiveTar writes an uncompressed tarball.
+	ArchiveTar ArchiveFormat = "tar"
+	// ArchiveZip writes a zip file.
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// IsValid reports whether f is a supported archive format.
+func (f ArchiveFormat) IsValid() bool {
+	return f == ArchiveTar || f == ArchiveZip
+}
+
+// ArchiveWriter implements Writer by streaming entries into a tar or zip
+// archive instead of the filesystem. Paths are stored relative, as given, so
+// extracting the archive reproduces the tree a FileWriter would have written.
+// The archive starts empty: Exists only reports entries written through the
+// ArchiveWriter itself. Close must be called to finish the archive.
+type ArchiveWriter struct {
+	format             ArchiveFormat
+	tw                 *tar.Writer
+	zw                 *zip.Writer
+	preserveExecutable bool
+	modTime            time.Time
+	entries            map[string]bool // entry name -> is directory
+}
+
+// NewArchiveWriter creates an ArchiveWriter that writes to out.
+// preserveExecutable has the same meaning as for NewFileWriter.
+func NewArchiveWriter(out io.Writer, format ArchiveFormat, preserveExecutable bool) (*ArchiveWriter, error) {
+	w := &ArchiveWriter{
+		format:             format,
+		preserveExecutable: preserveExecutable,
+		modTime:            time.Now(),
+		entries:            make(map[string]bool),
+	}
+	switch format {
+	case ArchiveTar:
+		w.tw = tar.NewWriter(out)
+	case ArchiveZip:
+		w.zw = zip.NewWriter(out)
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q (must be tar or zip)", format)
+	}
+	return w, nil
+}
+
+// WriteFile adds a regular file entry, adding entries for missing parent
+// directories first.
+func (w *ArchiveWriter) WriteFile(filePath string, content []byte, mode os.FileMode) error {
+	debug.Debug("[generator] Archiving file: %s (size: %d bytes, mode: %o)", filePath, len(content), mode)
+
+	name, err := w.entryName(filePath)
+	if err != nil {
+		return err
+	}
+	if _, exists := w.entries[name]; exists {
+		return newGeneratorError(GeneratorWriteFailed, "archive entry already written", filePath, nil)
+	}
+	if err := w.addParents(name); err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to archive parent directory", filePath, err)
+	}
+
+	fileMode := effectiveWriteFileMode(mode, w.preserveExecutable)
+	if w.tw != nil {
+		err = w.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(fileMode.Perm()),
+			Size:     int64(len(content)),
+			ModTime:  w.modTime,
+		})
+		if err == nil {
+			_, err = w.tw.Write(content)
+		}
+	} else {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.modTime}
+		header.SetMode(fileMode.Perm())
+		var entry io.Writer
+		entry, err = w.zw.CreateHeader(header)
+		if err == nil {
+			_, err = entry.Write(content)
+		}
+	}
+	if err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to write archive entry", filePath, err)
+	}
+	w.entries[name] = false
+	return nil
+}
+
+// WriteSymlink adds a symbolic link entry pointing to target.
+func (w *ArchiveWriter) WriteSymlink(linkPath string, target string) error {
+	debug.Debug("[generator] Archiving symlink: %s -> %s", linkPath, target)
+
+	name, err := w.entryName(linkPath)
+	if err != nil {
+		return err
+	}
+	if _, exists := w.entries[name]; exists {
+		return newGeneratorError(GeneratorWriteFailed, "archive entry already written", linkPath, nil)
+	}
+	if err := w.addParents(name); err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to archive parent directory for symlink", linkPath, err)
+	}
+
+	if w.tw != nil {
+		err = w.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeSymlink,
+			Name:     name,
+			Linkname: target,
+			Mode:     0777,
+			ModTime:  w.modTime,
+		})
+	} else {
+		// Zip stores a symlink as an entry whose mode says so and whose content
+		// is the target, which is what unzip and most extractors expect.
+		header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: w.modTime}
+		header.SetMode(os.ModeSymlink | 0777)
+		var entry io.Writer
+		entry, err = w.zw.CreateHeader(header)
+		if err == nil {
+			_, err = io.WriteString(entry, target)
+		}
+	}
+	if err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to write archive symlink", linkPath, err)
+	}
+	w.entries[name] = false
+	return nil
+}
+
+// ReplaceDirectoryWithSymlink is not supported: an archive never contains a
+// directory that existed before generation.
+func (w *ArchiveWriter) ReplaceDirectoryWithSymlink(linkPath string, target string) error {
+	return newGeneratorError(GeneratorWriteFailed,
+		"directory-to-symlink transitions are not supported in archives",
+		linkPath,
+		nil)
+}
+
+// CreateDir adds entries for a directory and its missing parents.
+func (w *ArchiveWriter) CreateDir(dirPath string) error {
+	name, err := w.entryName(dirPath)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	if err := w.addParents(name); err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to archive directory", dirPath, err)
+	}
+	if err := w.addDir(name); err != nil {
+		return newGeneratorError(GeneratorWriteFailed, "failed to archive directory", dirPath, err)
+	}
+	return nil
+}
+
+// Exists reports whether an entry has been written at the given path.
+func (w *ArchiveWriter) Exists(entryPath string) bool {
+	name, err := w.entryName(entryPath)
+	if err != nil {
+		return false
+	}
+	if name == "." {
+		return true
+	}
+	_, exists := w.entries[name]
+	return exists
+}
+
+// ReadFile fails: archive entries are streamed out as they are written and
+// cannot be read back. A path that was never written does not exist.
+func (w *ArchiveWriter) ReadFile(entryPath string) ([]byte, os.FileMode, error) {
+	if !w.Exists(entryPath) {
+		return nil, 0, &os.PathError{Op: "read", Path: entryPath, Err: os.ErrNotExist}
+	}
+	return nil, 0, newGeneratorError(GeneratorWriteFailed, "archive entries cannot be read back", entryPath, nil)
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (w *ArchiveWriter) Close() error {
+	if w.tw != nil {
+		return w.tw.Close()
+	}
+	return w.zw.Close()
+}
+
+// entryName converts an output path to a slash-separated archive name.
+// Absolute paths and paths leaving the archive root are rejected.
+func (w *ArchiveWriter) entryName(outputPath string) (string, error) {
+	name := path.Clean(filepath.ToSlash(outputPath))
+	if path.IsAbs(name) || filepath.IsAbs(outputPath) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", newGeneratorError(GeneratorWriteFailed, "archive paths must be relative to the archive root", outputPath, nil)
+	}
+	return name, nil
+}
+
+func (w *ArchiveWriter) addParents(name string) error {
+	dir := path.Dir(name)
+	if dir == "." {
+		return nil
+	}
+	if isDir, exists := w.entries[dir]; exists {
+		if !isDir {
+			return fmt.Errorf("%s is a file in the archive", dir)
+		}
+		return nil
+	}
+	if err := w.addParents(dir); err != nil {
+		return err
+	}
+	return w.addDir(dir)
+}
+
+func (w *ArchiveWriter) addDir(name string) error {
+	if isDir, exists := w.entries[name]; exists {
+		if !isDir {
+			return fmt.Errorf("%s is a file in the archive", name)
+		}
+		return nil
+	}
+
+	var err error
+	if w.tw != nil {
+		err = w.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name + "/",
+			Mode:     0755,
+			ModTime:  w.modTime,
+		})
+	} else {
+		header := &zip.FileHeader{Name: name + "/", Modified: w.modTime}
+		header.SetMode(os.ModeDir | 0755)
+		_, err = w.zw.CreateHeader(header)
+	}
+	if err != nil {
+		return err
+	}
+	w.entries[name] = true
+	return nil
+}
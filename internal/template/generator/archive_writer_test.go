@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestArchiveWriter_Tar(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewArchiveWriter(&buf, ArchiveTar, true)
+	if err != nil {
+		t.Fatalf("NewArchiveWriter() error = %v", err)
+	}
+	if err := w.CreateDir("proj"); err != nil {
+		t.Fatalf("CreateDir() error = %v", err)
+	}
+	if err := w.WriteFile("proj/cmd/app/main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := w.WriteFile("proj/run.sh", []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := w.WriteSymlink("proj/latest", "cmd/app"); err != nil {
+		t.Fatalf("WriteSymlink() error = %v", err)
+	}
+	if !w.Exists("proj/cmd/app/main.go") || !w.Exists("proj/cmd") || w.Exists("proj/missing") {
+		t.Error("Exists() should report only archived entries")
+	}
+	if _, _, err := w.ReadFile("proj/missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile() of a missing entry error = %v, want not exist", err)
+	}
+	if err := w.WriteFile("proj/run.sh", []byte("again"), 0644); err == nil {
+		t.Error("WriteFile() should reject a duplicate entry")
+	}
+	if err := w.WriteFile("../escape", []byte("x"), 0644); err == nil {
+		t.Error("WriteFile() should reject paths outside the archive root")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	type entry struct {
+		typeflag byte
+		mode     int64
+		content  string
+		link     string
+	}
+	got := map[string]entry{}
+	var order []string
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		got[header.Name] = entry{header.Typeflag, header.Mode, string(content), header.Linkname}
+		order = append(order, header.Name)
+	}
+
+	want := []string{"proj/", "proj/cmd/", "proj/cmd/app/", "proj/cmd/app/main.go", "proj/run.sh", "proj/latest"}
+	if len(order) != len(want) {
+		t.Fatalf("tar entries = %v, want %v", order, want)
+	}
+	for i, name := range want {
+		if order[i] != name {
+			t.Fatalf("tar entries = %v, want %v", order, want)
+		}
+	}
+	if e := got["proj/cmd/app/main.go"]; e.typeflag != tar.TypeReg || e.content != "package main\n" || e.mode != 0644 {
+		t.Errorf("main.go entry = %+v", e)
+	}
+	if e := got["proj/run.sh"]; e.mode != 0755 {
+		t.Errorf("run.sh mode = %o, want 755", e.mode)
+	}
+	if e := got["proj/latest"]; e.typeflag != tar.TypeSymlink || e.link != "cmd/app" {
+		t.Errorf("symlink entry = %+v", e)
+	}
+}
+
+func TestArchiveWriter_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewArchiveWriter(&buf, ArchiveZip, false)
+	if err != nil {
+		t.Fatalf("NewArchiveWriter() error = %v", err)
+	}
+	if err := w.WriteFile("docs/README.md", []byte("# hi\n"), 0755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := w.WriteSymlink("link", "docs/README.md"); err != nil {
+		t.Fatalf("WriteSymlink() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if f := files["docs/"]; f == nil || !f.Mode().IsDir() {
+		t.Error("zip should contain the parent directory entry")
+	}
+	readme := files["docs/README.md"]
+	if readme == nil {
+		t.Fatal("zip should contain docs/README.md")
+	}
+	if readme.Mode().Perm() != 0644 {
+		t.Errorf("README.md mode = %o, want 644 without preserve_executable", readme.Mode().Perm())
+	}
+	rc, err := readme.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(content) != "# hi\n" {
+		t.Errorf("README.md content = %q", content)
+	}
+	if link := files["link"]; link == nil || link.Mode()&os.ModeSymlink == 0 {
+		t.Error("zip should store link as a symlink entry")
+	}
+}
+
+func TestNewArchiveWriter_RejectsUnknownFormat(t *testing.T) {
+	if _, err := NewArchiveWriter(io.Discard, "rar", true); err == nil {
+		t.Error("NewArchiveWriter() should reject an unknown format")
+	}
+}
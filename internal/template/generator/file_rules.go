@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+// FileConditionsMet reports whether a template path satisfies every "when"
+// condition of the ign-template.json "files" rules that match it. Paths are
+// template paths (before filename substitution), so conditions do not depend
+// on how variables rename the output.
+//
+// Rules use the gitignore-style matching of .ign-overwrite-ignore: "docker/**",
+// "docker/", and "/docker" all cover a whole directory.
+func FileConditionsMet(template *model.Template, path string, vars parser.Variables) (bool, error) {
+	if template == nil || len(template.Config.Files) == 0 {
+		return true, nil
+	}
+
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+
+	// Evaluate rules in a stable order so error messages are deterministic.
+	patterns := make([]string, 0, len(template.Config.Files))
+	for pattern := range template.Config.Files {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		rule := template.Config.Files[pattern]
+		if rule.When == "" || !matchesIgnorePattern(path, filepath.ToSlash(strings.TrimSpace(pattern))) {
+			continue
+		}
+		met, err := evaluateWhen(rule.When, vars)
+		if err != nil {
+			return false, fmt.Errorf("files rule %q: %w", pattern, err)
+		}
+		if !met {
+			debug.Debug("[generator] Condition %q not met for %s (rule: %s)", rule.When, path, pattern)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FilePolicyFor returns the update policy declared for a template path by the
+// ign-template.json "files" rules. Paths without a declared policy return "".
+func FilePolicyFor(template *model.Template, path string) model.FilePolicy {
+	rule, ok := mostSpecificRule(template, path, func(rule model.FileRule) bool { return rule.Policy != "" })
+	if !ok {
+		return ""
+	}
+	return rule.Policy
+}
+
+// MergeStrategyFor reports whether a template path has a "merge" strategy rule
+// and returns its array policy, defaulting to replace.
+func MergeStrategyFor(template *model.Template, path string) (model.ArrayMergePolicy, bool) {
+	rule, ok := mostSpecificRule(template, path, func(rule model.FileRule) bool { return rule.Strategy != "" })
+	if !ok || rule.Strategy != model.FileStrategyMerge {
+		return "", false
+	}
+	if rule.Arrays == "" {
+		return model.ArrayMergeReplace, true
+	}
+	return rule.Arrays, true
+}
+
+// TextSettingsFor returns the text normalization settings of a template path:
+// the template-wide settings overlaid by every matching "files" rule, from the
+// least to the most specific pattern.
+func TextSettingsFor(template *model.Template, path string) model.TextSettings {
+	if template == nil {
+		return model.TextSettings{}
+	}
+	var text model.TextSettings
+	if template.Config.Settings != nil {
+		text = template.Config.Settings.TextSettings
+	}
+	if len(template.Config.Files) == 0 {
+		return text
+	}
+
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+	patterns := make([]string, 0, len(template.Config.Files))
+	for pattern, rule := range template.Config.Files {
+		if !rule.TextSettings.IsZero() && matchesIgnorePattern(path, filepath.ToSlash(strings.TrimSpace(pattern))) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) < len(patterns[j])
+		}
+		return patterns[i] > patterns[j]
+	})
+	for _, pattern := range patterns {
+		text = text.Overlay(template.Config.Files[pattern].TextSettings)
+	}
+	return text
+}
+
+// mostSpecificRule returns the matching rule, among those selected by has,
+// with the longest pattern, so that "config/local.yaml" can refine
+// "config/**".
+func mostSpecificRule(template *model.Template, path string, has func(model.FileRule) bool) (model.FileRule, bool) {
+	if template == nil || len(template.Config.Files) == 0 {
+		return model.FileRule{}, false
+	}
+
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+
+	var found model.FileRule
+	matched := ""
+	ok := false
+	for pattern, rule := range template.Config.Files {
+		if !has(rule) || !matchesIgnorePattern(path, filepath.ToSlash(strings.TrimSpace(pattern))) {
+			continue
+		}
+		if !ok || len(pattern) > len(matched) || (len(pattern) == len(matched) && pattern < matched) {
+			found = rule
+			matched = pattern
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// policyKeepsPath reports whether a file policy forbids writing an output path.
+// user files are never overwritten once they exist; seed files are also not
+// recreated after the project has removed them.
+func policyKeepsPath(policy model.FilePolicy, outputPath string, exists bool, seededPaths map[string]struct{}) bool {
+	switch policy {
+	case model.FilePolicyUser:
+		return exists
+	case model.FilePolicySeed:
+		if exists {
+			return true
+		}
+		if len(seededPaths) == 0 {
+			return false
+		}
+		absPath, err := filepath.Abs(outputPath)
+		if err != nil {
+			return false
+		}
+		_, seeded := seededPaths[filepath.Clean(absPath)]
+		return seeded
+	default:
+		return false
+	}
+}
+
+// evaluateWhen evaluates a "when" condition: a bool variable name with an
+// optional leading "!".
+func evaluateWhen(when string, vars parser.Variables) (bool, error) {
+	name := strings.TrimSpace(when)
+	negate := strings.HasPrefix(name, "!")
+	if negate {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "!"))
+	}
+
+	value, err := vars.GetBool(name)
+	if err != nil {
+		return false, fmt.Errorf("condition variable must be boolean: %s (%v)", name, err)
+	}
+	return value != negate, nil
+}
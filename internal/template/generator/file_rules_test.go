@@ -0,0 +1,248 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func conditionalTestTemplate(rootPath string) *model.Template {
+	return &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Files: map[string]model.FileRule{
+				"docker/**":       {When: "use_docker"},
+				"Dockerfile":      {When: "use_docker"},
+				"docs/local.md":   {When: "!use_docker"},
+				"docker/ci/**":    {When: "use_ci"},
+				"unconditional/*": {},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "README.md", Content: []byte("readme"), Mode: 0644},
+			{Path: "Dockerfile", Content: []byte("FROM scratch"), Mode: 0644},
+			{Path: "docker/compose.yml", Content: []byte("services: {}"), Mode: 0644},
+			{Path: "docker/ci/@ign-var:name@.yml", Content: []byte("ci"), Mode: 0644},
+			{Path: "docs/local.md", Content: []byte("local"), Mode: 0644},
+		},
+		RootPath: rootPath,
+	}
+}
+
+func TestFileConditionsMet(t *testing.T) {
+	template := conditionalTestTemplate(t.TempDir())
+
+	tests := []struct {
+		name string
+		path string
+		vars map[string]interface{}
+		want bool
+	}{
+		{name: "no rule", path: "README.md", vars: map[string]interface{}{"use_docker": false}, want: true},
+		{name: "file rule true", path: "Dockerfile", vars: map[string]interface{}{"use_docker": true}, want: true},
+		{name: "file rule false", path: "Dockerfile", vars: map[string]interface{}{"use_docker": false}, want: false},
+		{name: "directory rule false", path: "docker/compose.yml", vars: map[string]interface{}{"use_docker": false}, want: false},
+		{name: "negated rule", path: "docs/local.md", vars: map[string]interface{}{"use_docker": false}, want: true},
+		{name: "nested rules all true", path: "docker/ci/@ign-var:name@.yml", vars: map[string]interface{}{"use_docker": true, "use_ci": true}, want: true},
+		{name: "nested rule false", path: "docker/ci/@ign-var:name@.yml", vars: map[string]interface{}{"use_docker": true, "use_ci": false}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FileConditionsMet(template, tt.path, parser.NewMapVariables(tt.vars))
+			if err != nil {
+				t.Fatalf("FileConditionsMet() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FileConditionsMet(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FileConditionsMet(template, "Dockerfile", parser.NewMapVariables(map[string]interface{}{"use_docker": "yes"})); err == nil {
+		t.Error("expected error for non-bool condition variable")
+	}
+}
+
+func TestGenerator_GenerateSkipsExcludedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	template := conditionalTestTemplate(tmpDir)
+
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template: template,
+		Variables: parser.NewMapVariables(map[string]interface{}{
+			"use_docker": false,
+			"use_ci":     true,
+			"name":       "build",
+		}),
+		OutputDir: outDir,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if result.FilesCreated != 2 {
+		t.Errorf("FilesCreated = %d, want 2", result.FilesCreated)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("Files = %v, want README.md and docs/local.md only", result.Files)
+	}
+	if len(result.ExcludedFiles) != 3 {
+		t.Errorf("ExcludedFiles = %v, want 3 entries", result.ExcludedFiles)
+	}
+	for _, path := range []string{"Dockerfile", "docker"} {
+		if _, err := os.Stat(filepath.Join(outDir, path)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be generated, stat error = %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "docs", "local.md")); err != nil {
+		t.Errorf("docs/local.md should be generated: %v", err)
+	}
+}
+
+func TestFilePolicyFor(t *testing.T) {
+	template := &model.Template{
+		Config: model.IgnJson{
+			Files: map[string]model.FileRule{
+				"config/**":         {Policy: model.FilePolicySeed},
+				"config/shared.yml": {Policy: model.FilePolicyManaged},
+				"docker/":           {When: "use_docker"},
+				"LOCAL.md":          {Policy: model.FilePolicyUser},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want model.FilePolicy
+	}{
+		{path: "config/app.yml", want: model.FilePolicySeed},
+		{path: "config/shared.yml", want: model.FilePolicyManaged},
+		{path: "./LOCAL.md", want: model.FilePolicyUser},
+		{path: "docker/Dockerfile", want: ""},
+		{path: "README.md", want: ""},
+	}
+	for _, tt := range tests {
+		if got := FilePolicyFor(template, tt.path); got != tt.want {
+			t.Errorf("FilePolicyFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_GenerateAppliesFilePolicies(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	for name, content := range map[string]string{"Makefile": "old make", "LOCAL.md": "my notes"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	seededPath, err := filepath.Abs(filepath.Join(outDir, "seed.yml"))
+	if err != nil {
+		t.Fatalf("failed to resolve seed path: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "policies",
+			Version: "1.0.0",
+			Files: map[string]model.FileRule{
+				"Makefile": {Policy: model.FilePolicyManaged},
+				"LOCAL.md": {Policy: model.FilePolicyUser},
+				"seed.yml": {Policy: model.FilePolicySeed},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "Makefile", Content: []byte("new make"), Mode: 0644},
+			{Path: "LOCAL.md", Content: []byte("template notes"), Mode: 0644},
+			{Path: "seed.yml", Content: []byte("seed"), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteAll,
+		SyncManaged:   true,
+		SeededPaths:   map[string]struct{}{seededPath: {}},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"Makefile": "new make", "LOCAL.md": "my notes"} {
+		got, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "seed.yml")); !os.IsNotExist(err) {
+		t.Errorf("seed.yml was seeded before and must not be recreated, stat error = %v", err)
+	}
+	if result.FilesSkipped != 2 {
+		t.Errorf("FilesSkipped = %d, want 2", result.FilesSkipped)
+	}
+	if got := result.Policies[filepath.Join(outDir, "LOCAL.md")]; got != model.FilePolicyUser {
+		t.Errorf("Policies[LOCAL.md] = %q, want user", got)
+	}
+}
+
+func TestGenerator_SyncManagedIgnoresOverwriteNone(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	for _, name := range []string{"Makefile", "README.md"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte("old"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "policies",
+			Version: "1.0.0",
+			Files:   map[string]model.FileRule{"Makefile": {Policy: model.FilePolicyManaged}},
+		},
+		Files: []model.TemplateFile{
+			{Path: "Makefile", Content: []byte("new"), Mode: 0644},
+			{Path: "README.md", Content: []byte("new"), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+
+	if _, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteNone,
+		SyncManaged:   true,
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"Makefile": "new", "README.md": "old"} {
+		got, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
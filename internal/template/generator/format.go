@@ -0,0 +1,213 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/debug"
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// FormatError reports a formatter failure on a rendered file.
+type FormatError struct {
+	// Path is the template path of the file.
+	Path string
+	// Formatter is the formatter that failed.
+	Formatter model.Formatter
+	// Line is the 1-based line of the rendered content the formatter
+	// rejected, or 0 when the formatter did not report one.
+	Line int
+	// Text is the rendered text of Line.
+	Text string
+	// Fatal is set when settings.format_errors is "fail".
+	Fatal bool
+	// Err is the formatter's error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *FormatError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s formatter: %v", e.Path, e.Formatter, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s formatter: %v (rendered line: %q)", e.Path, e.Line, e.Formatter, e.Err, e.Text)
+}
+
+// Unwrap returns the formatter's error.
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// FormatterFor returns the formatter settings.formatters assigns to a
+// template path. When several patterns match, the longest one wins.
+func FormatterFor(template *model.Template, path string) (model.Formatter, bool) {
+	if template == nil || template.Config.Settings == nil || len(template.Config.Settings.Formatters) == 0 {
+		return "", false
+	}
+
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+	patterns := make([]string, 0, len(template.Config.Settings.Formatters))
+	for pattern := range template.Config.Settings.Formatters {
+		if matchesIgnorePattern(path, filepath.ToSlash(strings.TrimSpace(pattern))) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return "", false
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return template.Config.Settings.Formatters[patterns[0]], true
+}
+
+// formatRendered runs the formatter configured for a template path on its
+// rendered content. On failure it returns the content unchanged with a
+// *FormatError.
+func formatRendered(template *model.Template, path string, content []byte) ([]byte, error) {
+	formatter, ok := FormatterFor(template, path)
+	if !ok {
+		return content, nil
+	}
+
+	debug.Debug("[generator] Formatting %s with %s", path, formatter)
+	formatted, line, err := FormatContent(formatter, content)
+	if err != nil {
+		formatErr := &FormatError{
+			Path:      path,
+			Formatter: formatter,
+			Line:      line,
+			Fatal:     template.Config.Settings.FormatErrors == model.FormatErrorsFail,
+			Err:       err,
+		}
+		if line > 0 {
+			formatErr.Text = renderedLine(content, line)
+		}
+		return content, formatErr
+	}
+	return formatted, nil
+}
+
+// FormatContent formats content with formatter. On failure it returns the
+// 1-based line the formatter rejected, or 0 if unknown.
+func FormatContent(formatter model.Formatter, content []byte) ([]byte, int, error) {
+	switch formatter {
+	case model.FormatterGofmt:
+		formatted, err := format.Source(content)
+		if err != nil {
+			return nil, goErrorLine(err), err
+		}
+		return formatted, 0, nil
+	case model.FormatterSortImports:
+		return sortGoImports(content)
+	case model.FormatterJSON:
+		return indentJSON(content)
+	default:
+		return nil, 0, fmt.Errorf("unknown formatter %q", formatter)
+	}
+}
+
+// sortGoImports sorts the specs of every parenthesized import declaration
+// like gofmt does and leaves the rest of the source untouched.
+func sortGoImports(content []byte) ([]byte, int, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", content, goparser.ParseComments|goparser.ImportsOnly)
+	if err != nil {
+		return nil, goErrorLine(err), err
+	}
+
+	type span struct {
+		start, end int
+		decl       *ast.GenDecl
+	}
+	var spans []span
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT || !gen.Lparen.IsValid() {
+			continue
+		}
+		spans = append(spans, span{
+			start: fset.Position(gen.Pos()).Offset,
+			end:   fset.Position(gen.End()).Offset,
+			decl:  gen,
+		})
+	}
+	if len(spans) == 0 {
+		return content, 0, nil
+	}
+
+	ast.SortImports(fset, file)
+
+	out := append([]byte(nil), content...)
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		var comments []*ast.CommentGroup
+		for _, group := range file.Comments {
+			if group.Pos() >= s.decl.Pos() && group.End() <= s.decl.End() {
+				comments = append(comments, group)
+			}
+		}
+		var buf bytes.Buffer
+		if err := config.Fprint(&buf, fset, &printer.CommentedNode{Node: s.decl, Comments: comments}); err != nil {
+			return nil, 0, err
+		}
+		out = append(out[:s.start], append(buf.Bytes(), out[s.end:]...)...)
+	}
+	return out, 0, nil
+}
+
+// indentJSON re-indents a JSON document with two spaces, keeping key order
+// and the presence of a final newline.
+func indentJSON(content []byte) ([]byte, int, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, content, "", "  "); err != nil {
+		line := 0
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			offset := int(syntaxErr.Offset)
+			if offset > len(content) {
+				offset = len(content)
+			}
+			line = bytes.Count(content[:offset], []byte("\n")) + 1
+		}
+		return nil, line, err
+	}
+	formatted := bytes.TrimSpace(buf.Bytes())
+	if bytes.HasSuffix(content, []byte("\n")) {
+		formatted = append(formatted, '\n')
+	}
+	return formatted, 0, nil
+}
+
+// goErrorLine returns the line of the first error reported by the Go parser.
+func goErrorLine(err error) int {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return list[0].Pos.Line
+	}
+	return 0
+}
+
+// renderedLine returns line n (1-based) of content without its line ending.
+func renderedLine(content []byte, n int) string {
+	lines := bytes.Split(content, []byte("\n"))
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return string(bytes.TrimRight(lines[n-1], "\r"))
+}
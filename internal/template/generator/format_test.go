@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func TestFormatContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter model.Formatter
+		content   string
+		want      string
+		wantLine  int
+		wantErr   bool
+	}{
+		{
+			name:      "gofmt",
+			formatter: model.FormatterGofmt,
+			content:   "package main\nimport (\n\"os\"\n\"fmt\"\n)\nfunc main() {\nfmt.Println(os.Args)\n}\n",
+			want:      "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println(os.Args)\n}\n",
+		},
+		{
+			name:      "gofmt error",
+			formatter: model.FormatterGofmt,
+			content:   "package main\n\nfunc main() {\n\tfmt.Println(\n}\n",
+			wantLine:  5,
+			wantErr:   true,
+		},
+		{
+			name:      "sort imports only",
+			formatter: model.FormatterSortImports,
+			content:   "package main\n\nimport (\n\t\"os\" // args\n\t\"fmt\"\n)\n\nfunc main()   {fmt.Println(os.Args)}\n",
+			want:      "package main\n\nimport (\n\t\"fmt\"\n\t\"os\" // args\n)\n\nfunc main()   {fmt.Println(os.Args)}\n",
+		},
+		{
+			name:      "json",
+			formatter: model.FormatterJSON,
+			content:   "{\"b\": 1,\n\n\"a\": [1,2]}\n",
+			want:      "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}\n",
+		},
+		{
+			name:      "json error",
+			formatter: model.FormatterJSON,
+			content:   "{\n  \"a\": 1,\n}\n",
+			wantLine:  3,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, line, err := FormatContent(tt.formatter, []byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FormatContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if line != tt.wantLine {
+				t.Errorf("FormatContent() line = %d, want %d", line, tt.wantLine)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("FormatContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatterFor(t *testing.T) {
+	template := &model.Template{
+		Config: model.IgnJson{
+			Settings: &model.TemplateSettings{
+				Formatters: map[string]model.Formatter{
+					"*.go":            model.FormatterGofmt,
+					"gen/**":          model.FormatterSortImports,
+					"config/*.json":   model.FormatterJSON,
+					"config/raw.json": model.FormatterJSON,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want model.Formatter
+	}{
+		{path: "main.go", want: model.FormatterGofmt},
+		{path: "gen/zz_generated.go", want: model.FormatterSortImports},
+		{path: "config/app.json", want: model.FormatterJSON},
+		{path: "package.json", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, _ := FormatterFor(template, tt.path)
+			if got != tt.want {
+				t.Errorf("FormatterFor(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateFormatsFiles(t *testing.T) {
+	newTemplate := func(mode model.FormatErrorMode) *model.Template {
+		return &model.Template{
+			Config: model.IgnJson{
+				Name:    "test",
+				Version: "1.0.0",
+				Settings: &model.TemplateSettings{
+					Formatters: map[string]model.Formatter{
+						"*.go":   model.FormatterGofmt,
+						"*.json": model.FormatterJSON,
+					},
+					FormatErrors: mode,
+				},
+			},
+			Files: []model.TemplateFile{
+				{Path: "main.go", Content: []byte("package main\n\nimport (\n\t\"os\"\n@ign-if:fmt@\n\t\"fmt\"\n@ign-endif@\n)\n\nfunc main() {\n  @ign-if:fmt@\nfmt.Println(os.Args)\n  @ign-endif@\n}\n"), Mode: 0644},
+				{Path: "broken.json", Content: []byte("{\"a\": @ign-var:value@}\n"), Mode: 0644},
+			},
+			RootPath: t.TempDir(),
+		}
+	}
+	vars := parser.NewMapVariables(map[string]interface{}{"fmt": true, "value": "oops"})
+
+	t.Run("warn", func(t *testing.T) {
+		outputDir := t.TempDir()
+		result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+			Template:  newTemplate(""),
+			Variables: vars,
+			OutputDir: outputDir,
+		})
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+		if err != nil {
+			t.Fatalf("failed to read main.go: %v", err)
+		}
+		want := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println(os.Args)\n}\n"
+		if string(got) != want {
+			t.Errorf("main.go = %q, want %q", got, want)
+		}
+
+		got, err = os.ReadFile(filepath.Join(outputDir, "broken.json"))
+		if err != nil {
+			t.Fatalf("failed to read broken.json: %v", err)
+		}
+		if string(got) != "{\"a\": oops}\n" {
+			t.Errorf("broken.json = %q, want unformatted content", got)
+		}
+
+		if len(result.Errors) != 1 {
+			t.Fatalf("Errors = %v, want one format error", result.Errors)
+		}
+		var formatErr *FormatError
+		if !errors.As(result.Errors[0], &formatErr) || formatErr.Path != "broken.json" || formatErr.Line != 1 {
+			t.Fatalf("Errors[0] = %v, want format error for broken.json line 1", result.Errors[0])
+		}
+		if !strings.Contains(formatErr.Error(), `rendered line: "{\"a\": oops}"`) {
+			t.Errorf("error message %q should quote the rendered line", formatErr.Error())
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+			Template:  newTemplate(model.FormatErrorsFail),
+			Variables: vars,
+			OutputDir: t.TempDir(),
+		})
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) || formatErr.Path != "broken.json" {
+			t.Fatalf("Generate() error = %v, want format error for broken.json", err)
+		}
+	})
+}
@@ -3,9 +3,13 @@ package generator
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/tacogips/ign/internal/debug"
 	"github.com/tacogips/ign/internal/template/model"
@@ -34,6 +38,11 @@ const (
 	OverwriteSelective OverwriteMode = "selective"
 	// OverwriteAll overwrites all existing output files.
 	OverwriteAll OverwriteMode = "all"
+	// OverwriteMerge deep-merges the rendered JSON, YAML, or TOML document into
+	// the existing file. It is selected per path by a "merge" strategy rule in
+	// ign-template.json when GenerateOptions.MergeExisting is set and the path
+	// would otherwise be overwritten selectively.
+	OverwriteMerge OverwriteMode = "merge"
 )
 
 // GenerateOptions configures project generation.
@@ -66,6 +75,45 @@ type GenerateOptions struct {
 	// output paths. Callers that do not supply a transition retain the historical
 	// symlink behavior.
 	SymlinkTransitions map[string]SymlinkTransition
+
+	// SyncManaged overwrites paths with the "managed" file policy regardless of
+	// OverwriteMode and .ign-overwrite-ignore. Update sets it when the user asked
+	// to overwrite; checkout does not.
+	SyncManaged bool
+
+	// MergeExisting updates existing files in place instead of replacing them
+	// when the run overwrites them: managed regions are rewritten between their
+	// markers, and paths with a "merge" strategy rule are deep-merged
+	// (OverwriteMerge). Paths kept by their file policy or by overwrite-ignore
+	// patterns are not touched, and OverwriteNone edits nothing. A full
+	// overwrite (OverwriteAll) still merges regions but replaces files with a
+	// merge strategy. Update sets it.
+	MergeExisting bool
+
+	// SeededPaths holds absolute output paths previously generated with the
+	// "seed" policy. They are never written again, even after the project
+	// deletes them.
+	SeededPaths map[string]struct{}
+
+	// DeclinedChanges maps output paths to the ContentHash of content the
+	// project declined. A path whose generated content hashes to exactly that
+	// value is skipped and listed in GenerateResult.DeclinedFiles.
+	DeclinedChanges map[string]string
+
+	// ContentOverrides maps output paths to content written instead of the
+	// generated (or merged) content, such as a result edited during review.
+	// Injection targets are not overridden.
+	ContentOverrides map[string][]byte
+
+	// ProjectOverwriteIgnore holds the project's own overwrite-ignore
+	// patterns. They follow the template's .ign-overwrite-ignore patterns, so
+	// a project negation can re-include a path the template protects.
+	ProjectOverwriteIgnore []string
+
+	// AppliedInjections holds the injections earlier runs recorded, with the
+	// same paths as GenerateResult.Injections. An injection whose recorded
+	// text is still in its target is not applied again.
+	AppliedInjections []model.InjectionRecord
 }
 
 // SymlinkTransitionDisposition describes how an existing directory at a
@@ -122,6 +170,14 @@ type DryRunFile struct {
 	WouldSkip bool
 	// SymlinkTarget is populated for a template symlink entry.
 	SymlinkTarget string
+	// Mode is the permission a written regular file would get.
+	Mode os.FileMode
+	// Injected is set when Content is an existing file with injections
+	// applied rather than generated content.
+	Injected bool
+	// ProtectedByProject is set when the project's overwrite-ignore patterns,
+	// rather than the template's, make selective overwrite skip the path.
+	ProtectedByProject bool
 }
 
 // GenerateResult contains generation statistics.
@@ -142,17 +198,44 @@ type GenerateResult struct {
 	// FilesOverwritten is the number of existing files overwritten.
 	FilesOverwritten int
 
+	// Injections records the injections applied to existing files. Their
+	// targets are not listed in Files, so ign never takes ownership of them.
+	Injections []model.InjectionRecord
+
+	// FilesMerged is the number of existing files updated in place by a
+	// managed-region or structured merge. MergedFiles lists them; they are also
+	// in WrittenFiles.
+	FilesMerged int
+	MergedFiles []string
+
 	// Errors contains non-fatal errors encountered during generation.
 	Errors []error
 
 	// Files contains the paths of all files processed (created, skipped, or overwritten).
 	Files []string
 
+	// ExcludedFiles contains template paths excluded because a "files" rule
+	// condition was not met.
+	ExcludedFiles []string
+
+	// Policies maps output paths in Files to their declared file policy.
+	// Paths without a policy are omitted.
+	Policies map[string]model.FilePolicy
+
 	// DryRunFiles contains detailed information for dry-run mode (only populated in dry-run).
 	DryRunFiles []DryRunFile
 
 	// Directories contains directories that would be created (only populated in dry-run).
 	Directories []string
+
+	// DeclinedFiles maps the paths skipped because of
+	// GenerateOptions.DeclinedChanges to their content hash.
+	DeclinedFiles map[string]string
+
+	// ContentHashes maps the paths ign wrote, or found already up to date,
+	// to the ContentHash of the generated content (the link target for a
+	// symlink), so later commands can tell whether the user changed them.
+	ContentHashes map[string]string
 }
 
 // DefaultGenerator implements Generator.
@@ -173,15 +256,29 @@ func NewGenerator() Generator {
 	}
 }
 
+// NewGeneratorWithWriter creates a DefaultGenerator that writes through
+// writer instead of the filesystem, e.g. an ArchiveWriter.
+func NewGeneratorWithWriter(writer Writer) Generator {
+	return &DefaultGenerator{
+		parser: parser.NewParser(),
+		writer: writer,
+	}
+}
+
 // RenderTemplateFileContent applies the same content processing used by
 // generation for a single non-symlink template file.
 func RenderTemplateFileContent(ctx context.Context, template *model.Template, file model.TemplateFile, vars parser.Variables) ([]byte, error) {
 	if template == nil {
 		return nil, fmt.Errorf("template cannot be nil")
 	}
-	settings := getTemplateSettings(template)
-	processor := NewFileProcessor(parser.NewParser(), settings.BinaryExtensions)
-	return processor.Process(ctx, file, vars, template.RootPath)
+	processor := NewTemplateFileProcessor(parser.NewParser(), template)
+	content, err := processor.Process(ctx, file, vars, template.RootPath)
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) && !formatErr.Fatal {
+		// Generation writes the unformatted content in this case.
+		return content, nil
+	}
+	return content, err
 }
 
 // Generate creates a project from a template with the given options.
@@ -216,6 +313,7 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 		CreatedFiles: []string{},
 		WrittenFiles: []string{},
 		Files:        []string{},
+		Policies:     map[string]model.FilePolicy{},
 		DryRunFiles:  []DryRunFile{},
 		Directories:  []string{},
 	}
@@ -226,12 +324,13 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 	// Get template settings
 	settings := getTemplateSettings(opts.Template)
 	preserveExecutable := settings.PreserveExecutableEnabled()
-	overwriteIgnorePatterns := overwriteIgnorePatternsFromTemplate(opts.Template)
+	templateIgnorePatterns := overwriteIgnorePatternsFromTemplate(opts.Template)
+	overwriteIgnorePatterns := append(append([]string(nil), templateIgnorePatterns...), opts.ProjectOverwriteIgnore...)
 	debug.Debug("[generator] Template settings: preserveExecutable=%v, ignorePatterns=%v, binaryExtensions=%d",
 		preserveExecutable, settings.IgnorePatterns, len(settings.BinaryExtensions))
 
 	// Create processor and writer based on template settings
-	processor := NewFileProcessor(g.parser, settings.BinaryExtensions)
+	processor := NewTemplateFileProcessor(g.parser, opts.Template)
 	writer := g.writer
 	if writer == nil {
 		writer = NewFileWriter(preserveExecutable)
@@ -259,6 +358,18 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 			continue
 		}
 
+		// Conditional files are decided before filename substitution. Excluded
+		// paths are not reported in Files, so they stay out of the manifest and
+		// update cleanup removes them once a condition turns false.
+		included, err := FileConditionsMet(opts.Template, file.Path, opts.Variables)
+		if err != nil {
+			return result, err
+		}
+		if !included {
+			result.ExcludedFiles = append(result.ExcludedFiles, file.Path)
+			continue
+		}
+
 		// Process filename for variable substitution
 		processedFilePath, err := ProcessFilename(ctx, file.Path, opts.Variables, g.parser)
 		if err != nil {
@@ -270,24 +381,69 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 		// Construct output path
 		outputPath := filepath.Join(opts.OutputDir, processedFilePath)
 
+		if IsInjectFile(file.Path) {
+			targetPath := strings.TrimSuffix(outputPath, model.IgnInjectSuffix)
+			targetRelPath := strings.TrimSuffix(processedFilePath, model.IgnInjectSuffix)
+			// Injections edit the target in place, so they honor the target's
+			// user or seed policy and overwrite-ignore patterns unless the run
+			// overwrites everything.
+			targetPolicy := FilePolicyFor(opts.Template, strings.TrimSuffix(file.Path, model.IgnInjectSuffix))
+			if policyKeepsPath(targetPolicy, targetPath, true, nil) ||
+				(overwriteMode != OverwriteAll && MatchesGitIgnorePattern(targetRelPath, overwriteIgnorePatterns)) {
+				debug.Debug("[generator] Skipping injections into protected file: %s", targetPath)
+				if dryRun {
+					result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+						Path: targetPath, Exists: writer.Exists(targetPath), WouldSkip: true, Injected: true,
+						ProtectedByProject: targetPolicy == "" && !MatchesGitIgnorePattern(targetRelPath, templateIgnorePatterns),
+					})
+				}
+				continue
+			}
+			injectIntoExisting(ctx, processor, writer, result, opts, file, targetPath, dryRun)
+			continue
+		}
+
 		// Add to processed files list
 		result.Files = append(result.Files, outputPath)
 
+		// A managed path follows the template even when the run would otherwise
+		// keep existing files.
+		policy := FilePolicyFor(opts.Template, file.Path)
+		pathOverwriteMode := overwriteMode
+		if policy != "" {
+			result.Policies[filepath.Clean(outputPath)] = policy
+			if policy == model.FilePolicyManaged && opts.SyncManaged {
+				pathOverwriteMode = OverwriteAll
+			}
+		}
+		arrayPolicy, mergeDeclared := MergeStrategyFor(opts.Template, file.Path)
+
 		// Handle symlinks: create symbolic links instead of regular files
 		if file.SymlinkTarget != "" {
 			debug.Debug("[generator] Processing symlink: %s -> %s (processed path: %s)",
 				file.Path, file.SymlinkTarget, processedFilePath)
 
-			// Check if something already exists at the output path (use Lstat to detect dangling symlinks too)
-			_, lstatErr := os.Lstat(outputPath)
-			fileExists := lstatErr == nil
+			// Check if something, including a dangling symlink, already exists at the output path
+			fileExists := writer.Exists(outputPath)
+
+			if policyKeepsPath(policy, outputPath, fileExists, opts.SeededPaths) {
+				debug.Debug("[generator] Keeping symlink by %s policy: %s", policy, outputPath)
+				result.FilesSkipped++
+				if dryRun {
+					result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+						Path: outputPath, Exists: fileExists, WouldSkip: true, SymlinkTarget: file.SymlinkTarget,
+					})
+				}
+				continue
+			}
 
-			if shouldSkipPathForSelectiveOverwrite(processedFilePath, overwriteMode, overwriteIgnorePatterns) {
+			if shouldSkipPathForSelectiveOverwrite(processedFilePath, pathOverwriteMode, overwriteIgnorePatterns) {
 				debug.Debug("[generator] Skipping protected symlink: %s", outputPath)
 				result.FilesSkipped++
 				if dryRun {
 					result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
 						Path: outputPath, Exists: fileExists, WouldSkip: true, SymlinkTarget: file.SymlinkTarget,
+						ProtectedByProject: !MatchesGitIgnorePattern(processedFilePath, templateIgnorePatterns),
 					})
 				}
 				continue
@@ -316,11 +472,12 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 				} else {
 					result.WrittenFiles = append(result.WrittenFiles, outputPath)
 				}
+				recordContentHash(result, outputPath, []byte(file.SymlinkTarget))
 				result.FilesOverwritten++
 				continue
 			}
 
-			if fileExists && !shouldOverwritePath(processedFilePath, overwriteMode, overwriteIgnorePatterns) {
+			if fileExists && !shouldOverwritePath(processedFilePath, pathOverwriteMode, overwriteIgnorePatterns) {
 				debug.Debug("[generator] Skipping existing symlink: %s", outputPath)
 				result.FilesSkipped++
 				if dryRun {
@@ -335,6 +492,7 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 			}
 			if opts.SkipUnchanged && fileExists && symlinkTargetMatchesExisting(outputPath, file.SymlinkTarget) {
 				debug.Debug("[generator] Skipping unchanged symlink: %s", outputPath)
+				recordContentHash(result, outputPath, []byte(file.SymlinkTarget))
 				if dryRun {
 					result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
 						Path: outputPath, Exists: true, WouldSkip: true, SymlinkTarget: file.SymlinkTarget,
@@ -342,6 +500,16 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 				}
 				continue
 			}
+			if declinedChange(result, opts, outputPath, []byte(file.SymlinkTarget)) {
+				debug.Debug("[generator] Skipping declined symlink: %s", outputPath)
+				result.FilesSkipped++
+				if dryRun {
+					result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+						Path: outputPath, Exists: fileExists, WouldSkip: true, SymlinkTarget: file.SymlinkTarget,
+					})
+				}
+				continue
+			}
 			if dryRun {
 				trackDryRunDirectories(dirsToCreate, outputPath, opts.OutputDir)
 			}
@@ -382,6 +550,7 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 			if !dryRun {
 				result.WrittenFiles = append(result.WrittenFiles, outputPath)
 			}
+			recordContentHash(result, outputPath, []byte(file.SymlinkTarget))
 			continue
 		}
 
@@ -391,8 +560,8 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 		// Check if file exists
 		fileExists := writer.Exists(outputPath)
 
-		if shouldSkipPathForSelectiveOverwrite(processedFilePath, overwriteMode, overwriteIgnorePatterns) {
-			debug.Debug("[generator] Skipping protected file: %s", outputPath)
+		if policyKeepsPath(policy, outputPath, fileExists, opts.SeededPaths) {
+			debug.Debug("[generator] Keeping file by %s policy: %s", policy, outputPath)
 			result.FilesSkipped++
 			if dryRun {
 				result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
@@ -405,8 +574,53 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 			continue
 		}
 
+		if shouldSkipPathForSelectiveOverwrite(processedFilePath, pathOverwriteMode, overwriteIgnorePatterns) {
+			debug.Debug("[generator] Skipping protected file: %s", outputPath)
+			result.FilesSkipped++
+			if dryRun {
+				result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+					Path:               outputPath,
+					Content:            nil,
+					Exists:             fileExists,
+					WouldSkip:          true,
+					ProtectedByProject: !MatchesGitIgnorePattern(processedFilePath, templateIgnorePatterns),
+				})
+			}
+			continue
+		}
+
+		// Existing files with managed regions or a merge strategy are updated in
+		// place, so user edits outside the template's part survive. Regions are
+		// merged even under a full overwrite; a merge strategy gives way to it.
+		var processed []byte
+		rendered := false
+		if opts.MergeExisting && fileExists && pathOverwriteMode != OverwriteNone {
+			if mergeDeclared && pathOverwriteMode != OverwriteAll {
+				pathOverwriteMode = OverwriteMerge
+			}
+			var ok bool
+			processed, ok, err = processTemplateFile(ctx, processor, result, opts, file)
+			if err != nil {
+				return result, err
+			}
+			if !ok {
+				continue
+			}
+			rendered = true
+			switch {
+			case pathOverwriteMode == OverwriteMerge:
+				mergeIntoExisting(writer, result, opts, file, outputPath, processed, dryRun, "structured merge of", func(existing, rendered []byte) ([]byte, error) {
+					return MergeStructured(processedFilePath, existing, rendered, arrayPolicy)
+				})
+				continue
+			case HasRegions(processed):
+				mergeIntoExisting(writer, result, opts, file, outputPath, processed, dryRun, "managed regions in", MergeRegions)
+				continue
+			}
+		}
+
 		// Determine action
-		if fileExists && !shouldOverwritePath(processedFilePath, overwriteMode, overwriteIgnorePatterns) {
+		if fileExists && !shouldOverwritePath(processedFilePath, pathOverwriteMode, overwriteIgnorePatterns) {
 			// Skip existing file
 			debug.Debug("[generator] Skipping existing file: %s", outputPath)
 			result.FilesSkipped++
@@ -422,15 +636,33 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 		}
 
 		// Process file content
-		debug.Debug("[generator] Processing content for: %s", file.Path)
-		processed, err := processor.Process(ctx, file, opts.Variables, opts.Template.RootPath)
-		if err != nil {
-			// Record error but continue processing
-			result.Errors = append(result.Errors, fmt.Errorf("failed to process %s: %w", file.Path, err))
+		if !rendered {
+			debug.Debug("[generator] Processing content for: %s", file.Path)
+			var ok bool
+			processed, ok, err = processTemplateFile(ctx, processor, result, opts, file)
+			if err != nil {
+				return result, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if override, ok := opts.ContentOverrides[filepath.Clean(outputPath)]; ok {
+			processed = override
+		}
+		if declinedChange(result, opts, outputPath, processed) {
+			debug.Debug("[generator] Skipping declined change: %s", outputPath)
+			result.FilesSkipped++
+			if dryRun {
+				result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+					Path: outputPath, Exists: fileExists, WouldSkip: true,
+				})
+			}
 			continue
 		}
 		if opts.SkipUnchanged && fileExists && fileContentMatchesExisting(outputPath, processed, effectiveWriteFileMode(file.Mode, preserveExecutable)) {
 			debug.Debug("[generator] Skipping unchanged file: %s", outputPath)
+			recordContentHash(result, outputPath, processed)
 			continue
 		}
 		if dryRun {
@@ -457,6 +689,7 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 				Exists:         fileExists,
 				WouldOverwrite: fileExists,
 				WouldSkip:      false,
+				Mode:           effectiveWriteFileMode(file.Mode, preserveExecutable),
 			})
 		}
 
@@ -470,6 +703,7 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 		if !dryRun {
 			result.WrittenFiles = append(result.WrittenFiles, outputPath)
 		}
+		recordContentHash(result, outputPath, processed)
 	}
 
 	// Collect directories for dry-run result
@@ -492,6 +726,198 @@ func (g *DefaultGenerator) generate(ctx context.Context, opts GenerateOptions, d
 	return result, nil
 }
 
+// processTemplateFile renders a template file for writing. Processing errors
+// are recorded in result and reported as !ok so the file is skipped. A
+// formatter failure is recorded and the unformatted content written instead,
+// unless settings.format_errors is "fail": then the error is returned and
+// generation stops.
+func processTemplateFile(ctx context.Context, processor Processor, result *GenerateResult, opts GenerateOptions, file model.TemplateFile) ([]byte, bool, error) {
+	processed, err := processor.Process(ctx, file, opts.Variables, opts.Template.RootPath)
+	if err == nil {
+		return processed, true, nil
+	}
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) {
+		if formatErr.Fatal {
+			return nil, false, err
+		}
+		result.Errors = append(result.Errors, err)
+		return processed, true, nil
+	}
+	// Record error but continue processing
+	result.Errors = append(result.Errors, fmt.Errorf("failed to process %s: %w", file.Path, err))
+	return nil, false, nil
+}
+
+// injectIntoExisting applies an injection spec to its target file. The target
+// must already exist; failures are reported as non-fatal errors and leave it
+// untouched.
+func injectIntoExisting(ctx context.Context, processor Processor, writer Writer, result *GenerateResult, opts GenerateOptions, file model.TemplateFile, targetPath string, dryRun bool) {
+	processed, err := processor.Process(ctx, file, opts.Variables, opts.Template.RootPath)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to process %s: %w", file.Path, err))
+		return
+	}
+	spec, err := ParseInjectSpec(processed)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("invalid injection spec %s: %w", file.Path, err))
+		return
+	}
+
+	if !writer.Exists(targetPath) {
+		result.Errors = append(result.Errors, fmt.Errorf("injection target %s does not exist", targetPath))
+		return
+	}
+	existing, mode, err := writer.ReadFile(targetPath)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to read %s: %w", targetPath, err))
+		return
+	}
+	var applied []model.InjectionRecord
+	for _, record := range opts.AppliedInjections {
+		if filepath.Clean(record.Path) == filepath.Clean(targetPath) {
+			applied = append(applied, record)
+		}
+	}
+	injected, records, err := ApplyInjections(existing, spec, applied)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to inject into %s: %w", targetPath, err))
+		return
+	}
+	if len(records) == 0 {
+		debug.Debug("[generator] Injections already present in %s", targetPath)
+		if dryRun {
+			result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+				Path: targetPath, Exists: true, WouldSkip: true,
+			})
+		}
+		return
+	}
+
+	if declinedChange(result, opts, targetPath, injected) {
+		debug.Debug("[generator] Skipping declined injections into %s", targetPath)
+		if dryRun {
+			result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+				Path: targetPath, Exists: true, WouldSkip: true, Injected: true,
+			})
+		}
+		return
+	}
+
+	if dryRun {
+		debug.Debug("[generator] Dry run: would apply %d injections to %s", len(records), targetPath)
+		result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+			Path: targetPath, Content: injected, Exists: true, WouldOverwrite: true, Injected: true,
+			Mode: dryRunWriteMode(opts, mode),
+		})
+	} else {
+		debug.Debug("[generator] Applying %d injections to %s", len(records), targetPath)
+		if err := writer.WriteFile(targetPath, injected, mode); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to write %s: %w", targetPath, err))
+			return
+		}
+	}
+	for _, record := range records {
+		record.Path = targetPath
+		result.Injections = append(result.Injections, record)
+	}
+}
+
+// mergeIntoExisting writes the result of merging rendered template output into
+// an existing file and records the outcome. Merge failures, such as malformed
+// region markers or unparsable documents, are reported as non-fatal errors
+// and leave the file untouched.
+func mergeIntoExisting(writer Writer, result *GenerateResult, opts GenerateOptions, file model.TemplateFile, outputPath string, processed []byte, dryRun bool, what string, merge func(existing, rendered []byte) ([]byte, error)) {
+	existing, _, err := writer.ReadFile(outputPath)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to read %s: %w", outputPath, err))
+		return
+	}
+	merged, err := merge(existing, processed)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("%s %s: %w", what, outputPath, err))
+		return
+	}
+	if override, ok := opts.ContentOverrides[filepath.Clean(outputPath)]; ok {
+		merged = override
+	}
+	if declinedChange(result, opts, outputPath, merged) {
+		debug.Debug("[generator] Skipping declined merge into %s", outputPath)
+		result.FilesSkipped++
+		if dryRun {
+			result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+				Path: outputPath, Exists: true, WouldSkip: true,
+			})
+		}
+		return
+	}
+	if bytes.Equal(merged, existing) {
+		debug.Debug("[generator] Merge leaves %s unchanged", outputPath)
+		recordContentHash(result, outputPath, merged)
+		if dryRun {
+			result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+				Path: outputPath, Exists: true, WouldSkip: true,
+			})
+		}
+		return
+	}
+
+	if dryRun {
+		debug.Debug("[generator] Dry run: would merge into %s", outputPath)
+		result.DryRunFiles = append(result.DryRunFiles, DryRunFile{
+			Path: outputPath, Content: merged, Exists: true, WouldOverwrite: true,
+			Mode: dryRunWriteMode(opts, file.Mode),
+		})
+	} else {
+		debug.Debug("[generator] Merging into %s", outputPath)
+		if err := writer.WriteFile(outputPath, merged, file.Mode); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to write %s: %w", file.Path, err))
+			return
+		}
+		result.WrittenFiles = append(result.WrittenFiles, outputPath)
+	}
+	result.FilesMerged++
+	result.MergedFiles = append(result.MergedFiles, outputPath)
+	recordContentHash(result, outputPath, merged)
+}
+
+// dryRunWriteMode returns the permission the writer gives a file written with
+// mode under the template's settings.
+func dryRunWriteMode(opts GenerateOptions, mode os.FileMode) os.FileMode {
+	settings := getTemplateSettings(opts.Template)
+	return effectiveWriteFileMode(mode, settings.PreserveExecutableEnabled())
+}
+
+// ContentHash returns the hex-encoded SHA-256 of content, the form used by
+// GenerateOptions.DeclinedChanges.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordContentHash records the hash of the content generated for outputPath.
+func recordContentHash(result *GenerateResult, outputPath string, content []byte) {
+	if result.ContentHashes == nil {
+		result.ContentHashes = make(map[string]string)
+	}
+	result.ContentHashes[filepath.Clean(outputPath)] = ContentHash(content)
+}
+
+// declinedChange reports whether writing content to outputPath is a change
+// the project declined, recording it in result.DeclinedFiles if so.
+func declinedChange(result *GenerateResult, opts GenerateOptions, outputPath string, content []byte) bool {
+	path := filepath.Clean(outputPath)
+	hash, ok := opts.DeclinedChanges[path]
+	if !ok || hash != ContentHash(content) {
+		return false
+	}
+	if result.DeclinedFiles == nil {
+		result.DeclinedFiles = map[string]string{}
+	}
+	result.DeclinedFiles[path] = hash
+	return true
+}
+
 func symlinkTransitionForPath(transitions map[string]SymlinkTransition, path string) (SymlinkTransition, bool) {
 	if transition, ok := transitions[filepath.Clean(path)]; ok {
 		return transition, true
@@ -944,3 +944,59 @@ func TestGenerator_GenerateWithDirectorySymlink(t *testing.T) {
 		t.Errorf("settings.json content through symlink = %q, want %q", string(content), "{}")
 	}
 }
+
+func TestGenerator_DeclinedChangesAndOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	for _, name := range []string{"declined.txt", "stale.txt", "edited.txt"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte("local"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{Name: "declined", Version: "1.0.0"},
+		Files: []model.TemplateFile{
+			{Path: "declined.txt", Content: []byte("template"), Mode: 0644},
+			{Path: "stale.txt", Content: []byte("template"), Mode: 0644},
+			{Path: "edited.txt", Content: []byte("template"), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteAll,
+		DeclinedChanges: map[string]string{
+			filepath.Join(outDir, "declined.txt"): ContentHash([]byte("template")),
+			filepath.Join(outDir, "stale.txt"):    ContentHash([]byte("older template")),
+		},
+		ContentOverrides: map[string][]byte{
+			filepath.Join(outDir, "edited.txt"): []byte("edited"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"declined.txt": "local", "stale.txt": "template", "edited.txt": "edited"} {
+		got, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if result.FilesSkipped != 1 {
+		t.Errorf("FilesSkipped = %d, want 1", result.FilesSkipped)
+	}
+	if len(result.DeclinedFiles) != 1 || result.DeclinedFiles[filepath.Join(outDir, "declined.txt")] == "" {
+		t.Errorf("DeclinedFiles = %v, want only declined.txt", result.DeclinedFiles)
+	}
+}
@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+// HookStage names the point of a workflow at which template hooks run.
+type HookStage string
+
+const (
+	// HookPostCheckout runs after checkout generates the project.
+	HookPostCheckout HookStage = "post_checkout"
+	// HookPostUpdate runs after update regenerates the project.
+	HookPostUpdate HookStage = "post_update"
+)
+
+// ResolvedHook is a template hook whose condition held and whose variables
+// have been substituted.
+type ResolvedHook struct {
+	// Run is the command line, exactly as declared. It reads variables from
+	// the IGN_VAR_* environment, never from substitution, so a variable value
+	// cannot change what the shell runs.
+	Run string
+	// Dir is the working directory as declared, relative to the output
+	// directory ("" for the output directory itself).
+	Dir string
+	// WorkDir is the absolute working directory.
+	WorkDir string
+	// Env lists KEY=VALUE entries added to the inherited environment.
+	Env []string
+}
+
+// ResolveHooks returns the hooks of a stage that apply to vars, in declaration
+// order. Every variable is exported to the hooks as IGN_VAR_<NAME> (upper case,
+// non-alphanumerics replaced by "_"), and IGN_OUTPUT_DIR holds the absolute
+// output directory; a hook's own env entries are applied last. @ign-var:
+// substitution applies to dir and env values, which are not parsed by a shell.
+func ResolveHooks(ctx context.Context, template *model.Template, stage HookStage, vars parser.Variables, outputDir string) ([]ResolvedHook, error) {
+	if template == nil || template.Config.Hooks == nil {
+		return nil, nil
+	}
+	var hooks []model.Hook
+	switch stage {
+	case HookPostCheckout:
+		hooks = template.Config.Hooks.PostCheckout
+	case HookPostUpdate:
+		hooks = template.Config.Hooks.PostUpdate
+	default:
+		return nil, fmt.Errorf("unknown hook stage %q", stage)
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	root, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	baseEnv := hookVariableEnv(vars, root)
+
+	p := parser.NewParser()
+	substitute := func(value string) (string, error) {
+		out, err := p.Parse(ctx, []byte(value), vars)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+
+	var resolved []ResolvedHook
+	for i, hook := range hooks {
+		label := fmt.Sprintf("%s[%d]", stage, i)
+		if hook.When != "" {
+			met, err := evaluateWhen(hook.When, vars)
+			if err != nil {
+				return nil, fmt.Errorf("hook %s: %w", label, err)
+			}
+			if !met {
+				continue
+			}
+		}
+
+		dir, err := substitute(hook.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", label, err)
+		}
+		workDir := filepath.Join(root, filepath.FromSlash(dir))
+		if rel, err := filepath.Rel(root, workDir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("hook %s: directory %q escapes the output directory", label, dir)
+		}
+
+		env := append([]string(nil), baseEnv...)
+		names := make([]string, 0, len(hook.Env))
+		for name := range hook.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := substitute(hook.Env[name])
+			if err != nil {
+				return nil, fmt.Errorf("hook %s: env %s: %w", label, name, err)
+			}
+			env = append(env, name+"="+value)
+		}
+
+		resolved = append(resolved, ResolvedHook{Run: hook.Run, Dir: dir, WorkDir: workDir, Env: env})
+	}
+	return resolved, nil
+}
+
+// hookVariableEnv exports the template variables in a stable order.
+func hookVariableEnv(vars parser.Variables, outputDir string) []string {
+	values := vars.All()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names)+1)
+	env = append(env, "IGN_OUTPUT_DIR="+outputDir)
+	for _, name := range names {
+		env = append(env, "IGN_VAR_"+hookEnvName(name)+"="+parser.FormatValue(values[name]))
+	}
+	return env
+}
+
+func hookEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
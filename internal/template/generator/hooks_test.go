@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func TestResolveHooks(t *testing.T) {
+	outputDir := t.TempDir()
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Hooks: &model.TemplateHooks{
+				PostCheckout: []model.Hook{
+					{Run: "git init", When: "use_git"},
+					{Run: `go mod init "$IGN_VAR_MODULE"`, Dir: "@ign-var:name@", Env: map[string]string{"GOFLAGS": "-mod=mod", "APP": "@ign-var:name@"}},
+					{Run: "echo no git", When: "!use_git"},
+				},
+				PostUpdate: []model.Hook{{Run: "go mod tidy"}},
+			},
+		},
+	}
+	vars := parser.NewMapVariables(map[string]interface{}{
+		"name":    "my-app",
+		"module":  "example.com/my-app",
+		"use_git": true,
+	})
+
+	hooks, err := ResolveHooks(context.Background(), template, HookPostCheckout, vars, outputDir)
+	if err != nil {
+		t.Fatalf("ResolveHooks() error = %v", err)
+	}
+	if len(hooks) != 2 {
+		t.Fatalf("ResolveHooks() returned %d hooks, want 2: %+v", len(hooks), hooks)
+	}
+	if hooks[0].Run != "git init" || hooks[0].WorkDir != outputDir {
+		t.Errorf("hooks[0] = %+v", hooks[0])
+	}
+	if hooks[1].Run != `go mod init "$IGN_VAR_MODULE"` || hooks[1].Dir != "my-app" || hooks[1].WorkDir != filepath.Join(outputDir, "my-app") {
+		t.Errorf("hooks[1] = %+v", hooks[1])
+	}
+	wantEnv := []string{
+		"IGN_OUTPUT_DIR=" + outputDir,
+		"IGN_VAR_MODULE=example.com/my-app",
+		"IGN_VAR_NAME=my-app",
+		"IGN_VAR_USE_GIT=true",
+		"APP=my-app",
+		"GOFLAGS=-mod=mod",
+	}
+	if !reflect.DeepEqual(hooks[1].Env, wantEnv) {
+		t.Errorf("hooks[1].Env = %v, want %v", hooks[1].Env, wantEnv)
+	}
+
+	updateHooks, err := ResolveHooks(context.Background(), template, HookPostUpdate, vars, outputDir)
+	if err != nil {
+		t.Fatalf("ResolveHooks(post_update) error = %v", err)
+	}
+	if len(updateHooks) != 1 || updateHooks[0].Run != "go mod tidy" {
+		t.Errorf("post_update hooks = %+v", updateHooks)
+	}
+}
+
+func TestResolveHooksRejectsEscapingDirectory(t *testing.T) {
+	template := &model.Template{
+		Config: model.IgnJson{
+			Hooks: &model.TemplateHooks{
+				PostCheckout: []model.Hook{{Run: "ls", Dir: "@ign-var:dir@"}},
+			},
+		},
+	}
+	vars := parser.NewMapVariables(map[string]interface{}{"dir": "../outside"})
+
+	if _, err := ResolveHooks(context.Background(), template, HookPostCheckout, vars, t.TempDir()); err == nil {
+		t.Fatal("ResolveHooks() should reject a directory outside the output directory")
+	}
+}
+
+func TestResolveHooksKeepsVariablesOutOfCommand(t *testing.T) {
+	template := &model.Template{
+		Config: model.IgnJson{
+			Hooks: &model.TemplateHooks{
+				PostCheckout: []model.Hook{{Run: `echo "$IGN_VAR_NAME"`}},
+			},
+		},
+	}
+	vars := parser.NewMapVariables(map[string]interface{}{
+		"name": "x; touch pwned",
+		"big":  float64(1e21),
+	})
+
+	hooks, err := ResolveHooks(context.Background(), template, HookPostCheckout, vars, t.TempDir())
+	if err != nil {
+		t.Fatalf("ResolveHooks() error = %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].Run != `echo "$IGN_VAR_NAME"` {
+		t.Fatalf("hooks = %+v, want the command as declared", hooks)
+	}
+	for _, want := range []string{"IGN_VAR_NAME=x; touch pwned", "IGN_VAR_BIG=1000000000000000000000"} {
+		if !slices.Contains(hooks[0].Env, want) {
+			t.Errorf("Env = %v, want %q", hooks[0].Env, want)
+		}
+	}
+}
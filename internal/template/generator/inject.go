@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// IsInjectFile reports whether a template path is an injection spec rather
+// than a file to generate.
+func IsInjectFile(path string) bool {
+	return strings.HasSuffix(path, model.IgnInjectSuffix)
+}
+
+// ParseInjectSpec decodes and validates an injection spec.
+func ParseInjectSpec(content []byte) (*model.InjectSpec, error) {
+	var spec model.InjectSpec
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	seen := make(map[string]bool, len(spec.Injections))
+	for i, injection := range spec.Injections {
+		if injection.ID == "" {
+			return nil, fmt.Errorf("injection %d: id is required", i+1)
+		}
+		if seen[injection.ID] {
+			return nil, fmt.Errorf("duplicate injection id %q", injection.ID)
+		}
+		seen[injection.ID] = true
+
+		ops := 0
+		for _, set := range []bool{injection.After != "", injection.Before != "", injection.Append, injection.Replace != ""} {
+			if set {
+				ops++
+			}
+		}
+		if ops != 1 {
+			return nil, fmt.Errorf("injection %q: exactly one of after, before, append, or replace is required", injection.ID)
+		}
+		if injection.Content == "" {
+			return nil, fmt.Errorf("injection %q: content is required", injection.ID)
+		}
+		for _, pattern := range []string{injection.After, injection.Before, injection.Replace} {
+			if pattern == "" {
+				continue
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("injection %q: invalid regex %q: %w", injection.ID, pattern, err)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// ApplyInjections applies a spec to the content of its target file. It returns
+// the edited content and a record for each injection it applied. An injection
+// is skipped when its content already sits where it would be inserted, or when
+// applied, the target's records from earlier runs, holds a record of it whose
+// text can still be located. This makes re-applying a spec a no-op without
+// skipping content, such as a closing brace, that merely appears elsewhere in
+// the file. The records' Path is left for the caller to fill in.
+func ApplyInjections(content []byte, spec *model.InjectSpec, applied []model.InjectionRecord) ([]byte, []model.InjectionRecord, error) {
+	previous := make(map[string]model.InjectionRecord, len(applied))
+	for _, record := range applied {
+		previous[record.ID] = record
+	}
+
+	current := string(content)
+	var records []model.InjectionRecord
+	// shift moves the recorded offsets at or past at by delta bytes, keeping
+	// the records made so far accurate as later injections edit the file.
+	shift := func(at, delta int) {
+		for _, record := range records {
+			if *record.Offset >= at {
+				*record.Offset += delta
+			}
+		}
+	}
+	for _, injection := range spec.Injections {
+		if record, ok := previous[injection.ID]; ok &&
+			strings.Trim(record.Content, "\n") == strings.Trim(injection.Content, "\n") &&
+			locateInjection(current, record) >= 0 {
+			continue
+		}
+
+		record := model.InjectionRecord{ID: injection.ID}
+		switch {
+		case injection.Append:
+			if endsWithLines(current, injection.Content) {
+				continue
+			}
+			inserted := injection.Content
+			if current != "" && !strings.HasSuffix(current, "\n") {
+				inserted = "\n" + inserted
+			}
+			record.Offset = intPtr(len(current))
+			current += inserted
+			record.Content = inserted
+		case injection.Replace != "":
+			loc := regexp.MustCompile("(?m)" + injection.Replace).FindStringIndex(current)
+			if loc == nil {
+				// A replace usually removes its own pattern, so finding the
+				// content instead means it was applied before.
+				if strings.Contains(current, injection.Content) {
+					continue
+				}
+				return nil, nil, fmt.Errorf("injection %q: pattern %q not found", injection.ID, injection.Replace)
+			}
+			if current[loc[0]:loc[1]] == injection.Content {
+				continue
+			}
+			record.Original = current[loc[0]:loc[1]]
+			record.Content = injection.Content
+			record.Offset = intPtr(loc[0])
+			shift(loc[1], len(injection.Content)-len(record.Original))
+			current = current[:loc[0]] + injection.Content + current[loc[1]:]
+		default:
+			anchor := injection.After
+			if anchor == "" {
+				anchor = injection.Before
+			}
+			loc := regexp.MustCompile("(?m)" + anchor).FindStringIndex(current)
+			if loc == nil {
+				return nil, nil, fmt.Errorf("injection %q: anchor %q not found", injection.ID, anchor)
+			}
+
+			inserted := injection.Content
+			if !strings.HasSuffix(inserted, "\n") {
+				inserted += "\n"
+			}
+			var at int
+			if injection.After != "" {
+				at = lineEnd(current, loc[1])
+				if startsWithLines(current[at:], inserted) {
+					continue
+				}
+				if at == len(current) && !strings.HasSuffix(current, "\n") {
+					inserted = "\n" + strings.TrimSuffix(inserted, "\n")
+				}
+			} else {
+				at = strings.LastIndex(current[:loc[0]], "\n") + 1
+				if endsWithLines(current[:at], inserted) {
+					continue
+				}
+			}
+			record.After, record.Before = injection.After, injection.Before
+			record.Offset = intPtr(at)
+			shift(at, len(inserted))
+			current = current[:at] + inserted + current[at:]
+			record.Content = inserted
+		}
+		records = append(records, record)
+	}
+	return []byte(current), records, nil
+}
+
+// startsWithLines reports whether s begins with the whole lines of text. The
+// final newline of text may be missing at the end of s.
+func startsWithLines(s, text string) bool {
+	text = strings.TrimSuffix(text, "\n")
+	return strings.HasPrefix(s, text) && (len(s) == len(text) || s[len(text)] == '\n')
+}
+
+// endsWithLines reports whether s ends with the whole lines of text, ignoring
+// trailing newlines on either side.
+func endsWithLines(s, text string) bool {
+	s, text = strings.TrimRight(s, "\n"), strings.TrimRight(text, "\n")
+	if text == "" || !strings.HasSuffix(s, text) {
+		return false
+	}
+	return len(s) == len(text) || s[len(s)-len(text)-1] == '\n'
+}
+
+// lineEnd returns the offset just past the newline ending the line that
+// contains offset, or the end of s on its last line.
+func lineEnd(s string, offset int) int {
+	if offset > 0 && s[offset-1] == '\n' {
+		return offset
+	}
+	if i := strings.IndexByte(s[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(s)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// locateInjection returns the offset of a recorded injection's text in
+// content, or -1 when it cannot be told apart from the user's own text. The
+// text is taken at its recorded offset, then on the side of the first line
+// matching its anchor, and otherwise only when it occurs exactly once.
+func locateInjection(content string, record model.InjectionRecord) int {
+	text := record.Content
+	if text == "" {
+		return -1
+	}
+	var found []int
+	for from := 0; ; {
+		i := strings.Index(content[from:], text)
+		if i < 0 {
+			break
+		}
+		found = append(found, from+i)
+		from += i + 1
+	}
+	if len(found) == 0 {
+		return -1
+	}
+
+	if record.Offset != nil {
+		at := *record.Offset
+		if at >= 0 && at+len(text) <= len(content) && content[at:at+len(text)] == text {
+			return at
+		}
+	}
+	if anchor := record.After + record.Before; anchor != "" {
+		if re, err := regexp.Compile("(?m)" + anchor); err == nil {
+			if loc := re.FindStringIndex(content); loc != nil {
+				start := strings.LastIndex(content[:loc[0]], "\n") + 1
+				end := lineEnd(content, loc[1])
+				for _, at := range found {
+					if record.Before != "" && at+len(text) == start {
+						return at
+					}
+					// An injection after the file's last line starts with the
+					// newline that ends the anchor line.
+					if record.After != "" && (at == end || (at+1 == end && text[0] == '\n')) {
+						return at
+					}
+				}
+			}
+		}
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	return -1
+}
+
+// RevertInjection undoes a recorded injection. It reports false when the
+// injected text is no longer present, for example because the user edited it,
+// or when it cannot be told apart from identical text the user wrote.
+func RevertInjection(content []byte, record model.InjectionRecord) ([]byte, bool) {
+	at := locateInjection(string(content), record)
+	if at < 0 {
+		return content, false
+	}
+	reverted := string(content[:at]) + record.Original + string(content[at+len(record.Content):])
+	return []byte(reverted), true
+}
@@ -0,0 +1,207 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func TestApplyInjections(t *testing.T) {
+	original := "package routes\n\nfunc Register(r Router) {\n\t// routes\n}\n"
+
+	tests := []struct {
+		name      string
+		injection model.Injection
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "after anchor",
+			injection: model.Injection{ID: "users", After: `^\s*// routes$`, Content: "\tr.Handle(\"/users\", users.Handler())"},
+			want:      "package routes\n\nfunc Register(r Router) {\n\t// routes\n\tr.Handle(\"/users\", users.Handler())\n}\n",
+		},
+		{
+			name:      "before anchor",
+			injection: model.Injection{ID: "import", Before: `^func Register`, Content: "// Register wires routes.\n"},
+			want:      "package routes\n\n// Register wires routes.\nfunc Register(r Router) {\n\t// routes\n}\n",
+		},
+		{
+			name:      "append",
+			injection: model.Injection{ID: "footer", Append: true, Content: "// end\n"},
+			want:      original + "// end\n",
+		},
+		{
+			name:      "replace",
+			injection: model.Injection{ID: "sig", Replace: `func Register\(r Router\)`, Content: "func Register(r *Router)"},
+			want:      "package routes\n\nfunc Register(r *Router) {\n\t// routes\n}\n",
+		},
+		{
+			name:      "already in place",
+			injection: model.Injection{ID: "close", Append: true, Content: "}\n"},
+			want:      original,
+		},
+		{
+			name:      "line present elsewhere",
+			injection: model.Injection{ID: "close", Before: `^\s*// routes$`, Content: "}"},
+			want:      "package routes\n\nfunc Register(r Router) {\n}\n\t// routes\n}\n",
+		},
+		{
+			name:      "missing anchor",
+			injection: model.Injection{ID: "users", After: `// handlers`, Content: "x"},
+			wantErr:   `injection "users": anchor "// handlers" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &model.InjectSpec{Injections: []model.Injection{tt.injection}}
+			got, records, err := ApplyInjections([]byte(original), spec, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplyInjections() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyInjections() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("ApplyInjections() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			// Applying again is a no-op, with or without the records, and
+			// every record reverts cleanly.
+			for _, applied := range [][]model.InjectionRecord{nil, records} {
+				again, more, err := ApplyInjections(got, spec, applied)
+				if err != nil || string(again) != tt.want || len(more) != 0 {
+					t.Errorf("second ApplyInjections() = %q, %v, %v; want no-op", again, more, err)
+				}
+			}
+			reverted := got
+			for i := len(records) - 1; i >= 0; i-- {
+				var ok bool
+				reverted, ok = RevertInjection(reverted, records[i])
+				if !ok {
+					t.Fatalf("RevertInjection(%+v) found nothing to revert", records[i])
+				}
+			}
+			if string(reverted) != original {
+				t.Errorf("reverted content =\n%s\nwant original", reverted)
+			}
+		})
+	}
+}
+
+func TestRevertInjection_KeepsIdenticalUserText(t *testing.T) {
+	original := "x\n// routes\n}\n"
+	spec := &model.InjectSpec{Injections: []model.Injection{{ID: "x", After: "// routes", Content: "x"}}}
+	injected, records, err := ApplyInjections([]byte(original), spec, nil)
+	if err != nil {
+		t.Fatalf("ApplyInjections() error = %v", err)
+	}
+	if string(injected) != "x\n// routes\nx\n}\n" || len(records) != 1 {
+		t.Fatalf("ApplyInjections() = %q, %+v", injected, records)
+	}
+
+	// An edit above the injection moves it off its recorded offset, so the
+	// anchor must pick it over the user's identical first line.
+	edited := "// header\n" + string(injected)
+	reverted, ok := RevertInjection([]byte(edited), records[0])
+	if !ok {
+		t.Fatal("RevertInjection() found nothing to revert")
+	}
+	if string(reverted) != "// header\n"+original {
+		t.Errorf("RevertInjection() = %q, want %q", reverted, "// header\n"+original)
+	}
+
+	// Without an anchor to go by, ambiguous text is left alone.
+	record := records[0]
+	record.After = ""
+	if _, ok := RevertInjection([]byte(edited), record); ok {
+		t.Error("RevertInjection() reverted text it cannot tell apart from the user's")
+	}
+}
+
+func TestParseInjectSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{name: "valid", spec: `{"injections": [{"id": "a", "append": true, "content": "x"}]}`},
+		{name: "missing id", spec: `{"injections": [{"append": true, "content": "x"}]}`, wantErr: "id is required"},
+		{name: "duplicate id", spec: `{"injections": [{"id": "a", "append": true, "content": "x"}, {"id": "a", "append": true, "content": "y"}]}`, wantErr: "duplicate injection id"},
+		{name: "two operations", spec: `{"injections": [{"id": "a", "append": true, "after": "x", "content": "x"}]}`, wantErr: "exactly one of"},
+		{name: "bad regex", spec: `{"injections": [{"id": "a", "after": "(", "content": "x"}]}`, wantErr: "invalid regex"},
+		{name: "empty content", spec: `{"injections": [{"id": "a", "append": true}]}`, wantErr: "content is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseInjectSpec([]byte(tt.spec))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseInjectSpec() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseInjectSpec() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateAppliesInjectFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	routesPath := filepath.Join(outDir, "routes.go")
+	if err := os.WriteFile(routesPath, []byte("func Register() {\n\t// routes\n}\n"), 0644); err != nil {
+		t.Fatalf("failed to write routes.go: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{Name: "addon", Version: "1.0.0"},
+		Files: []model.TemplateFile{
+			{Path: "routes.go.ign-inject", Content: []byte(`{"injections": [{"id": "route", "after": "// routes", "content": "\tregister(\"@ign-var:name@\")"}]}`), Mode: 0644},
+			{Path: "missing.go.ign-inject", Content: []byte(`{"injections": [{"id": "x", "append": true, "content": "x"}]}`), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:  template,
+		Variables: parser.NewMapVariables(map[string]interface{}{"name": "users"}),
+		OutputDir: outDir,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	got, err := os.ReadFile(routesPath)
+	if err != nil {
+		t.Fatalf("failed to read routes.go: %v", err)
+	}
+	if string(got) != "func Register() {\n\t// routes\n\tregister(\"users\")\n}\n" {
+		t.Errorf("routes.go =\n%s", got)
+	}
+	if len(result.Injections) != 1 || result.Injections[0].Path != routesPath || result.Injections[0].ID != "route" {
+		t.Errorf("Injections = %+v", result.Injections)
+	}
+	if len(result.Files) != 0 || len(result.WrittenFiles) != 0 {
+		t.Errorf("injection targets must not be tracked as generated files: files=%v written=%v", result.Files, result.WrittenFiles)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "injection target") {
+		t.Errorf("Errors = %v, want missing target error", result.Errors)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "routes.go.ign-inject")); !os.IsNotExist(err) {
+		t.Errorf("inject spec must not be written, stat error = %v", err)
+	}
+}
@@ -16,6 +16,8 @@ type Processor interface {
 	// Process processes a single template file and returns the processed content.
 	// For binary files, returns the content unchanged.
 	// For text files, processes template directives using the parser.
+	// When a configured formatter fails, Process returns the unformatted
+	// content together with a *FormatError.
 	Process(ctx context.Context, file model.TemplateFile, vars parser.Variables, templateRoot string) ([]byte, error)
 
 	// ShouldProcess determines if a file should be template-processed.
@@ -27,6 +29,7 @@ type Processor interface {
 type FileProcessor struct {
 	parser           parser.Parser
 	binaryExtensions []string
+	template         *model.Template
 }
 
 // NewFileProcessor creates a new FileProcessor.
@@ -41,6 +44,15 @@ func NewFileProcessor(p parser.Parser, binaryExtensions []string) Processor {
 	}
 }
 
+// NewTemplateFileProcessor creates a FileProcessor configured by a template's
+// settings: its binary extensions and its text normalization settings.
+func NewTemplateFileProcessor(p parser.Parser, template *model.Template) Processor {
+	settings := getTemplateSettings(template)
+	processor := NewFileProcessor(p, settings.BinaryExtensions).(*FileProcessor)
+	processor.template = template
+	return processor
+}
+
 // defaultBinaryExtensions returns a default list of binary file extensions.
 func defaultBinaryExtensions() []string {
 	return []string{
@@ -100,7 +112,8 @@ func isBinaryContent(content []byte) bool {
 
 // Process processes a single template file.
 // For binary files or files that should not be processed, returns content unchanged.
-// For text files, processes template directives using the parser.
+// For text files, processes template directives using the parser, then runs
+// the template's formatter and text normalization settings for the file's path.
 func (p *FileProcessor) Process(ctx context.Context, file model.TemplateFile, vars parser.Variables, templateRoot string) ([]byte, error) {
 	// If file should not be processed, return unchanged
 	if !p.ShouldProcess(file) {
@@ -130,8 +143,14 @@ func (p *FileProcessor) Process(ctx context.Context, file model.TemplateFile, va
 			err)
 	}
 
+	var formatErr error
+	if p.template != nil {
+		processed, formatErr = formatRendered(p.template, file.Path, processed)
+		processed = NormalizeText(processed, TextSettingsFor(p.template, file.Path))
+	}
+
 	debug.Debug("[generator] Template processing complete: %s (input: %d bytes, output: %d bytes)",
 		file.Path, len(file.Content), len(processed))
 
-	return processed, nil
+	return processed, formatErr
 }
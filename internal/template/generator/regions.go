@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// Managed regions are marker-delimited blocks inside a generated file that
+// update rewrites while leaving the rest of the file to the user:
+//
+//	# ign:begin ci-steps
+//	...template-owned lines...
+//	# ign:end ci-steps
+//
+// Any comment leader works ("#", "//", "<!--", ";"), because markers are
+// matched after leading punctuation and whitespace.
+var (
+	regionBeginPattern = regexp.MustCompile(`^[^\w\r\n]*ign:begin\s+([\w.-]+)`)
+	regionEndPattern   = regexp.MustCompile(`^[^\w\r\n]*ign:end\s+([\w.-]+)`)
+)
+
+// region is a managed region. start and end are the byte offsets of its body,
+// which excludes the marker lines.
+type region struct {
+	name  string
+	start int
+	end   int
+}
+
+// HasRegions reports whether content contains a managed region begin marker.
+func HasRegions(content []byte) bool {
+	if !bytes.Contains(content, []byte("ign:begin")) {
+		return false
+	}
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if regionBeginPattern.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRegions returns the managed regions of content in file order.
+// Regions may not nest, repeat a name, or be left open.
+func parseRegions(content []byte) ([]region, error) {
+	var regions []region
+	seen := make(map[string]bool)
+	var open *region
+	lineNum := 0
+	offset := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		lineNum++
+		lineStart := offset
+		offset += len(line)
+
+		if match := regionBeginPattern.FindSubmatch(line); match != nil {
+			name := string(match[1])
+			if open != nil {
+				return nil, fmt.Errorf("line %d: region %q begins inside region %q", lineNum, name, open.name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("line %d: duplicate region %q", lineNum, name)
+			}
+			seen[name] = true
+			open = &region{name: name, start: offset}
+			continue
+		}
+		if match := regionEndPattern.FindSubmatch(line); match != nil {
+			name := string(match[1])
+			if open == nil || open.name != name {
+				return nil, fmt.Errorf("line %d: end of region %q without matching begin", lineNum, name)
+			}
+			open.end = lineStart
+			regions = append(regions, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		return nil, fmt.Errorf("region %q is not closed", open.name)
+	}
+	return regions, nil
+}
+
+// MergeRegions replaces the body of each managed region in existing with the
+// body of the same region in rendered. Everything outside the regions is kept
+// byte for byte. Regions the user removed from existing stay removed, and
+// regions only present in existing are left untouched.
+func MergeRegions(existing []byte, rendered []byte) ([]byte, error) {
+	renderedRegions, err := parseRegions(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("template rendering: %w", err)
+	}
+	existingRegions, err := parseRegions(existing)
+	if err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+
+	bodies := make(map[string][]byte, len(renderedRegions))
+	for _, r := range renderedRegions {
+		bodies[r.name] = rendered[r.start:r.end]
+	}
+
+	var merged bytes.Buffer
+	merged.Grow(len(existing))
+	pos := 0
+	for _, r := range existingRegions {
+		merged.Write(existing[pos:r.start])
+		if body, ok := bodies[r.name]; ok {
+			merged.Write(body)
+		} else {
+			merged.Write(existing[r.start:r.end])
+		}
+		pos = r.end
+	}
+	merged.Write(existing[pos:])
+	return merged.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func TestMergeRegions(t *testing.T) {
+	rendered := "all:\n\tmake build\n\n# ign:begin ci-steps\nlint:\n\tgolangci-lint run\n# ign:end ci-steps\n" +
+		"<!-- ign:begin docs -->\nnew docs\n<!-- ign:end docs -->\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "rewrites region and keeps user content",
+			existing: "all:\n\tmake custom\n\n# ign:begin ci-steps\nlint:\n\tgo vet ./...\n# ign:end ci-steps\nmine:\n\techo me\n",
+			want:     "all:\n\tmake custom\n\n# ign:begin ci-steps\nlint:\n\tgolangci-lint run\n# ign:end ci-steps\nmine:\n\techo me\n",
+		},
+		{
+			name:     "any comment leader",
+			existing: "top\n<!-- ign:begin docs -->\nold docs\n<!-- ign:end docs -->\n",
+			want:     "top\n<!-- ign:begin docs -->\nnew docs\n<!-- ign:end docs -->\n",
+		},
+		{
+			name:     "regions removed by the user stay removed",
+			existing: "user only\n",
+			want:     "user only\n",
+		},
+		{
+			name:     "unknown existing region is kept",
+			existing: "# ign:begin local\nkeep\n# ign:end local\n",
+			want:     "# ign:begin local\nkeep\n# ign:end local\n",
+		},
+		{
+			name:     "unclosed region",
+			existing: "# ign:begin ci-steps\nlint:\n",
+			wantErr:  `region "ci-steps" is not closed`,
+		},
+		{
+			name:     "mismatched end",
+			existing: "# ign:begin ci-steps\n# ign:end docs\n",
+			wantErr:  `line 2: end of region "docs" without matching begin`,
+		},
+		{
+			name:     "nested region",
+			existing: "# ign:begin a\n# ign:begin b\n# ign:end b\n# ign:end a\n",
+			wantErr:  `line 2: region "b" begins inside region "a"`,
+		},
+		{
+			name:     "duplicate region",
+			existing: "# ign:begin a\n# ign:end a\n# ign:begin a\n# ign:end a\n",
+			wantErr:  `line 3: duplicate region "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeRegions([]byte(tt.existing), []byte(rendered))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("MergeRegions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeRegions() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MergeRegions() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasRegions(t *testing.T) {
+	if !HasRegions([]byte("x\n  // ign:begin deps\n// ign:end deps\n")) {
+		t.Error("expected region marker to be detected")
+	}
+	if HasRegions([]byte("see ign:begin in docs\n")) {
+		t.Error("marker text inside prose should not count as a region")
+	}
+}
+
+func TestGenerator_MergeExistingRewritesRegions(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	existing := "node_modules/\n# ign:begin defaults\n*.log\n# ign:end defaults\n.env.local\n"
+	gitignorePath := filepath.Join(outDir, ".gitignore")
+	if err := os.WriteFile(gitignorePath, []byte(existing), 0644); err != nil {
+		t.Fatalf("failed to write .gitignore: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{Name: "regions", Version: "1.0.0"},
+		Files: []model.TemplateFile{
+			{Path: ".gitignore", Content: []byte("# ign:begin defaults\n*.log\n@ign-var:build_dir@/\n# ign:end defaults\n"), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+	opts := GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{"build_dir": "dist"}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteNone,
+		MergeExisting: true,
+	}
+
+	// Without an overwrite the file is not edited.
+	kept, err := NewGenerator().DryRun(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if kept.FilesMerged != 0 || len(kept.DryRunFiles) != 1 || !kept.DryRunFiles[0].WouldSkip {
+		t.Fatalf("dry run without overwrite merged=%d files=%+v, want a skip", kept.FilesMerged, kept.DryRunFiles)
+	}
+
+	// A full overwrite still only rewrites the regions.
+	opts.OverwriteMode = OverwriteAll
+	preview, err := NewGenerator().DryRun(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if preview.FilesMerged != 1 || len(preview.DryRunFiles) != 1 || !preview.DryRunFiles[0].WouldOverwrite {
+		t.Fatalf("dry run merged=%d files=%+v, want one merge", preview.FilesMerged, preview.DryRunFiles)
+	}
+
+	result, err := NewGenerator().Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.FilesMerged != 1 || len(result.WrittenFiles) != 1 {
+		t.Fatalf("merged=%d written=%v, want one merged file", result.FilesMerged, result.WrittenFiles)
+	}
+	got, err := os.ReadFile(gitignorePath)
+	if err != nil {
+		t.Fatalf("failed to read .gitignore: %v", err)
+	}
+	want := "node_modules/\n# ign:begin defaults\n*.log\ndist/\n# ign:end defaults\n.env.local\n"
+	if string(got) != want {
+		t.Errorf(".gitignore =\n%s\nwant\n%s", got, want)
+	}
+
+	// A second run finds nothing to change.
+	again, err := NewGenerator().Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("second Generate() error = %v", err)
+	}
+	if again.FilesMerged != 0 || len(again.WrittenFiles) != 0 {
+		t.Errorf("second run merged=%d written=%v, want no writes", again.FilesMerged, again.WrittenFiles)
+	}
+}
+
+func TestGenerator_MergeExistingSkipsProtectedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	existing := "mine\n# ign:begin a\nold\n# ign:end a\n"
+	for _, name := range []string{"LOCAL.md", "Makefile"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(existing), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	rendered := []byte("# ign:begin a\nnew\n# ign:end a\n")
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name: "regions", Version: "1.0.0",
+			Files: map[string]model.FileRule{"LOCAL.md": {Policy: model.FilePolicyUser}},
+		},
+		Files: []model.TemplateFile{
+			{Path: model.IgnOverwriteIgnoreFile, Content: []byte("Makefile\n"), Mode: 0644},
+			{Path: "LOCAL.md", Content: rendered, Mode: 0644},
+			{Path: "Makefile", Content: rendered, Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteSelective,
+		MergeExisting: true,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.FilesMerged != 0 {
+		t.Errorf("merged %v, want no merges", result.MergedFiles)
+	}
+	for _, name := range []string{"LOCAL.md", "Makefile"} {
+		got, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != existing {
+			t.Errorf("%s was edited:\n%s", name, got)
+		}
+	}
+}
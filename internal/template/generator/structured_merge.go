@@ -0,0 +1,411 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+// MergeStructured deep-merges the rendered template document into the
+// existing one (OverwriteMerge). Template values win on keys the template
+// declares, keys only the user has are preserved, and arrays present in both
+// follow the array policy. The format is chosen by the extension of path.
+//
+// A document the merge does not change is returned as is. Otherwise JSON keeps
+// the existing key order and indentation, YAML keeps key order and comments,
+// and TOML is re-encoded with sorted keys and without comments.
+func MergeStructured(path string, existing []byte, rendered []byte, arrays model.ArrayMergePolicy) ([]byte, error) {
+	if len(bytes.TrimSpace(existing)) == 0 {
+		return rendered, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return mergeJSON(existing, rendered, arrays)
+	case ".yaml", ".yml":
+		return mergeYAML(existing, rendered, arrays)
+	case ".toml":
+		return mergeTOML(existing, rendered, arrays)
+	default:
+		return nil, fmt.Errorf("merge strategy supports .json, .yaml, .yml, and .toml files")
+	}
+}
+
+// mergeValues merges decoded documents. Objects merge key by key, arrays follow
+// the array policy, and any other template value replaces the user's.
+func mergeValues(user interface{}, tmpl interface{}, arrays model.ArrayMergePolicy) interface{} {
+	switch t := tmpl.(type) {
+	case *jsonObject:
+		u, ok := user.(*jsonObject)
+		if !ok {
+			return t
+		}
+		merged := &jsonObject{
+			keys:   append([]string(nil), u.keys...),
+			values: make(map[string]interface{}, len(u.values)+len(t.values)),
+		}
+		for key, value := range u.values {
+			merged.values[key] = value
+		}
+		for _, key := range t.keys {
+			if current, exists := merged.values[key]; exists {
+				merged.values[key] = mergeValues(current, t.values[key], arrays)
+				continue
+			}
+			merged.keys = append(merged.keys, key)
+			merged.values[key] = t.values[key]
+		}
+		return merged
+	case map[string]interface{}:
+		u, ok := user.(map[string]interface{})
+		if !ok {
+			return t
+		}
+		merged := make(map[string]interface{}, len(u)+len(t))
+		for key, value := range u {
+			merged[key] = value
+		}
+		for key, value := range t {
+			if current, exists := merged[key]; exists {
+				merged[key] = mergeValues(current, value, arrays)
+				continue
+			}
+			merged[key] = value
+		}
+		return merged
+	case []interface{}:
+		u, ok := user.([]interface{})
+		if !ok {
+			return t
+		}
+		switch arrays {
+		case model.ArrayMergeKeep:
+			return u
+		case model.ArrayMergeUnion:
+			merged := append([]interface{}(nil), u...)
+			for _, item := range t {
+				if !containsValue(merged, item) {
+					merged = append(merged, item)
+				}
+			}
+			return merged
+		default:
+			return t
+		}
+	default:
+		return tmpl
+	}
+}
+
+func containsValue(items []interface{}, value interface{}) bool {
+	for _, item := range items {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonObject is a JSON object that remembers its key order.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func mergeJSON(existing []byte, rendered []byte, arrays model.ArrayMergePolicy) ([]byte, error) {
+	user, err := decodeOrderedJSON(existing)
+	if err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+	tmpl, err := decodeOrderedJSON(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("template rendering: %w", err)
+	}
+
+	merged := mergeValues(user, tmpl, arrays)
+	if reflect.DeepEqual(merged, user) {
+		return existing, nil
+	}
+
+	var buf bytes.Buffer
+	if err := encodeOrderedJSON(&buf, merged, detectIndent(existing), ""); err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(existing, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeOrderedJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level JSON value")
+	}
+	return value, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := &jsonObject{values: map[string]interface{}{}}
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON object key %v", keyToken)
+			}
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj.values[key]; !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		items := []interface{}{}
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+	}
+}
+
+func encodeOrderedJSON(buf *bytes.Buffer, value interface{}, indent string, prefix string) error {
+	switch v := value.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range v.keys {
+			buf.WriteString(prefix + indent)
+			if err := encodeJSONScalar(buf, key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := encodeOrderedJSON(buf, v.values[key], indent, prefix+indent); err != nil {
+				return err
+			}
+			if i < len(v.keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(prefix + "}")
+		return nil
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range v {
+			buf.WriteString(prefix + indent)
+			if err := encodeOrderedJSON(buf, item, indent, prefix+indent); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(prefix + "]")
+		return nil
+	default:
+		return encodeJSONScalar(buf, v)
+	}
+}
+
+// encodeJSONScalar writes a scalar without HTML escaping, so values such as
+// "npm test && npm run lint" stay readable.
+func encodeJSONScalar(buf *bytes.Buffer, value interface{}) error {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(scalar.Bytes(), []byte("\n")))
+	return nil
+}
+
+// detectIndent returns the indentation of the first indented line, or two
+// spaces when the document has none.
+func detectIndent(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+func mergeYAML(existing []byte, rendered []byte, arrays model.ArrayMergePolicy) ([]byte, error) {
+	var user, tmpl yaml.Node
+	if err := yaml.Unmarshal(existing, &user); err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+	if err := yaml.Unmarshal(rendered, &tmpl); err != nil {
+		return nil, fmt.Errorf("template rendering: %w", err)
+	}
+	if len(tmpl.Content) == 0 {
+		return existing, nil
+	}
+	if len(user.Content) == 0 {
+		return rendered, nil
+	}
+
+	var before interface{}
+	if err := user.Content[0].Decode(&before); err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+	user.Content[0] = mergeYAMLNodes(user.Content[0], tmpl.Content[0], arrays)
+	var after interface{}
+	if err := user.Content[0].Decode(&after); err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(before, after) {
+		return existing, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(len(detectIndent(existing)))
+	if err := encoder.Encode(&user); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeYAMLNodes is mergeValues for YAML nodes, which carry comments and
+// key order through the merge.
+func mergeYAMLNodes(user *yaml.Node, tmpl *yaml.Node, arrays model.ArrayMergePolicy) *yaml.Node {
+	if user.Kind != tmpl.Kind {
+		return tmpl
+	}
+
+	switch tmpl.Kind {
+	case yaml.MappingNode:
+		merged := *user
+		merged.Content = append([]*yaml.Node(nil), user.Content...)
+		for i := 0; i+1 < len(tmpl.Content); i += 2 {
+			key, value := tmpl.Content[i], tmpl.Content[i+1]
+			if j := yamlMappingIndex(merged.Content, key.Value); j >= 0 {
+				merged.Content[j+1] = mergeYAMLNodes(merged.Content[j+1], value, arrays)
+				continue
+			}
+			merged.Content = append(merged.Content, key, value)
+		}
+		return &merged
+	case yaml.SequenceNode:
+		switch arrays {
+		case model.ArrayMergeKeep:
+			return user
+		case model.ArrayMergeUnion:
+			merged := *user
+			merged.Content = append([]*yaml.Node(nil), user.Content...)
+			for _, item := range tmpl.Content {
+				if !containsYAMLNode(merged.Content, item) {
+					merged.Content = append(merged.Content, item)
+				}
+			}
+			return &merged
+		default:
+			return tmpl
+		}
+	default:
+		merged := *tmpl
+		if merged.HeadComment == "" {
+			merged.HeadComment = user.HeadComment
+		}
+		if merged.LineComment == "" {
+			merged.LineComment = user.LineComment
+		}
+		return &merged
+	}
+}
+
+func yamlMappingIndex(content []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsYAMLNode(items []*yaml.Node, node *yaml.Node) bool {
+	var want interface{}
+	if err := node.Decode(&want); err != nil {
+		return false
+	}
+	for _, item := range items {
+		var got interface{}
+		if err := item.Decode(&got); err == nil && reflect.DeepEqual(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeTOML(existing []byte, rendered []byte, arrays model.ArrayMergePolicy) ([]byte, error) {
+	var user, tmpl map[string]interface{}
+	if err := toml.Unmarshal(existing, &user); err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+	if err := toml.Unmarshal(rendered, &tmpl); err != nil {
+		return nil, fmt.Errorf("template rendering: %w", err)
+	}
+	merged := mergeValues(user, tmpl, arrays)
+	if reflect.DeepEqual(merged, user) {
+		return existing, nil
+	}
+	return toml.Marshal(merged)
+}
@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func TestMergeStructured_JSON(t *testing.T) {
+	existing := `{
+    "name": "my-app",
+    "scripts": {
+        "dev": "vite",
+        "test": "jest"
+    },
+    "keywords": ["mine", "shared"]
+}
+`
+	rendered := `{"name": "template", "scripts": {"test": "vitest && eslint ."}, "keywords": ["shared", "tmpl"], "engines": {"node": ">=20"}}`
+
+	tests := []struct {
+		arrays   model.ArrayMergePolicy
+		keywords string
+	}{
+		{arrays: model.ArrayMergeReplace, keywords: `"shared",
+        "tmpl"`},
+		{arrays: model.ArrayMergeUnion, keywords: `"mine",
+        "shared",
+        "tmpl"`},
+		{arrays: model.ArrayMergeKeep, keywords: `"mine",
+        "shared"`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.arrays), func(t *testing.T) {
+			got, err := MergeStructured("package.json", []byte(existing), []byte(rendered), tt.arrays)
+			if err != nil {
+				t.Fatalf("MergeStructured() error = %v", err)
+			}
+			want := `{
+    "name": "template",
+    "scripts": {
+        "dev": "vite",
+        "test": "vitest && eslint ."
+    },
+    "keywords": [
+        ` + tt.keywords + `
+    ],
+    "engines": {
+        "node": ">=20"
+    }
+}
+`
+			if string(got) != want {
+				t.Errorf("MergeStructured() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeStructured_YAMLKeepsComments(t *testing.T) {
+	existing := `# project lint config
+run:
+  timeout: 5m # slow CI
+linters:
+  enable:
+    - govet
+    - mylinter
+`
+	rendered := `run:
+  timeout: 3m
+linters:
+  enable:
+    - govet
+    - errcheck
+`
+	got, err := MergeStructured(".golangci.yml", []byte(existing), []byte(rendered), model.ArrayMergeUnion)
+	if err != nil {
+		t.Fatalf("MergeStructured() error = %v", err)
+	}
+	for _, want := range []string{"# project lint config", "timeout: 3m # slow CI", "- mylinter", "- errcheck"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("merged YAML missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMergeStructured_TOML(t *testing.T) {
+	existing := "[tool]\nname = \"mine\"\nextra = true\n"
+	rendered := "[tool]\nname = \"tmpl\"\n"
+
+	got, err := MergeStructured("pyproject.toml", []byte(existing), []byte(rendered), model.ArrayMergeReplace)
+	if err != nil {
+		t.Fatalf("MergeStructured() error = %v", err)
+	}
+	for _, want := range []string{"name = 'tmpl'", "extra = true"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("merged TOML missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMergeStructured_UnchangedAndErrors(t *testing.T) {
+	existing := "{\n\t\"a\": 1,\n\t\"b\": 2\n}"
+	got, err := MergeStructured("x.json", []byte(existing), []byte(`{"a": 1}`), model.ArrayMergeReplace)
+	if err != nil {
+		t.Fatalf("MergeStructured() error = %v", err)
+	}
+	if string(got) != existing {
+		t.Errorf("unchanged document was rewritten:\n%s", got)
+	}
+
+	if _, err := MergeStructured("x.json", []byte("{broken"), []byte(`{}`), model.ArrayMergeReplace); err == nil || !strings.Contains(err.Error(), "existing file") {
+		t.Errorf("expected existing file parse error, got %v", err)
+	}
+	if _, err := MergeStructured("x.ini", []byte("a=1"), []byte("a=2"), model.ArrayMergeReplace); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
+
+func TestGenerator_MergeExistingAppliesMergeStrategy(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("failed to create output dir: %v", err)
+	}
+	configPath := filepath.Join(outDir, "config.json")
+	if err := os.WriteFile(configPath, []byte("{\n  \"user\": true,\n  \"port\": 1\n}\n"), 0644); err != nil {
+		t.Fatalf("failed to write config.json: %v", err)
+	}
+
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "merge",
+			Version: "1.0.0",
+			Files:   map[string]model.FileRule{"config.json": {Strategy: model.FileStrategyMerge}},
+		},
+		Files: []model.TemplateFile{
+			{Path: "config.json", Content: []byte(`{"port": @ign-var:port@}`), Mode: 0644},
+		},
+		RootPath: tmpDir,
+	}
+
+	result, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:      template,
+		Variables:     parser.NewMapVariables(map[string]interface{}{"port": 8080}),
+		OutputDir:     outDir,
+		OverwriteMode: OverwriteSelective,
+		MergeExisting: true,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.FilesMerged != 1 || result.FilesOverwritten != 0 {
+		t.Fatalf("merged/overwritten = %d/%d, want 1/0", result.FilesMerged, result.FilesOverwritten)
+	}
+	got, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config.json: %v", err)
+	}
+	if string(got) != "{\n  \"user\": true,\n  \"port\": 8080\n}\n" {
+		t.Errorf("config.json =\n%s", got)
+	}
+}
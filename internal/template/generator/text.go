@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"bytes"
+
+	"github.com/tacogips/ign/internal/template/model"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// NormalizeText applies text normalization settings to rendered content:
+// BOM stripping, blank-line collapsing, line-ending conversion, and the final
+// newline, in that order.
+func NormalizeText(content []byte, text model.TextSettings) []byte {
+	if text.IsZero() {
+		return content
+	}
+
+	if text.StripBOM != nil && *text.StripBOM {
+		content = bytes.TrimPrefix(content, utf8BOM)
+	}
+	if text.CollapseAllBlankLines != nil && *text.CollapseAllBlankLines {
+		content = collapseBlankLines(content)
+	}
+
+	switch text.EOL {
+	case model.EOLLF:
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	case model.EOLCRLF:
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+	}
+
+	if text.FinalNewline != nil && *text.FinalNewline && len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		eol := []byte("\n")
+		if text.EOL == model.EOLCRLF || (text.EOL != model.EOLLF && bytes.Contains(content, []byte("\r\n"))) {
+			eol = []byte("\r\n")
+		}
+		content = append(append([]byte(nil), content...), eol...)
+	}
+	return content
+}
+
+// collapseBlankLines replaces every run of whitespace-only lines with a single
+// empty line, keeping each run's line ending. It cannot tell runs left by
+// removed directives from runs written in the template.
+func collapseBlankLines(content []byte) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	out := make([]byte, 0, len(content))
+	previousBlank := false
+	for _, line := range lines {
+		body := bytes.TrimRight(line, "\r\n")
+		blank := len(bytes.TrimSpace(body)) == 0 && len(line) > len(body)
+		if blank {
+			if previousBlank {
+				continue
+			}
+			out = append(out, line[len(body):]...)
+		} else {
+			out = append(out, line...)
+		}
+		previousBlank = blank
+	}
+	return out
+}
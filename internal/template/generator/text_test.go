@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tacogips/ign/internal/template/model"
+	"github.com/tacogips/ign/internal/template/parser"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		text    model.TextSettings
+		want    string
+	}{
+		{name: "no settings", content: "\uFEFFa\r\nb", want: "\uFEFFa\r\nb"},
+		{name: "lf", content: "a\r\nb\nc\r\n", text: model.TextSettings{EOL: model.EOLLF}, want: "a\nb\nc\n"},
+		{name: "crlf", content: "a\r\nb\nc", text: model.TextSettings{EOL: model.EOLCRLF}, want: "a\r\nb\r\nc"},
+		{name: "preserve", content: "a\r\nb\n", text: model.TextSettings{EOL: model.EOLPreserve}, want: "a\r\nb\n"},
+		{name: "strip bom", content: "\uFEFFa\n", text: model.TextSettings{StripBOM: boolPtr(true)}, want: "a\n"},
+		{name: "final newline", content: "a\nb", text: model.TextSettings{FinalNewline: boolPtr(true)}, want: "a\nb\n"},
+		{name: "final newline follows crlf", content: "a\r\nb", text: model.TextSettings{FinalNewline: boolPtr(true)}, want: "a\r\nb\r\n"},
+		{name: "final newline with eol", content: "a\nb", text: model.TextSettings{EOL: model.EOLCRLF, FinalNewline: boolPtr(true)}, want: "a\r\nb\r\n"},
+		{name: "final newline keeps empty file", content: "", text: model.TextSettings{FinalNewline: boolPtr(true)}, want: ""},
+		{name: "collapse blank lines", content: "a\n\n  \n\t\nb\n\n\nc\n", text: model.TextSettings{CollapseAllBlankLines: boolPtr(true)}, want: "a\n\nb\n\nc\n"},
+		{name: "collapse keeps crlf", content: "a\r\n\r\n\r\nb\r\n", text: model.TextSettings{CollapseAllBlankLines: boolPtr(true)}, want: "a\r\n\r\nb\r\n"},
+		{name: "disabled flags", content: "\uFEFFa\n\n\nb", text: model.TextSettings{StripBOM: boolPtr(false), FinalNewline: boolPtr(false), CollapseAllBlankLines: boolPtr(false)}, want: "\uFEFFa\n\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(NormalizeText([]byte(tt.content), tt.text)); got != tt.want {
+				t.Errorf("NormalizeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextSettingsFor(t *testing.T) {
+	template := &model.Template{
+		Config: model.IgnJson{
+			Settings: &model.TemplateSettings{
+				TextSettings: model.TextSettings{EOL: model.EOLLF, FinalNewline: boolPtr(true)},
+			},
+			Files: map[string]model.FileRule{
+				"scripts/**":       {TextSettings: model.TextSettings{EOL: model.EOLCRLF}},
+				"scripts/keep.bat": {TextSettings: model.TextSettings{EOL: model.EOLPreserve}},
+				"docker/**":        {When: "use_docker"},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want model.TextSettings
+	}{
+		{path: "README.md", want: model.TextSettings{EOL: model.EOLLF, FinalNewline: boolPtr(true)}},
+		{path: "scripts/build.bat", want: model.TextSettings{EOL: model.EOLCRLF, FinalNewline: boolPtr(true)}},
+		{path: "scripts/keep.bat", want: model.TextSettings{EOL: model.EOLPreserve, FinalNewline: boolPtr(true)}},
+		{path: "docker/Dockerfile", want: model.TextSettings{EOL: model.EOLLF, FinalNewline: boolPtr(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := TextSettingsFor(template, tt.path)
+			if got.EOL != tt.want.EOL || *got.FinalNewline != *tt.want.FinalNewline {
+				t.Errorf("TextSettingsFor(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateNormalizesText(t *testing.T) {
+	outputDir := t.TempDir()
+	template := &model.Template{
+		Config: model.IgnJson{
+			Name:    "test",
+			Version: "1.0.0",
+			Settings: &model.TemplateSettings{
+				TextSettings: model.TextSettings{
+					EOL:                   model.EOLLF,
+					StripBOM:              boolPtr(true),
+					FinalNewline:          boolPtr(true),
+					CollapseAllBlankLines: boolPtr(true),
+				},
+			},
+			Files: map[string]model.FileRule{
+				"*.bat": {TextSettings: model.TextSettings{EOL: model.EOLCRLF}},
+			},
+		},
+		Files: []model.TemplateFile{
+			{Path: "README.md", Content: []byte("\uFEFF# title\r\n\r\n@ign-if:docker@\nDocker\n@ign-endif@\n\r\nend"), Mode: 0644},
+			{Path: "build.bat", Content: []byte("echo one\necho two"), Mode: 0644},
+			{Path: "logo.png", Content: []byte("\uFEFFraw\r\n"), Mode: 0644},
+		},
+		RootPath: t.TempDir(),
+	}
+
+	_, err := NewGenerator().Generate(context.Background(), GenerateOptions{
+		Template:  template,
+		Variables: parser.NewMapVariables(map[string]interface{}{"docker": false}),
+		OutputDir: outputDir,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string]string{
+		"README.md": "# title\n\nend\n",
+		"build.bat": "echo one\r\necho two\r\n",
+		"logo.png":  "\uFEFFraw\r\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
@@ -25,8 +25,13 @@ type Writer interface {
 	// CreateDir creates a directory and any necessary parent directories.
 	CreateDir(path string) error
 
-	// Exists checks if a file or directory exists at the given path.
+	// Exists reports whether anything, including a dangling symlink, is at
+	// the given path.
 	Exists(path string) bool
+
+	// ReadFile returns the content and permissions of an existing file, so
+	// it can be merged with template output before being written back.
+	ReadFile(path string) ([]byte, os.FileMode, error)
 }
 
 // FileWriter implements Writer for filesystem operations.
@@ -185,12 +190,27 @@ func (w *FileWriter) CreateDir(path string) error {
 	return nil
 }
 
-// Exists checks if a file or directory exists at the given path.
+// Exists reports whether anything, including a dangling symlink, is at the
+// given path.
 func (w *FileWriter) Exists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := os.Lstat(path)
 	return err == nil
 }
 
+// ReadFile returns the content and permissions of the file at path,
+// following symlinks.
+func (w *FileWriter) ReadFile(path string) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return content, info.Mode().Perm(), nil
+}
+
 // CopyFile is a utility function to copy a file from src to dst.
 // This is useful for binary files that should be copied as-is.
 func CopyFile(src, dst string, mode os.FileMode) error {
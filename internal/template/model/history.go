@@ -0,0 +1,53 @@
+package model
+
+import (
+	"os"
+	"time"
+)
+
+// HistorySnapshot records the state of every path a mutating command changed,
+// as it was before the command ran. It is stored as
+// .ign/history/<id>/snapshot.json and restored by `ign undo`.
+type HistorySnapshot struct {
+	// ID is the snapshot directory name. IDs sort in creation order.
+	ID string `json:"id"`
+	// Command is the command that made the changes, e.g. "update".
+	Command string `json:"command"`
+	// CreatedAt is when the command finished.
+	CreatedAt time.Time `json:"created_at"`
+	// Entries lists the changed paths.
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// HistoryEntryKind is the kind of node a path held before the command ran.
+type HistoryEntryKind string
+
+const (
+	// HistoryEntryAbsent means the path did not exist; undo removes it.
+	HistoryEntryAbsent HistoryEntryKind = "absent"
+	// HistoryEntryFile is a regular file whose content is kept in Blob.
+	HistoryEntryFile HistoryEntryKind = "file"
+	// HistoryEntrySymlink is a symlink to Target.
+	HistoryEntrySymlink HistoryEntryKind = "symlink"
+	// HistoryEntryDir is a directory. Its contents are separate entries.
+	HistoryEntryDir HistoryEntryKind = "dir"
+)
+
+// HistoryEntry is one path in a HistorySnapshot.
+type HistoryEntry struct {
+	// Path is relative to the directory containing .ign, or absolute when it
+	// lies outside it.
+	Path string `json:"path"`
+	// Kind is the node the path held before the command ran.
+	Kind HistoryEntryKind `json:"kind"`
+	// Mode is the permission bits of a file or directory.
+	Mode os.FileMode `json:"mode,omitempty"`
+	// Target is the symlink target.
+	Target string `json:"target,omitempty"`
+	// Blob names the copy of a file's content in the snapshot's files/
+	// directory.
+	Blob string `json:"blob,omitempty"`
+	// After fingerprints the path as the command left it. Undo refuses to
+	// restore a path that changed since, unless forced.
+	After string `json:"after"`
+}
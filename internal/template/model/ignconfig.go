@@ -1,5 +1,10 @@
 package model
 
+import (
+	"fmt"
+	"strconv"
+)
+
 // IgnJson represents the ign.json template configuration file.
 type IgnJson struct {
 	// Name is the template identifier (required).
@@ -18,6 +23,17 @@ type IgnJson struct {
 	Tags []string `json:"tags,omitempty"`
 	// Variables defines all template variables.
 	Variables map[string]VarDef `json:"variables"`
+	// VariableMigrations describes how values stored by projects generated from
+	// earlier template versions are carried forward. Applied in order on update.
+	VariableMigrations []VarMigration `json:"variable_migrations,omitempty"`
+	// Migrations lists the steps an update runs once when a project crosses
+	// the template version that introduced them.
+	Migrations []TemplateMigration `json:"migrations,omitempty"`
+	// Files declares per-path generation rules, keyed by a gitignore-style
+	// pattern matched against template paths (e.g. "docker/**").
+	Files map[string]FileRule `json:"files,omitempty"`
+	// Hooks declares commands to run in the project after generation.
+	Hooks *TemplateHooks `json:"hooks,omitempty"`
 	// Settings contains template-specific settings.
 	Settings *TemplateSettings `json:"settings,omitempty"`
 	// Hash is a SHA256 hash of all template files content (excluding ign.json itself).
@@ -48,6 +64,172 @@ type VarDef struct {
 	Max *float64 `json:"max,omitempty"`
 }
 
+// FileRule declares how matching template paths are generated.
+type FileRule struct {
+	// When names a bool variable; matching paths are generated only when it is
+	// true. A leading "!" inverts the condition (e.g. "!use_docker").
+	When string `json:"when,omitempty"`
+	// Policy controls how update and rewind treat generated paths.
+	Policy FilePolicy `json:"policy,omitempty"`
+	// Strategy selects how update writes an existing file. "merge" deep-merges
+	// a JSON, YAML, or TOML document into the user's copy.
+	Strategy FileStrategy `json:"strategy,omitempty"`
+	// Arrays is the array policy of a merge strategy (default "replace").
+	Arrays ArrayMergePolicy `json:"arrays,omitempty"`
+	// TextSettings overrides the template's text normalization settings for
+	// matching paths (e.g. "eol": "crlf" for "*.bat").
+	TextSettings
+}
+
+// TemplateHooks declares the commands run after a generation completes.
+// They only run with the user's consent.
+type TemplateHooks struct {
+	// PostCheckout runs after checkout (and switch) generate the project.
+	PostCheckout []Hook `json:"post_checkout,omitempty"`
+	// PostUpdate runs after update regenerates the project.
+	PostUpdate []Hook `json:"post_update,omitempty"`
+}
+
+// Hook is a shell command run in the generated project.
+// Run, Dir, and Env values support @ign-var: substitution.
+type Hook struct {
+	// Run is the command line, executed with "sh -c" (required).
+	Run string `json:"run"`
+	// Dir is the working directory relative to the output directory.
+	Dir string `json:"dir,omitempty"`
+	// Env sets additional environment variables for the command.
+	Env map[string]string `json:"env,omitempty"`
+	// When names a bool variable; the hook runs only when it is true.
+	// A leading "!" inverts the condition.
+	When string `json:"when,omitempty"`
+}
+
+// FileStrategy is the update write strategy of a generated file.
+type FileStrategy string
+
+// FileStrategyMerge deep-merges template keys into the existing document:
+// template values win on keys it declares and user-only keys are preserved.
+const FileStrategyMerge FileStrategy = "merge"
+
+// ArrayMergePolicy decides how arrays present in both documents are merged.
+type ArrayMergePolicy string
+
+const (
+	// ArrayMergeReplace uses the template's array.
+	ArrayMergeReplace ArrayMergePolicy = "replace"
+	// ArrayMergeUnion keeps the user's items and appends template items they lack.
+	ArrayMergeUnion ArrayMergePolicy = "union"
+	// ArrayMergeKeep keeps the user's array.
+	ArrayMergeKeep ArrayMergePolicy = "keep"
+)
+
+// IsValid reports whether the array policy is a known value.
+func (p ArrayMergePolicy) IsValid() bool {
+	switch p {
+	case ArrayMergeReplace, ArrayMergeUnion, ArrayMergeKeep:
+		return true
+	default:
+		return false
+	}
+}
+
+// FilePolicy is the update policy of a generated file.
+type FilePolicy string
+
+const (
+	// FilePolicyManaged keeps the file in sync with the template on every update.
+	FilePolicyManaged FilePolicy = "managed"
+	// FilePolicySeed creates the file once; update and rewind never touch it again.
+	FilePolicySeed FilePolicy = "seed"
+	// FilePolicyUser creates the file when missing but never overwrites it.
+	FilePolicyUser FilePolicy = "user"
+)
+
+// IsValid reports whether the policy is a known value.
+func (p FilePolicy) IsValid() bool {
+	switch p {
+	case FilePolicyManaged, FilePolicySeed, FilePolicyUser:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeepsFile reports whether rewind and update cleanup must leave the file in place.
+func (p FilePolicy) KeepsFile() bool {
+	return p == FilePolicySeed || p == FilePolicyUser
+}
+
+// VarMigration declares how a project's stored value for one variable is
+// migrated when the template renames, retypes, remaps, or removes it.
+type VarMigration struct {
+	// Variable is the variable the migration applies to. For renames, value
+	// mappings, and type conversions it is the current (declared) name; for
+	// removals it is the name being dropped.
+	Variable string `json:"variable"`
+	// RenamedFrom lists previous names whose stored value moves to Variable.
+	RenamedFrom []string `json:"renamed_from,omitempty"`
+	// ConvertFrom is the previous type of Variable. Stored values of that type
+	// are converted to the currently declared type.
+	ConvertFrom VarType `json:"convert_from,omitempty"`
+	// ValueMap replaces stored values, keyed by their string form, with new values.
+	ValueMap map[string]interface{} `json:"value_map,omitempty"`
+	// Remove drops the stored value of Variable.
+	Remove bool `json:"remove,omitempty"`
+}
+
+// ValueMapKey returns the string form of a stored value that is looked up in
+// VarMigration.ValueMap. Numbers are written without exponent, so 1e+06 and
+// 1000000 share the key "1000000".
+func ValueMapKey(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// TemplateMigration declares the steps that move a project generated from an
+// earlier template version to Version. Update runs them once, when the
+// project's recorded template version is older than Version and the fetched
+// template is at Version or later.
+type TemplateMigration struct {
+	// Version is the template version that introduced the steps (required).
+	Version string `json:"version"`
+	// Moves relocates generated files, keeping the project's copy and its
+	// modifications. Run before Deletes.
+	Moves []FileMove `json:"moves,omitempty"`
+	// Deletes lists generated files or directories the template dropped.
+	Deletes []string `json:"deletes,omitempty"`
+	// Variables migrates stored variable values, run before the top-level
+	// variable_migrations.
+	Variables []VarMigration `json:"variables,omitempty"`
+}
+
+// FileMove relocates a generated file, or every generated file beneath a
+// directory, to a new template path.
+type FileMove struct {
+	// From is the previous template path (required).
+	From string `json:"from"`
+	// To is the new template path (required).
+	To string `json:"to"`
+}
+
 // TemplateSettings contains template-specific settings for generation.
 type TemplateSettings struct {
 	// PreserveExecutable preserves the executable bit from template files.
@@ -61,6 +243,103 @@ type TemplateSettings struct {
 	IncludeDotfiles bool `json:"include_dotfiles,omitempty"`
 	// MaxIncludeDepth is the maximum nested include depth for @ign-include directives.
 	MaxIncludeDepth int `json:"max_include_depth,omitempty"`
+	// Formatters maps glob patterns to the formatter run on matching
+	// generated files after rendering. When several patterns match a path,
+	// the most specific one applies.
+	Formatters map[string]Formatter `json:"formatters,omitempty"`
+	// FormatErrors decides what a formatter failure does: "warn" (default)
+	// reports it and writes the file unformatted, "fail" stops generation.
+	FormatErrors FormatErrorMode `json:"format_errors,omitempty"`
+	// TextSettings normalizes the text of every generated file. "files" rules
+	// can override it per path.
+	TextSettings
+}
+
+// Formatter names an in-process formatter for generated files.
+type Formatter string
+
+const (
+	// FormatterGofmt formats Go source like gofmt, including sorting imports.
+	FormatterGofmt Formatter = "gofmt"
+	// FormatterSortImports sorts Go import blocks and leaves the rest of the
+	// file as rendered.
+	FormatterSortImports Formatter = "sort-imports"
+	// FormatterJSON re-indents JSON with two spaces, keeping key order.
+	FormatterJSON Formatter = "json"
+)
+
+// IsValid reports whether the formatter is a known value.
+func (f Formatter) IsValid() bool {
+	return f == FormatterGofmt || f == FormatterSortImports || f == FormatterJSON
+}
+
+// FormatErrorMode is how generation handles a formatter failure.
+type FormatErrorMode string
+
+const (
+	// FormatErrorsWarn reports the failure and keeps the rendered content.
+	FormatErrorsWarn FormatErrorMode = "warn"
+	// FormatErrorsFail stops generation.
+	FormatErrorsFail FormatErrorMode = "fail"
+)
+
+// IsValid reports whether the mode is a known value.
+func (m FormatErrorMode) IsValid() bool {
+	return m == FormatErrorsWarn || m == FormatErrorsFail
+}
+
+// TextSettings normalizes generated text files. Unset fields leave the
+// output as rendered; binary files are never normalized.
+type TextSettings struct {
+	// EOL converts line endings: "lf", "crlf", or "preserve" (default).
+	EOL EOLMode `json:"eol,omitempty"`
+	// StripBOM removes a leading UTF-8 byte order mark.
+	StripBOM *bool `json:"strip_bom,omitempty"`
+	// FinalNewline ensures a non-empty file ends with a line ending.
+	FinalNewline *bool `json:"final_newline,omitempty"`
+	// CollapseAllBlankLines collapses every run of blank lines in the file
+	// into a single blank line: the template's own runs as well as those left
+	// behind by removed @ign-if: blocks.
+	CollapseAllBlankLines *bool `json:"collapse_all_blank_lines,omitempty"`
+}
+
+// IsZero reports whether no text setting is specified.
+func (t TextSettings) IsZero() bool {
+	return t.EOL == "" && t.StripBOM == nil && t.FinalNewline == nil && t.CollapseAllBlankLines == nil
+}
+
+// Overlay returns t with every field that is set in override replaced.
+func (t TextSettings) Overlay(override TextSettings) TextSettings {
+	if override.EOL != "" {
+		t.EOL = override.EOL
+	}
+	if override.StripBOM != nil {
+		t.StripBOM = override.StripBOM
+	}
+	if override.FinalNewline != nil {
+		t.FinalNewline = override.FinalNewline
+	}
+	if override.CollapseAllBlankLines != nil {
+		t.CollapseAllBlankLines = override.CollapseAllBlankLines
+	}
+	return t
+}
+
+// EOLMode is the line ending written to generated text files.
+type EOLMode string
+
+const (
+	// EOLPreserve keeps line endings as rendered.
+	EOLPreserve EOLMode = "preserve"
+	// EOLLF converts line endings to "\n".
+	EOLLF EOLMode = "lf"
+	// EOLCRLF converts line endings to "\r\n".
+	EOLCRLF EOLMode = "crlf"
+)
+
+// IsValid reports whether the mode is a known value.
+func (m EOLMode) IsValid() bool {
+	return m == EOLPreserve || m == EOLLF || m == EOLCRLF
 }
 
 // DefaultPreserveExecutable is the executable-bit preservation default applied when
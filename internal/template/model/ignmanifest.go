@@ -4,4 +4,46 @@ package model
 type IgnManifest struct {
 	// Files contains generated file paths as written during checkout/update.
 	Files []string `json:"files"`
+	// Policies records the declared update policy of files in Files, keyed by
+	// the same path. Files without a declared policy are omitted.
+	Policies map[string]FilePolicy `json:"policies,omitempty"`
+	// Injections records edits ign applied to existing files, so rewind can
+	// undo them.
+	Injections []InjectionRecord `json:"injections,omitempty"`
+	// Declined maps paths whose template change was skipped during an
+	// interactive update to the hash of the declined content. The same
+	// change is not offered again.
+	Declined map[string]string `json:"declined,omitempty"`
+	// Hashes maps paths in Files to the SHA-256 of the content ign last
+	// generated for them (the link target for a symlink), so later commands
+	// can tell which files the user changed without fetching the template.
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// InjectionRecord is one applied injection.
+type InjectionRecord struct {
+	// Path is the edited file, in the same form as Files entries.
+	Path string `json:"path"`
+	// ID is the injection id from the template's inject spec.
+	ID string `json:"id"`
+	// Content is the exact text ign inserted.
+	Content string `json:"content"`
+	// Original is the text a replace injection overwrote. It is empty for
+	// insertions.
+	Original string `json:"original,omitempty"`
+	// After and Before are the anchor regex of an after or before injection.
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+	// Offset is the byte offset of Content in the file when it was injected.
+	// Together with the anchor it tells the injected text apart from
+	// identical text the user wrote.
+	Offset *int `json:"offset,omitempty"`
+}
+
+// PolicyFor returns the recorded policy of a manifest path, if any.
+func (m *IgnManifest) PolicyFor(path string) FilePolicy {
+	if m == nil {
+		return ""
+	}
+	return m.Policies[path]
 }
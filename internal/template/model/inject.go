@@ -0,0 +1,27 @@
+package model
+
+// InjectSpec is the content of a template file ending in IgnInjectSuffix. It
+// lists edits applied, in order, to the existing file the spec targets.
+type InjectSpec struct {
+	Injections []Injection `json:"injections"`
+}
+
+// Injection is one idempotent edit. Exactly one of After, Before, Append, and
+// Replace must be set. An injection whose Content already sits where it would
+// be inserted, or that an earlier run recorded and whose text is still in
+// place, is skipped.
+type Injection struct {
+	// ID identifies the injection in the manifest. It must be unique in a spec.
+	ID string `json:"id"`
+	// After inserts Content on the line after the first line matching this regex.
+	After string `json:"after,omitempty"`
+	// Before inserts Content on the line before the first line matching this regex.
+	Before string `json:"before,omitempty"`
+	// Append adds Content at the end of the file.
+	Append bool `json:"append,omitempty"`
+	// Replace replaces the first match of this regex with Content.
+	Replace string `json:"replace,omitempty"`
+	// Content is the text to insert. Variables are substituted like in any
+	// template file.
+	Content string `json:"content"`
+}
@@ -16,6 +16,25 @@ const (
 	IgnVarFile = "ign-var.json"
 	// IgnManifestFile is the generated file manifest stored in .ign/ directory.
 	IgnManifestFile = "ign-files.json"
+	// IgnProjectOverwriteIgnoreFile is the project-side ignore file in .ign/
+	// directory. Its patterns protect paths from update and rewind in addition
+	// to the template's .ign-overwrite-ignore.
+	IgnProjectOverwriteIgnoreFile = "overwrite-ignore"
+	// IgnLockFile is the advisory lock held in .ign/ directory while a
+	// command changes the project.
+	IgnLockFile = "lock"
+	// IgnJournalFile records, in .ign/ directory, the state of each path a
+	// running command is about to change. It outlives a command that was
+	// killed, so `ign recover` can restore the project.
+	IgnJournalFile = "journal"
+	// IgnHistoryDir holds undo snapshots in .ign/ directory, one subdirectory
+	// per snapshot.
+	IgnHistoryDir = "history"
+	// IgnHistorySnapshotFile describes one snapshot in .ign/history/<id>/.
+	IgnHistorySnapshotFile = "snapshot.json"
+	// IgnInjectSuffix marks a template file that edits an existing project file
+	// instead of producing one: "routes.go.ign-inject" edits "routes.go".
+	IgnInjectSuffix = ".ign-inject"
 )
 
 // VarType represents the type of a template variable.
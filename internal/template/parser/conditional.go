@@ -23,8 +23,10 @@ type conditionalBlock struct {
 }
 
 // processConditionals processes all @ign-if:/@ign-else@/@ign-endif@ blocks in input.
+// Whitespace around the directives is trimmed first (see trimBlockDirectives).
 // Returns the processed content with conditional blocks evaluated.
 func processConditionals(input []byte, vars Variables) ([]byte, error) {
+	input = trimBlockDirectives(input)
 	text := string(input)
 	matches := findDirectives(input)
 
@@ -62,13 +62,22 @@ type DirectiveMatch struct {
 	Args string
 	// RawText is the original matched text.
 	RawText string
+	// TrimLeft is set by a leading trim marker (@-ign-...@). Only block
+	// directives accept trim markers.
+	TrimLeft bool
+	// TrimRight is set by a trailing trim marker (@ign-...-@). Only block
+	// directives without arguments accept it, since variable names may end
+	// with "-".
+	TrimRight bool
 }
 
 var (
 	// Regex patterns for matching directives
-	// Pattern: @ign-DIRECTIVE:ARGS@ or @ign-DIRECTIVE@
+	// Pattern: @ign-DIRECTIVE:ARGS@ or @ign-DIRECTIVE@, with optional trim
+	// markers: @-ign-DIRECTIVE...@ and @ign-DIRECTIVE-@. ARGS is greedy, so a
+	// trailing "-" after ARGS stays part of ARGS.
 	// Note: For @ign-raw:, we need special handling since content can contain @
-	directivePattern = regexp.MustCompile(`@ign-([a-z]+)(?::([^@]*))?@`)
+	directivePattern = regexp.MustCompile(`@(-?)ign-([a-z]+)(?::([^@]*))?(-?)@`)
 
 	// Special pattern for @ign-raw: that allows @ in content
 	// Matches @ign-raw:CONTENT@ where CONTENT can contain @ symbols
@@ -119,10 +128,12 @@ func findDirectives(input []byte) []DirectiveMatch {
 		}
 
 		// match[0], match[1]: full match start, end
-		// match[2], match[3]: directive name group start, end
-		// match[4], match[5]: args group start, end (may be -1 if no args)
+		// match[2], match[3]: leading trim marker group start, end
+		// match[4], match[5]: directive name group start, end
+		// match[6], match[7]: args group start, end (may be -1 if no args)
+		// match[8], match[9]: trailing trim marker group start, end
 
-		directiveName := text[match[2]:match[3]]
+		directiveName := text[match[4]:match[5]]
 
 		// Skip raw directives here since we already handled them
 		if directiveName == "raw" {
@@ -130,20 +141,30 @@ func findDirectives(input []byte) []DirectiveMatch {
 		}
 
 		var args string
-		if match[4] != -1 && match[5] != -1 {
-			args = text[match[4]:match[5]]
+		if match[6] != -1 && match[7] != -1 {
+			args = text[match[6]:match[7]]
 		}
 
 		// Determine directive type
 		dirType := parseDirectiveType(directiveName)
 
+		trimLeft := match[3] > match[2]
+		trimRight := match[9] > match[8]
+		// Trim markers on other directives are not directive syntax; leave
+		// the text as it was before trim markers existed.
+		if (trimLeft || trimRight) && !isBlockDirective(dirType) {
+			continue
+		}
+
 		matches = append(matches, DirectiveMatch{
-			Type:    dirType,
-			Start:   match[0],
-			End:     match[1],
-			Name:    directiveName,
-			Args:    args,
-			RawText: text[match[0]:match[1]],
+			Type:      dirType,
+			Start:     match[0],
+			End:       match[1],
+			Name:      directiveName,
+			Args:      args,
+			RawText:   text[match[0]:match[1]],
+			TrimLeft:  trimLeft,
+			TrimRight: trimRight,
 		})
 	}
 
@@ -203,3 +224,15 @@ func parseDirectiveType(name string) DirectiveType {
 		return DirectiveType(-1) // Unknown directive
 	}
 }
+
+// isBlockDirective reports whether directives of type dt open, continue, or
+// close a block. Block directives produce no output of their own, so
+// trimBlockDirectives removes the whitespace around them.
+func isBlockDirective(dt DirectiveType) bool {
+	switch dt {
+	case DirectiveIf, DirectiveElse, DirectiveEndif:
+		return true
+	default:
+		return false
+	}
+}
@@ -238,35 +238,77 @@ func TestConditionalDirective(t *testing.T) {
 			name:     "if true",
 			input:    "config:\n  @ign-if:use_tls@\n  tls: enabled\n  @ign-endif@",
 			vars:     map[string]interface{}{"use_tls": true},
-			expected: "config:\n  \n  tls: enabled\n  ",
+			expected: "config:\n  tls: enabled\n",
 			wantErr:  false,
 		},
 		{
 			name:     "if false",
 			input:    "config:\n  @ign-if:use_tls@\n  tls: enabled\n  @ign-endif@",
 			vars:     map[string]interface{}{"use_tls": false},
-			expected: "config:\n  ",
+			expected: "config:\n",
 			wantErr:  false,
 		},
 		{
 			name:     "if-else true",
 			input:    "@ign-if:use_cache@\ncache: redis\n@ign-else@\ncache: memory\n@ign-endif@",
 			vars:     map[string]interface{}{"use_cache": true},
-			expected: "\ncache: redis\n",
+			expected: "cache: redis\n",
 			wantErr:  false,
 		},
 		{
 			name:     "if-else false",
 			input:    "@ign-if:use_cache@\ncache: redis\n@ign-else@\ncache: memory\n@ign-endif@",
 			vars:     map[string]interface{}{"use_cache": false},
-			expected: "\ncache: memory\n",
+			expected: "cache: memory\n",
 			wantErr:  false,
 		},
 		{
 			name:     "nested conditionals",
 			input:    "@ign-if:enable_api@\napi: true\n@ign-if:api_auth@\nauth: jwt\n@ign-endif@\n@ign-endif@",
 			vars:     map[string]interface{}{"enable_api": true, "api_auth": true},
-			expected: "\napi: true\n\nauth: jwt\n\n",
+			expected: "api: true\nauth: jwt\n",
+			wantErr:  false,
+		},
+		{
+			name:     "standalone lines with crlf",
+			input:    "a:\r\n  @ign-if:use_tls@  \r\n  tls: on\r\n  @ign-endif@\r\nb: 1\r\n",
+			vars:     map[string]interface{}{"use_tls": true},
+			expected: "a:\r\n  tls: on\r\nb: 1\r\n",
+			wantErr:  false,
+		},
+		{
+			name:     "inline block is not trimmed",
+			input:    "port: @ign-if:use_tls@443@ign-else@80@ign-endif@\n",
+			vars:     map[string]interface{}{"use_tls": false},
+			expected: "port: 80\n",
+			wantErr:  false,
+		},
+		{
+			name:     "nested false block leaves outer lines trimmed",
+			input:    "@ign-if:outer@\nx\n  @ign-if:inner@\n  y\n  @ign-endif@\n@ign-endif@\nz\n",
+			vars:     map[string]interface{}{"outer": true, "inner": false},
+			expected: "x\nz\n",
+			wantErr:  false,
+		},
+		{
+			name:     "leading trim marker",
+			input:    "deps = [\"a\"\n    @-ign-if:extra@, \"b\"@ign-endif@]\n",
+			vars:     map[string]interface{}{"extra": true},
+			expected: "deps = [\"a\", \"b\"]\n",
+			wantErr:  false,
+		},
+		{
+			name:     "trailing trim marker",
+			input:    "@ign-if:debug@debug: true@ign-else-@\n\n  debug: false@ign-endif-@\n\nnext\n",
+			vars:     map[string]interface{}{"debug": false},
+			expected: "debug: falsenext\n",
+			wantErr:  false,
+		},
+		{
+			name:     "trim marker on var is literal",
+			input:    "@-ign-var:name@",
+			vars:     map[string]interface{}{"name": "x"},
+			expected: "@-ign-var:name@",
 			wantErr:  false,
 		},
 		{
@@ -525,6 +567,11 @@ func TestExtractVariables(t *testing.T) {
 			input:    "@ign-if:debug@Debug: @ign-var:level@@ign-endif@",
 			expected: []string{"debug", "level"},
 		},
+		{
+			name:     "conditional with trim markers",
+			input:    "@-ign-if:debug@x@ign-endif-@",
+			expected: []string{"debug"},
+		},
 		{
 			name:     "comment directive not treated as variable",
 			input:    "@ign-comment:this is just a comment@\n@ign-var:name@",
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/tacogips/ign/internal/debug"
+)
+
+// trimBlockDirectives removes the whitespace that block directives would
+// otherwise leave in the output, and strips their trim markers:
+//
+//   - A block directive without trim markers that is alone on its line
+//     (only spaces or tabs around it) takes the whole line with it,
+//     including the line ending.
+//   - @-ign-...@ removes all whitespace, including newlines, before the
+//     directive, and @ign-...-@ removes all whitespace after it. A directive
+//     with a trim marker is not subject to standalone-line trimming.
+//
+// The trimming is decided once on the input, before any block is evaluated,
+// so removing an inner block never makes an outer directive standalone.
+func trimBlockDirectives(input []byte) []byte {
+	text := string(input)
+	matches := findDirectives(input)
+
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0 // end of the text already copied to b
+	trimmed := 0
+	for _, match := range matches {
+		if !isBlockDirective(match.Type) {
+			continue
+		}
+
+		start, end := match.Start, match.End
+		if match.TrimLeft || match.TrimRight {
+			if match.TrimLeft {
+				for start > last && isTrimSpace(text[start-1]) {
+					start--
+				}
+			}
+			if match.TrimRight {
+				for end < len(text) && isTrimSpace(text[end]) {
+					end++
+				}
+			}
+		} else if lineStart, lineEnd, ok := standaloneLine(text, match.Start, match.End, last); ok {
+			start, end = lineStart, lineEnd
+		}
+		if start != match.Start || end != match.End {
+			trimmed++
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString(untrimmedDirective(match))
+		last = end
+	}
+	b.WriteString(text[last:])
+
+	debug.Debug("[parser] trimBlockDirectives: trimmed whitespace around %d block directive(s)", trimmed)
+	return []byte(b.String())
+}
+
+// standaloneLine returns the bounds of the line holding the directive at
+// [start, end), including its line ending, if the directive is the only
+// non-blank content on that line. lower is the earliest offset the line may
+// start at; a line partly consumed by an earlier directive is not standalone.
+func standaloneLine(text string, start, end, lower int) (int, int, bool) {
+	lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+	if lineStart < lower || strings.Trim(text[lineStart:start], " \t") != "" {
+		return 0, 0, false
+	}
+
+	lineEnd := len(text)
+	if i := strings.IndexByte(text[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if strings.TrimRight(text[end:lineEnd], " \t\r\n") != "" {
+		return 0, 0, false
+	}
+	return lineStart, lineEnd, true
+}
+
+// untrimmedDirective returns the directive text without its trim markers.
+func untrimmedDirective(match DirectiveMatch) string {
+	raw := match.RawText
+	if match.TrimLeft {
+		raw = "@" + raw[2:]
+	}
+	if match.TrimRight {
+		raw = raw[:len(raw)-2] + "@"
+	}
+	return raw
+}
+
+func isTrimSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -23,6 +24,11 @@ type Variables interface {
 	// Returns error if variable not found or type mismatch.
 	GetInt(name string) (int, error)
 
+	// GetNumber retrieves a floating-point number variable.
+	// Integer values are widened to float64.
+	// Returns error if variable not found or type mismatch.
+	GetNumber(name string) (float64, error)
+
 	// GetBool retrieves a boolean variable.
 	// Returns error if variable not found or type mismatch.
 	GetBool(name string) (bool, error)
@@ -88,7 +94,11 @@ func (m *MapVariables) GetInt(name string) (int, error) {
 	case int:
 		return v, nil
 	case float64:
-		// JSON unmarshaling produces float64 for all numbers
+		// JSON unmarshaling produces float64 for all numbers; reject fractional
+		// values instead of silently truncating them.
+		if !isIntegral(v) {
+			return 0, fmt.Errorf("variable %s is not an integer (got %s)", name, valueToString(v))
+		}
 		return int(v), nil
 	case int64:
 		return int(v), nil
@@ -99,6 +109,19 @@ func (m *MapVariables) GetInt(name string) (int, error) {
 	}
 }
 
+// GetNumber retrieves a floating-point number variable.
+func (m *MapVariables) GetNumber(name string) (float64, error) {
+	val, ok := m.data[name]
+	if !ok {
+		return 0, fmt.Errorf("variable not found: %s", name)
+	}
+
+	if f, ok := toFloat64(val); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("variable %s is not a number (got %T)", name, val)
+}
+
 // GetBool retrieves a boolean variable.
 func (m *MapVariables) GetBool(name string) (bool, error) {
 	val, ok := m.data[name]
@@ -225,8 +248,8 @@ func parseVarSyntax(args string) (name string, varType string, defaultValue inte
 		varType = strings.TrimSpace(args[colonIdx+1:])
 
 		// Validate type
-		if varType != "" && varType != "string" && varType != "int" && varType != "bool" {
-			err = fmt.Errorf("invalid type %q (must be string, int, or bool)", varType)
+		if varType != "" && varType != "string" && varType != "int" && varType != "number" && varType != "bool" {
+			err = fmt.Errorf("invalid type %q (must be string, int, number, or bool)", varType)
 			return
 		}
 	} else {
@@ -260,6 +283,9 @@ func parseVarSyntax(args string) (name string, varType string, defaultValue inte
 // - "true"/"false" -> bool
 // - numeric string -> int
 // - otherwise -> string
+//
+// Decimal strings such as "0.5" stay strings here so that untyped defaults like
+// versions ("1.0") render verbatim; an explicit :number annotation coerces them.
 func parseDefaultValue(value string) interface{} {
 	value = strings.TrimSpace(value)
 
@@ -285,8 +311,14 @@ func inferType(val interface{}) string {
 	switch val.(type) {
 	case bool:
 		return "bool"
-	case int, int64, int32, float64:
+	case int, int64, int32:
 		return "int"
+	case float64, float32:
+		f, _ := toFloat64(val)
+		if isIntegral(f) {
+			return "int"
+		}
+		return "number"
 	default:
 		return "string"
 	}
@@ -304,12 +336,23 @@ func coerceValue(val interface{}, targetType string) (interface{}, error) {
 		case int64:
 			return int(v), nil
 		case float64:
+			if !isIntegral(v) {
+				return nil, fmt.Errorf("cannot coerce %s to int", valueToString(v))
+			}
 			return int(v), nil
 		case string:
 			return strconv.Atoi(v)
 		default:
 			return nil, fmt.Errorf("cannot coerce %T to int", val)
 		}
+	case "number":
+		if str, ok := val.(string); ok {
+			return parseNumber(str)
+		}
+		if f, ok := toFloat64(val); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("cannot coerce %T to number", val)
 	case "bool":
 		switch v := val.(type) {
 		case bool:
@@ -331,11 +374,20 @@ func validateVarType(name string, val interface{}, expectedType string) error {
 	// Special handling for numeric types (JSON unmarshaling produces float64)
 	if expectedType == "int" {
 		switch val.(type) {
-		case int, int64, int32, float64:
+		case int, int64, int32, float64, float32:
+			if actualType == "int" {
+				return nil
+			}
+		}
+		return fmt.Errorf("variable %s: type mismatch, expected %s but got %s", name, expectedType, actualType)
+	}
+
+	// Any numeric value satisfies number; integers are widened on render.
+	if expectedType == "number" {
+		if _, ok := toFloat64(val); ok {
 			return nil
-		default:
-			return fmt.Errorf("variable %s: type mismatch, expected %s but got %s", name, expectedType, actualType)
 		}
+		return fmt.Errorf("variable %s: type mismatch, expected %s but got %s", name, expectedType, actualType)
 	}
 
 	if actualType != expectedType {
@@ -345,6 +397,11 @@ func validateVarType(name string, val interface{}, expectedType string) error {
 	return nil
 }
 
+// FormatValue returns a variable value as @ign-var: renders it.
+func FormatValue(val interface{}) string {
+	return valueToString(val)
+}
+
 // valueToString converts a variable value to its string representation.
 func valueToString(val interface{}) string {
 	switch v := val.(type) {
@@ -354,15 +411,53 @@ func valueToString(val interface{}) string {
 		return strconv.Itoa(v)
 	case int64:
 		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
 	case float64:
-		// Convert float to int if it's a whole number (for JSON numbers)
-		if v == float64(int(v)) {
-			return strconv.Itoa(int(v))
-		}
+		// Shortest decimal form that round-trips, never exponent notation:
+		// 30.0 -> "30", 0.1 -> "0.1", 1e21 -> "1000000000000000000000".
 		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
 	case bool:
 		return strconv.FormatBool(v)
 	default:
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+// parseNumber parses a decimal number string, rejecting NaN and infinities
+// which have no stable textual representation in rendered files.
+func parseNumber(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return f, nil
+}
+
+// toFloat64 widens any supported numeric value to float64.
+func toFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// isIntegral reports whether a float has no fractional part.
+func isIntegral(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0) && f == math.Trunc(f)
+}
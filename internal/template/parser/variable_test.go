@@ -53,6 +53,48 @@ func TestExtendedVarSyntax(t *testing.T) {
 			expected: "true",
 			wantErr:  false,
 		},
+		{
+			name:     "type annotation - number",
+			input:    "@ign-var:ratio:number@",
+			vars:     map[string]interface{}{"ratio": 0.75},
+			expected: "0.75",
+			wantErr:  false,
+		},
+		{
+			name:     "type annotation - number accepts whole value",
+			input:    "@ign-var:timeout:number@",
+			vars:     map[string]interface{}{"timeout": float64(30)},
+			expected: "30",
+			wantErr:  false,
+		},
+		{
+			name:     "type annotation - number with default",
+			input:    "@ign-var:ratio:number=0.5@",
+			vars:     map[string]interface{}{},
+			expected: "0.5",
+			wantErr:  false,
+		},
+		{
+			name:     "type annotation - number with invalid default",
+			input:    "@ign-var:ratio:number=half@",
+			vars:     map[string]interface{}{},
+			expected: "",
+			wantErr:  true,
+		},
+		{
+			name:     "type annotation - int rejects fractional value",
+			input:    "@ign-var:port:int@",
+			vars:     map[string]interface{}{"port": 80.5},
+			expected: "",
+			wantErr:  true,
+		},
+		{
+			name:     "untyped decimal default renders verbatim",
+			input:    "@ign-var:version=1.0@",
+			vars:     map[string]interface{}{},
+			expected: "1.0",
+			wantErr:  false,
+		},
 		{
 			name:     "type annotation - type mismatch",
 			input:    "@ign-var:port:string@",
@@ -471,6 +513,34 @@ func TestValidateVarType(t *testing.T) {
 			expectedType: "int",
 			wantErr:      false,
 		},
+		{
+			name:         "fractional float64 as int",
+			varName:      "num",
+			value:        float64(4.2),
+			expectedType: "int",
+			wantErr:      true,
+		},
+		{
+			name:         "float64 as number",
+			varName:      "ratio",
+			value:        float64(0.5),
+			expectedType: "number",
+			wantErr:      false,
+		},
+		{
+			name:         "int as number",
+			varName:      "timeout",
+			value:        30,
+			expectedType: "number",
+			wantErr:      false,
+		},
+		{
+			name:         "type mismatch - string expected number",
+			varName:      "ratio",
+			value:        "0.5",
+			expectedType: "number",
+			wantErr:      true,
+		},
 		{
 			name:         "bool valid",
 			varName:      "debug",
@@ -543,6 +613,11 @@ func TestInferType(t *testing.T) {
 		{
 			name:     "float64",
 			value:    float64(3.14),
+			expected: "number",
+		},
+		{
+			name:     "whole float64",
+			value:    float64(8080),
 			expected: "int",
 		},
 		{
@@ -562,3 +637,54 @@ func TestInferType(t *testing.T) {
 		})
 	}
 }
+
+// TestMapVariablesGetNumber tests numeric accessors on MapVariables
+func TestMapVariablesGetNumber(t *testing.T) {
+	vars := NewMapVariables(map[string]interface{}{
+		"ratio":   0.25,
+		"timeout": 30,
+		"name":    "app",
+	})
+
+	ratio, err := vars.GetNumber("ratio")
+	if err != nil || ratio != 0.25 {
+		t.Errorf("GetNumber(ratio) = %v, %v; want 0.25, nil", ratio, err)
+	}
+
+	timeout, err := vars.GetNumber("timeout")
+	if err != nil || timeout != 30 {
+		t.Errorf("GetNumber(timeout) = %v, %v; want 30, nil", timeout, err)
+	}
+
+	if _, err := vars.GetNumber("name"); err == nil {
+		t.Error("expected error for non-numeric variable")
+	}
+	if _, err := vars.GetNumber("missing"); err == nil {
+		t.Error("expected error for missing variable")
+	}
+
+	if _, err := vars.GetInt("ratio"); err == nil {
+		t.Error("expected GetInt to reject fractional value instead of truncating")
+	}
+}
+
+// TestValueToStringNumbers tests stable formatting of numeric values
+func TestValueToStringNumbers(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{float64(30), "30"},
+		{0.1, "0.1"},
+		{1.5e-7, "0.00000015"},
+		{1e21, "1000000000000000000000"},
+		{float32(0.1), "0.1"},
+		{int32(7), "7"},
+	}
+
+	for _, tt := range tests {
+		if got := valueToString(tt.value); got != tt.expected {
+			t.Errorf("valueToString(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}